@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/exchange/derive"
+	"github.com/wakamex/atomizer/internal/margin"
+)
+
+func runCollateral(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: atomizer collateral <status|transfer|rebalance> [options]")
+		fmt.Println("  status     Show collateral and margin usage of each subaccount")
+		fmt.Println("  transfer   Move USDC between subaccounts (--from, --to, --amount)")
+		fmt.Println("  rebalance  Move USDC so each subaccount's margin usage stays in a band")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("collateral "+args[0], flag.ExitOnError)
+	derivePrivateKey := fs.String("derive-private-key", os.Getenv("DERIVE_PRIVATE_KEY"), "Derive private key (hex)")
+	deriveWalletAddress := fs.String("derive-wallet-address", os.Getenv("DERIVE_WALLET_ADDRESS"), "Derive wallet address")
+
+	// Transfer flags
+	from := fs.Uint64("from", 0, "Subaccount to transfer from")
+	to := fs.Uint64("to", 0, "Subaccount to transfer to")
+	amount := fs.Float64("amount", 0, "USDC to transfer")
+
+	// Rebalance flags
+	low := fs.Float64("low", 0.3, "Margin usage below which a subaccount funds others")
+	target := fs.Float64("target", 0.5, "Margin usage rebalanced subaccounts are moved towards")
+	high := fs.Float64("high", 0.7, "Margin usage above which a subaccount is topped up")
+	minAmount := fs.Float64("min-amount", 10, "Smallest transfer worth making in USDC")
+	watch := fs.Duration("watch", 0, "Keep rebalancing at this interval instead of once")
+	dryRun := fs.Bool("dry-run", false, "Log the transfers rebalancing would make without making them")
+
+	fs.Parse(args[1:])
+
+	if *derivePrivateKey == "" || *deriveWalletAddress == "" {
+		log.Fatal("Derive requires DERIVE_PRIVATE_KEY and DERIVE_WALLET_ADDRESS")
+	}
+	deriveExchange, err := derive.NewDeriveMarketMakerExchange(*derivePrivateKey, *deriveWalletAddress)
+	if err != nil {
+		log.Fatalf("Failed to create Derive exchange: %v", err)
+	}
+
+	switch args[0] {
+	case "status":
+		printCollateralStatus(deriveExchange)
+
+	case "transfer":
+		if *from == 0 || *to == 0 || *amount <= 0 {
+			log.Fatal("Transfer requires --from, --to and a positive --amount")
+		}
+		if err := deriveExchange.Transfer(*from, *to, decimal.NewFromFloat(*amount)); err != nil {
+			log.Fatalf("Transfer failed: %v", err)
+		}
+		log.Printf("Transferred %.2f USDC from subaccount %d to %d", *amount, *from, *to)
+
+		// Give the transfer a moment to settle before reading balances back
+		time.Sleep(time.Second)
+		printCollateralStatus(deriveExchange)
+
+	case "rebalance":
+		band := margin.Band{
+			Low:    decimal.NewFromFloat(*low),
+			Target: decimal.NewFromFloat(*target),
+			High:   decimal.NewFromFloat(*high),
+		}
+		if err := band.Validate(); err != nil {
+			log.Fatalf("Invalid band: %v", err)
+		}
+		rebalancer := margin.NewRebalancer(deriveExchange, band, decimal.NewFromFloat(*minAmount), *watch, *dryRun)
+
+		if *watch <= 0 {
+			transfers, err := rebalancer.Rebalance()
+			if err != nil {
+				log.Fatalf("Rebalance failed: %v", err)
+			}
+			if len(transfers) == 0 {
+				log.Println("All subaccounts are inside the margin usage band")
+			}
+			printCollateralStatus(deriveExchange)
+			return
+		}
+
+		log.Printf("Rebalancing every %v to keep margin usage between %.0f%% and %.0f%%", *watch, *low*100, *high*100)
+		rebalancer.Start()
+		defer rebalancer.Stop()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+
+	default:
+		fmt.Printf("Unknown collateral command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// printCollateralStatus prints each managed subaccount's collateral and margin
+func printCollateralStatus(deriveExchange *derive.DeriveMarketMakerExchange) {
+	margins, err := deriveExchange.GetAllMargins()
+	if err != nil {
+		log.Fatalf("Failed to get margins: %v", err)
+	}
+
+	fmt.Printf("%-12s %14s %14s %14s %14s %8s\n", "SUBACCOUNT", "COLLATERAL", "VALUE", "INITIAL", "AVAILABLE", "USAGE")
+	for _, state := range margins {
+		fmt.Printf("%-12d %14s %14s %14s %14s %7s%%\n",
+			state.SubaccountID,
+			state.Collateral.StringFixed(2),
+			state.SubaccountValue.StringFixed(2),
+			state.InitialMargin.StringFixed(2),
+			state.AvailableMargin.StringFixed(2),
+			state.Utilization().Mul(decimal.NewFromInt(100)).StringFixed(1))
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,15 +20,24 @@ import (
 	// Import internal packages
 	"github.com/wakamex/atomizer/internal/api"
 	"github.com/wakamex/atomizer/internal/arbitrage"
+	"github.com/wakamex/atomizer/internal/cache"
 	"github.com/wakamex/atomizer/internal/config"
 	"github.com/wakamex/atomizer/internal/exchange"
 	"github.com/wakamex/atomizer/internal/hedging"
 	"github.com/wakamex/atomizer/internal/hedging/gamma"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/journal"
 	"github.com/wakamex/atomizer/internal/manual"
+	"github.com/wakamex/atomizer/internal/margin"
+	"github.com/wakamex/atomizer/internal/marketdata"
 	"github.com/wakamex/atomizer/internal/marketmaker"
+	"github.com/wakamex/atomizer/internal/policy"
+	"github.com/wakamex/atomizer/internal/pricing"
+	"github.com/wakamex/atomizer/internal/quoter"
 	"github.com/wakamex/atomizer/internal/rfq"
 	"github.com/wakamex/atomizer/internal/risk"
 	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/atomizer/internal/volsurface"
 	"github.com/wakamex/atomizer/internal/websocket"
 )
 
@@ -39,6 +51,7 @@ func main() {
 		fmt.Println("  manual-order      Place a manual order")
 		fmt.Println("  pure-gamma-hedger Run the pure gamma hedger (closes perp positions when no options exist)")
 		fmt.Println("                    Options: --delta-threshold, --min-hedge-size, --hedge-interval, --aggressiveness")
+		fmt.Println("  collateral        Show, transfer or rebalance collateral between Derive subaccounts")
 		os.Exit(1)
 	}
 
@@ -51,6 +64,8 @@ func main() {
 		runManualOrder(os.Args[2:])
 	case "pure-gamma-hedger":
 		runPureGammaHedger(os.Args[2:])
+	case "collateral":
+		runCollateral(os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		os.Exit(1)
@@ -80,16 +95,21 @@ func runMarketMaker(args []string) {
 	// Risk parameters
 	maxPosition := fs.Float64("max-position", 10.0, "Maximum position per instrument")
 	maxExposure := fs.Float64("max-exposure", 100.0, "Maximum total exposure")
+	maxMarginUsage := fs.Float64("max-margin-usage", 0.8, "Stop quoting at this share of the subaccount value used as margin (0 = no limit)")
 	
 	// Aggression parameter
 	aggression := fs.Float64("aggression", 1.0, "Aggression level: 0=join best, 0.9=near mid, 1.0+=cross spread (default: 1.0)")
 	
 	// Operational parameters
 	refresh := fs.Int("refresh", 5, "Refresh interval in seconds")
-	dryRun := fs.Bool("dry-run", false, "Dry run mode (no real orders)")
+	dryRun := fs.Bool("dry-run", false, "Paper trade against live market data (no real orders)")
+	paperLatency := fs.Duration("paper-latency", 100*time.Millisecond, "Simulated order latency in dry run mode")
+	recordPath := fs.String("record", "", "Record all exchange calls and market data to this file")
+	replayPath := fs.String("replay", "", "Replay a recording instead of connecting to the exchange")
 	test := fs.Bool("test", false, "Use test environment")
 	bidOnly := fs.Bool("bid-only", false, "Only place bid orders (buy side)")
 	askOnly := fs.Bool("ask-only", false, "Only place ask orders (sell side)")
+	subaccount := fs.Uint64("subaccount", 0, "Derive subaccount to trade from (default DERIVE_SUBACCOUNT_ID or the wallet's first)")
 	
 	fs.Parse(args)
 	
@@ -109,19 +129,20 @@ func runMarketMaker(args []string) {
 	}
 	
 	// Build instrument list
-	instruments := buildInstrumentList(*underlying, *expiry, *strikes, *allStrikes)
+	instrumentList := buildInstrumentList(*exchangeName, *underlying, *expiry, *strikes, *allStrikes)
 	
 	// Create market maker config
 	config := &types.MarketMakerConfig{
 		Exchange:         *exchangeName,
 		ExchangeTestMode: *test,
-		Instruments:      instruments,
+		Instruments:      instrumentList,
 		SpreadBps:        *spread,
 		MinSpreadBps:     *minSpread,
 		QuoteSize:        decimal.NewFromFloat(*size),
 		RefreshInterval:  time.Duration(*refresh) * time.Second,
 		MaxPositionSize:  decimal.NewFromFloat(*maxPosition),
 		MaxTotalExposure: decimal.NewFromFloat(*maxExposure),
+		MaxMarginUsage:   decimal.NewFromFloat(*maxMarginUsage),
 		Improvement:      decimal.NewFromFloat(*improvement),
 		ImprovementReferenceSize: decimal.NewFromFloat(*improvementRefSize),
 		CancelThreshold:  decimal.NewFromFloat(0.005), // 0.5% default
@@ -129,12 +150,16 @@ func runMarketMaker(args []string) {
 		Aggression:       decimal.NewFromFloat(*aggression),
 		BidOnly:          *bidOnly,
 		AskOnly:          *askOnly,
-		// DryRun: *dryRun, // TODO: Add dry run support
+		DryRun:           *dryRun,
+		PaperLatency:     *paperLatency,
+		RecordPath:       *recordPath,
+		ReplayPath:       *replayPath,
+		Subaccount:       *subaccount,
 	}
 	
 	// Show dry run warning if enabled
 	if *dryRun {
-		log.Println("WARNING: Running in DRY RUN mode - no real orders will be placed")
+		log.Println("WARNING: Running in DRY RUN mode - orders are simulated on a paper exchange")
 	}
 	
 	// Create exchange
@@ -144,11 +169,22 @@ func runMarketMaker(args []string) {
 		log.Fatalf("Failed to create exchange: %v", err)
 	}
 	
-	// Create market maker
+	// Create market maker, streaming tickers through a market data hub
 	mm := marketmaker.NewMarketMaker(config, exchangeImpl)
+	mm.SetMarketData(marketdata.NewHub(exchangeImpl))
+	
+	// Pause quoting when margin runs short, on exchanges that report it
+	if source, ok := exchangeImpl.(margin.Source); ok {
+		monitor := margin.NewMonitor(source, config.MaxMarginUsage, margin.DefaultInterval)
+		if err := monitor.Start(); err != nil {
+			log.Printf("Margin monitoring disabled: %v", err)
+		} else {
+			mm.SetMarginMonitor(monitor)
+		}
+	}
 	
 	// Start market maker
-	log.Printf("Starting market maker with %d instruments...", len(instruments))
+	log.Printf("Starting market maker with %d instruments...", len(instrumentList))
 	log.Printf("Spread: %d bps, Size: %.2f, Refresh: %ds", *spread, *size, *refresh)
 	
 	if err := mm.Start(); err != nil {
@@ -160,23 +196,36 @@ func runMarketMaker(args []string) {
 	select {} // Block forever until killed
 }
 
-func buildInstrumentList(underlying, expiry, strikes string, allStrikes bool) []string {
-	var instruments []string
+func buildInstrumentList(exchangeName, underlying, expiry, strikes string, allStrikes bool) []string {
+	var instrumentList []string
 	
 	if strikes != "" {
+		expiryDate, err := time.Parse("20060102", expiry)
+		if err != nil {
+			log.Printf("Warning: Invalid expiry %q, expected YYYYMMDD", expiry)
+			return []string{}
+		}
+		
 		// Parse comma-separated strikes
 		strikeList := strings.Split(strikes, ",")
 		for _, strike := range strikeList {
 			strike = strings.TrimSpace(strike)
-			if strike != "" {
-				// Add both call and put for each strike
-				instruments = append(instruments, 
-					fmt.Sprintf("%s-%s-%s-C", underlying, expiry, strike),
-					fmt.Sprintf("%s-%s-%s-P", underlying, expiry, strike),
-				)
+			if strike == "" {
+				continue
+			}
+			strikeDec, err := decimal.NewFromString(strike)
+			if err != nil {
+				log.Printf("Warning: Skipping invalid strike %q", strike)
+				continue
+			}
+			
+			// Add both call and put for each strike, named for the exchange
+			for _, isPut := range []bool{false, true} {
+				option := instruments.NewOption(strings.ToUpper(underlying), strikeDec, expiryDate, isPut)
+				instrumentList = append(instrumentList, option.NameOn(exchangeName))
 			}
 		}
-		return instruments
+		return instrumentList
 	}
 	
 	if allStrikes {
@@ -197,19 +246,51 @@ func runRFQResponder(args []string) {
 	// WebSocket configuration
 	wsURL := fs.String("ws-url", "wss://rip-testnet.rysk.finance/maker", "WebSocket URL for RFQ stream")
 	rfqAssets := fs.String("rfq-assets", "", "Comma-separated list of asset addresses for RFQ streams")
+	assetsURL := fs.String("assets-url", rfq.DefaultAssetsURL, "Rysk asset list mapping asset addresses to underlyings per chain")
+	assetRefresh := fs.Duration("asset-refresh", rfq.DefaultAssetRefresh, "How often to reload the Rysk asset list")
 	
 	// Exchange configuration
 	exchangeName := fs.String("exchange", "derive", "Exchange to use (derive, deribit)")
 	testMode := fs.Bool("test", false, "Use exchange testnet")
+	dryRun := fs.Bool("dry-run", false, "Sign and log quotes without sending them, and paper trade hedges against live market data")
+	subaccount := fs.Uint64("subaccount", 0, "Derive subaccount to hedge from (default DERIVE_SUBACCOUNT_ID or the wallet's first)")
+	hedgeVenues := fs.String("hedge-venues", "", "Comma-separated exchanges option hedges may also be routed to (e.g. deribit)")
+	hedgeFillTimeout := fs.Duration("hedge-fill-timeout", hedging.DefaultFillTimeout, "How long a hedge order rests unfilled before it is cancelled and repriced")
+	hedgeReprices := fs.Int("hedge-reprices", hedging.DefaultMaxReprices, "Reprices of an unfilled hedge before it is escalated")
 	
 	// Trading configuration
 	dummyPrice := fs.String("dummy-price", "1000000", "Fallback price for quotes")
 	quoteDuration := fs.Int64("quote-duration", 30, "Quote validity duration in seconds")
 	
+	// Pricing configuration
+	pricingMode := fs.String("pricing-mode", "book", "Quote pricing mode (book, model, blend)")
+	volSource := fs.String("vol-source", "derive", "Exchange to build the vol surface from (derive, deribit)")
+	modelWeight := fs.Float64("model-weight", 0.5, "Weight of the model price in blend mode (0-1)")
+	edgeBps := fs.Float64("edge-bps", 0, "Quote edge in bps of underlying notional")
+	edgeSizeBps := fs.Float64("edge-size-bps", 0, "Additional quote edge in bps per contract of size")
+	minEdge := fs.Float64("min-edge", 0, "Minimum quote edge per contract in USD")
+	
 	// Risk configuration
 	maxDelta := fs.Float64("max-delta", 10.0, "Maximum position delta exposure")
 	enableGamma := fs.Bool("enable-gamma", false, "Enable gamma hedging")
 	gammaThreshold := fs.Float64("gamma-threshold", 0.1, "Gamma threshold for hedging")
+	maxMarginUsage := fs.Float64("max-margin-usage", 0.8, "Stop responding to RFQs at this share of the subaccount value used as margin (0 = no limit)")
+	
+	// Quoting policy
+	maxSize := fs.String("max-size", "", "Largest RFQ to quote per underlying, e.g. ETH=50,BTC=2,*=10")
+	minExpiry := fs.Duration("min-expiry", 0, "Decline RFQs expiring sooner than this")
+	maxExpiry := fs.Duration("max-expiry", 0, "Decline RFQs expiring later than this")
+	minMoneyness := fs.Float64("min-moneyness", 0, "Decline RFQs with strike/spot below this")
+	maxMoneyness := fs.Float64("max-moneyness", 0, "Decline RFQs with strike/spot above this")
+	minAPR := fs.Float64("min-apr", 0, "Minimum taker APR in percent; sells are raised to it, buys under it declined")
+	allowTakers := fs.String("allow-takers", "", "Comma-separated taker addresses to quote exclusively")
+	denyTakers := fs.String("deny-takers", "", "Comma-separated taker addresses never to quote")
+	minBookCoverage := fs.Float64("min-book-coverage", 0, "Decline RFQs unless the hedge book holds this multiple of their size")
+	checkRisk := fs.Bool("check-risk", true, "Decline RFQs whose fill would breach risk limits")
+	dummyQuotes := fs.Bool("dummy-quotes", false, "Answer RFQs that can't be priced with --dummy-price instead of declining them")
+	
+	// Trade journal
+	journalPath := fs.String("journal", "atomizer-journal.jsonl", "Append-only trade journal, replayed on startup to resume unhedged trades (empty keeps trades in memory)")
 	
 	// API configuration
 	httpPort := fs.Int("http-port", 8080, "Port for HTTP API server")
@@ -238,11 +319,20 @@ func runRFQResponder(args []string) {
 		PrivateKey:                privateKey,
 		ExchangeName:              *exchangeName,
 		ExchangeTestMode:          *testMode,
+		DryRun:                    *dryRun,
+		DeriveSubaccount:          *subaccount,
 		DummyPrice:                *dummyPrice,
 		QuoteValidDurationSeconds: *quoteDuration,
+		PricingMode:               *pricingMode,
+		VolSource:                 *volSource,
+		ModelWeight:               *modelWeight,
+		EdgeBps:                   *edgeBps,
+		EdgeSizeBps:               *edgeSizeBps,
+		MinEdge:                   *minEdge,
 		MaxPositionDelta:          *maxDelta,
 		EnableGammaHedging:        *enableGamma,
 		GammaThreshold:            *gammaThreshold,
+		MaxMarginUsage:            *maxMarginUsage,
 		HTTPPort:                  fmt.Sprintf("%d", *httpPort),
 		EnableManualTrades:        *enableManual,
 		AssetMapping:              config.DefaultAssetMapping, // Use default mappings
@@ -253,22 +343,112 @@ func runRFQResponder(args []string) {
 		log.Fatalf("Failed to parse private key: %v", err)
 	}
 	
+	// Resolve Rysk asset addresses with the configured mapping, overridden per
+	// chain by Rysk's asset list
+	instruments.SetRyskAssets(cfg.AssetMapping)
+	assetMapper := rfq.NewAssetMapper(*assetsURL, newMarketCache(), *assetRefresh)
+	if err := assetMapper.Load(); err != nil {
+		log.Printf("Failed to load Rysk asset list, using default asset mappings: %v", err)
+	}
+	assetMapper.Start(*assetRefresh)
+	defer assetMapper.Stop()
+	
 	// Create exchange
 	exchange, err := createExchange(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create exchange: %v", err)
 	}
 	
-	// Create components
+	// Create components, pricing Greeks from the exchange's own tickers
+	pricer := pricing.NewEngine(pricing.NewTickerSource(cfg.ExchangeName, cfg.ExchangeTestMode))
 	riskManager := risk.NewManager(cfg)
+	riskManager.SetPricingEngine(pricer)
 	hedgeManager := hedging.NewManager(exchange, cfg)
+	hedgeManager.SetPositionRecorder(riskManager)
+	hedgeManager.SetFillPolicy(*hedgeFillTimeout, *hedgeReprices)
+	defer hedgeManager.Stop()
+	for _, name := range strings.Split(*hedgeVenues, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == cfg.ExchangeName {
+			continue
+		}
+		venueCfg := *cfg
+		venueCfg.ExchangeName = name
+		venueExchange, err := createExchange(&venueCfg)
+		if err != nil {
+			log.Fatalf("Failed to create hedge venue %s: %v", name, err)
+		}
+		hedgeManager.AddVenue(name, venueExchange)
+		log.Printf("Routing option hedges between %s and %s", cfg.ExchangeName, name)
+	}
 	gammaModule := gamma.NewModule(cfg.GammaThreshold)
 	gammaHedger := gamma.NewHedger(exchange, cfg, hedgeManager)
+	gammaHedger.SetPricingEngine(pricer)
+	
+	// Share one ticker stream per instrument between the hedger and quoter
+	var marketData *marketdata.Hub
+	if source, ok := exchange.(marketdata.TickerSource); ok {
+		marketData = marketdata.NewHub(source)
+		defer marketData.Close()
+		gammaHedger.SetMarketData(marketData)
+	}
+	
+	// Pause RFQ responses when margin runs short, on exchanges that report it
+	var marginMonitor *margin.Monitor
+	if source, ok := exchange.(margin.Source); ok {
+		monitor := margin.NewMonitor(source, decimal.NewFromFloat(cfg.MaxMarginUsage), margin.DefaultInterval)
+		if err := monitor.Start(); err != nil {
+			log.Printf("Margin monitoring disabled: %v", err)
+		} else {
+			defer monitor.Stop()
+			marginMonitor = monitor
+		}
+	}
+	
+	// Decide which RFQs to quote before pricing them
+	sizeLimits, err := parseSizeLimits(*maxSize)
+	if err != nil {
+		log.Fatalf("Invalid --max-size: %v", err)
+	}
+	quotePolicy := policy.NewEngine()
+	quotePolicy.SetDummyQuotes(*dummyQuotes)
+	if len(sizeLimits) > 0 {
+		quotePolicy.AddRule(sizeLimits)
+	}
+	if *minExpiry > 0 || *maxExpiry > 0 {
+		quotePolicy.AddRule(policy.ExpiryWindow{Min: *minExpiry, Max: *maxExpiry})
+	}
+	if *allowTakers != "" || *denyTakers != "" {
+		quotePolicy.AddRule(policy.NewTakers(strings.Split(*allowTakers, ","), strings.Split(*denyTakers, ",")))
+	}
+	if *minBookCoverage > 0 {
+		quotePolicy.AddRule(policy.BookLiquidity{Exchange: exchange, MinCoverage: *minBookCoverage})
+	}
+	if *checkRisk {
+		quotePolicy.AddRule(policy.RiskHeadroom{Risk: riskManager})
+	}
+	if *minMoneyness > 0 || *maxMoneyness > 0 {
+		quotePolicy.AddPriceRule(policy.Moneyness{Min: *minMoneyness, Max: *maxMoneyness})
+	}
+	if *minAPR > 0 {
+		quotePolicy.AddPriceRule(policy.MinAPR(*minAPR))
+	}
+	
+	// Follow each RFQ through to its confirmation
+	lifecycle := rfq.NewLifecycle(cfg.MakerAddress, rfq.DefaultLifecycleRecords)
 	
 	// Create arbitrage orchestrator
 	orchestrator := arbitrage.NewOrchestrator(
 		cfg, exchange, hedgeManager, riskManager, gammaModule, gammaHedger,
 	)
+	if *journalPath != "" {
+		tradeJournal, err := journal.Open(*journalPath)
+		if err != nil {
+			log.Fatalf("Failed to open trade journal: %v", err)
+		}
+		defer tradeJournal.Close()
+		orchestrator.SetJournal(tradeJournal)
+	}
 	
 	// Start orchestrator
 	orchestrator.Start()
@@ -277,6 +457,12 @@ func runRFQResponder(args []string) {
 	// Create and start HTTP server if enabled
 	if cfg.EnableManualTrades {
 		httpServer := api.NewServer(orchestrator, riskManager, *httpPort)
+		if marginMonitor != nil {
+			httpServer.SetMarginMonitor(marginMonitor)
+		}
+		httpServer.SetPolicy(quotePolicy)
+		httpServer.SetLifecycle(lifecycle)
+		httpServer.SetJournal(orchestrator.Journal())
 		go func() {
 			log.Printf("Starting HTTP API server on port %d", *httpPort)
 			if err := httpServer.Start(); err != nil && err != http.ErrServerClosed {
@@ -288,6 +474,28 @@ func runRFQResponder(args []string) {
 	
 	// Create RFQ processor
 	rfqProcessor := rfq.NewProcessor(cfg, exchange)
+	rfqProcessor.SetHedgeRouter(hedgeManager)
+	if marketData != nil {
+		rfqProcessor.SetMarketData(marketData)
+	}
+	if marginMonitor != nil {
+		rfqProcessor.SetMarginMonitor(marginMonitor)
+	}
+	rfqProcessor.SetPolicy(quotePolicy)
+	rfqProcessor.SetLifecycle(lifecycle)
+	orchestrator.SetQuoteLookup(rfqProcessor)
+	
+	// Build vol surfaces for model pricing
+	if cfg.PricingMode != quoter.PricingModeBook {
+		surfaces, err := createVolSurfaces(cfg)
+		if err != nil {
+			log.Fatalf("Failed to create vol surfaces: %v", err)
+		}
+		surfaceCtx, cancelSurfaces := context.WithCancel(context.Background())
+		defer cancelSurfaces()
+		surfaces.Start(surfaceCtx)
+		rfqProcessor.SetVolSurface(surfaces)
+	}
 	
 	// Create WebSocket client
 	wsClient := websocket.NewSimpleRFQClient(cfg, orchestrator, rfqProcessor)
@@ -319,13 +527,46 @@ func parsePrivateKey(cfg *config.Config) error {
 }
 
 
+// createVolSurfaces creates a surface service for every mapped underlying
+func createVolSurfaces(cfg *config.Config) (*volsurface.Service, error) {
+	var source volsurface.QuoteSource
+	switch cfg.VolSource {
+	case "derive":
+		source = volsurface.NewDeriveSource()
+	case "deribit":
+		source = volsurface.NewDeribitSource(cfg.ExchangeTestMode)
+	default:
+		return nil, fmt.Errorf("unsupported vol source: %s", cfg.VolSource)
+	}
+	
+	return volsurface.NewService(source, instruments.RyskUnderlyings(), time.Minute), nil
+}
+
+// newMarketCache returns a file cache in the user cache directory, or nil if
+// there is none
+func newMarketCache() cache.MarketCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		log.Printf("No cache directory: %v", err)
+		return nil
+	}
+	fileCache, err := cache.NewFileMarketCache(filepath.Join(dir, "atomizer"))
+	if err != nil {
+		log.Printf("Failed to create market cache: %v", err)
+		return nil
+	}
+	return fileCache
+}
+
 // createExchange creates an exchange instance based on config
 func createExchange(cfg *config.Config) (types.Exchange, error) {
 	factory := exchange.NewFactory()
 	
 	// Map config to exchange config
 	exchangeConfig := map[string]interface{}{
-		"test_mode": cfg.ExchangeTestMode,
+		"test_mode":  cfg.ExchangeTestMode,
+		"dry_run":    cfg.DryRun,
+		"subaccount": cfg.DeriveSubaccount,
 	}
 	
 	// Add Deribit credentials if needed
@@ -349,6 +590,61 @@ func createExchange(cfg *config.Config) (types.Exchange, error) {
 	return exchange, nil
 }
 
+// parseSizeLimits parses per-underlying RFQ size limits such as
+// ETH=50,BTC=2,*=10
+func parseSizeLimits(s string) (policy.MaxSize, error) {
+	limits := make(policy.MaxSize)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		
+		underlying, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected UNDERLYING=SIZE, got %q", entry)
+		}
+		size, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid size in %q", entry)
+		}
+		limits[strings.ToUpper(strings.TrimSpace(underlying))] = size
+	}
+	return limits, nil
+}
+
+// parseUnderlyingLimits parses per-underlying hedge limits such as
+// BTC=0.01/0.001,SOL=5/1
+func parseUnderlyingLimits(s string) (map[string]hedging.HedgeLimits, error) {
+	limits := make(map[string]hedging.HedgeLimits)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		
+		underlying, values, ok := strings.Cut(entry, "=")
+		threshold, minSize, ok2 := strings.Cut(values, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("expected UNDERLYING=THRESHOLD/MIN, got %q", entry)
+		}
+		
+		thresholdDec, err := decimal.NewFromString(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delta threshold in %q: %w", entry, err)
+		}
+		minSizeDec, err := decimal.NewFromString(minSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min hedge size in %q: %w", entry, err)
+		}
+		limits[strings.ToUpper(strings.TrimSpace(underlying))] = hedging.HedgeLimits{
+			DeltaThreshold: thresholdDec,
+			MinHedgeSize:   minSizeDec,
+		}
+	}
+	return limits, nil
+}
+
 func runManualOrder(args []string) {
 	// Parse flags
 	fs := flag.NewFlagSet("manual-order", flag.ExitOnError)
@@ -356,12 +652,16 @@ func runManualOrder(args []string) {
 	// Exchange configuration
 	exchangeName := fs.String("exchange", "derive", "Exchange to use (derive, deribit)")
 	testMode := fs.Bool("test", false, "Use test environment")
+	dryRun := fs.Bool("dry-run", false, "Paper trade against live market data (no real orders)")
 	
 	// Order parameters
 	instrument := fs.String("instrument", "", "Instrument to trade (required)")
 	side := fs.String("side", "", "Order side: buy or sell (required)")
 	price := fs.Float64("price", 0, "Order price (required)")
 	amount := fs.Float64("amount", 0, "Order amount (required)")
+	timeInForce := fs.String("tif", "gtc", "Time in force (gtc, ioc, fok)")
+	postOnly := fs.Bool("post-only", false, "Reject the order rather than take liquidity")
+	reduceOnly := fs.Bool("reduce-only", false, "Only reduce an existing position")
 	
 	// Deribit specific
 	deribitApiKey := fs.String("deribit-api-key", os.Getenv("DERIBIT_API_KEY"), "Deribit API key")
@@ -378,10 +678,16 @@ func runManualOrder(args []string) {
 		log.Fatal("All order parameters are required: --instrument, --side, --price, --amount")
 	}
 	
+	tif, err := types.ParseTimeInForce(*timeInForce)
+	if err != nil {
+		log.Fatalf("Invalid --tif: %v", err)
+	}
+	
 	// Create configuration
 	cfg := &config.Config{
 		ExchangeName:     *exchangeName,
 		ExchangeTestMode: *testMode,
+		DryRun:           *dryRun,
 		DeribitApiKey:    *deribitApiKey,
 		DeribitApiSecret: *deribitApiSecret,
 		PrivateKey:       *derivePrivateKey,
@@ -402,6 +708,7 @@ func runManualOrder(args []string) {
 	mmConfig := &types.MarketMakerConfig{
 		Exchange:         *exchangeName,
 		ExchangeTestMode: *testMode,
+		DryRun:           *dryRun,
 	}
 	
 	mmExchange, err := exchange.NewExchange(mmConfig)
@@ -415,6 +722,11 @@ func runManualOrder(args []string) {
 		Side:       *side,
 		Price:      *price,
 		Amount:     *amount,
+		Options: types.OrderOptions{
+			TimeInForce: tif,
+			PostOnly:    *postOnly,
+			ReduceOnly:  *reduceOnly,
+		},
 	}
 	
 	// Override from environment if specified
@@ -433,12 +745,18 @@ func runPureGammaHedger(args []string) {
 	// Exchange selection
 	exchangeName := fs.String("exchange", "derive", "Exchange to use (derive, deribit)")
 	testMode := fs.Bool("test", false, "Use test environment")
+	dryRun := fs.Bool("dry-run", false, "Paper trade against live market data (no real orders)")
+	recordPath := fs.String("record", "", "Record all exchange calls and market data to this file")
+	replayPath := fs.String("replay", "", "Replay a recording instead of connecting to the exchange")
+	subaccount := fs.Uint64("subaccount", 0, "Derive subaccount to hedge from (default DERIVE_SUBACCOUNT_ID or the wallet's first)")
 	
 	// Gamma hedging parameters
 	deltaThreshold := fs.Float64("delta-threshold", 0.1, "Maximum delta before hedging")
 	minHedgeSize := fs.Float64("min-hedge-size", 0.1, "Minimum hedge size")
 	hedgeInterval := fs.Int("hedge-interval", 30, "Hedge check interval in seconds")
 	aggressiveness := fs.Float64("aggressiveness", 0.7, "Order placement aggressiveness (0=passive at bid/ask, 1=cross spread, >1=beyond spread)")
+	hedgeInstrument := fs.String("hedge-instrument", "", "Comma-separated perps to hedge with, one per underlying (default each underlying's perp sized in it, e.g. ETH-PERP on Derive, ETH_USDC-PERPETUAL on Deribit)")
+	underlyingLimits := fs.String("underlying-limits", "", "Per-underlying delta threshold/min hedge size, e.g. BTC=0.01/0.001,SOL=5/1")
 	debug := fs.Bool("debug", false, "Enable debug logging")
 	
 	// Derive-specific flags
@@ -455,14 +773,15 @@ func runPureGammaHedger(args []string) {
 	cfg := &config.Config{
 		ExchangeName:     *exchangeName,
 		ExchangeTestMode: *testMode,
+		DryRun:           *dryRun,
 		DeribitApiKey:    *deribitApiKey,
 		DeribitApiSecret: *deribitApiSecret,
 		PrivateKey:       *derivePrivateKey,
 		MakerAddress:     *deriveWalletAddress,
 	}
 	
-	// Parse private key if using Derive
-	if *exchangeName == "derive" {
+	// Parse private key if using Derive (a replay needs no credentials)
+	if *exchangeName == "derive" && *replayPath == "" {
 		if *derivePrivateKey == "" || *deriveWalletAddress == "" {
 			log.Fatal("Derive requires DERIVE_PRIVATE_KEY and DERIVE_WALLET_ADDRESS")
 		}
@@ -480,6 +799,10 @@ func runPureGammaHedger(args []string) {
 	mmConfig := &types.MarketMakerConfig{
 		Exchange:         *exchangeName,
 		ExchangeTestMode: *testMode,
+		DryRun:           *dryRun,
+		RecordPath:       *recordPath,
+		ReplayPath:       *replayPath,
+		Subaccount:       *subaccount,
 	}
 	
 	// Create exchange
@@ -491,6 +814,9 @@ func runPureGammaHedger(args []string) {
 	
 	// Create pure gamma hedger
 	hedger := hedging.NewPureGammaHedger(mmExchange)
+	if *replayPath == "" {
+		hedger.SetPricingEngine(pricing.NewEngine(pricing.NewTickerSource(*exchangeName, *testMode)))
+	}
 	
 	// Set parameters
 	hedger.SetParameters(
@@ -500,6 +826,24 @@ func runPureGammaHedger(args []string) {
 		time.Duration(*hedgeInterval) * time.Second,
 	)
 	
+	// Underlyings without a hedge instrument use their listed perp
+	for _, instrument := range strings.Split(*hedgeInstrument, ",") {
+		if instrument = strings.TrimSpace(instrument); instrument == "" {
+			continue
+		}
+		if err := hedger.SetHedgeInstrument(instrument); err != nil {
+			log.Fatalf("Invalid hedge instrument %s: %v", instrument, err)
+		}
+	}
+	
+	limits, err := parseUnderlyingLimits(*underlyingLimits)
+	if err != nil {
+		log.Fatalf("Invalid --underlying-limits: %v", err)
+	}
+	for underlying, limit := range limits {
+		hedger.SetUnderlyingLimits(underlying, limit)
+	}
+	
 	// Enable debug mode if requested
 	hedger.SetDebugMode(*debug)
 	
@@ -507,8 +851,8 @@ func runPureGammaHedger(args []string) {
 	log.Printf("Starting Pure Gamma Hedger")
 	log.Printf("Configuration:")
 	log.Printf("  Exchange: %s (test mode: %v)", *exchangeName, *testMode)
-	log.Printf("  Delta Threshold: %.4f ETH", *deltaThreshold)
-	log.Printf("  Min Hedge Size: %.4f ETH", *minHedgeSize)
+	log.Printf("  Delta Threshold: %.4f", *deltaThreshold)
+	log.Printf("  Min Hedge Size: %.4f", *minHedgeSize)
 	log.Printf("  Hedge Interval: %d seconds", *hedgeInterval)
 	log.Printf("  Aggressiveness: %.2f (%.0f%% through spread)", *aggressiveness, *aggressiveness*100)
 	log.Printf("  Debug Mode: %v", *debug)
@@ -528,4 +872,9 @@ func runPureGammaHedger(args []string) {
 	
 	log.Println("Shutting down pure gamma hedger...")
 	hedger.Stop()
+	
+	// Flush any recording
+	if closer, ok := mmExchange.(interface{ Close() error }); ok {
+		closer.Close()
+	}
 }
\ No newline at end of file
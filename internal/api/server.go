@@ -6,8 +6,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/wakamex/atomizer/internal/journal"
+	"github.com/wakamex/atomizer/internal/margin"
+	"github.com/wakamex/atomizer/internal/policy"
+	"github.com/wakamex/atomizer/internal/ratelimit"
+	"github.com/wakamex/atomizer/internal/rfq"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
@@ -15,6 +22,10 @@ import (
 type Server struct {
 	orchestrator Orchestrator
 	riskManager  types.RiskManager
+	margin       *margin.Monitor
+	policy       *policy.Engine
+	lifecycle    *rfq.Lifecycle
+	journal      *journal.Journal
 	port         int
 	server       *http.Server
 }
@@ -34,6 +45,27 @@ func NewServer(orchestrator Orchestrator, riskManager types.RiskManager, port in
 	}
 }
 
+// SetMarginMonitor publishes a margin monitor's readings. Call before Start.
+func (s *Server) SetMarginMonitor(monitor *margin.Monitor) {
+	s.margin = monitor
+}
+
+// SetPolicy publishes the RFQ quoting policy's decision counts. Call before
+// Start.
+func (s *Server) SetPolicy(engine *policy.Engine) {
+	s.policy = engine
+}
+
+// SetLifecycle publishes RFQs, their quotes and win rates. Call before Start.
+func (s *Server) SetLifecycle(lifecycle *rfq.Lifecycle) {
+	s.lifecycle = lifecycle
+}
+
+// SetJournal serves trade history from a trade journal. Call before Start.
+func (s *Server) SetJournal(j *journal.Journal) {
+	s.journal = j
+}
+
 // Start begins serving HTTP requests
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
@@ -41,10 +73,16 @@ func (s *Server) Start() error {
 	// Trade endpoints
 	mux.HandleFunc("/api/trade", s.handleTrade)
 	mux.HandleFunc("/api/trades", s.handleGetTrades)
+	mux.HandleFunc("/api/trades/history", s.handleGetTradeHistory)
 	
 	// Risk endpoints
 	mux.HandleFunc("/api/risk", s.handleGetRisk)
 	mux.HandleFunc("/api/positions", s.handleGetPositions)
+	mux.HandleFunc("/api/margin", s.handleGetMargin)
+	
+	// RFQ endpoints
+	mux.HandleFunc("/api/rfqs", s.handleGetRFQs)
+	mux.HandleFunc("/api/rfqs/stats", s.handleGetRFQStats)
 	
 	// Health check
 	mux.HandleFunc("/health", s.handleHealth)
@@ -116,19 +154,91 @@ func (s *Server) handleTrade(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(trade)
 }
 
-// handleGetTrades returns active trades
+// handleGetTrades returns active trades, or with any of the from, to,
+// status and limit query parameters, journaled trades matching them. Times
+// are RFC 3339 or Unix seconds.
 func (s *Server) handleGetTrades(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	trades := s.orchestrator.GetActiveTrades()
+	params := r.URL.Query()
+	if !params.Has("from") && !params.Has("to") && !params.Has("status") && !params.Has("limit") {
+		trades := s.orchestrator.GetActiveTrades()
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(trades)
+		return
+	}
+	
+	if s.journal == nil {
+		http.Error(w, "Trade journal not enabled", http.StatusNotFound)
+		return
+	}
+	
+	var query journal.Query
+	var err error
+	if query.From, err = parseTime(params.Get("from")); err != nil {
+		http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.To, err = parseTime(params.Get("to")); err != nil {
+		http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Status = types.TradeStatus(strings.ToUpper(params.Get("status")))
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	
+	trades := s.journal.Trades(query)
+	if trades == nil {
+		trades = []journal.Trade{}
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trades)
 }
 
+// handleGetTradeHistory returns every journaled state of the trade given by
+// the id query parameter, oldest first
+func (s *Server) handleGetTradeHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	if s.journal == nil {
+		http.Error(w, "Trade journal not enabled", http.StatusNotFound)
+		return
+	}
+	
+	history := s.journal.History(r.URL.Query().Get("id"))
+	if len(history) == 0 {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// parseTime parses an RFC 3339 time or Unix seconds. An empty value is the
+// zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 // handleGetRisk returns current risk metrics
 func (s *Server) handleGetRisk(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -159,13 +269,15 @@ func (s *Server) handleGetPositions(w http.ResponseWriter, r *http.Request) {
 	
 	// Convert positions map to slice for JSON response
 	positionList := make([]PositionResponse, 0, len(positions))
-	for instrument, pos := range positions {
+	for _, pos := range positions {
 		positionList = append(positionList, PositionResponse{
-			Instrument:  instrument,
+			Instrument:  pos.Instrument,
+			Venue:       pos.Venue,
 			Quantity:    pos.Quantity.String(),
 			AvgPrice:    pos.AvgPrice.String(),
 			Delta:       pos.Delta.String(),
 			Gamma:       pos.Gamma.String(),
+			Estimated:   pos.Estimated,
 			LastUpdated: pos.LastUpdated.Unix(),
 		})
 	}
@@ -174,6 +286,152 @@ func (s *Server) handleGetPositions(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(positionList)
 }
 
+// handleGetMargin returns the trading subaccount's latest margin reading
+func (s *Server) handleGetMargin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	if s.margin == nil {
+		http.Error(w, "Margin monitoring not enabled", http.StatusNotFound)
+		return
+	}
+	state, ok := s.margin.State()
+	if !ok {
+		http.Error(w, "No margin reading yet", http.StatusServiceUnavailable)
+		return
+	}
+	
+	response := MarginResponse{
+		SubaccountID:      state.SubaccountID,
+		Collateral:        state.Collateral.String(),
+		SubaccountValue:   state.SubaccountValue.String(),
+		InitialMargin:     state.InitialMargin.String(),
+		MaintenanceMargin: state.MaintenanceMargin.String(),
+		AvailableMargin:   state.AvailableMargin.String(),
+		Utilization:       state.Utilization().StringFixed(4),
+		MaxUsage:          s.margin.MaxUsage().String(),
+		Blocking:          s.margin.Blocking(),
+		BlockedQuotes:     s.margin.Blocked(),
+		UpdatedAt:         state.UpdatedAt.Unix(),
+	}
+	if err := s.margin.LastError(); err != nil {
+		response.LastError = err.Error()
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetRFQs returns the latest RFQs and what became of them. The limit
+// query parameter caps how many are returned (default 100).
+func (s *Server) handleGetRFQs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	if s.lifecycle == nil {
+		http.Error(w, "RFQ tracking not enabled", http.StatusNotFound)
+		return
+	}
+	
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	
+	records := s.lifecycle.Records(limit)
+	response := make([]RFQResponse, 0, len(records))
+	for _, record := range records {
+		item := RFQResponse{
+			RFQID:      record.RFQID,
+			QuoteNonce: record.QuoteNonce,
+			Underlying: record.Underlying,
+			Strike:     record.Strike,
+			Expiry:     record.Expiry.Unix(),
+			IsPut:      record.IsPut,
+			IsTakerBuy: record.IsTakerBuy,
+			Quantity:   record.Quantity,
+			Taker:      record.Taker,
+			Status:     string(record.Status),
+			Reason:     record.Reason,
+			Price:      record.Price,
+			APR:        record.APR,
+			Mid:        record.Mid,
+			TradePrice: record.TradePrice,
+			ReceivedAt: record.ReceivedAt.Unix(),
+		}
+		if !record.QuotedAt.IsZero() {
+			item.QuotedAt = record.QuotedAt.Unix()
+			item.QuoteLatencyMs = record.QuotedAt.Sub(record.ReceivedAt).Milliseconds()
+		}
+		if !record.ResolvedAt.IsZero() {
+			item.ResolvedAt = record.ResolvedAt.Unix()
+		}
+		response = append(response, item)
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetRFQStats returns hit rates, competitiveness and latency by bucket.
+// The by query parameter picks the buckets from asset, expiry and size
+// (default all three).
+func (s *Server) handleGetRFQStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	if s.lifecycle == nil {
+		http.Error(w, "RFQ tracking not enabled", http.StatusNotFound)
+		return
+	}
+	
+	groupBy := []string{"asset", "expiry", "size"}
+	if value, ok := r.URL.Query()["by"]; ok {
+		groupBy = strings.Split(strings.Join(value, ","), ",")
+	}
+	
+	stats := s.lifecycle.Stats(groupBy)
+	response := make([]RFQStatsResponse, 0, len(stats))
+	for _, bucket := range stats {
+		response = append(response, RFQStatsResponse{
+			Underlying:        bucket.Underlying,
+			Expiry:            bucket.Expiry,
+			Size:              bucket.Size,
+			RFQs:              bucket.RFQs,
+			Quoted:            bucket.Quoted,
+			Declined:          bucket.Declined,
+			Filled:            bucket.Filled,
+			Lost:              bucket.Lost,
+			Missed:            bucket.Missed,
+			Live:              bucket.Live,
+			Unresolved:        bucket.Unresolved,
+			QuoteRate:         bucket.QuoteRate,
+			HitRate:           bucket.HitRate,
+			AvgEdgeBps:        bucket.AvgEdgeBps,
+			AvgFilledEdgeBps:  bucket.AvgFilledEdgeBps,
+			AvgMissedEdgeBps:  bucket.AvgMissedEdgeBps,
+			AvgLossGapBps:     bucket.AvgLossGapBps,
+			AvgQuoteLatencyMs: bucket.AvgQuoteLatencyMs,
+			P95QuoteLatencyMs: bucket.P95QuoteLatencyMs,
+			AvgFillLatencyMs:  bucket.AvgFillLatencyMs,
+		})
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleHealth returns server health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
@@ -204,17 +462,104 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "# TYPE active_positions gauge\n")
 	fmt.Fprintf(w, "active_positions %d\n", len(positions))
 	
+	estimated := 0
+	for _, pos := range positions {
+		if pos.Estimated {
+			estimated++
+		}
+	}
+	fmt.Fprintf(w, "# HELP estimated_positions Positions whose Greeks assume the option is at the money\n")
+	fmt.Fprintf(w, "# TYPE estimated_positions gauge\n")
+	fmt.Fprintf(w, "estimated_positions %d\n", estimated)
+	
 	fmt.Fprintf(w, "# HELP active_trades Number of active trades\n")
 	fmt.Fprintf(w, "# TYPE active_trades gauge\n")
 	fmt.Fprintf(w, "active_trades %d\n", len(trades))
+	
+	ratelimit.WriteMetrics(w)
+	
+	if s.margin != nil {
+		s.margin.WriteMetrics(w)
+	}
+	
+	if s.policy != nil {
+		s.policy.WriteMetrics(w)
+	}
 }
 
 // PositionResponse represents a position in the API response
 type PositionResponse struct {
 	Instrument  string `json:"instrument"`
+	Venue       string `json:"venue,omitempty"`
 	Quantity    string `json:"quantity"`
 	AvgPrice    string `json:"avg_price"`
 	Delta       string `json:"delta"`
 	Gamma       string `json:"gamma"`
+	Estimated   bool   `json:"estimated,omitempty"` // Greeks assume the option is at the money
 	LastUpdated int64  `json:"last_updated"`
-}
\ No newline at end of file
+}
+
+// MarginResponse represents a margin reading in the API response
+type MarginResponse struct {
+	SubaccountID      uint64 `json:"subaccount_id"`
+	Collateral        string `json:"collateral"`
+	SubaccountValue   string `json:"subaccount_value"`
+	InitialMargin     string `json:"initial_margin"`
+	MaintenanceMargin string `json:"maintenance_margin"`
+	AvailableMargin   string `json:"available_margin"`
+	Utilization       string `json:"utilization"`
+	MaxUsage          string `json:"max_usage"`
+	Blocking          bool   `json:"blocking"`
+	BlockedQuotes     int64  `json:"blocked_quotes"`
+	UpdatedAt         int64  `json:"updated_at"`
+	LastError         string `json:"last_error,omitempty"`
+}
+
+// RFQResponse represents a tracked RFQ in the API response. Prices are per
+// contract in quote currency.
+type RFQResponse struct {
+	RFQID          string  `json:"rfq_id"`
+	QuoteNonce     string  `json:"quote_nonce,omitempty"`
+	Underlying     string  `json:"underlying"`
+	Strike         float64 `json:"strike"`
+	Expiry         int64   `json:"expiry"`
+	IsPut          bool    `json:"is_put"`
+	IsTakerBuy     bool    `json:"is_taker_buy"`
+	Quantity       float64 `json:"quantity"`
+	Taker          string  `json:"taker,omitempty"`
+	Status         string  `json:"status"`
+	Reason         string  `json:"reason,omitempty"`
+	Price          float64 `json:"price,omitempty"`
+	APR            float64 `json:"apr,omitempty"`
+	Mid            float64 `json:"mid,omitempty"`
+	TradePrice     float64 `json:"trade_price,omitempty"`
+	ReceivedAt     int64   `json:"received_at"`
+	QuotedAt       int64   `json:"quoted_at,omitempty"`
+	QuoteLatencyMs int64   `json:"quote_latency_ms,omitempty"`
+	ResolvedAt     int64   `json:"resolved_at,omitempty"`
+}
+
+// RFQStatsResponse represents one bucket of RFQ statistics in the API
+// response. Edges are in basis points of the hedge venue mid in our favour.
+type RFQStatsResponse struct {
+	Underlying        string  `json:"underlying,omitempty"`
+	Expiry            string  `json:"expiry,omitempty"`
+	Size              string  `json:"size,omitempty"`
+	RFQs              int     `json:"rfqs"`
+	Quoted            int     `json:"quoted"`
+	Declined          int     `json:"declined"`
+	Filled            int     `json:"filled"`
+	Lost              int     `json:"lost"`
+	Missed            int     `json:"missed"`
+	Live              int     `json:"live"`
+	Unresolved        int     `json:"unresolved"`
+	QuoteRate         float64 `json:"quote_rate"`
+	HitRate           float64 `json:"hit_rate"`
+	AvgEdgeBps        float64 `json:"avg_edge_bps"`
+	AvgFilledEdgeBps  float64 `json:"avg_filled_edge_bps"`
+	AvgMissedEdgeBps  float64 `json:"avg_missed_edge_bps"`
+	AvgLossGapBps     float64 `json:"avg_loss_gap_bps"`
+	AvgQuoteLatencyMs float64 `json:"avg_quote_latency_ms"`
+	P95QuoteLatencyMs float64 `json:"p95_quote_latency_ms"`
+	AvgFillLatencyMs  float64 `json:"avg_fill_latency_ms"`
+}
@@ -17,4 +17,15 @@ type GammaModule interface {
 type GammaHedger interface {
 	Start(ctx context.Context)
 	Stop()
-}
\ No newline at end of file
+}
+// ReportingHedger is a hedge manager that reports a hedge's venue, orders and
+// fills while it executes
+type ReportingHedger interface {
+	ExecuteHedgeReporting(ctx context.Context, trade *types.TradeEvent, report func(*types.TradeEvent)) error
+}
+
+// QuoteLookup returns the economics of a sent quote by its nonce, or by the
+// RFQ it answered
+type QuoteLookup interface {
+	QuoteEconomics(rfqID, nonce string) (expectedFee, netEdge decimal.Decimal, ok bool)
+}
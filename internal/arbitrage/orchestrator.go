@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/journal"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
@@ -28,14 +28,17 @@ type Orchestrator struct {
 	riskManager    types.RiskManager
 	gammaModule    GammaModule
 	gammaHedger    GammaHedger
+	quotes         QuoteLookup
 	tradeQueue     chan types.TradeEvent
-	activeTrades   map[string]*types.TradeEvent
-	mu             sync.RWMutex
+	journal        *journal.Journal
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
 
 
+// activeTradeWindow is how long finished trades are reported as active
+const activeTradeWindow = 5 * time.Minute
+
 // NewOrchestrator creates a new arbitrage orchestrator
 func NewOrchestrator(cfg *config.Config, exchange types.Exchange, hedgeManager types.HedgeManager, 
 	riskManager types.RiskManager, gammaModule GammaModule, gammaHedger GammaHedger) *Orchestrator {
@@ -50,16 +53,34 @@ func NewOrchestrator(cfg *config.Config, exchange types.Exchange, hedgeManager t
 		gammaModule:  gammaModule,
 		gammaHedger:  gammaHedger,
 		tradeQueue:   make(chan types.TradeEvent, 100),
-		activeTrades: make(map[string]*types.TradeEvent),
+		journal:      journal.New(),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
-// Start begins the orchestrator's processing loops
+// SetQuoteLookup sets where RFQ trades look up the fees and edge of their quote
+func (o *Orchestrator) SetQuoteLookup(quotes QuoteLookup) {
+	o.quotes = quotes
+}
+
+// SetJournal records trades in j instead of in memory only. Call before Start.
+func (o *Orchestrator) SetJournal(j *journal.Journal) {
+	o.journal = j
+}
+
+// Journal returns where trades are recorded
+func (o *Orchestrator) Journal() *journal.Journal {
+	return o.journal
+}
+
+// Start begins the orchestrator's processing loops, first picking back up
+// journaled trades that were never hedged
 func (o *Orchestrator) Start() {
 	log.Println("Starting arbitrage orchestrator...")
 	
+	go o.resumeUnhedged(o.journal.Unhedged())
+	
 	// Start gamma hedger if enabled
 	if o.config.EnableGammaHedging && o.gammaHedger != nil {
 		go o.gammaHedger.Start(o.ctx)
@@ -104,11 +125,23 @@ func (o *Orchestrator) SubmitRFQTrade(rfqResult types.RFQResult, confirmation *t
 		trade.Status = types.TradeStatusExecuted
 		trade.Price = DecimalFromString(confirmation.Price)
 	}
+
+	// Record the fees and edge we priced in
+	if o.quotes != nil {
+		nonce := ""
+		if confirmation != nil {
+			nonce = confirmation.QuoteNonce
+		}
+		if fee, edge, ok := o.quotes.QuoteEconomics(rfqResult.RFQId, nonce); ok {
+			trade.ExpectedFee = fee
+			trade.NetEdge = edge
+			log.Printf("RFQ trade %s: expected fee %s, net edge %s", trade.ID, fee, edge)
+		} else {
+			log.Printf("RFQ trade %s: no quote economics for RFQ %s, nonce %s", trade.ID, rfqResult.RFQId, nonce)
+		}
+	}
 	
-	// Add to active trades
-	o.mu.Lock()
-	o.activeTrades[trade.ID] = &trade
-	o.mu.Unlock()
+	o.record(&trade)
 	
 	// Queue for processing
 	select {
@@ -141,13 +174,11 @@ func (o *Orchestrator) SubmitManualTrade(req types.ManualTradeRequest) (*types.T
 	if err := o.riskManager.ValidateTrade(trade); err != nil {
 		trade.Status = types.TradeStatusFailed
 		trade.Error = err
+		o.record(trade)
 		return trade, err
 	}
 	
-	// Add to active trades
-	o.mu.Lock()
-	o.activeTrades[trade.ID] = trade
-	o.mu.Unlock()
+	o.record(trade)
 	
 	// Queue for processing
 	select {
@@ -160,15 +191,15 @@ func (o *Orchestrator) SubmitManualTrade(req types.ManualTradeRequest) (*types.T
 	}
 }
 
-// GetActiveTrades returns all active trades
+// GetActiveTrades returns trades still being processed or hedged, and those
+// from the last few minutes
 func (o *Orchestrator) GetActiveTrades() []types.TradeEvent {
-	o.mu.RLock()
-	defer o.mu.RUnlock()
+	cutoff := time.Now().Add(-activeTradeWindow)
 	
-	trades := make([]types.TradeEvent, 0, len(o.activeTrades))
-	for _, trade := range o.activeTrades {
-		if trade != nil {
-			trades = append(trades, *trade)
+	trades := make([]types.TradeEvent, 0)
+	for _, trade := range o.journal.Trades(journal.Query{}) {
+		if trade.Unhedged() || trade.Timestamp.After(cutoff) {
+			trades = append(trades, trade.Event())
 		}
 	}
 	return trades
@@ -195,39 +226,70 @@ func (o *Orchestrator) processTrade(trade *types.TradeEvent) {
 		map[bool]string{true: "BUY", false: "SELL"}[trade.IsTakerBuy])
 	
 	// Update status
-	o.updateTradeStatus(trade.ID, types.TradeStatusExecuted)
+	o.updateTradeStatus(trade, types.TradeStatusExecuted)
 	
 	// Update risk manager
 	o.riskManager.UpdatePosition(trade)
 	
 	// Execute hedge if not already a hedge trade
 	if trade.Source != types.TradeSourceHedge {
-		if err := o.hedgeManager.ExecuteHedge(o.ctx, trade); err != nil {
+		if err := o.executeHedge(trade); err != nil {
 			log.Printf("Failed to hedge trade %s: %v", trade.ID, err)
-			o.updateTradeStatus(trade.ID, types.TradeStatusFailed)
 			trade.Error = err
+			o.updateTradeStatus(trade, types.TradeStatusFailed)
 			return
 		}
-		o.updateTradeStatus(trade.ID, types.TradeStatusHedged)
+		o.updateTradeStatus(trade, types.TradeStatusHedged)
 	}
-	
-	// Clean up completed trades after some time
-	go func() {
-		time.Sleep(5 * time.Minute)
-		o.mu.Lock()
-		delete(o.activeTrades, trade.ID)
-		o.mu.Unlock()
-	}()
 }
 
-// updateTradeStatus updates the status of a trade
-func (o *Orchestrator) updateTradeStatus(tradeID string, status types.TradeStatus) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-	
-	if trade, exists := o.activeTrades[tradeID]; exists {
-		trade.Status = status
-		log.Printf("Trade %s status updated to %s", tradeID, status)
+// executeHedge hedges a trade, journaling its hedge orders and fills as they
+// happen when the hedge manager reports them
+func (o *Orchestrator) executeHedge(trade *types.TradeEvent) error {
+	if hedger, ok := o.hedgeManager.(ReportingHedger); ok {
+		return hedger.ExecuteHedgeReporting(o.ctx, trade, o.record)
+	}
+	return o.hedgeManager.ExecuteHedge(o.ctx, trade)
+}
+
+// updateTradeStatus updates the status of a trade and journals it
+func (o *Orchestrator) updateTradeStatus(trade *types.TradeEvent, status types.TradeStatus) {
+	trade.Status = status
+	log.Printf("Trade %s status updated to %s", trade.ID, status)
+	o.record(trade)
+}
+
+// record journals a trade's current state
+func (o *Orchestrator) record(trade *types.TradeEvent) {
+	if err := o.journal.Record(trade); err != nil {
+		log.Printf("Failed to journal trade %s: %v", trade.ID, err)
+	}
+}
+
+// resumeUnhedged queues trades left unhedged by a previous run. Trades whose
+// hedge had reached a venue are failed for review instead, since orders of
+// theirs may still be working.
+func (o *Orchestrator) resumeUnhedged(trades []types.TradeEvent) {
+	for i := range trades {
+		trade := trades[i]
+		switch {
+		case trade.Status == types.TradeStatusExecuted && trade.HedgeExchange != "":
+			trade.Error = fmt.Errorf("hedge interrupted on %s with orders [%s] filled %s of %s; review before hedging the rest",
+				trade.HedgeExchange, trade.HedgeOrderID, trade.HedgeQuantity, trade.Quantity)
+			log.Printf("Not resuming trade %s: %v", trade.ID, trade.Error)
+			o.updateTradeStatus(&trade, types.TradeStatusFailed)
+			continue
+		case trade.Status == types.TradeStatusExecuted:
+			log.Printf("Resuming trade %s, which was being hedged when last stopped with no hedge order journaled", trade.ID)
+		default:
+			log.Printf("Resuming unprocessed trade %s", trade.ID)
+		}
+		
+		select {
+		case o.tradeQueue <- trade:
+		case <-o.ctx.Done():
+			return
+		}
 	}
 }
 
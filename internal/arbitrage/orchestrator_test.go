@@ -0,0 +1,246 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/journal"
+	"github.com/wakamex/atomizer/internal/rfq"
+	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/rysk-v12-cli/ryskcore"
+)
+
+// bookExchange serves one order book for every RFQ
+type bookExchange struct {
+	types.Exchange
+	book types.CCXTOrderBook
+}
+
+func (e *bookExchange) GetOrderBook(req types.RFQResult, asset string) (types.CCXTOrderBook, error) {
+	return e.book, nil
+}
+
+// sentQuotes records the quotes sent to Rysk
+type sentQuotes []ryskcore.Quote
+
+func (s *sentQuotes) Send(data []byte) {
+	var request struct {
+		Params ryskcore.Quote `json:"params"`
+	}
+	if err := json.Unmarshal(data, &request); err == nil {
+		*s = append(*s, request.Params)
+	}
+}
+
+func TestRFQTradeCarriesQuoteEconomics(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		ExchangeName:              "derive",
+		MakerAddress:              crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		ParsedPrivateKey:          key,
+		QuoteValidDurationSeconds: 30,
+		MinEdge:                   2,
+	}
+	exchange := &bookExchange{book: types.CCXTOrderBook{
+		Bids:  [][]float64{{90, 10}},
+		Asks:  [][]float64{{100, 10}},
+		Index: 3000,
+	}}
+	processor := rfq.NewProcessor(cfg, exchange)
+
+	req := types.RFQResult{
+		RFQId:      "rfq-1",
+		Asset:      "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+		Strike:     "300000000000",
+		Expiry:     time.Now().Add(7 * 24 * time.Hour).Unix(),
+		Quantity:   "2000000000000000000",
+		IsTakerBuy: true,
+		MaxFee:     "1000000",
+	}
+	var sent sentQuotes
+	if err := processor.ProcessRFQ(&sent, req, req.RFQId); err != nil {
+		t.Fatalf("ProcessRFQ: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Nonce == "" || sent[0].Nonce == req.RFQId {
+		t.Fatalf("sent quotes = %+v, want one with its own nonce", sent)
+	}
+
+	orchestrator := NewOrchestrator(cfg, exchange, nil, nil, nil, nil)
+	orchestrator.SetQuoteLookup(processor)
+
+	// Confirmations name the quote by its nonce, not the RFQ
+	confirmed := req
+	confirmed.RFQId = "confirmation-1"
+	err = orchestrator.SubmitRFQTrade(confirmed, &types.RFQConfirmation{
+		QuoteNonce: sent[0].Nonce,
+		Price:      sent[0].Price,
+	})
+	if err != nil {
+		t.Fatalf("SubmitRFQTrade: %v", err)
+	}
+
+	trade := <-orchestrator.tradeQueue
+	if !trade.ExpectedFee.IsPositive() || !trade.NetEdge.IsPositive() {
+		t.Fatalf("trade fee = %s, edge = %s, want both set", trade.ExpectedFee, trade.NetEdge)
+	}
+	// $2 of edge per contract on 2 contracts
+	if got := trade.NetEdge.String(); got != "4" {
+		t.Errorf("net edge = %s, want 4", got)
+	}
+	if journaled, _ := orchestrator.Journal().Trade(trade.ID); !journaled.ExpectedFee.Equal(trade.ExpectedFee) {
+		t.Errorf("journaled fee = %s, want %s", journaled.ExpectedFee, trade.ExpectedFee)
+	}
+}
+
+// orderHedger hedges each trade with one order on derive, reporting it placed
+// and then half filled before it completes
+type orderHedger struct {
+	mu     sync.Mutex
+	hedged []string
+}
+
+func (h *orderHedger) ExecuteHedge(ctx context.Context, trade *types.TradeEvent) error {
+	return h.ExecuteHedgeReporting(ctx, trade, func(*types.TradeEvent) {})
+}
+
+func (h *orderHedger) ExecuteHedgeReporting(ctx context.Context, trade *types.TradeEvent, report func(*types.TradeEvent)) error {
+	h.mu.Lock()
+	h.hedged = append(h.hedged, trade.ID)
+	h.mu.Unlock()
+
+	trade.HedgeExchange = "derive"
+	trade.HedgeOrderID = "order-" + trade.ID
+	report(trade)
+	trade.HedgeQuantity = trade.Quantity.Div(decimal.NewFromInt(2))
+	report(trade)
+	trade.HedgeQuantity = trade.Quantity
+	return nil
+}
+
+func (h *orderHedger) trades() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.hedged...)
+}
+
+// nullRisk accepts every trade
+type nullRisk struct{}
+
+func (nullRisk) ValidateTrade(trade *types.TradeEvent) error { return nil }
+func (nullRisk) UpdatePosition(trade *types.TradeEvent)      {}
+func (nullRisk) GetGreeks() (delta, gamma decimal.Decimal)   { return decimal.Zero, decimal.Zero }
+func (nullRisk) GetPositions() map[string]types.Position     { return nil }
+
+// awaitStatus waits for a journaled trade to reach status
+func awaitStatus(t *testing.T, j *journal.Journal, id string, status types.TradeStatus) types.TradeEvent {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		trade, _ := j.Trade(id)
+		if trade.Status == status {
+			return trade
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("trade %s is %q, want %s", id, trade.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJournalsHedgeOrdersAsTheyFill(t *testing.T) {
+	orchestrator := NewOrchestrator(&config.Config{}, nil, &orderHedger{}, nullRisk{}, nil, nil)
+	orchestrator.Start()
+	defer orchestrator.Stop()
+
+	trade, err := orchestrator.SubmitManualTrade(types.ManualTradeRequest{
+		Asset:    "ETH",
+		Strike:   "3000",
+		Expiry:   time.Now().Add(24 * time.Hour).Unix(),
+		Quantity: decimal.NewFromInt(2),
+	})
+	if err != nil {
+		t.Fatalf("SubmitManualTrade: %v", err)
+	}
+	awaitStatus(t, orchestrator.Journal(), trade.ID, types.TradeStatusHedged)
+
+	// The order and its partial fill are journaled before the hedge completes
+	var placed, partial bool
+	for _, entry := range orchestrator.Journal().History(trade.ID) {
+		if entry.Trade.Status != types.TradeStatusExecuted || entry.Trade.HedgeOrderID != "order-"+trade.ID {
+			continue
+		}
+		placed = true
+		partial = partial || entry.Trade.HedgeQuantity.Equal(decimal.NewFromInt(1))
+	}
+	if !placed || !partial {
+		t.Errorf("journaled mid-hedge: placed %v, partial fill %v; history %+v", placed, partial, orchestrator.Journal().History(trade.ID))
+	}
+}
+
+func TestResumesUnhedgedTradesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.jsonl")
+	j, err := journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Where a previous run stopped
+	start := time.Now().Add(-time.Hour)
+	trades := []types.TradeEvent{
+		{ID: "queued", Status: types.TradeStatusPending},
+		{ID: "unrouted", Status: types.TradeStatusExecuted},
+		{ID: "mid-hedge", Status: types.TradeStatusExecuted, HedgeExchange: "derive", HedgeOrderID: "order-1", HedgeQuantity: decimal.NewFromInt(1)},
+		{ID: "done", Status: types.TradeStatusHedged, HedgeExchange: "derive", HedgeOrderID: "order-2", HedgeQuantity: decimal.NewFromInt(2)},
+	}
+	for i := range trades {
+		trades[i].Source = types.TradeSourceRysk
+		trades[i].Instrument = "ETH"
+		trades[i].Quantity = decimal.NewFromInt(2)
+		trades[i].Timestamp = start.Add(time.Duration(i) * time.Minute)
+		if err := j.Record(&trades[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	j, err = journal.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	hedger := &orderHedger{}
+	orchestrator := NewOrchestrator(&config.Config{}, nil, hedger, nullRisk{}, nil, nil)
+	orchestrator.SetJournal(j)
+	orchestrator.Start()
+	defer orchestrator.Stop()
+
+	awaitStatus(t, j, "queued", types.TradeStatusHedged)
+	awaitStatus(t, j, "unrouted", types.TradeStatusHedged)
+
+	// A hedge that reached a venue is left for review, not hedged again
+	parked := awaitStatus(t, j, "mid-hedge", types.TradeStatusFailed)
+	if parked.Error == nil || !strings.Contains(parked.Error.Error(), "order-1") {
+		t.Errorf("mid-hedge error = %v, want it to name order-1", parked.Error)
+	}
+	if !parked.HedgeQuantity.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("mid-hedge filled = %s, want 1", parked.HedgeQuantity)
+	}
+	if hedged := hedger.trades(); len(hedged) != 2 || hedged[0] != "queued" || hedged[1] != "unrouted" {
+		t.Errorf("hedged = %v, want queued and unrouted", hedged)
+	}
+	if done, _ := j.Trade("done"); done.Status != types.TradeStatusHedged || len(j.History("done")) != 1 {
+		t.Errorf("finished trade was touched: %+v", done)
+	}
+}
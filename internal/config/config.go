@@ -18,17 +18,27 @@ type Config struct {
 	ExchangeTestMode          bool
 	DeribitApiKey             string
 	DeribitApiSecret          string
+	DeriveSubaccount          uint64 // Derive subaccount to trade from (0 = default)
 	
 	// Trading configuration
 	DummyPrice                string
 	QuoteValidDurationSeconds int64
 	AssetMapping              map[string]string
 	
+	// Pricing configuration
+	PricingMode               string  // book, model or blend
+	VolSource                 string  // Exchange to build the vol surface from (derive, deribit)
+	ModelWeight               float64 // Weight of the model price when blending (0-1)
+	EdgeBps                   float64 // Edge in bps of underlying notional per contract
+	EdgeSizeBps               float64 // Additional edge in bps per contract of size
+	MinEdge                   float64 // Minimum edge per contract in quote currency
+	
 	// Risk and hedging configuration
 	MaxPositionDelta          float64
 	MinLiquidityScore         float64
 	EnableGammaHedging        bool
 	GammaThreshold            float64
+	MaxMarginUsage            float64 // Stop responding to RFQs at this margin utilization (0 = no limit)
 	EnableManualTrades        bool
 	
 	// Infrastructure configuration
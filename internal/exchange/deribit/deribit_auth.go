@@ -0,0 +1,28 @@
+package deribit
+
+// Authenticator supplies the public/auth parameters for a Deribit session.
+// It is called for every (re)authentication, so signature based backends
+// must sign a fresh timestamp and nonce each time.
+type Authenticator interface {
+	AuthParams() map[string]interface{}
+}
+
+// ClientCredentials authenticates with an API key and secret
+type ClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// AuthParams returns client_credentials parameters
+func (c *ClientCredentials) AuthParams() map[string]interface{} {
+	return map[string]interface{}{
+		"grant_type":    "client_credentials",
+		"client_id":     c.ClientID,
+		"client_secret": c.ClientSecret,
+	}
+}
+
+var (
+	_ Authenticator = (*ClientCredentials)(nil)
+	_ Authenticator = (*DeribitEd25519Client)(nil)
+)
@@ -2,32 +2,38 @@ package deribit
 
 import (
 	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/ratelimit"
 	"bytes"
-	"crypto/ed25519"
-	"crypto/x509"
-	"encoding/base64"
+	"context"
 	"encoding/json"
-	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
-// DeribitClient is a complete Deribit API client using Ed25519 authentication
+// DeribitClient is a complete Deribit API client. Authentication is
+// delegated to an Authenticator, either an Ed25519 key or an API secret.
 type DeribitClient struct {
 	ClientID   string
-	PrivateKey ed25519.PrivateKey
 	BaseURL    string
 	HTTPClient *http.Client
+	auth       Authenticator
+	limiter    *ratelimit.Limiter
 
 	// Token management
 	accessToken  string
 	refreshToken string
 	tokenExpiry  time.Time
 	tokenMutex   sync.RWMutex
+	authMutex    sync.Mutex // Serializes re-authentication
 }
 
 // DeribitResponse is the standard JSON-RPC response
@@ -44,30 +50,35 @@ type DeribitError struct {
 	Message string `json:"message"`
 }
 
+// Error implements the error interface
+func (e *DeribitError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Code, e.Message)
+}
+
+// Deribit error codes we handle explicitly
+const (
+	errCodeNotOpenOrder = 11044
+	errCodeUnauthorized = 13009
+)
+
 // NewDeribitClient creates a new Deribit client with Ed25519 authentication
 func NewDeribitClient(clientID string, privateKeyPEM string, testnet bool) (*DeribitClient, error) {
-	// Parse the private key
-	block, _ := pem.Decode([]byte(privateKeyPEM))
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block containing the private key")
-	}
-
-	// Try parsing as PKCS8 first
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	ed25519Client, err := NewDeribitEd25519Client(clientID, privateKeyPEM, testnet)
 	if err != nil {
-		// Try as raw Ed25519 seed
-		if len(block.Bytes) == ed25519.SeedSize {
-			key = ed25519.NewKeyFromSeed(block.Bytes)
-		} else {
-			return nil, fmt.Errorf("failed to parse private key: %v", err)
-		}
+		return nil, err
 	}
 
-	ed25519Key, ok := key.(ed25519.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("not an Ed25519 private key")
-	}
+	return NewDeribitClientWithAuth(clientID, ed25519Client, testnet)
+}
+
+// NewDeribitClientWithSecret creates a new Deribit client with API key
+// (client_credentials) authentication
+func NewDeribitClientWithSecret(clientID string, clientSecret string, testnet bool) (*DeribitClient, error) {
+	return NewDeribitClientWithAuth(clientID, &ClientCredentials{ClientID: clientID, ClientSecret: clientSecret}, testnet)
+}
 
+// NewDeribitClientWithAuth creates a new Deribit client using auth for tokens
+func NewDeribitClientWithAuth(clientID string, auth Authenticator, testnet bool) (*DeribitClient, error) {
 	baseURL := "https://www.deribit.com"
 	if testnet {
 		baseURL = "https://test.deribit.com"
@@ -75,9 +86,10 @@ func NewDeribitClient(clientID string, privateKeyPEM string, testnet bool) (*Der
 
 	client := &DeribitClient{
 		ClientID:   clientID,
-		PrivateKey: ed25519Key,
 		BaseURL:    baseURL,
 		HTTPClient: shared.NewHTTPClient(),
+		auth:       auth,
+		limiter:    ratelimit.For("deribit", deribitLimits),
 	}
 
 	// Authenticate immediately
@@ -88,27 +100,27 @@ func NewDeribitClient(clientID string, privateKeyPEM string, testnet bool) (*Der
 	return client, nil
 }
 
-// authenticate performs the Ed25519 authentication
-func (c *DeribitClient) authenticate() error {
-	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
-	nonce := fmt.Sprintf("%d", timestamp)
-	data := ""
+// Authenticator returns the credentials backing the client, so other
+// connections such as the WebSocket can authenticate the same way
+func (c *DeribitClient) Authenticator() Authenticator {
+	return c.auth
+}
 
-	// Create signature
-	stringToSign := fmt.Sprintf("%d\n%s\n%s", timestamp, nonce, data)
-	signature := ed25519.Sign(c.PrivateKey, []byte(stringToSign))
-	signatureB64 := base64.StdEncoding.EncodeToString(signature)
+// authenticate obtains a fresh access token from the authenticator
+func (c *DeribitClient) authenticate() error {
+	return c.requestToken(c.auth.AuthParams())
+}
 
-	// Build request
-	params := map[string]interface{}{
-		"grant_type": "client_signature",
-		"client_id":  c.ClientID,
-		"timestamp":  timestamp,
-		"signature":  signatureB64,
-		"nonce":      nonce,
-		"data":       data,
-	}
+// refresh exchanges the refresh token for a new access token
+func (c *DeribitClient) refresh(refreshToken string) error {
+	return c.requestToken(map[string]interface{}{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
+}
 
+// requestToken calls public/auth and stores the resulting tokens
+func (c *DeribitClient) requestToken(params map[string]interface{}) error {
 	resp, err := c.call("public/auth", params, false)
 	if err != nil {
 		return err
@@ -137,20 +149,109 @@ func (c *DeribitClient) authenticate() error {
 	return nil
 }
 
-// ensureAuthenticated checks and refreshes token if needed
+// ensureAuthenticated checks and refreshes token if needed, falling back to
+// full authentication when the refresh token is rejected
 func (c *DeribitClient) ensureAuthenticated() error {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+
 	c.tokenMutex.RLock()
 	needsRefresh := time.Now().After(c.tokenExpiry)
+	refreshToken := c.refreshToken
 	c.tokenMutex.RUnlock()
 
-	if needsRefresh {
-		return c.authenticate()
+	if !needsRefresh {
+		return nil
 	}
-	return nil
+
+	if refreshToken != "" {
+		err := c.refresh(refreshToken)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Token refresh failed, re-authenticating: %v", err)
+	}
+	return c.authenticate()
+}
+
+// reauthenticate replaces a token the server rejected
+func (c *DeribitClient) reauthenticate() error {
+	c.authMutex.Lock()
+	defer c.authMutex.Unlock()
+	return c.authenticate()
+}
+
+// deribitLimits are Deribit's default matching engine and non-matching
+// request rates
+var deribitLimits = ratelimit.Limits{
+	Matching:    ratelimit.Rate{PerSecond: 5, Burst: 20},
+	NonMatching: ratelimit.Rate{PerSecond: 20, Burst: 100},
+}
+
+// requestPriority ranks a method under the rate limit: cancels first, then
+// orders and account queries, then market data
+func requestPriority(method string) ratelimit.Priority {
+	switch {
+	case strings.HasPrefix(method, "private/cancel"):
+		return ratelimit.PriorityHigh
+	case strings.HasPrefix(method, "private/"):
+		return ratelimit.PriorityNormal
+	default:
+		return ratelimit.PriorityLow
+	}
+}
+
+// isMatching reports whether a method goes to the matching engine
+func isMatching(method string) bool {
+	switch method {
+	case "private/buy", "private/sell", "private/edit":
+		return true
+	}
+	return strings.HasPrefix(method, "private/cancel")
+}
+
+// wait waits for room under the rate limit for a request
+func (c *DeribitClient) wait(method string, priority ratelimit.Priority) error {
+	if c.limiter == nil {
+		return nil
+	}
+	bucket := c.limiter.NonMatching
+	if isMatching(method) {
+		bucket = c.limiter.Matching
+	}
+	return bucket.Wait(context.Background(), priority)
 }
 
-// call makes an API call
+// call makes an API call. A private call rejected for an invalid token is
+// retried once after re-authenticating.
 func (c *DeribitClient) call(method string, params map[string]interface{}, private bool) (*DeribitResponse, error) {
+	return c.callAt(method, params, private, requestPriority(method))
+}
+
+// callAt makes an API call at a rate limit priority
+func (c *DeribitClient) callAt(method string, params map[string]interface{}, private bool, priority ratelimit.Priority) (*DeribitResponse, error) {
+	if err := c.wait(method, priority); err != nil {
+		return nil, err
+	}
+	resp, err := c.send(method, params, private)
+
+	var apiErr *DeribitError
+	if private && errors.As(err, &apiErr) && apiErr.Code == errCodeUnauthorized {
+		log.Printf("Access token rejected for %s, re-authenticating", method)
+		if authErr := c.reauthenticate(); authErr != nil {
+			return nil, fmt.Errorf("re-authentication failed: %w", authErr)
+		}
+		if err := c.wait(method, priority); err != nil {
+			return nil, err
+		}
+		return c.send(method, params, private)
+	}
+
+	return resp, err
+}
+
+// send makes a single JSON-RPC request
+func (c *DeribitClient) send(method string, params map[string]interface{}, private bool) (*DeribitResponse, error) {
 	request := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"id":      time.Now().UnixNano(),
@@ -194,7 +295,7 @@ func (c *DeribitClient) call(method string, params map[string]interface{}, priva
 	}
 
 	if result.Error != nil {
-		return nil, fmt.Errorf("API error %d: %s", result.Error.Code, result.Error.Message)
+		return nil, result.Error
 	}
 
 	return &result, nil
@@ -204,9 +305,14 @@ func (c *DeribitClient) call(method string, params map[string]interface{}, priva
 
 // GetOrderBook fetches the order book for an instrument
 func (c *DeribitClient) GetOrderBook(instrument string) (*OrderBook, error) {
+	return c.GetOrderBookDepth(instrument, 10)
+}
+
+// GetOrderBookDepth fetches the order book for an instrument to the given depth
+func (c *DeribitClient) GetOrderBookDepth(instrument string, depth int) (*OrderBook, error) {
 	params := map[string]interface{}{
 		"instrument_name": instrument,
-		"depth":           10,
+		"depth":           depth,
 	}
 
 	resp, err := c.call("public/get_order_book", params, false)
@@ -222,6 +328,46 @@ func (c *DeribitClient) GetOrderBook(instrument string) (*OrderBook, error) {
 	return &book, nil
 }
 
+// GetInstrument fetches the contract specification for an instrument
+func (c *DeribitClient) GetInstrument(instrument string) (*Instrument, error) {
+	params := map[string]interface{}{
+		"instrument_name": instrument,
+	}
+
+	resp, err := c.call("public/get_instrument", params, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var inst Instrument
+	if err := json.Unmarshal(resp.Result, &inst); err != nil {
+		return nil, err
+	}
+
+	return &inst, nil
+}
+
+// GetInstruments fetches the specifications of every active instrument in a
+// currency, or in all currencies for "any"
+func (c *DeribitClient) GetInstruments(currency string) ([]Instrument, error) {
+	params := map[string]interface{}{
+		"currency": currency,
+		"expired":  false,
+	}
+
+	resp, err := c.call("public/get_instruments", params, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Instrument
+	if err := json.Unmarshal(resp.Result, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
 // Private API Methods
 
 // GetAccountSummary gets account summary for a currency
@@ -267,18 +413,89 @@ func (c *DeribitClient) PlaceOrder(instrument string, amount float64, orderType
 		params["price"] = price
 	}
 
+	return c.SubmitOrder(side, params, ratelimit.PriorityNormal)
+}
+
+// SubmitOrder sends private/buy or private/sell with raw order parameters.
+// Unlike PlaceOrder, prices are in the instrument's own currency.
+func (c *DeribitClient) SubmitOrder(side string, params map[string]interface{}, priority ratelimit.Priority) (*Order, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	if side != "buy" && side != "sell" {
+		return nil, fmt.Errorf("invalid side: %s", side)
+	}
+
 	method := fmt.Sprintf("private/%s", side) // private/buy or private/sell
-	resp, err := c.call(method, params, true)
+	resp, err := c.callAt(method, params, true, priority)
 	if err != nil {
 		return nil, err
 	}
 
-	var order Order
-	if err := json.Unmarshal(resp.Result, &order); err != nil {
+	return parseOrderResult(resp.Result)
+}
+
+// EditOrder changes the amount and price of an open order in place
+func (c *DeribitClient) EditOrder(orderID string, amount float64, price float64) (*Order, error) {
+	return c.EditOrderWithParams(map[string]interface{}{
+		"order_id": orderID,
+		"amount":   amount,
+		"price":    price,
+	}, ratelimit.PriorityNormal)
+}
+
+// EditOrderWithParams sends private/edit with raw parameters, which must
+// include order_id
+func (c *DeribitClient) EditOrderWithParams(params map[string]interface{}, priority ratelimit.Priority) (*Order, error) {
+	if err := c.ensureAuthenticated(); err != nil {
 		return nil, err
 	}
 
-	return &order, nil
+	resp, err := c.callAt("private/edit", params, true, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOrderResult(resp.Result)
+}
+
+// parseOrderResult extracts the order from a buy/sell/edit result
+func parseOrderResult(raw json.RawMessage) (*Order, error) {
+	var result struct {
+		Order Order `json:"order"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	if result.Order.OrderID == "" {
+		return nil, fmt.Errorf("no order in response: %s", string(raw))
+	}
+
+	return &result.Order, nil
+}
+
+// GetPositions gets open positions across all currencies
+func (c *DeribitClient) GetPositions() ([]Position, error) {
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"currency": "any",
+	}
+
+	resp, err := c.call("private/get_positions", params, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []Position
+	if err := json.Unmarshal(resp.Result, &positions); err != nil {
+		return nil, err
+	}
+
+	return positions, nil
 }
 
 // GetOpenOrders gets all open orders
@@ -339,5 +556,92 @@ type Order struct {
 	OrderState     string  `json:"order_state"`
 	FilledAmount   float64 `json:"filled_amount"`
 	AveragePrice   float64 `json:"average_price"`
+	CreationTime   int64   `json:"creation_timestamp"`
 	LastUpdateTime int64   `json:"last_update_timestamp"`
 }
+
+// UserTrade is an execution of one of our orders
+type UserTrade struct {
+	TradeID        string  `json:"trade_id"`
+	OrderID        string  `json:"order_id"`
+	InstrumentName string  `json:"instrument_name"`
+	Direction      string  `json:"direction"`
+	Price          float64 `json:"price"`
+	Amount         float64 `json:"amount"`
+	Fee            float64 `json:"fee"`
+	ProfitLoss     float64 `json:"profit_loss"`
+	Timestamp      int64   `json:"timestamp"`
+}
+
+// Position is a Deribit position. Size is signed, in USD for inverse futures
+// and in the base currency otherwise.
+type Position struct {
+	InstrumentName  string  `json:"instrument_name"`
+	Kind            string  `json:"kind"`
+	Direction       string  `json:"direction"`
+	Size            float64 `json:"size"`
+	AveragePrice    float64 `json:"average_price"`
+	MarkPrice       float64 `json:"mark_price"`
+	IndexPrice      float64 `json:"index_price"`
+	TotalProfitLoss float64 `json:"total_profit_loss"`
+}
+
+// Instrument is a Deribit contract specification
+type Instrument struct {
+	InstrumentName     string  `json:"instrument_name"`
+	Kind               string  `json:"kind"`
+	TickSize           float64 `json:"tick_size"`
+	MinTradeAmount     float64 `json:"min_trade_amount"`
+	ContractSize       float64 `json:"contract_size"`
+	Strike             float64 `json:"strike"`
+	OptionType         string  `json:"option_type"`
+	ExpirationTime     int64   `json:"expiration_timestamp"`
+	BaseCurrency       string  `json:"base_currency"`
+	QuoteCurrency      string  `json:"quote_currency"`
+	SettlementCurrency string  `json:"settlement_currency"`
+	SettlementPeriod   string  `json:"settlement_period"`
+	InstrumentType     string  `json:"instrument_type"` // "linear" or "reversed"
+	IsActive           bool    `json:"is_active"`
+}
+
+// ToInstrument converts to registry metadata. It returns false for kinds the
+// registry doesn't track, such as spot and combos.
+func (inst Instrument) ToInstrument() (instruments.Instrument, bool) {
+	result := instruments.Instrument{
+		Name:       inst.InstrumentName,
+		Exchange:   "deribit",
+		Underlying: inst.BaseCurrency,
+		TickSize:   decimal.NewFromFloat(inst.TickSize),
+		MinAmount:  decimal.NewFromFloat(inst.MinTradeAmount),
+		// Amounts are traded in multiples of the minimum, which for options
+		// is finer than their contract size of 1
+		AmountStep: decimal.NewFromFloat(inst.MinTradeAmount),
+	}
+
+	// Inverse contracts are sized in USD
+	if inst.InstrumentType != "reversed" {
+		result.ContractMultiplier = decimal.NewFromInt(1)
+	}
+	if inst.ExpirationTime > 0 {
+		result.Expiry = time.UnixMilli(inst.ExpirationTime).UTC()
+	}
+
+	switch {
+	case inst.Kind == "option":
+		result.Kind = instruments.KindOption
+		result.Strike = decimal.NewFromFloat(inst.Strike)
+		result.OptionType = "C"
+		if inst.OptionType == "put" {
+			result.OptionType = "P"
+		}
+	case inst.Kind == "future" && inst.SettlementPeriod == "perpetual":
+		result.Kind = instruments.KindPerp
+		result.Expiry = time.Time{}
+	case inst.Kind == "future":
+		result.Kind = instruments.KindFuture
+	default:
+		return result, false
+	}
+
+	return result, true
+}
@@ -60,8 +60,9 @@ func NewDeribitEd25519Client(clientID string, privateKeyPEM string, testnet bool
 	}, nil
 }
 
-// Authenticate performs the client_signature authentication
-func (c *DeribitEd25519Client) Authenticate() (string, error) {
+// AuthParams returns freshly signed client_signature parameters for
+// public/auth. Each call uses a new timestamp and nonce.
+func (c *DeribitEd25519Client) AuthParams() map[string]interface{} {
 	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
 	nonce := fmt.Sprintf("%d", timestamp) // Simple nonce using timestamp
 	data := ""
@@ -75,19 +76,24 @@ func (c *DeribitEd25519Client) Authenticate() (string, error) {
 	// Encode signature as base64
 	signatureB64 := base64.StdEncoding.EncodeToString(signature)
 
+	return map[string]interface{}{
+		"grant_type": "client_signature",
+		"client_id":  c.ClientID,
+		"timestamp":  timestamp,
+		"signature":  signatureB64,
+		"nonce":      nonce,
+		"data":       data,
+	}
+}
+
+// Authenticate performs the client_signature authentication
+func (c *DeribitEd25519Client) Authenticate() (string, error) {
 	// Create auth request
 	authRequest := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"id":      1,
 		"method":  "public/auth",
-		"params": map[string]interface{}{
-			"grant_type": "client_signature",
-			"client_id":  c.ClientID,
-			"timestamp":  timestamp,
-			"signature":  signatureB64,
-			"nonce":      nonce,
-			"data":       data,
-		},
+		"params":  c.AuthParams(),
 	}
 
 	// Send request
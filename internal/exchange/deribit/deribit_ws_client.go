@@ -0,0 +1,369 @@
+package deribit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DeribitWSClient maintains a Deribit WebSocket connection, reconnecting,
+// re-authenticating and resubscribing when it drops
+type DeribitWSClient struct {
+	wsURL   string
+	auth    Authenticator // nil for public channels only
+	conn    *websocket.Conn
+	mu      sync.Mutex
+	writeMu sync.Mutex // Protects WebSocket writes
+
+	// channel -> handler id -> handler
+	handlers  map[string]map[int]func(json.RawMessage)
+	nextID    int
+	handlerMu sync.RWMutex
+
+	// Connection state management
+	isConnected       bool
+	reconnectChan     chan struct{}
+	shutdownChan      chan struct{}
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+}
+
+// wsReauthInterval re-authenticates the session before Deribit's 15 minute
+// access tokens lapse
+const wsReauthInterval = 10 * time.Minute
+
+// NewDeribitWSClient connects to the Deribit WebSocket API. With a non-nil
+// auth the session is authenticated on every connect, so private channels
+// survive reconnects.
+func NewDeribitWSClient(testnet bool, auth Authenticator) (*DeribitWSClient, error) {
+	wsURL := "wss://www.deribit.com/ws/api/v2"
+	if testnet {
+		wsURL = "wss://test.deribit.com/ws/api/v2"
+	}
+
+	client := &DeribitWSClient{
+		wsURL:             wsURL,
+		auth:              auth,
+		handlers:          make(map[string]map[int]func(json.RawMessage)),
+		reconnectChan:     make(chan struct{}, 1),
+		shutdownChan:      make(chan struct{}),
+		reconnectDelay:    1 * time.Second,
+		maxReconnectDelay: 30 * time.Second,
+	}
+
+	if err := client.connect(); err != nil {
+		return nil, fmt.Errorf("failed to establish initial connection: %w", err)
+	}
+
+	go client.connectionMonitor()
+
+	return client, nil
+}
+
+// connect dials the WebSocket, enables heartbeats and resubscribes
+func (c *DeribitWSClient) connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+
+	log.Printf("[Deribit WS] Connecting to %s", c.wsURL)
+
+	dialer := websocket.DefaultDialer
+	dialer.HandshakeTimeout = 10 * time.Second
+
+	conn, _, err := dialer.Dial(c.wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Deribit WebSocket: %w", err)
+	}
+	c.conn = conn
+	c.isConnected = true
+
+	go c.handleMessages(conn)
+
+	// Deribit sends test_request heartbeats we must answer, and we treat
+	// missing traffic as a dead connection
+	if err := c.writeLocked("public/set_heartbeat", map[string]interface{}{"interval": 30}); err != nil {
+		log.Printf("[Deribit WS] Failed to enable heartbeat: %v", err)
+	}
+
+	// Requests are handled in order, so auth completes before resubscribing
+	if c.auth != nil {
+		if err := c.writeLocked("public/auth", c.auth.AuthParams()); err != nil {
+			conn.Close()
+			c.conn = nil
+			c.isConnected = false
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	c.resubscribe()
+
+	log.Printf("[Deribit WS] Connected")
+	return nil
+}
+
+// connectionMonitor reconnects when the read loop reports a failure and
+// keeps an authenticated session's token fresh
+func (c *DeribitWSClient) connectionMonitor() {
+	reauth := time.NewTicker(wsReauthInterval)
+	defer reauth.Stop()
+
+	for {
+		select {
+		case <-c.reconnectChan:
+			c.performReconnection()
+		case <-reauth.C:
+			if c.auth == nil {
+				continue
+			}
+			if err := c.write("public/auth", c.auth.AuthParams()); err != nil {
+				log.Printf("[Deribit WS] Re-authentication failed: %v", err)
+				c.triggerReconnection()
+			}
+		case <-c.shutdownChan:
+			return
+		}
+	}
+}
+
+// performReconnection retries the connection with exponential backoff
+func (c *DeribitWSClient) performReconnection() {
+	c.mu.Lock()
+	c.isConnected = false
+	c.mu.Unlock()
+
+	delay := c.reconnectDelay
+	for {
+		select {
+		case <-c.shutdownChan:
+			return
+		default:
+		}
+
+		log.Printf("[Deribit WS] Attempting reconnection in %v", delay)
+		time.Sleep(delay)
+
+		if err := c.connect(); err != nil {
+			log.Printf("[Deribit WS] Reconnection failed: %v", err)
+			delay = delay * 2
+			if delay > c.maxReconnectDelay {
+				delay = c.maxReconnectDelay
+			}
+			continue
+		}
+		return
+	}
+}
+
+// triggerReconnection schedules a reconnection if one isn't already pending
+func (c *DeribitWSClient) triggerReconnection() {
+	select {
+	case c.reconnectChan <- struct{}{}:
+	default:
+	}
+}
+
+// handleMessages reads from conn until it fails
+func (c *DeribitWSClient) handleMessages(conn *websocket.Conn) {
+	for {
+		// Heartbeats arrive every 30s, so a minute of silence means trouble
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-c.shutdownChan:
+				return
+			default:
+			}
+
+			c.mu.Lock()
+			current := c.conn == conn
+			c.mu.Unlock()
+
+			// Only the live connection may trigger a reconnect
+			if current {
+				log.Printf("[Deribit WS] Read error: %v", err)
+				c.triggerReconnection()
+			}
+			return
+		}
+
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Type    string          `json:"type"`
+				Channel string          `json:"channel"`
+				Data    json.RawMessage `json:"data"`
+			} `json:"params"`
+			Error *DeribitError `json:"error"`
+		}
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
+		}
+
+		switch msg.Method {
+		case "heartbeat":
+			if msg.Params.Type == "test_request" {
+				if err := c.write("public/test", map[string]interface{}{}); err != nil {
+					log.Printf("[Deribit WS] Failed to answer heartbeat: %v", err)
+				}
+			}
+		case "subscription":
+			c.dispatch(msg.Params.Channel, msg.Params.Data)
+		default:
+			if msg.Error != nil {
+				log.Printf("[Deribit WS] Request failed: %v", msg.Error)
+				if msg.Error.Code == errCodeUnauthorized {
+					c.triggerReconnection()
+				}
+			}
+		}
+	}
+}
+
+// dispatch delivers a subscription message to the channel's handlers
+func (c *DeribitWSClient) dispatch(channel string, data json.RawMessage) {
+	c.handlerMu.RLock()
+	handlers := make([]func(json.RawMessage), 0, len(c.handlers[channel]))
+	for _, handler := range c.handlers[channel] {
+		handlers = append(handlers, handler)
+	}
+	c.handlerMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+}
+
+// Subscribe registers handler for a channel such as ticker.ETH-PERPETUAL.100ms.
+// The returned function removes the handler, unsubscribing from the channel
+// once no handlers remain. Private user.* channels need an authenticated
+// client.
+func (c *DeribitWSClient) Subscribe(channel string, handler func(json.RawMessage)) (func(), error) {
+	select {
+	case <-c.shutdownChan:
+		return nil, fmt.Errorf("client closed")
+	default:
+	}
+
+	c.handlerMu.Lock()
+	first := len(c.handlers[channel]) == 0
+	if first {
+		c.handlers[channel] = make(map[int]func(json.RawMessage))
+	}
+	id := c.nextID
+	c.nextID++
+	c.handlers[channel][id] = handler
+	c.handlerMu.Unlock()
+
+	if first {
+		if err := c.write(channelMethod(channel, "subscribe"), map[string]interface{}{"channels": []string{channel}}); err != nil {
+			// Still registered, so the subscription is retried on reconnect
+			log.Printf("[Deribit WS] Failed to subscribe to %s: %v", channel, err)
+		}
+	}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { c.removeHandler(channel, id) })
+	}
+	return unsubscribe, nil
+}
+
+// removeHandler drops a handler and unsubscribes from an unused channel
+func (c *DeribitWSClient) removeHandler(channel string, id int) {
+	c.handlerMu.Lock()
+	delete(c.handlers[channel], id)
+	last := len(c.handlers[channel]) == 0
+	if last {
+		delete(c.handlers, channel)
+	}
+	c.handlerMu.Unlock()
+
+	if last {
+		if err := c.write(channelMethod(channel, "unsubscribe"), map[string]interface{}{"channels": []string{channel}}); err != nil {
+			log.Printf("[Deribit WS] Failed to unsubscribe from %s: %v", channel, err)
+		}
+	}
+}
+
+// resubscribe re-subscribes to all channels with handlers. Caller holds c.mu.
+func (c *DeribitWSClient) resubscribe() {
+	// Public and private channels use different methods
+	byMethod := make(map[string][]string)
+	c.handlerMu.RLock()
+	for channel := range c.handlers {
+		method := channelMethod(channel, "subscribe")
+		byMethod[method] = append(byMethod[method], channel)
+	}
+	c.handlerMu.RUnlock()
+
+	for method, channels := range byMethod {
+		if err := c.writeLocked(method, map[string]interface{}{"channels": channels}); err != nil {
+			log.Printf("[Deribit WS] Failed to resubscribe: %v", err)
+		} else {
+			log.Printf("[Deribit WS] Resubscribed to %d channels via %s", len(channels), method)
+		}
+	}
+}
+
+// channelMethod returns the public or private variant of action for channel
+func channelMethod(channel, action string) string {
+	if strings.HasPrefix(channel, "user.") {
+		return "private/" + action
+	}
+	return "public/" + action
+}
+
+// write sends a JSON-RPC request without waiting for the response
+func (c *DeribitWSClient) write(method string, params map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeLocked(method, params)
+}
+
+// writeLocked is write for callers already holding c.mu
+func (c *DeribitWSClient) writeLocked(method string, params map[string]interface{}) error {
+	if c.conn == nil || !c.isConnected {
+		return fmt.Errorf("not connected")
+	}
+
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      time.Now().UnixNano(),
+		"method":  method,
+		"params":  params,
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+// Close shuts down the connection and stops reconnecting
+func (c *DeribitWSClient) Close() error {
+	select {
+	case <-c.shutdownChan:
+		return nil
+	default:
+		close(c.shutdownChan)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isConnected = false
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
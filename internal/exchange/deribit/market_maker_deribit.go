@@ -1,55 +1,559 @@
 package deribit
 
 import (
-    "context"
-    "fmt"
-    
-    "github.com/shopspring/decimal"
-    "github.com/wakamex/atomizer/internal/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/ratelimit"
+	"github.com/wakamex/atomizer/internal/types"
 )
 
-// DeribitMarketMakerExchange implements types.MarketMakerExchange for Deribit
+// DeribitMarketMakerExchange implements types.MarketMakerExchange for Deribit.
+// Orders go over REST via DeribitClient; tickers and books stream over
+// DeribitWSClient. Prices are in the instrument's own currency (ETH for ETH
+// options, USD/USDC for perps), matching what the tickers report.
 type DeribitMarketMakerExchange struct {
-    // TODO: Add WebSocket client and other fields as needed
+	client   *DeribitClient
+	wsClient *DeribitWSClient
+
+	// Cached order books from book subscriptions
+	orderbooks    map[string]*types.MarketMakerOrderBook
+	orderbookSubs map[string]func()
+	orderbookMu   sync.RWMutex
+
+	// Registry key for this environment's instruments
+	venue string
 }
 
-// NewDeribitMarketMakerExchange creates a new Deribit exchange adapter
-func NewDeribitMarketMakerExchange(apiKey, apiSecret string) (*DeribitMarketMakerExchange, error) {
-    // TODO: Implement Deribit WebSocket client initialization
-    return &DeribitMarketMakerExchange{}, nil
+// NewDeribitMarketMakerExchange creates a new Deribit exchange adapter on an
+// authenticated client
+func NewDeribitMarketMakerExchange(client *DeribitClient, testnet bool) (*DeribitMarketMakerExchange, error) {
+	wsClient, err := NewDeribitWSClient(testnet, client.Authenticator())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebSocket client: %w", err)
+	}
+
+	venue := "deribit"
+	if testnet {
+		venue = "deribit_testnet"
+	}
+	instruments.Default.Register(venue, func() ([]instruments.Instrument, error) {
+		return loadInstruments(client)
+	})
+
+	return &DeribitMarketMakerExchange{
+		client:        client,
+		wsClient:      wsClient,
+		orderbooks:    make(map[string]*types.MarketMakerOrderBook),
+		orderbookSubs: make(map[string]func()),
+		venue:         venue,
+	}, nil
 }
 
-// SubscribeTickers subscribes to real-time ticker updates
+// loadInstruments fetches every active Deribit instrument as registry metadata
+func loadInstruments(client *DeribitClient) ([]instruments.Instrument, error) {
+	raw, err := client.GetInstruments("any")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]instruments.Instrument, 0, len(raw))
+	for _, inst := range raw {
+		if converted, ok := inst.ToInstrument(); ok {
+			list = append(list, converted)
+		}
+	}
+	return list, nil
+}
+
+// GetInstrument returns an instrument's metadata from the shared registry
+func (d *DeribitMarketMakerExchange) GetInstrument(instrument string) (*instruments.Instrument, error) {
+	return instruments.Default.Get(d.venue, instrument)
+}
+
+// SubscribeTickers streams ticker updates for the given instruments until ctx
+// is cancelled
 func (d *DeribitMarketMakerExchange) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
-    return nil, fmt.Errorf("deribit market maker not yet implemented")
+	tickerChan := make(chan types.TickerUpdate, 100)
+
+	var (
+		unsubscribes []func()
+		mu           sync.Mutex
+		closed       bool
+	)
+
+	handler := func(data json.RawMessage) {
+		ticker, err := parseTicker(data)
+		if err != nil {
+			log.Printf("[Deribit] Failed to parse ticker: %v", err)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+
+		select {
+		case tickerChan <- *ticker:
+		default:
+			log.Printf("Ticker channel full, dropping update for %s", ticker.Instrument)
+		}
+	}
+
+	for _, instrument := range instruments {
+		unsubscribe, err := d.wsClient.Subscribe(fmt.Sprintf("ticker.%s.100ms", instrument), handler)
+		if err != nil {
+			for _, u := range unsubscribes {
+				u()
+			}
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", instrument, err)
+		}
+		unsubscribes = append(unsubscribes, unsubscribe)
+	}
+
+	go func() {
+		<-ctx.Done()
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+
+		mu.Lock()
+		closed = true
+		close(tickerChan)
+		mu.Unlock()
+	}()
+
+	return tickerChan, nil
+}
+
+// deribitTimeInForce maps a time in force to Deribit's names
+var deribitTimeInForce = map[types.TimeInForce]string{
+	types.TimeInForceGTC: "good_til_cancelled",
+	types.TimeInForceIOC: "immediate_or_cancel",
+	types.TimeInForceFOK: "fill_or_kill",
+}
+
+// addOrderOptions sets the order option parameters. Post-only orders that
+// would cross are rejected rather than repriced, matching Derive.
+func addOrderOptions(params map[string]interface{}, opts types.OrderOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.PostOnly {
+		params["post_only"] = true
+		params["reject_post_only"] = true
+	}
+	if opts.ReduceOnly {
+		params["reduce_only"] = true
+	}
+	return nil
 }
 
-// PlaceLimitOrder places a limit order
-func (d *DeribitMarketMakerExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal) (string, error) {
-    return "", fmt.Errorf("deribit market maker not yet implemented")
+// PlaceLimitOrder places a limit order, rounding to the instrument's tick and
+// contract size
+func (d *DeribitMarketMakerExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	price, amount, err := d.roundOrder(instrument, price, amount)
+	if err != nil {
+		return "", err
+	}
+
+	params := map[string]interface{}{
+		"instrument_name": instrument,
+		"amount":          amount.InexactFloat64(),
+		"type":            "limit",
+		"price":           price.InexactFloat64(),
+		"label":           "atomizer",
+		"time_in_force":   deribitTimeInForce[opts.TIF()],
+	}
+	if err := addOrderOptions(params, opts); err != nil {
+		return "", err
+	}
+
+	order, err := d.client.SubmitOrder(side, params, orderPriority(opts))
+	if err != nil {
+		return "", fmt.Errorf("failed to place order: %w", err)
+	}
+
+	log.Printf("[Deribit] Placed %s %s %s @ %s: %s", side, amount, instrument, price, order.OrderID)
+	return order.OrderID, nil
 }
 
-// ReplaceOrder replaces an existing order
-func (d *DeribitMarketMakerExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal) (string, error) {
-    return "", fmt.Errorf("deribit market maker not yet implemented")
+// orderPriority puts urgent orders, such as hedges, ahead of quotes under the
+// rate limit
+func orderPriority(opts types.OrderOptions) ratelimit.Priority {
+	if opts.Urgent {
+		return ratelimit.PriorityHigh
+	}
+	return ratelimit.PriorityNormal
 }
 
-// CancelOrder cancels an order
+// ReplaceOrder amends an open order in place with private/edit. Deribit keeps
+// the order ID, so the same ID is returned.
+func (d *DeribitMarketMakerExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	price, amount, err := d.roundOrder(instrument, price, amount)
+	if err != nil {
+		return "", err
+	}
+
+	params := map[string]interface{}{
+		"order_id": orderID,
+		"amount":   amount.InexactFloat64(),
+		"price":    price.InexactFloat64(),
+	}
+	if err := addOrderOptions(params, opts); err != nil {
+		return "", err
+	}
+
+	order, err := d.client.EditOrderWithParams(params, orderPriority(opts))
+	if err != nil {
+		return "", fmt.Errorf("failed to edit order: %w", err)
+	}
+
+	return order.OrderID, nil
+}
+
+// CancelOrder cancels an order. Orders that are no longer open are treated as
+// already cancelled.
 func (d *DeribitMarketMakerExchange) CancelOrder(orderID string) error {
-    return fmt.Errorf("deribit market maker not yet implemented")
+	err := d.client.CancelOrder(orderID)
+
+	var apiErr *DeribitError
+	if errors.As(err, &apiErr) && apiErr.Code == errCodeNotOpenOrder {
+		return nil
+	}
+
+	return err
 }
 
-// GetOpenOrders gets active orders
+// GetOpenOrders gets all open orders
 func (d *DeribitMarketMakerExchange) GetOpenOrders() ([]types.MarketMakerOrder, error) {
-    return nil, fmt.Errorf("deribit market maker not yet implemented")
+	rawOrders, err := d.client.GetOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]types.MarketMakerOrder, 0, len(rawOrders))
+	for _, raw := range rawOrders {
+		orders = append(orders, convertOrder(raw))
+	}
+
+	return orders, nil
 }
 
-// GetPositions gets current positions
+// GetPositions gets current non-zero positions. Amounts are signed.
 func (d *DeribitMarketMakerExchange) GetPositions() ([]types.ExchangePosition, error) {
-    return nil, fmt.Errorf("deribit market maker not yet implemented")
+	rawPositions, err := d.client.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]types.ExchangePosition, 0, len(rawPositions))
+	for _, raw := range rawPositions {
+		if raw.Size == 0 {
+			continue
+		}
+		positions = append(positions, types.ExchangePosition{
+			InstrumentName: raw.InstrumentName,
+			Amount:         raw.Size,
+			Direction:      raw.Direction,
+			AveragePrice:   raw.AveragePrice,
+			MarkPrice:      raw.MarkPrice,
+			IndexPrice:     raw.IndexPrice,
+			PnL:            raw.TotalProfitLoss,
+		})
+	}
+
+	return positions, nil
 }
 
-// GetOrderBook gets the order book for an instrument
+// GetOrderBook returns the subscribed order book, falling back to REST
 func (d *DeribitMarketMakerExchange) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
-    return nil, fmt.Errorf("deribit market maker not yet implemented")
-}
\ No newline at end of file
+	d.orderbookMu.RLock()
+	book, ok := d.orderbooks[instrument]
+	d.orderbookMu.RUnlock()
+
+	if ok {
+		return book, nil
+	}
+
+	raw, err := d.client.GetOrderBookDepth(instrument, 20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order book: %w", err)
+	}
+
+	return &types.MarketMakerOrderBook{
+		Bids:      convertLevels(raw.Bids),
+		Asks:      convertLevels(raw.Asks),
+		Index:     decimal.NewFromFloat(raw.IndexPrice),
+		Timestamp: time.UnixMilli(raw.Timestamp),
+	}, nil
+}
+
+// SubscribeOrderBook keeps a 20 level snapshot of an instrument's book
+func (d *DeribitMarketMakerExchange) SubscribeOrderBook(instrument string) error {
+	d.orderbookMu.Lock()
+	defer d.orderbookMu.Unlock()
+
+	if _, ok := d.orderbookSubs[instrument]; ok {
+		return nil
+	}
+
+	unsubscribe, err := d.wsClient.Subscribe(fmt.Sprintf("book.%s.none.20.100ms", instrument), func(data json.RawMessage) {
+		var snapshot struct {
+			Timestamp int64       `json:"timestamp"`
+			Bids      [][]float64 `json:"bids"`
+			Asks      [][]float64 `json:"asks"`
+		}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Printf("[Deribit] Failed to parse book for %s: %v", instrument, err)
+			return
+		}
+
+		d.orderbookMu.Lock()
+		d.orderbooks[instrument] = &types.MarketMakerOrderBook{
+			Bids:      convertLevels(snapshot.Bids),
+			Asks:      convertLevels(snapshot.Asks),
+			Timestamp: time.UnixMilli(snapshot.Timestamp),
+		}
+		d.orderbookMu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+
+	d.orderbookSubs[instrument] = unsubscribe
+	return nil
+}
+
+// SubscribeFills streams executions of our orders on any instrument until
+// ctx is cancelled
+func (d *DeribitMarketMakerExchange) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	fillChan := make(chan types.Fill, 1000)
+
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+
+	unsubscribe, err := d.wsClient.Subscribe("user.trades.any.any.raw", func(data json.RawMessage) {
+		var trades []UserTrade
+		if err := json.Unmarshal(data, &trades); err != nil {
+			log.Printf("[Deribit] Failed to parse trades: %v", err)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		for _, trade := range trades {
+			select {
+			case fillChan <- convertTrade(trade):
+			default:
+				log.Printf("WARNING: Fill channel full, dropping fill %s on %s", trade.TradeID, trade.InstrumentName)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to trades: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+
+		mu.Lock()
+		closed = true
+		close(fillChan)
+		mu.Unlock()
+	}()
+
+	return fillChan, nil
+}
+
+// SubscribeOrderUpdates streams state changes of our orders on any instrument
+// until ctx is cancelled
+func (d *DeribitMarketMakerExchange) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	orderChan := make(chan types.MarketMakerOrder, 1000)
+
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+
+	unsubscribe, err := d.wsClient.Subscribe("user.orders.any.any.raw", func(data json.RawMessage) {
+		// Raw order channels deliver one order per message
+		var order Order
+		if err := json.Unmarshal(data, &order); err != nil {
+			log.Printf("[Deribit] Failed to parse order update: %v", err)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case orderChan <- convertOrder(order):
+		default:
+			log.Printf("WARNING: Order update channel full, dropping update for %s", order.OrderID)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to orders: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+
+		mu.Lock()
+		closed = true
+		close(orderChan)
+		mu.Unlock()
+	}()
+
+	return orderChan, nil
+}
+
+// Close closes the exchange connections
+func (d *DeribitMarketMakerExchange) Close() error {
+	return d.wsClient.Close()
+}
+
+// roundOrder rounds price to the tick size and amount down to the contract
+// size. Unknown instruments are passed through unchanged.
+func (d *DeribitMarketMakerExchange) roundOrder(instrument string, price, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	spec, err := d.GetInstrument(instrument)
+	if err != nil {
+		log.Printf("[Deribit] No instrument spec for %s, sending unrounded: %v", instrument, err)
+		return price, amount, nil
+	}
+
+	return spec.Round(price, amount)
+}
+
+// parseTicker converts a ticker subscription message
+func parseTicker(data json.RawMessage) (*types.TickerUpdate, error) {
+	var raw struct {
+		Timestamp      int64   `json:"timestamp"`
+		InstrumentName string  `json:"instrument_name"`
+		BestBidPrice   float64 `json:"best_bid_price"`
+		BestBidAmount  float64 `json:"best_bid_amount"`
+		BestAskPrice   float64 `json:"best_ask_price"`
+		BestAskAmount  float64 `json:"best_ask_amount"`
+		LastPrice      float64 `json:"last_price"`
+		MarkPrice      float64 `json:"mark_price"`
+		IndexPrice     float64 `json:"index_price"`
+		Greeks         *struct {
+			Delta float64 `json:"delta"`
+			Gamma float64 `json:"gamma"`
+			Vega  float64 `json:"vega"`
+			Theta float64 `json:"theta"`
+		} `json:"greeks"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	ticker := &types.TickerUpdate{
+		Instrument:  raw.InstrumentName,
+		BestBid:     decimal.NewFromFloat(raw.BestBidPrice),
+		BestBidSize: decimal.NewFromFloat(raw.BestBidAmount),
+		BestAsk:     decimal.NewFromFloat(raw.BestAskPrice),
+		BestAskSize: decimal.NewFromFloat(raw.BestAskAmount),
+		LastPrice:   decimal.NewFromFloat(raw.LastPrice),
+		MarkPrice:   decimal.NewFromFloat(raw.MarkPrice),
+		IndexPrice:  decimal.NewFromFloat(raw.IndexPrice),
+		Timestamp:   time.UnixMilli(raw.Timestamp),
+	}
+
+	// Greeks are only present for options
+	if raw.Greeks != nil {
+		delta := decimal.NewFromFloat(raw.Greeks.Delta)
+		gamma := decimal.NewFromFloat(raw.Greeks.Gamma)
+		vega := decimal.NewFromFloat(raw.Greeks.Vega)
+		theta := decimal.NewFromFloat(raw.Greeks.Theta)
+
+		ticker.Delta = &delta
+		ticker.Gamma = &gamma
+		ticker.Vega = &vega
+		ticker.Theta = &theta
+	}
+
+	// Deribit tickers don't carry option details, so take them from the name
+	if strike, expiry, optionType, ok := parseOptionName(raw.InstrumentName); ok {
+		ticker.Strike = &strike
+		ticker.Expiry = &expiry
+		ticker.OptionType = &optionType
+	}
+
+	return ticker, nil
+}
+
+// parseOptionName parses option names like ETH-30MAY25-3000-C
+func parseOptionName(name string) (strike decimal.Decimal, expiry time.Time, optionType string, ok bool) {
+	inst, err := instruments.Parse(name)
+	if err != nil || !inst.IsOption() {
+		return decimal.Zero, time.Time{}, "", false
+	}
+	return inst.Strike, inst.Expiry, inst.OptionType, true
+}
+
+// convertOrder converts a Deribit order
+func convertOrder(raw Order) types.MarketMakerOrder {
+	return types.MarketMakerOrder{
+		OrderID:      raw.OrderID,
+		Instrument:   raw.InstrumentName,
+		Side:         raw.Direction,
+		Price:        decimal.NewFromFloat(raw.Price),
+		Amount:       decimal.NewFromFloat(raw.Amount),
+		FilledAmount: decimal.NewFromFloat(raw.FilledAmount),
+		Status:       raw.OrderState,
+		CreatedAt:    time.UnixMilli(raw.CreationTime),
+		UpdatedAt:    time.UnixMilli(raw.LastUpdateTime),
+	}
+}
+
+// convertTrade converts a Deribit user trade
+func convertTrade(raw UserTrade) types.Fill {
+	return types.Fill{
+		TradeID:     raw.TradeID,
+		OrderID:     raw.OrderID,
+		Instrument:  raw.InstrumentName,
+		Side:        raw.Direction,
+		Price:       decimal.NewFromFloat(raw.Price),
+		Amount:      decimal.NewFromFloat(raw.Amount),
+		Fee:         decimal.NewFromFloat(raw.Fee),
+		RealizedPnL: decimal.NewFromFloat(raw.ProfitLoss),
+		Timestamp:   time.UnixMilli(raw.Timestamp),
+	}
+}
+
+// convertLevels converts [price, amount] pairs to order book levels
+func convertLevels(levels [][]float64) []types.OrderBookLevel {
+	result := make([]types.OrderBookLevel, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			continue
+		}
+		result = append(result, types.OrderBookLevel{
+			Price: decimal.NewFromFloat(level[0]),
+			Size:  decimal.NewFromFloat(level[1]),
+		})
+	}
+	return result
+}
+
+var _ types.MarketMakerExchange = (*DeribitMarketMakerExchange)(nil)
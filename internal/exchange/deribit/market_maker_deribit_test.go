@@ -0,0 +1,123 @@
+package deribit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseTicker(t *testing.T) {
+	data := json.RawMessage(`{
+		"timestamp": 1748592000000,
+		"instrument_name": "ETH-30MAY25-3000-C",
+		"best_bid_price": 0.0215,
+		"best_bid_amount": 12,
+		"best_ask_price": 0.0225,
+		"best_ask_amount": 8,
+		"last_price": null,
+		"mark_price": 0.022,
+		"greeks": {"delta": 0.52, "gamma": 0.0011, "vega": 2.1, "theta": -4.3}
+	}`)
+
+	ticker, err := parseTicker(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !ticker.BestBid.Equal(decimal.NewFromFloat(0.0215)) || !ticker.BestAskSize.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("bad top of book: bid=%s askSize=%s", ticker.BestBid, ticker.BestAskSize)
+	}
+	if !ticker.LastPrice.IsZero() {
+		t.Errorf("null last price should be zero, got %s", ticker.LastPrice)
+	}
+	if ticker.Delta == nil || !ticker.Delta.Equal(decimal.NewFromFloat(0.52)) {
+		t.Errorf("delta = %v, want 0.52", ticker.Delta)
+	}
+	if ticker.Strike == nil || !ticker.Strike.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("strike = %v, want 3000", ticker.Strike)
+	}
+	if want := time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC); ticker.Expiry == nil || !ticker.Expiry.Equal(want) {
+		t.Errorf("expiry = %v, want %v", ticker.Expiry, want)
+	}
+	if ticker.OptionType == nil || *ticker.OptionType != "C" {
+		t.Errorf("option type = %v, want C", ticker.OptionType)
+	}
+
+	perp, err := parseTicker(json.RawMessage(`{"instrument_name": "ETH-PERPETUAL", "mark_price": 2500.5}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if perp.Delta != nil || perp.Strike != nil {
+		t.Error("perp ticker should have no option fields")
+	}
+}
+
+func TestRoundToInstrument(t *testing.T) {
+	tests := []struct {
+		name       string
+		inst       Instrument
+		price      float64
+		amount     float64
+		wantPrice  float64
+		wantAmount float64
+	}{
+		{"option", Instrument{Kind: "option", TickSize: 0.0005, ContractSize: 1, MinTradeAmount: 1}, 0.02237, 3.7, 0.0225, 3},
+		{"btc option", Instrument{Kind: "option", TickSize: 0.0001, ContractSize: 1, MinTradeAmount: 0.1}, 0.01234, 0.35, 0.0123, 0.3},
+		{"linear perp", Instrument{Kind: "future", SettlementPeriod: "perpetual", TickSize: 0.05, ContractSize: 0.001, MinTradeAmount: 0.001}, 2500.123, 0.12345, 2500.1, 0.123},
+		{"no spec", Instrument{Kind: "future"}, 1.23456, 0.5, 1.23456, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, _ := tt.inst.ToInstrument()
+			price, amount := spec.RoundPrice(decimal.NewFromFloat(tt.price)), spec.RoundAmount(decimal.NewFromFloat(tt.amount))
+			if !price.Equal(decimal.NewFromFloat(tt.wantPrice)) {
+				t.Errorf("price = %s, want %v", price, tt.wantPrice)
+			}
+			if !amount.Equal(decimal.NewFromFloat(tt.wantAmount)) {
+				t.Errorf("amount = %s, want %v", amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestChannelMethod(t *testing.T) {
+	if got := channelMethod("ticker.ETH-PERPETUAL.100ms", "subscribe"); got != "public/subscribe" {
+		t.Errorf("public channel method = %s", got)
+	}
+	if got := channelMethod("user.orders.any.any.raw", "unsubscribe"); got != "private/unsubscribe" {
+		t.Errorf("private channel method = %s", got)
+	}
+}
+
+func TestInstrumentMetadata(t *testing.T) {
+	put := Instrument{
+		InstrumentName: "ETH-30MAY25-3000-P",
+		Kind:           "option",
+		BaseCurrency:   "ETH",
+		Strike:         3000,
+		OptionType:     "put",
+		ExpirationTime: 1748592000000,
+		TickSize:       0.0005,
+		ContractSize:   1,
+		MinTradeAmount: 1,
+	}
+	spec, ok := put.ToInstrument()
+	if !ok || !spec.IsPut() || spec.Underlying != "ETH" || !spec.Strike.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("unexpected option metadata: %+v", spec)
+	}
+	if want := time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC); !spec.Expiry.Equal(want) {
+		t.Errorf("expiry = %v, want %v", spec.Expiry, want)
+	}
+
+	inverse, ok := Instrument{InstrumentName: "ETH-PERPETUAL", Kind: "future", SettlementPeriod: "perpetual", InstrumentType: "reversed"}.ToInstrument()
+	if !ok || inverse.Kind != "perp" || !inverse.Expiry.IsZero() || !inverse.ContractMultiplier.IsZero() {
+		t.Errorf("unexpected inverse perp metadata: %+v", inverse)
+	}
+
+	if _, ok := (Instrument{Kind: "spot"}).ToInstrument(); ok {
+		t.Error("expected spot to be skipped")
+	}
+}
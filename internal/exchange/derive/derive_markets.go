@@ -2,12 +2,17 @@ package derive
 
 import (
 	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/ratelimit"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // DeriveInstrument represents an instrument from Derive/Lyra API
@@ -17,6 +22,11 @@ type DeriveInstrument struct {
 	QuoteCurrency  string `json:"quote_currency"`
 	InstrumentType string `json:"instrument_type"`
 	IsActive       bool   `json:"is_active"`
+	TickSize         string `json:"tick_size"`
+	MinimumAmount    string `json:"minimum_amount"`
+	AmountStep       string `json:"amount_step"`
+	BaseAssetAddress string `json:"base_asset_address"`
+	BaseAssetSubID   string `json:"base_asset_sub_id"`
 	OptionDetails  struct {
 		Strike     string `json:"strike"`
 		OptionType string `json:"option_type"`
@@ -42,6 +52,63 @@ type DeriveInstrumentsResponse struct {
 
 // LoadAllDeriveMarkets fetches all option markets from Derive using pagination
 func LoadAllDeriveMarkets() (map[string]DeriveInstrument, error) {
+	return loadDeriveInstruments("option")
+}
+
+// LoadInstruments fetches every Derive option and perp as registry metadata
+func LoadInstruments() ([]instruments.Instrument, error) {
+	var list []instruments.Instrument
+	for _, instrumentType := range []string{"option", "perp"} {
+		markets, err := loadDeriveInstruments(instrumentType)
+		if err != nil {
+			return nil, err
+		}
+		for _, market := range markets {
+			list = append(list, market.ToInstrument())
+		}
+	}
+	return list, nil
+}
+
+// ToInstrument converts to registry metadata. Derive amounts are in the
+// underlying.
+func (inst DeriveInstrument) ToInstrument() instruments.Instrument {
+	result := instruments.Instrument{
+		Name:               inst.InstrumentName,
+		Exchange:           "derive",
+		Underlying:         inst.BaseCurrency,
+		Kind:               inst.InstrumentType,
+		TickSize:           parseDecimal(inst.TickSize),
+		MinAmount:          parseDecimal(inst.MinimumAmount),
+		AmountStep:         parseDecimal(inst.AmountStep),
+		ContractMultiplier: decimal.NewFromInt(1),
+		Extra: map[string]string{
+			"base_asset_address": inst.BaseAssetAddress,
+			"base_asset_sub_id":  inst.BaseAssetSubID,
+		},
+	}
+	if inst.InstrumentType == "option" {
+		result.Kind = instruments.KindOption
+		result.Strike = parseDecimal(inst.OptionDetails.Strike)
+		result.OptionType = inst.OptionDetails.OptionType
+		result.Expiry = time.Unix(inst.OptionDetails.Expiry, 0).UTC()
+	} else if inst.InstrumentType == "perp" {
+		result.Kind = instruments.KindPerp
+	}
+	return result
+}
+
+// parseDecimal parses a decimal string, returning zero if it is malformed
+func parseDecimal(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+// loadDeriveInstruments fetches all active instruments of a type
+func loadDeriveInstruments(instrumentType string) (map[string]DeriveInstrument, error) {
 	url := "https://api.lyra.finance/public/get_all_instruments"
 	instruments := make(map[string]DeriveInstrument)
 	page := 1
@@ -49,7 +116,7 @@ func LoadAllDeriveMarkets() (map[string]DeriveInstrument, error) {
 	for {
 		// Prepare request
 		payload := map[string]interface{}{
-			"instrument_type": "option",
+			"instrument_type": instrumentType,
 			"expired":         false,
 			"page":            page,
 			"page_size":       1000, // Max allowed
@@ -69,6 +136,9 @@ func LoadAllDeriveMarkets() (map[string]DeriveInstrument, error) {
 		req.Header.Set("accept", "application/json")
 		req.Header.Set("content-type", "application/json")
 
+		if err := waitNonMatching(ratelimit.PriorityLow); err != nil {
+			return nil, err
+		}
 		resp, err := shared.NewHTTPClient().Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch instruments: %w", err)
@@ -15,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/ratelimit"
 )
 
 // Global debug mode flags
@@ -61,8 +62,8 @@ type DeriveAction struct {
 // Sign signs the action using EIP-712
 func (a *DeriveAction) Sign(privateKey *ecdsa.PrivateKey) error {
 	// Protocol constants for mainnet - FROM PRODUCTION CODE (working test values)
-	domainSeparator := common.HexToHash("0xd96e5f90797da7ec8dc4e276260c7f3f87fedf68775fbe1ef116e996fc60441b")
-	actionTypehash := common.HexToHash("0x4d7a9f27c403ff9c0f19bce61d76d82f9aa29f8d6d4b0c5474607d9770d1af17")
+	domainSeparator := deriveDomainSeparator
+	actionTypehash := deriveActionTypehash
 
 	shared.DeriveDebugLog("Sign: Starting EIP-712 signature generation")
 	shared.DeriveDebugLog("Sign: Using domain separator: %s", domainSeparator.Hex())
@@ -338,6 +339,9 @@ func FetchDeriveInstrumentDetails(instrumentName string) (*DeriveInstrumentDetai
 
 	req.Header.Set("Content-Type", "application/json")
 
+	if err := waitNonMatching(ratelimit.PriorityLow); err != nil {
+		return nil, err
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
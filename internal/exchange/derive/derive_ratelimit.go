@@ -0,0 +1,55 @@
+package derive
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/wakamex/atomizer/internal/ratelimit"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Derive's rate limits for trader accounts, in requests per second, with
+// bursts of five seconds' worth. Accounts on a higher tier can raise them
+// with DERIVE_MATCHING_TPS and DERIVE_NON_MATCHING_TPS.
+const (
+	defaultMatchingTPS    = 1
+	defaultNonMatchingTPS = 5
+	burstSeconds          = 5
+)
+
+// rateLimiter returns the limiter shared by every call to Derive
+func rateLimiter() *ratelimit.Limiter {
+	matching := tpsFromEnv("DERIVE_MATCHING_TPS", defaultMatchingTPS)
+	nonMatching := tpsFromEnv("DERIVE_NON_MATCHING_TPS", defaultNonMatchingTPS)
+	return ratelimit.For("derive", ratelimit.Limits{
+		Matching:    ratelimit.Rate{PerSecond: matching, Burst: int(matching * burstSeconds)},
+		NonMatching: ratelimit.Rate{PerSecond: nonMatching, Burst: int(nonMatching * burstSeconds)},
+	})
+}
+
+// tpsFromEnv reads a rate from the environment
+func tpsFromEnv(name string, fallback float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// waitMatching waits for room for an order, replace or cancel
+func waitMatching(priority ratelimit.Priority) error {
+	return rateLimiter().Matching.Wait(context.Background(), priority)
+}
+
+// waitNonMatching waits for room for any other request
+func waitNonMatching(priority ratelimit.Priority) error {
+	return rateLimiter().NonMatching.Wait(context.Background(), priority)
+}
+
+// orderPriority puts urgent orders, such as hedges, ahead of quotes
+func orderPriority(opts types.OrderOptions) ratelimit.Priority {
+	if opts.Urgent {
+		return ratelimit.PriorityHigh
+	}
+	return ratelimit.PriorityNormal
+}
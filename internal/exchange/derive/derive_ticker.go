@@ -2,6 +2,7 @@ package derive
 
 import (
 	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/ratelimit"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -138,6 +139,16 @@ func (t *DeriveTicker) GetIV() float64 {
 	return f
 }
 
+// GetForwardPrice returns forward price as float64 (0 if not available)
+func (t *DeriveTicker) GetForwardPrice() float64 {
+	if t.OptionPricing == nil {
+		return 0
+	}
+	var f float64
+	fmt.Sscanf(t.OptionPricing.ForwardPrice, "%f", &f)
+	return f
+}
+
 // DeriveTickerResponse represents the API response
 type DeriveTickerResponse struct {
 	Result DeriveTicker `json:"result"`
@@ -164,6 +175,9 @@ func FetchDeriveTicker(instrumentName string) (*DeriveTicker, error) {
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("content-type", "application/json")
 
+	if err := waitNonMatching(ratelimit.PriorityLow); err != nil {
+		return nil, err
+	}
 	resp, err := shared.NewHTTPClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch ticker: %w", err)
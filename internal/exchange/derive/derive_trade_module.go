@@ -11,6 +11,18 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// Mainnet EIP-712 domain separator and action typehash every action is
+// signed under
+var (
+	deriveDomainSeparator = common.HexToHash("0xd96e5f90797da7ec8dc4e276260c7f3f87fedf68775fbe1ef116e996fc60441b")
+	deriveActionTypehash  = common.HexToHash("0x4d7a9f27c403ff9c0f19bce61d76d82f9aa29f8d6d4b0c5474607d9770d1af17")
+)
+
+// ModuleData is the module-specific part of an action
+type ModuleData interface {
+	ToABIEncoded() ([]byte, error)
+}
+
 // TradeModuleData represents the data for a trade order
 type TradeModuleData struct {
 	AssetAddress string
@@ -60,7 +72,7 @@ type Action struct {
 	SignatureExpirySec uint64
 	Nonce              uint64
 	ModuleAddress      common.Address
-	ModuleData         *TradeModuleData
+	ModuleData         ModuleData
 	DomainSeparator    [32]byte
 	ActionTypehash     [32]byte
 	Signature          []byte
@@ -147,18 +159,36 @@ func (a *Action) Sign(privateKey *ecdsa.PrivateKey) error {
 	return nil
 }
 
+// NewAction creates an unsigned mainnet action for a module
+func NewAction(subaccountID uint64, owner, signer common.Address, moduleAddress common.Address, data ModuleData) *Action {
+	return &Action{
+		SubaccountID:       subaccountID,
+		Owner:              owner,
+		Signer:             signer,
+		SignatureExpirySec: uint64(time.Now().Unix() + 3600),
+		Nonce:              GetActionNonce(),
+		ModuleAddress:      moduleAddress,
+		ModuleData:         data,
+		DomainSeparator:    deriveDomainSeparator,
+		ActionTypehash:     deriveActionTypehash,
+	}
+}
+
 // ToJSON returns the JSON representation needed for the API
 func (a *Action) ToJSON() map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"subaccount_id":        a.SubaccountID,
 		"nonce":                a.Nonce,
 		"signer":               a.Signer.Hex(),
 		"signature_expiry_sec": a.SignatureExpirySec,
 		"signature":            "0x" + common.Bytes2Hex(a.Signature),
-		"limit_price":          a.ModuleData.LimitPrice.String(),
-		"amount":               a.ModuleData.Amount.String(),
-		"max_fee":              a.ModuleData.MaxFee.String(),
 	}
+	if trade, ok := a.ModuleData.(*TradeModuleData); ok {
+		result["limit_price"] = trade.LimitPrice.String()
+		result["amount"] = trade.Amount.String()
+		result["max_fee"] = trade.MaxFee.String()
+	}
+	return result
 }
 
 // DecimalToBigInt converts a decimal amount to big.Int with 18 decimals
@@ -168,9 +198,8 @@ func DecimalToBigInt(amount float64) *big.Int {
 	return new(big.Int).SetInt64(int64(amountWei))
 }
 
-// GetActionNonce generates a nonce based on timestamp + random suffix
+// GetActionNonce generates a nonce based on timestamp + a 3 digit suffix, so
+// actions signed in the same millisecond differ
 func GetActionNonce() uint64 {
-	// Use current timestamp in milliseconds + 3 digit suffix
-	// In production, add random 3 digits, for now just use 001
-	return uint64(time.Now().UnixMilli())*1000 + 1
+	return uint64(time.Now().UnixMilli())*1000 + uint64(time.Now().Nanosecond()%1000)
 }
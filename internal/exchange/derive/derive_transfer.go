@@ -0,0 +1,202 @@
+package derive
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/ratelimit"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Mainnet transfer module and the USDC cash asset collateral is held in
+const (
+	TransferModuleAddress = "0x01259207A40925b794C8ac320456F7F6c8FE2636"
+	CashAssetAddress      = "0x57B03E14d409ADC7fAb6CFc44b5886CAD2D5f02b"
+)
+
+// TransferDetails is one asset moved by a transfer
+type TransferDetails struct {
+	AssetAddress string
+	SubID        *big.Int
+	Amount       *big.Int // 18 decimals
+}
+
+// TransferModuleData is the sender's half of a transfer between subaccounts
+type TransferModuleData struct {
+	ToSubaccountID uint64
+	Transfers      []TransferDetails
+}
+
+// ToABIEncoded encodes the data as the transfer module's
+// (uint toAccountId, address managerForNewAccount, (address,uint,int)[] transfers)
+func (t *TransferModuleData) ToABIEncoded() ([]byte, error) {
+	transferDataType, err := abi.NewType("tuple", "", []abi.ArgumentMarshaling{
+		{Name: "toAccountId", Type: "uint256"},
+		{Name: "managerForNewAccount", Type: "address"},
+		{Name: "transfers", Type: "tuple[]", Components: []abi.ArgumentMarshaling{
+			{Name: "asset", Type: "address"},
+			{Name: "subId", Type: "uint256"},
+			{Name: "amount", Type: "int256"},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type transfer struct {
+		Asset  common.Address
+		SubId  *big.Int
+		Amount *big.Int
+	}
+	transfers := make([]transfer, 0, len(t.Transfers))
+	for _, details := range t.Transfers {
+		transfers = append(transfers, transfer{
+			Asset:  common.HexToAddress(details.AssetAddress),
+			SubId:  details.SubID,
+			Amount: details.Amount,
+		})
+	}
+
+	return abi.Arguments{{Type: transferDataType}}.Pack(struct {
+		ToAccountId          *big.Int
+		ManagerForNewAccount common.Address
+		Transfers            []transfer
+	}{
+		ToAccountId: new(big.Int).SetUint64(t.ToSubaccountID),
+		Transfers:   transfers,
+	})
+}
+
+// recipientModuleData is the recipient's half of a transfer, which carries no
+// data of its own
+type recipientModuleData struct{}
+
+func (recipientModuleData) ToABIEncoded() ([]byte, error) {
+	return []byte{}, nil
+}
+
+// Transfer moves USDC collateral between two of the managed subaccounts. Both
+// sides of the transfer are signed with the session key.
+func (d *DeriveMarketMakerExchange) Transfer(fromSubaccount, toSubaccount uint64, amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("transfer amount must be positive, got %s", amount)
+	}
+	if fromSubaccount == toSubaccount {
+		return fmt.Errorf("cannot transfer from subaccount %d to itself", fromSubaccount)
+	}
+	for _, id := range []uint64{fromSubaccount, toSubaccount} {
+		if !d.manages(id) {
+			return fmt.Errorf("subaccount %d is not one of %v", id, d.subaccounts)
+		}
+	}
+
+	auth := d.wsClient.GetAuth()
+	owner := common.HexToAddress(d.wsClient.GetWallet())
+	signer := common.HexToAddress(auth.GetAddress())
+	amountWei, ok := new(big.Int).SetString(amount.Mul(decimal.New(1, 18)).Truncate(0).String(), 10)
+	if !ok {
+		return fmt.Errorf("invalid transfer amount %s", amount)
+	}
+
+	sender := NewAction(fromSubaccount, owner, signer, common.HexToAddress(TransferModuleAddress), &TransferModuleData{
+		ToSubaccountID: toSubaccount,
+		Transfers: []TransferDetails{{
+			AssetAddress: CashAssetAddress,
+			SubID:        big.NewInt(0),
+			Amount:       amountWei,
+		}},
+	})
+	recipient := NewAction(toSubaccount, owner, signer, common.HexToAddress(TransferModuleAddress), recipientModuleData{})
+	recipient.Nonce = sender.Nonce + 1
+
+	for _, action := range []*Action{sender, recipient} {
+		if err := action.Sign(auth.GetPrivateKey()); err != nil {
+			return fmt.Errorf("failed to sign transfer: %w", err)
+		}
+	}
+
+	if err := waitNonMatching(ratelimit.PriorityNormal); err != nil {
+		return err
+	}
+
+	log.Printf("[Derive] Transferring %s USDC from subaccount %d to %d", amount, fromSubaccount, toSubaccount)
+	return d.wsClient.TransferERC20(map[string]interface{}{
+		"subaccount_id":           fromSubaccount,
+		"recipient_subaccount_id": toSubaccount,
+		"sender_details":          transferSignature(sender),
+		"recipient_details":       transferSignature(recipient),
+		"transfer": map[string]interface{}{
+			"address": CashAssetAddress,
+			"amount":  amount.String(),
+			"sub_id":  0,
+		},
+	})
+}
+
+// transferSignature is the signature half of one side of a transfer request
+func transferSignature(action *Action) map[string]interface{} {
+	return map[string]interface{}{
+		"nonce":                action.Nonce,
+		"signer":               action.Signer.Hex(),
+		"signature_expiry_sec": action.SignatureExpirySec,
+		"signature":            "0x" + common.Bytes2Hex(action.Signature),
+	}
+}
+
+// GetAllMargins returns the margin of every managed subaccount
+func (d *DeriveMarketMakerExchange) GetAllMargins() ([]types.MarginState, error) {
+	margins := make([]types.MarginState, 0, len(d.subaccounts))
+	for _, id := range d.subaccounts {
+		state, err := d.marginOf(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get margin of subaccount %d: %w", id, err)
+		}
+		margins = append(margins, *state)
+	}
+	return margins, nil
+}
+
+// TransferERC20 submits a signed transfer between subaccounts
+func (c *DeriveWSClient) TransferERC20(params map[string]interface{}) error {
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "private/transfer_erc20",
+		"params":  params,
+		"id":      fmt.Sprintf("%d", time.Now().UnixMilli()),
+	}
+
+	respChan := c.sendRequest(req)
+
+	select {
+	case resp := <-respChan:
+		if len(resp) == 0 {
+			return fmt.Errorf("received empty response from WebSocket")
+		}
+
+		var result struct {
+			Result struct {
+				Status string `json:"status"`
+			} `json:"result"`
+			Error *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return fmt.Errorf("failed to parse transfer response: %w", err)
+		}
+		if result.Error != nil {
+			return fmt.Errorf("transfer error: %s (code: %d)", result.Error.Message, result.Error.Code)
+		}
+		return nil
+
+	case <-time.After(30 * time.Second):
+		return fmt.Errorf("transfer timeout")
+	}
+}
@@ -0,0 +1,64 @@
+package derive
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTransferModuleDataEncoding(t *testing.T) {
+	data := &TransferModuleData{
+		ToSubaccountID: 42,
+		Transfers: []TransferDetails{{
+			AssetAddress: CashAssetAddress,
+			SubID:        big.NewInt(0),
+			Amount:       new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18)),
+		}},
+	}
+
+	encoded, err := data.ToABIEncoded()
+	if err != nil {
+		t.Fatalf("ToABIEncoded failed: %v", err)
+	}
+
+	// Tuple offset, account, manager, array offset, length, one transfer
+	if len(encoded) != 8*32 {
+		t.Fatalf("encoded %d bytes, want %d", len(encoded), 8*32)
+	}
+	word := func(i int) []byte { return encoded[i*32 : (i+1)*32] }
+	if new(big.Int).SetBytes(word(1)).Uint64() != 42 {
+		t.Errorf("toAccountId = %x", word(1))
+	}
+	if common.BytesToAddress(word(5)) != common.HexToAddress(CashAssetAddress) {
+		t.Errorf("asset = %x", word(5))
+	}
+}
+
+func TestTransferActionSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	action := NewAction(1, common.HexToAddress("0x1"), signer, common.HexToAddress(TransferModuleAddress), recipientModuleData{})
+	if err := action.Sign(key); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	hash, err := action.GetTypedDataHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := append([]byte(nil), action.Signature...)
+	signature[64] -= 27
+	pub, err := crypto.SigToPub(hash[:], signature)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != signer {
+		t.Error("signature does not recover to the signer")
+	}
+}
@@ -29,6 +29,11 @@ type DeriveWSClient struct {
 	orderbookMu   sync.RWMutex
 	orderbookSubs map[string]bool
 
+	// Ticker and private channel subscriptions: channel -> handler id -> handler
+	handlers      map[string]map[int]func(json.RawMessage)
+	nextHandlerID int
+	handlerMu     sync.RWMutex
+
 	// Connection state management
 	isConnected       bool
 	lastActivity      time.Time
@@ -62,6 +67,7 @@ func NewDeriveWSClient(privateKey string, deriveWallet string) (*DeriveWSClient,
 		requests:          make(map[string]chan json.RawMessage),
 		orderbooks:        make(map[string]*OrderBookData),
 		orderbookSubs:     make(map[string]bool),
+		handlers:          make(map[string]map[int]func(json.RawMessage)),
 		wsURL:             "wss://api.lyra.finance/ws",
 		reconnectDelay:    1 * time.Second,
 		maxReconnectDelay: 30 * time.Second,
@@ -151,8 +157,9 @@ func (c *DeriveWSClient) connect() error {
 	// Clear read deadline after successful login
 	c.conn.SetReadDeadline(time.Time{})
 
-	// Resubscribe to orderbooks
+	// Resubscribe to orderbooks, tickers and private channels
 	c.resubscribeOrderbooks()
+	c.resubscribeChannels()
 
 	log.Printf("[Derive WS] Successfully connected and authenticated")
 
@@ -257,6 +264,38 @@ func (c *DeriveWSClient) resubscribeOrderbooks() {
 	}
 }
 
+// resubscribeChannels re-subscribes to all channels with handlers. Private
+// channels are accepted because login has already completed.
+func (c *DeriveWSClient) resubscribeChannels() {
+	c.handlerMu.RLock()
+	channels := make([]string, 0, len(c.handlers))
+	for channel := range c.handlers {
+		channels = append(channels, channel)
+	}
+	c.handlerMu.RUnlock()
+
+	if len(channels) > 0 {
+		msg := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "subscribe",
+			"params": map[string]interface{}{
+				"channels": channels,
+			},
+			"id": fmt.Sprintf("resubscribe_channels_%d", time.Now().UnixNano()),
+		}
+
+		c.writeMu.Lock()
+		err := c.conn.WriteJSON(msg)
+		c.writeMu.Unlock()
+
+		if err != nil {
+			log.Printf("[Derive WS] Failed to resubscribe to channels: %v", err)
+		} else {
+			log.Printf("[Derive WS] Resubscribed to %d ticker and private channels", len(channels))
+		}
+	}
+}
+
 // heartbeat sends periodic pings to keep the connection alive
 func (c *DeriveWSClient) heartbeat() {
 	c.pingTicker = time.NewTicker(15 * time.Second)
@@ -378,7 +417,9 @@ func (c *DeriveWSClient) login() error {
 		}
 
 		// Store subaccount IDs
+		c.mu.Lock()
 		c.subaccounts = result.Result
+		c.mu.Unlock()
 
 		shared.DeriveDebugLog("[Derive WS] Login successful. Subaccounts: %v", result.Result)
 		return nil
@@ -479,6 +520,15 @@ func (c *DeriveWSClient) handleMessages() {
 
 // handleSubscriptionUpdate processes subscription updates (orderbook, trades, etc)
 func (c *DeriveWSClient) handleSubscriptionUpdate(params json.RawMessage) {
+	var envelope struct {
+		Channel string          `json:"channel"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(params, &envelope); err == nil && !strings.HasPrefix(envelope.Channel, "orderbook.") {
+		c.dispatch(envelope.Channel, envelope.Data)
+		return
+	}
+
 	var update struct {
 		Channel string `json:"channel"`
 		Data    struct {
@@ -763,12 +813,25 @@ func (c *DeriveWSClient) GetOpenOrders(subaccountID uint64) ([]map[string]interf
 
 // GetDefaultSubaccount returns the first subaccount ID
 func (c *DeriveWSClient) GetDefaultSubaccount() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if len(c.subaccounts) > 0 {
 		return uint64(c.subaccounts[0])
 	}
 	return 0
 }
 
+// Subaccounts returns the wallet's subaccount IDs from login
+func (c *DeriveWSClient) Subaccounts() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]uint64, 0, len(c.subaccounts))
+	for _, id := range c.subaccounts {
+		ids = append(ids, uint64(id))
+	}
+	return ids
+}
+
 // GetAuth returns the auth instance for signing
 func (c *DeriveWSClient) GetAuth() *DeriveAuth {
 	return c.auth
@@ -847,6 +910,53 @@ func (c *DeriveWSClient) GetPositions(subaccountID uint64) ([]map[string]interfa
 	}
 }
 
+// GetSubaccount returns a subaccount's collateral, margin and value
+func (c *DeriveWSClient) GetSubaccount(subaccountID uint64) (map[string]interface{}, error) {
+	c.mu.Lock()
+	if !c.isConnected || c.conn == nil {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("WebSocket connection not available")
+	}
+	c.mu.Unlock()
+
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "private/get_subaccount",
+		"params": map[string]interface{}{
+			"subaccount_id": subaccountID,
+		},
+		"id": fmt.Sprintf("%d", time.Now().UnixMilli()),
+	}
+
+	respChan := c.sendRequest(req)
+
+	select {
+	case resp := <-respChan:
+		if len(resp) == 0 {
+			return nil, fmt.Errorf("received empty response from WebSocket")
+		}
+
+		var result struct {
+			Result map[string]interface{} `json:"result"`
+			Error  *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse subaccount response: %w", err)
+		}
+		if result.Error != nil {
+			return nil, fmt.Errorf("get subaccount error: %s", result.Error.Message)
+		}
+
+		return result.Result, nil
+
+	case <-time.After(10 * time.Second):
+		return nil, fmt.Errorf("get subaccount timeout")
+	}
+}
+
 // Close closes the WebSocket connection
 func (c *DeriveWSClient) Close() error {
 	c.mu.Lock()
@@ -935,6 +1045,110 @@ func (c *DeriveWSClient) SubscribeOrderBook(instrument string, depth int) error
 	return nil
 }
 
+// SubscribeTicker registers handler for ticker.{instrument}.{interval} updates,
+// where interval is 100 or 1000 (ms). Handlers receive the channel's data
+// payload. The returned function removes the handler, unsubscribing once no
+// handlers remain. Subscriptions are restored on reconnect.
+func (c *DeriveWSClient) SubscribeTicker(instrument string, interval int, handler func(json.RawMessage)) (func(), error) {
+	return c.Subscribe(fmt.Sprintf("ticker.%s.%d", instrument, interval), handler)
+}
+
+// SubscribeOrders registers handler for the private {subaccount}.orders channel,
+// which carries an array of orders whenever any of them changes state
+func (c *DeriveWSClient) SubscribeOrders(subaccountID uint64, handler func(json.RawMessage)) (func(), error) {
+	return c.Subscribe(fmt.Sprintf("%d.orders", subaccountID), handler)
+}
+
+// SubscribeTrades registers handler for the private {subaccount}.trades channel,
+// which carries an array of the subaccount's executions
+func (c *DeriveWSClient) SubscribeTrades(subaccountID uint64, handler func(json.RawMessage)) (func(), error) {
+	return c.Subscribe(fmt.Sprintf("%d.trades", subaccountID), handler)
+}
+
+// Subscribe registers handler for a ticker or private channel. The returned
+// function removes the handler, unsubscribing once no handlers remain.
+func (c *DeriveWSClient) Subscribe(channel string, handler func(json.RawMessage)) (func(), error) {
+	c.handlerMu.Lock()
+	first := len(c.handlers[channel]) == 0
+	if first {
+		c.handlers[channel] = make(map[int]func(json.RawMessage))
+	}
+	id := c.nextHandlerID
+	c.nextHandlerID++
+	c.handlers[channel][id] = handler
+	c.handlerMu.Unlock()
+
+	if first {
+		if err := c.writeSubscription("subscribe", channel); err != nil {
+			// Leave the handler registered so the reconnect picks it up
+			log.Printf("[Derive WS] Failed to subscribe to %s (will retry on reconnect): %v", channel, err)
+		} else {
+			shared.DeriveDebugLog("[Derive WS] Subscribed to channel: %s", channel)
+		}
+	}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { c.removeHandler(channel, id) })
+	}
+	return unsubscribe, nil
+}
+
+// removeHandler drops a handler and unsubscribes from an unused channel
+func (c *DeriveWSClient) removeHandler(channel string, id int) {
+	c.handlerMu.Lock()
+	delete(c.handlers[channel], id)
+	last := len(c.handlers[channel]) == 0
+	if last {
+		delete(c.handlers, channel)
+	}
+	c.handlerMu.Unlock()
+
+	if last {
+		if err := c.writeSubscription("unsubscribe", channel); err != nil {
+			shared.DeriveDebugLog("[Derive WS] Failed to unsubscribe from %s: %v", channel, err)
+		}
+	}
+}
+
+// dispatch delivers a subscription update to the channel's handlers
+func (c *DeriveWSClient) dispatch(channel string, data json.RawMessage) {
+	c.handlerMu.RLock()
+	handlers := make([]func(json.RawMessage), 0, len(c.handlers[channel]))
+	for _, handler := range c.handlers[channel] {
+		handlers = append(handlers, handler)
+	}
+	c.handlerMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+}
+
+// writeSubscription sends a subscribe or unsubscribe request for one channel
+func (c *DeriveWSClient) writeSubscription(method string, channel string) error {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params": map[string]interface{}{
+			"channels": []string{channel},
+		},
+		"id": fmt.Sprintf("%s_%d", method, time.Now().UnixNano()),
+	}
+
+	c.mu.Lock()
+	if !c.isConnected || c.conn == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("connection not available")
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.WriteJSON(msg)
+}
+
 // GetOrderBook returns the cached orderbook for an instrument
 func (c *DeriveWSClient) GetOrderBook(instrument string) *OrderBookData {
 	c.orderbookMu.RLock()
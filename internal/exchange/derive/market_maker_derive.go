@@ -11,11 +11,14 @@ import (
     "net/http"
     "os"
     "strconv"
+    "strings"
     "sync"
     "time"
     
     "github.com/gorilla/websocket"
     "github.com/shopspring/decimal"
+    "github.com/wakamex/atomizer/internal/instruments"
+    "github.com/wakamex/atomizer/internal/ratelimit"
     "github.com/wakamex/atomizer/internal/types"
 )
 
@@ -23,6 +26,7 @@ import (
 type DeriveMarketMakerExchange struct {
 	wsClient     *DeriveWSClient
 	subaccountID uint64
+	subaccounts  []uint64 // Subaccounts whose positions count towards risk
 	privateKey   string // Store private key for order signing
 	
 	// Ticker subscriptions
@@ -58,16 +62,97 @@ func NewDeriveMarketMakerExchange(privateKey, walletAddress string) (*DeriveMark
 		log.Printf("Using default subaccount ID: %d", subaccountID)
 	}
 	
+	subaccounts, err := managedSubaccounts(wsClient, subaccountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subaccounts) > 1 {
+		log.Printf("Aggregating positions across subaccounts: %v", subaccounts)
+	}
+	
+	instruments.Default.Register("derive", LoadInstruments)
+	
 	return &DeriveMarketMakerExchange{
 		wsClient:        wsClient,
 		subaccountID:    subaccountID,
+		subaccounts:     subaccounts,
 		privateKey:      privateKey,
 		subscriptions:   make(map[string]bool),
 		instrumentCache: make(map[string]*DeriveInstrumentDetails),
 	}, nil
 }
 
-// getInstrumentDetails gets instrument details from cache or fetches if needed
+// managedSubaccounts returns the subaccounts listed in DERIVE_SUBACCOUNT_IDS,
+// or every subaccount of the wallet, always including the trading one
+func managedSubaccounts(wsClient *DeriveWSClient, subaccountID uint64) ([]uint64, error) {
+	var subaccounts []uint64
+	if idsStr := os.Getenv("DERIVE_SUBACCOUNT_IDS"); idsStr != "" {
+		for _, idStr := range strings.Split(idsStr, ",") {
+			if idStr = strings.TrimSpace(idStr); idStr == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(idStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DERIVE_SUBACCOUNT_IDS entry %q: %w", idStr, err)
+			}
+			subaccounts = append(subaccounts, id)
+		}
+	} else {
+		subaccounts = wsClient.Subaccounts()
+	}
+	
+	for _, id := range subaccounts {
+		if id == subaccountID {
+			return subaccounts, nil
+		}
+	}
+	return append([]uint64{subaccountID}, subaccounts...), nil
+}
+
+// SubaccountID returns the subaccount orders are placed from
+func (d *DeriveMarketMakerExchange) SubaccountID() uint64 {
+	return d.subaccountID
+}
+
+// Subaccounts returns the subaccounts whose positions GetAllPositions reports
+func (d *DeriveMarketMakerExchange) Subaccounts() []uint64 {
+	return append([]uint64(nil), d.subaccounts...)
+}
+
+// manages reports whether a subaccount is one of the managed subaccounts
+func (d *DeriveMarketMakerExchange) manages(subaccountID uint64) bool {
+	for _, id := range d.subaccounts {
+		if id == subaccountID {
+			return true
+		}
+	}
+	return false
+}
+
+// ForSubaccount returns an exchange that trades from another of the managed
+// subaccounts over the same connection
+func (d *DeriveMarketMakerExchange) ForSubaccount(subaccountID uint64) (*DeriveMarketMakerExchange, error) {
+	if !d.manages(subaccountID) {
+		return nil, fmt.Errorf("subaccount %d is not one of %v", subaccountID, d.subaccounts)
+	}
+	
+	return &DeriveMarketMakerExchange{
+		wsClient:        d.wsClient,
+		subaccountID:    subaccountID,
+		subaccounts:     d.subaccounts,
+		privateKey:      d.privateKey,
+		subscriptions:   make(map[string]bool),
+		instrumentCache: make(map[string]*DeriveInstrumentDetails),
+	}, nil
+}
+
+// GetInstrument returns an instrument's metadata from the shared registry
+func (d *DeriveMarketMakerExchange) GetInstrument(instrument string) (*instruments.Instrument, error) {
+	return instruments.Default.Get("derive", instrument)
+}
+
+// getInstrumentDetails gets the asset details orders are signed with, from
+// the registry when it has them, otherwise fetching them
 func (d *DeriveMarketMakerExchange) getInstrumentDetails(instrument string) (*DeriveInstrumentDetails, error) {
 	// Check cache first
 	d.cacheMu.RLock()
@@ -78,9 +163,13 @@ func (d *DeriveMarketMakerExchange) getInstrumentDetails(instrument string) (*De
 		return details, nil
 	}
 	
-	// Fetch if not cached
-	details, err := FetchDeriveInstrumentDetails(instrument)
-	if err != nil {
+	if spec, err := d.GetInstrument(instrument); err == nil && spec.Extra["base_asset_address"] != "" {
+		details = &DeriveInstrumentDetails{
+			InstrumentName:   instrument,
+			BaseAssetAddress: spec.Extra["base_asset_address"],
+			BaseAssetSubID:   spec.Extra["base_asset_sub_id"],
+		}
+	} else if details, err = FetchDeriveInstrumentDetails(instrument); err != nil {
 		return nil, err
 	}
 	
@@ -92,64 +181,133 @@ func (d *DeriveMarketMakerExchange) getInstrumentDetails(instrument string) (*De
 	return details, nil
 }
 
-// SubscribeTickers polls ticker updates for given instruments
+// tickerStaleAfter is how long an instrument can go without a WebSocket ticker
+// before the REST fallback polls it
+const tickerStaleAfter = 5 * time.Second
+
+// SubscribeTickers streams ticker updates for given instruments over the
+// WebSocket, polling REST only for instruments whose stream has gone quiet
 func (d *DeriveMarketMakerExchange) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
 	tickerChan := make(chan types.TickerUpdate, 100)
 	
-	// Store subscriptions
+	var (
+		mu           sync.Mutex
+		closed       bool
+		lastUpdate   = make(map[string]time.Time)
+		unsubscribes []func()
+	)
+	
+	send := func(ticker *types.TickerUpdate) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		lastUpdate[ticker.Instrument] = time.Now()
+		select {
+		case tickerChan <- *ticker:
+		default:
+			log.Printf("Ticker channel full, dropping update for %s", ticker.Instrument)
+		}
+	}
+	
 	for _, instrument := range instruments {
+		d.tickerMu.Lock()
 		d.subscriptions[instrument] = true
-		log.Printf("Starting ticker polling for %s", instrument)
+		d.tickerMu.Unlock()
+		
+		unsubscribe, err := d.wsClient.SubscribeTicker(instrument, 100, func(data json.RawMessage) {
+			var update struct {
+				InstrumentTicker json.RawMessage `json:"instrument_ticker"`
+			}
+			if err := json.Unmarshal(data, &update); err != nil || len(update.InstrumentTicker) == 0 {
+				return
+			}
+			ticker, err := parseTicker(update.InstrumentTicker)
+			if err != nil {
+				if debugMode {
+					log.Printf("DEBUG: Failed to parse ticker: %v", err)
+				}
+				return
+			}
+			send(ticker)
+		})
+		if err != nil {
+			log.Printf("Failed to subscribe to %s ticker, relying on polling: %v", instrument, err)
+			continue
+		}
+		unsubscribes = append(unsubscribes, unsubscribe)
 	}
 	
-	// Start polling for each instrument
-	go d.pollTickers(ctx, instruments, tickerChan)
+	go func() {
+		d.pollStaleTickers(ctx, instruments, func(instrument string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return time.Since(lastUpdate[instrument]) > tickerStaleAfter
+		}, send)
+		
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+		
+		mu.Lock()
+		closed = true
+		close(tickerChan)
+		mu.Unlock()
+	}()
 	
 	return tickerChan, nil
 }
 
-// pollTickers polls for ticker updates
-func (d *DeriveMarketMakerExchange) pollTickers(ctx context.Context, instruments []string, tickerChan chan<- types.TickerUpdate) {
-	defer close(tickerChan)
-	
-	ticker := time.NewTicker(500 * time.Millisecond) // Poll every 500ms
+// pollStaleTickers is the REST fallback for SubscribeTickers. It polls
+// instruments one at a time, and only those isStale reports, until ctx is done.
+// Only an instrument's first failed fetch and its recovery are logged.
+func (d *DeriveMarketMakerExchange) pollStaleTickers(ctx context.Context, instruments []string, isStale func(string) bool, send func(*types.TickerUpdate)) {
+	ticker := time.NewTicker(tickerStaleAfter)
 	defer ticker.Stop()
 	
+	failures := make(map[string]int) // Consecutive failed fetches by instrument
+	
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// Poll each instrument
 			for _, instrument := range instruments {
-				go func(inst string) {
-					ticker, err := d.fetchTicker(inst)
-					if err != nil {
-						// Only log periodically to avoid spam
-						if time.Now().Unix() % 10 == 0 {
-							log.Printf("Failed to fetch ticker for %s: %v", inst, err)
-						}
-						return
-					}
-					
-					// Send update
-					select {
-					case tickerChan <- *ticker:
-					default:
-						log.Printf("Ticker channel full, dropping update for %s", inst)
+				if ctx.Err() != nil {
+					return
+				}
+				if !isStale(instrument) {
+					continue
+				}
+				
+				update, err := d.fetchTicker(instrument)
+				if err != nil {
+					if failures[instrument] == 0 {
+						log.Printf("Failed to fetch ticker for %s, retrying quietly: %v", instrument, err)
 					}
-				}(instrument)
+					failures[instrument]++
+					continue
+				}
+				if n := failures[instrument]; n > 0 {
+					log.Printf("Fetched ticker for %s after %d failed attempts", instrument, n)
+					delete(failures, instrument)
+				}
+				send(update)
 			}
 		}
 	}
 }
 
-// fetchTicker fetches ticker data for a single instrument
+// fetchTicker fetches ticker data for a single instrument over REST
 func (d *DeriveMarketMakerExchange) fetchTicker(instrument string) (*types.TickerUpdate, error) {
 	url := "https://api.lyra.finance/public/get_ticker"
 	payload := map[string]string{"instrument_name": instrument}
 	jsonData, _ := json.Marshal(payload)
 	
+	if err := waitNonMatching(ratelimit.PriorityLow); err != nil {
+		return nil, err
+	}
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
@@ -162,30 +320,8 @@ func (d *DeriveMarketMakerExchange) fetchTicker(instrument string) (*types.Ticke
 	}
 	
 	var result struct {
-		Result struct {
-			InstrumentName string `json:"instrument_name"`
-			BestBidPrice   string `json:"best_bid_price"`
-			BestAskPrice   string `json:"best_ask_price"`
-			BestBidAmount  string `json:"best_bid_amount"`
-			BestAskAmount  string `json:"best_ask_amount"`
-			LastPrice      string `json:"last_price"`
-			MarkPrice      string `json:"mark_price"`
-			MinimumAmount  string `json:"minimum_amount"`
-			AmountStep     string `json:"amount_step"`
-			TickSize       string `json:"tick_size"`
-			OptionPricing  *struct {
-				Delta string `json:"delta"`
-				Gamma string `json:"gamma"`
-				Vega  string `json:"vega"`
-				Theta string `json:"theta"`
-			} `json:"option_pricing"`
-			OptionDetails *struct {
-				Expiry int64 `json:"expiry"`
-				Strike string `json:"strike"`
-				OptionType string `json:"option_type"`
-			} `json:"option_details"`
-		} `json:"result"`
-		Error *struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
 			Message string `json:"message"`
 		} `json:"error"`
 	}
@@ -198,31 +334,68 @@ func (d *DeriveMarketMakerExchange) fetchTicker(instrument string) (*types.Ticke
 		return nil, fmt.Errorf("API error: %s", result.Error.Message)
 	}
 	
+	return parseTicker(result.Result)
+}
+
+// parseTicker converts a Derive ticker, as returned by public/get_ticker or
+// the ticker channel's instrument_ticker, into a TickerUpdate
+func parseTicker(data json.RawMessage) (*types.TickerUpdate, error) {
+	var result struct {
+		InstrumentName string `json:"instrument_name"`
+		BestBidPrice   string `json:"best_bid_price"`
+		BestAskPrice   string `json:"best_ask_price"`
+		BestBidAmount  string `json:"best_bid_amount"`
+		BestAskAmount  string `json:"best_ask_amount"`
+		LastPrice      string `json:"last_price"`
+		MarkPrice      string `json:"mark_price"`
+		IndexPrice     string `json:"index_price"`
+		OptionPricing  *struct {
+			Delta string `json:"delta"`
+			Gamma string `json:"gamma"`
+			Vega  string `json:"vega"`
+			Theta string `json:"theta"`
+		} `json:"option_pricing"`
+		OptionDetails *struct {
+			Expiry int64 `json:"expiry"`
+			Strike string `json:"strike"`
+			OptionType string `json:"option_type"`
+		} `json:"option_details"`
+	}
+	
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ticker: %w", err)
+	}
+	if result.InstrumentName == "" {
+		return nil, fmt.Errorf("ticker has no instrument name")
+	}
+	
 	// Convert strings to decimals
-	bestBid, _ := decimal.NewFromString(result.Result.BestBidPrice)
-	bestAsk, _ := decimal.NewFromString(result.Result.BestAskPrice)
-	bestBidAmount, _ := decimal.NewFromString(result.Result.BestBidAmount)
-	bestAskAmount, _ := decimal.NewFromString(result.Result.BestAskAmount)
-	lastPrice, _ := decimal.NewFromString(result.Result.LastPrice)
-	markPrice, _ := decimal.NewFromString(result.Result.MarkPrice)
+	bestBid, _ := decimal.NewFromString(result.BestBidPrice)
+	bestAsk, _ := decimal.NewFromString(result.BestAskPrice)
+	bestBidAmount, _ := decimal.NewFromString(result.BestBidAmount)
+	bestAskAmount, _ := decimal.NewFromString(result.BestAskAmount)
+	lastPrice, _ := decimal.NewFromString(result.LastPrice)
+	markPrice, _ := decimal.NewFromString(result.MarkPrice)
+	indexPrice, _ := decimal.NewFromString(result.IndexPrice)
 	
 	ticker := &types.TickerUpdate{
-		Instrument:  result.Result.InstrumentName,
+		Instrument:  result.InstrumentName,
 		BestBid:     bestBid,
 		BestBidSize: bestBidAmount,
 		BestAsk:     bestAsk,
 		BestAskSize: bestAskAmount,
 		LastPrice:   lastPrice,
 		MarkPrice:   markPrice,
+		IndexPrice:  indexPrice,
 		Timestamp:   time.Now(),
 	}
 	
 	// Add Greeks if available (for options)
-	if result.Result.OptionPricing != nil {
-		delta, _ := decimal.NewFromString(result.Result.OptionPricing.Delta)
-		gamma, _ := decimal.NewFromString(result.Result.OptionPricing.Gamma)
-		vega, _ := decimal.NewFromString(result.Result.OptionPricing.Vega)
-		theta, _ := decimal.NewFromString(result.Result.OptionPricing.Theta)
+	if result.OptionPricing != nil {
+		delta, _ := decimal.NewFromString(result.OptionPricing.Delta)
+		gamma, _ := decimal.NewFromString(result.OptionPricing.Gamma)
+		vega, _ := decimal.NewFromString(result.OptionPricing.Vega)
+		theta, _ := decimal.NewFromString(result.OptionPricing.Theta)
 		
 		ticker.Delta = &delta
 		ticker.Gamma = &gamma
@@ -231,28 +404,64 @@ func (d *DeriveMarketMakerExchange) fetchTicker(instrument string) (*types.Ticke
 	}
 	
 	// Add option details if available
-	if result.Result.OptionDetails != nil {
-		expiryTime := time.Unix(result.Result.OptionDetails.Expiry, 0)
+	if result.OptionDetails != nil {
+		expiryTime := time.Unix(result.OptionDetails.Expiry, 0)
 		ticker.Expiry = &expiryTime
 		
-		if result.Result.OptionDetails.Strike != "" {
-			strike, _ := decimal.NewFromString(result.Result.OptionDetails.Strike)
+		if result.OptionDetails.Strike != "" {
+			strike, _ := decimal.NewFromString(result.OptionDetails.Strike)
 			ticker.Strike = &strike
 		}
 		
-		if result.Result.OptionDetails.OptionType != "" {
-			ticker.OptionType = &result.Result.OptionDetails.OptionType
+		if result.OptionDetails.OptionType != "" {
+			ticker.OptionType = &result.OptionDetails.OptionType
 		}
 	}
 	
 	return ticker, nil
 }
 
+// deriveTimeInForce maps order options to Derive's time_in_force, which also
+// carries post-only. Derive only accepts reduce-only on orders that can't rest.
+func deriveTimeInForce(opts types.OrderOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if opts.ReduceOnly && opts.TIF() == types.TimeInForceGTC {
+		return "", fmt.Errorf("derive only supports reduce-only on IOC or FOK orders")
+	}
+	if opts.PostOnly {
+		return "post_only", nil
+	}
+	return string(opts.TIF()), nil
+}
+
+// roundOrder rounds price to the tick size and amount down to the amount
+// step. Unknown instruments are passed through unchanged.
+func (d *DeriveMarketMakerExchange) roundOrder(instrument string, price, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	spec, err := d.GetInstrument(instrument)
+	if err != nil {
+		log.Printf("[Derive] No instrument spec for %s, sending unrounded: %v", instrument, err)
+		return price, amount, nil
+	}
+	return spec.Round(price, amount)
+}
+
 // PlaceLimitOrder places a limit order on Derive
-func (d *DeriveMarketMakerExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal) (string, error) {
+func (d *DeriveMarketMakerExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
 	// Create order directly using our existing WebSocket connection
 	// This avoids creating a new connection for each order
 	
+	timeInForce, err := deriveTimeInForce(opts)
+	if err != nil {
+		return "", err
+	}
+	
+	price, amount, err = d.roundOrder(instrument, price, amount)
+	if err != nil {
+		return "", err
+	}
+	
 	if debugMode {
 		log.Printf("DEBUG PlaceLimitOrder: Starting order placement for %s %s %.6f @ %.6f", 
 			instrument, side, amount.InexactFloat64(), price.InexactFloat64())
@@ -351,7 +560,8 @@ func (d *DeriveMarketMakerExchange) PlaceLimitOrder(instrument string, side stri
 		"instrument_name":      instrument,
 		"direction":           side,
 		"order_type":         "limit",
-		"time_in_force":      "gtc",
+		"time_in_force":      timeInForce,
+		"reduce_only":        opts.ReduceOnly,
 		"mmp":                true, // Market maker protection
 		"subaccount_id":      d.subaccountID,      // int64
 		"nonce":              action.Nonce,         // uint64
@@ -395,6 +605,9 @@ func (d *DeriveMarketMakerExchange) PlaceLimitOrder(instrument string, side stri
 	}
 	
 	// Submit order via our existing WebSocket client
+	if err := waitMatching(orderPriority(opts)); err != nil {
+		return "", err
+	}
 	orderResp, err := d.wsClient.SubmitOrder(orderReq)
 	if err != nil {
 		if debugMode {
@@ -430,7 +643,17 @@ func (d *DeriveMarketMakerExchange) PlaceLimitOrder(instrument string, side stri
 }
 
 // ReplaceOrder replaces an existing order with new parameters
-func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal) (string, error) {
+func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	timeInForce, err := deriveTimeInForce(opts)
+	if err != nil {
+		return "", err
+	}
+	
+	price, amount, err = d.roundOrder(instrument, price, amount)
+	if err != nil {
+		return "", err
+	}
+	
 	if debugMode {
 		log.Printf("DEBUG ReplaceOrder: Starting order replacement for order %s -> %s %s %.6f @ %.6f", 
 			orderID, instrument, side, amount.InexactFloat64(), price.InexactFloat64())
@@ -517,7 +740,8 @@ func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument stri
 		"instrument_name":      instrument,
 		"direction":           side,
 		"order_type":         "limit",
-		"time_in_force":      "gtc",
+		"time_in_force":      timeInForce,
+		"reduce_only":        opts.ReduceOnly,
 		"amount":             fmt.Sprintf("%.6f", amount.InexactFloat64()),
 		"limit_price":        fmt.Sprintf("%.6f", price.InexactFloat64()),
 		"max_fee":            "100",
@@ -540,6 +764,9 @@ func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument stri
 	}
 	
 	log.Printf("Sending replace order request for order %s", orderID)
+	if err := waitMatching(orderPriority(opts)); err != nil {
+		return "", err
+	}
 	respChan := d.wsClient.sendRequest(req)
 	
 	select {
@@ -586,7 +813,7 @@ func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument stri
 				log.Printf("Attempting to recover by placing a new order: %s %s %.6f @ %.6f", 
 					instrument, side, amount.InexactFloat64(), price.InexactFloat64())
 				
-				return d.PlaceLimitOrder(instrument, side, price, amount)
+				return d.PlaceLimitOrder(instrument, side, price, amount, opts)
 			}
 			
 			// Success case: both cancelled and created
@@ -606,8 +833,9 @@ func (d *DeriveMarketMakerExchange) ReplaceOrder(orderID string, instrument stri
 
 // CancelOrder cancels an order on Derive
 func (d *DeriveMarketMakerExchange) CancelOrder(orderID string) error {
-	// First, we need to find the instrument name for this order
-	orders, err := d.GetOpenOrders()
+	// First, we need to find the instrument name for this order. Cancels go
+	// ahead of everything else when rate limited.
+	orders, err := d.openOrders(ratelimit.PriorityHigh)
 	if err != nil {
 		return fmt.Errorf("failed to get open orders: %w", err)
 	}
@@ -638,6 +866,9 @@ func (d *DeriveMarketMakerExchange) CancelOrder(orderID string) error {
 		"id": id,
 	}
 	
+	if err := waitMatching(ratelimit.PriorityHigh); err != nil {
+		return err
+	}
 	respChan := d.wsClient.sendRequest(req)
 	
 	select {
@@ -665,6 +896,14 @@ func (d *DeriveMarketMakerExchange) CancelOrder(orderID string) error {
 
 // GetOpenOrders gets all open orders
 func (d *DeriveMarketMakerExchange) GetOpenOrders() ([]types.MarketMakerOrder, error) {
+	return d.openOrders(ratelimit.PriorityNormal)
+}
+
+// openOrders gets all open orders at a rate limit priority
+func (d *DeriveMarketMakerExchange) openOrders(priority ratelimit.Priority) ([]types.MarketMakerOrder, error) {
+	if err := waitNonMatching(priority); err != nil {
+		return nil, err
+	}
 	rawOrders, err := d.wsClient.GetOpenOrders(d.subaccountID)
 	if err != nil {
 		return nil, err
@@ -672,18 +911,7 @@ func (d *DeriveMarketMakerExchange) GetOpenOrders() ([]types.MarketMakerOrder, e
 	
 	orders := make([]types.MarketMakerOrder, 0, len(rawOrders))
 	for _, raw := range rawOrders {
-		order := types.MarketMakerOrder{
-			OrderID:    getString(raw, "order_id"),
-			Instrument: getString(raw, "instrument_name"),
-			Side:       getString(raw, "direction"),
-			Price:      getDecimal(raw, "limit_price"),
-			Amount:     getDecimal(raw, "amount"),
-			FilledAmount: getDecimal(raw, "filled_amount"),
-			Status:     getString(raw, "status"),
-			CreatedAt:  time.Unix(getInt64(raw, "created_at")/1000, 0),
-			UpdatedAt:  time.Unix(getInt64(raw, "updated_at")/1000, 0),
-		}
-		orders = append(orders, order)
+		orders = append(orders, parseOrder(raw))
 	}
 	
 	return orders, nil
@@ -691,7 +919,29 @@ func (d *DeriveMarketMakerExchange) GetOpenOrders() ([]types.MarketMakerOrder, e
 
 // GetPositions gets current positions
 func (d *DeriveMarketMakerExchange) GetPositions() ([]types.ExchangePosition, error) {
-	rawPositions, err := d.wsClient.GetPositions(d.subaccountID)
+	return d.positionsOf(d.subaccountID)
+}
+
+// GetAllPositions returns the positions of every managed subaccount, so risk
+// can be aggregated across them
+func (d *DeriveMarketMakerExchange) GetAllPositions() ([]types.ExchangePosition, error) {
+	var positions []types.ExchangePosition
+	for _, id := range d.subaccounts {
+		subPositions, err := d.positionsOf(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get positions of subaccount %d: %w", id, err)
+		}
+		positions = append(positions, subPositions...)
+	}
+	return positions, nil
+}
+
+// positionsOf returns a subaccount's positions
+func (d *DeriveMarketMakerExchange) positionsOf(subaccountID uint64) ([]types.ExchangePosition, error) {
+	if err := waitNonMatching(ratelimit.PriorityNormal); err != nil {
+		return nil, err
+	}
+	rawPositions, err := d.wsClient.GetPositions(subaccountID)
 	if err != nil {
 		return nil, err
 	}
@@ -720,6 +970,7 @@ func (d *DeriveMarketMakerExchange) GetPositions() ([]types.ExchangePosition, er
 			MarkPrice:      getFloat64(raw, "mark_price"),
 			IndexPrice:     getFloat64(raw, "index_price"),
 			PnL:            getFloat64(raw, "pnl"),
+			SubaccountID:   subaccountID,
 		}
 		positions = append(positions, position)
 	}
@@ -727,6 +978,36 @@ func (d *DeriveMarketMakerExchange) GetPositions() ([]types.ExchangePosition, er
 	return positions, nil
 }
 
+// GetMargin returns the trading subaccount's collateral and margin. Derive
+// reports initial and maintenance margin as what is left over the
+// requirement, so the requirements are the subaccount value less those.
+func (d *DeriveMarketMakerExchange) GetMargin() (*types.MarginState, error) {
+	return d.marginOf(d.subaccountID)
+}
+
+// marginOf returns a subaccount's collateral and margin
+func (d *DeriveMarketMakerExchange) marginOf(subaccountID uint64) (*types.MarginState, error) {
+	if err := waitNonMatching(ratelimit.PriorityNormal); err != nil {
+		return nil, err
+	}
+	raw, err := d.wsClient.GetSubaccount(subaccountID)
+	if err != nil {
+		return nil, err
+	}
+	
+	value := getDecimal(raw, "subaccount_value")
+	available := getDecimal(raw, "initial_margin")
+	return &types.MarginState{
+		SubaccountID:      subaccountID,
+		Collateral:        getDecimal(raw, "collaterals_value"),
+		SubaccountValue:   value,
+		InitialMargin:     value.Sub(available),
+		MaintenanceMargin: value.Sub(getDecimal(raw, "maintenance_margin")),
+		AvailableMargin:   available,
+		UpdatedAt:         time.Now(),
+	}, nil
+}
+
 // GetOrderBook returns the cached orderbook from WebSocket subscription
 func (d *DeriveMarketMakerExchange) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
 	// Get cached orderbook from WebSocket client
@@ -750,6 +1031,160 @@ func (d *DeriveMarketMakerExchange) SubscribeOrderBook(instrument string) error
 	return d.wsClient.SubscribeOrderBook(instrument, 20) // Subscribe with depth 20
 }
 
+// SubscribeFills streams executions on our subaccount from the private
+// {subaccount}.trades channel until ctx is cancelled
+func (d *DeriveMarketMakerExchange) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	fillChan := make(chan types.Fill, 1000)
+	
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+	
+	unsubscribe, err := d.wsClient.SubscribeTrades(d.subaccountID, func(data json.RawMessage) {
+		trades, err := decodeObjects(data)
+		if err != nil {
+			log.Printf("[Derive] Failed to parse trades: %v", err)
+			return
+		}
+		
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		for _, raw := range trades {
+			fill := parseFill(raw)
+			select {
+			case fillChan <- fill:
+			default:
+				log.Printf("WARNING: Fill channel full, dropping fill %s on %s", fill.TradeID, fill.Instrument)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to trades: %w", err)
+	}
+	
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+		
+		mu.Lock()
+		closed = true
+		close(fillChan)
+		mu.Unlock()
+	}()
+	
+	return fillChan, nil
+}
+
+// SubscribeOrderUpdates streams our subaccount's order state changes from the
+// private {subaccount}.orders channel until ctx is cancelled
+func (d *DeriveMarketMakerExchange) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	orderChan := make(chan types.MarketMakerOrder, 1000)
+	
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+	
+	unsubscribe, err := d.wsClient.SubscribeOrders(d.subaccountID, func(data json.RawMessage) {
+		orders, err := decodeObjects(data)
+		if err != nil {
+			log.Printf("[Derive] Failed to parse order updates: %v", err)
+			return
+		}
+		
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		for _, raw := range orders {
+			order := parseOrder(raw)
+			select {
+			case orderChan <- order:
+			default:
+				log.Printf("WARNING: Order update channel full, dropping update for %s", order.OrderID)
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to orders: %w", err)
+	}
+	
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+		
+		mu.Lock()
+		closed = true
+		close(orderChan)
+		mu.Unlock()
+	}()
+	
+	return orderChan, nil
+}
+
+// decodeObjects decodes a private channel payload, which is normally an array
+// of objects but may be a single object
+func decodeObjects(data json.RawMessage) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err == nil {
+		return objects, nil
+	}
+	
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	return []map[string]interface{}{object}, nil
+}
+
+// parseOrder converts a Derive order object
+func parseOrder(raw map[string]interface{}) types.MarketMakerOrder {
+	status := getString(raw, "order_status")
+	if status == "" {
+		status = getString(raw, "status")
+	}
+	created := getInt64(raw, "creation_timestamp")
+	if created == 0 {
+		created = getInt64(raw, "created_at")
+	}
+	updated := getInt64(raw, "last_update_timestamp")
+	if updated == 0 {
+		updated = getInt64(raw, "updated_at")
+	}
+	
+	return types.MarketMakerOrder{
+		OrderID:      getString(raw, "order_id"),
+		Instrument:   getString(raw, "instrument_name"),
+		Side:         getString(raw, "direction"),
+		Price:        getDecimal(raw, "limit_price"),
+		Amount:       getDecimal(raw, "amount"),
+		FilledAmount: getDecimal(raw, "filled_amount"),
+		Status:       status,
+		CreatedAt:    time.UnixMilli(created),
+		UpdatedAt:    time.UnixMilli(updated),
+	}
+}
+
+// parseFill converts a Derive trade object
+func parseFill(raw map[string]interface{}) types.Fill {
+	return types.Fill{
+		TradeID:     getString(raw, "trade_id"),
+		OrderID:     getString(raw, "order_id"),
+		Instrument:  getString(raw, "instrument_name"),
+		Side:        getString(raw, "direction"),
+		Price:       getDecimal(raw, "trade_price"),
+		Amount:      getDecimal(raw, "trade_amount"),
+		Fee:         getDecimal(raw, "trade_fee"),
+		RealizedPnL: getDecimal(raw, "realized_pnl_excl_fees"),
+		Timestamp:   time.UnixMilli(getInt64(raw, "timestamp")),
+	}
+}
+
 // Helper functions to extract values from map
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
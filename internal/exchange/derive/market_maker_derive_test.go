@@ -0,0 +1,115 @@
+package derive
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestParseTicker(t *testing.T) {
+	// instrument_ticker payload from the ticker.{instrument}.100 channel
+	data := json.RawMessage(`{
+		"instrument_name": "ETH-20250530-3000-C",
+		"best_bid_price": "95.5",
+		"best_bid_amount": "2",
+		"best_ask_price": "98",
+		"best_ask_amount": "1.5",
+		"mark_price": "96.7",
+		"option_pricing": {"delta": "0.48", "gamma": "0.0009", "vega": "3.2", "theta": "-5.1"},
+		"option_details": {"expiry": 1748592000, "strike": "3000", "option_type": "C"}
+	}`)
+
+	ticker, err := parseTicker(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ticker.Instrument != "ETH-20250530-3000-C" {
+		t.Errorf("instrument = %s", ticker.Instrument)
+	}
+	if !ticker.BestBid.Equal(decimal.NewFromFloat(95.5)) || !ticker.BestAsk.Equal(decimal.NewFromInt(98)) {
+		t.Errorf("bad top of book: %s / %s", ticker.BestBid, ticker.BestAsk)
+	}
+	if ticker.Delta == nil || !ticker.Delta.Equal(decimal.NewFromFloat(0.48)) {
+		t.Errorf("delta = %v, want 0.48", ticker.Delta)
+	}
+	if ticker.Strike == nil || !ticker.Strike.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("strike = %v, want 3000", ticker.Strike)
+	}
+
+	if _, err := parseTicker(json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error for ticker without instrument")
+	}
+}
+
+func TestParsePrivateChannels(t *testing.T) {
+	trades, err := decodeObjects(json.RawMessage(`[{
+		"trade_id": "t1",
+		"order_id": "o1",
+		"instrument_name": "ETH-PERP",
+		"direction": "sell",
+		"trade_price": "2500.5",
+		"trade_amount": "0.2",
+		"trade_fee": "0.35",
+		"realized_pnl_excl_fees": "12",
+		"timestamp": 1748592000000
+	}]`))
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("decodeObjects(trades) = %d, %v", len(trades), err)
+	}
+
+	fill := parseFill(trades[0])
+	if fill.OrderID != "o1" || fill.Side != "sell" {
+		t.Errorf("bad fill identity: %+v", fill)
+	}
+	if !fill.Amount.Equal(decimal.RequireFromString("0.2")) || !fill.Fee.Equal(decimal.RequireFromString("0.35")) {
+		t.Errorf("amount = %s, fee = %s", fill.Amount, fill.Fee)
+	}
+	if fill.Timestamp.UnixMilli() != 1748592000000 {
+		t.Errorf("timestamp = %v", fill.Timestamp)
+	}
+
+	// A single order object is accepted as well as an array
+	orders, err := decodeObjects(json.RawMessage(`{
+		"order_id": "o1",
+		"instrument_name": "ETH-PERP",
+		"direction": "sell",
+		"limit_price": "2500.5",
+		"amount": "0.2",
+		"filled_amount": "0.2",
+		"order_status": "filled"
+	}`))
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("decodeObjects(order) = %d, %v", len(orders), err)
+	}
+
+	order := parseOrder(orders[0])
+	if order.Status != "filled" || !order.FilledAmount.Equal(order.Amount) {
+		t.Errorf("bad order: %+v", order)
+	}
+}
+
+func TestDeriveTimeInForce(t *testing.T) {
+	tests := []struct {
+		opts    types.OrderOptions
+		want    string
+		wantErr bool
+	}{
+		{types.OrderOptions{}, "gtc", false},
+		{types.OrderOptions{PostOnly: true}, "post_only", false},
+		{types.OrderOptions{TimeInForce: types.TimeInForceIOC, ReduceOnly: true}, "ioc", false},
+		{types.OrderOptions{TimeInForce: types.TimeInForceFOK}, "fok", false},
+		{types.OrderOptions{ReduceOnly: true}, "", true},
+		{types.OrderOptions{PostOnly: true, TimeInForce: types.TimeInForceIOC}, "", true},
+		{types.OrderOptions{TimeInForce: "day"}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := deriveTimeInForce(tt.opts)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("deriveTimeInForce(%+v) = %q, %v; want %q, error %v", tt.opts, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
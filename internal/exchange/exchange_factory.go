@@ -1,11 +1,11 @@
 package exchange
 
 import (
+	"context"
 	"fmt"
-	"strings"
-	"time"
 	
 	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
@@ -30,6 +30,16 @@ func (f *Factory) CreateExchange(exchangeName string, config map[string]interfac
 		mmConfig.ExchangeTestMode = testMode
 	}
 	
+	// Check dry run
+	if dryRun, ok := config["dry_run"].(bool); ok {
+		mmConfig.DryRun = dryRun
+	}
+	
+	// Check Derive subaccount
+	if subaccount, ok := config["subaccount"].(uint64); ok {
+		mmConfig.Subaccount = subaccount
+	}
+	
 	// Create the market maker exchange
 	mmExchange, err := NewExchange(mmConfig)
 	if err != nil {
@@ -65,30 +75,27 @@ func (a *marketMakerExchangeAdapter) GetOrderBook(req types.RFQResult, asset str
 		return types.CCXTOrderBook{}, fmt.Errorf("failed to get order book: %w", err)
 	}
 	
-	// Convert to CCXT format
-	ccxtBook := types.CCXTOrderBook{
-		Symbol: instrument,
-		Bids:   make([][]float64, len(orderBook.Bids)),
-		Asks:   make([][]float64, len(orderBook.Asks)),
-	}
-	
-	for i, bid := range orderBook.Bids {
-		price, _ := bid.Price.Float64()
-		size, _ := bid.Size.Float64()
-		ccxtBook.Bids[i] = []float64{price, size}
-	}
-	
-	for i, ask := range orderBook.Asks {
-		price, _ := ask.Price.Float64()
-		size, _ := ask.Size.Float64()
-		ccxtBook.Asks[i] = []float64{price, size}
+	return orderBook.ToCCXT(instrument), nil
+}
+
+// GetInstrumentOrderBook gets the order book of a named instrument, such as a
+// hedge perp
+func (a *marketMakerExchangeAdapter) GetInstrumentOrderBook(instrument string) (types.CCXTOrderBook, error) {
+	orderBook, err := a.mmExchange.GetOrderBook(instrument)
+	if err != nil {
+		return types.CCXTOrderBook{}, fmt.Errorf("failed to get order book: %w", err)
 	}
-	
-	return ccxtBook, nil
+	return orderBook.ToCCXT(instrument), nil
+}
+
+// SubscribeTickers streams the underlying exchange's tickers, so the adapter
+// can feed a market data hub
+func (a *marketMakerExchangeAdapter) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	return a.mmExchange.SubscribeTickers(ctx, instruments)
 }
 
 // PlaceOrder places an order based on RFQ confirmation
-func (a *marketMakerExchangeAdapter) PlaceOrder(conf types.RFQConfirmation, instrument string, cfg interface{}) error {
+func (a *marketMakerExchangeAdapter) PlaceOrder(conf types.RFQConfirmation, instrument string, cfg interface{}) (string, error) {
 	// Determine side from confirmation
 	side := "buy"
 	if conf.IsTakerBuy {
@@ -98,52 +105,59 @@ func (a *marketMakerExchangeAdapter) PlaceOrder(conf types.RFQConfirmation, inst
 	// Parse price and quantity
 	price, err := decimal.NewFromString(conf.Price)
 	if err != nil {
-		return fmt.Errorf("invalid price: %w", err)
+		return "", fmt.Errorf("invalid price: %w", err)
 	}
 	
 	quantity, err := decimal.NewFromString(conf.Quantity)
 	if err != nil {
-		return fmt.Errorf("invalid quantity: %w", err)
+		return "", fmt.Errorf("invalid quantity: %w", err)
 	}
 	
-	// Place order via market maker exchange
-	_, err = a.mmExchange.PlaceLimitOrder(instrument, side, price, quantity)
+	// Place order via market maker exchange. These are hedges, so they go
+	// ahead of routine requests when rate limited.
+	orderID, err := a.mmExchange.PlaceLimitOrder(instrument, side, price, quantity, types.OrderOptions{Urgent: true})
 	if err != nil {
-		return fmt.Errorf("failed to place order: %w", err)
+		return "", fmt.Errorf("failed to place order: %w", err)
 	}
 	
-	return nil
+	return orderID, nil
+}
+
+// CancelOrder cancels an order
+func (a *marketMakerExchangeAdapter) CancelOrder(orderID string) error {
+	return a.mmExchange.CancelOrder(orderID)
+}
+
+// SubscribeFills streams executions of our orders
+func (a *marketMakerExchangeAdapter) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	return a.mmExchange.SubscribeFills(ctx)
+}
+
+// SubscribeOrderUpdates streams state changes of our orders
+func (a *marketMakerExchangeAdapter) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	return a.mmExchange.SubscribeOrderUpdates(ctx)
 }
 
 // ConvertToInstrument converts option parameters to exchange-specific instrument name
 func (a *marketMakerExchangeAdapter) ConvertToInstrument(asset string, strike string, expiry int64, isPut bool) (string, error) {
-	// Convert to standard format: ASSET-YYYYMMDD-STRIKE-TYPE
-	expiryTime := time.Unix(expiry, 0).UTC()
-	expiryStr := expiryTime.Format("20060102")
-	
-	optionType := "C"
-	if isPut {
-		optionType = "P"
-	}
-	
-	// For Derive/Lyra format
-	if a.name == "derive" {
-		return fmt.Sprintf("%s-%s-%s-%s", asset, expiryStr, strike, optionType), nil
-	}
-	
-	// For Deribit format
-	if a.name == "deribit" {
-		// Deribit uses format like: ETH-29DEC23-3000-C
-		expiryStr = expiryTime.Format("2Jan06")
-		expiryStr = strings.ToUpper(expiryStr)
-		return fmt.Sprintf("%s-%s-%s-%s", asset, expiryStr, strike, optionType), nil
+	inst, err := instruments.FromRysk(asset, strike, expiry, isPut)
+	if err != nil {
+		return "", err
 	}
-	
-	// Default format
-	return fmt.Sprintf("%s-%s-%s-%s", asset, expiryStr, strike, optionType), nil
+	return inst.NameOn(a.name), nil
 }
 
 // GetPositions returns current positions
 func (a *marketMakerExchangeAdapter) GetPositions() ([]types.ExchangePosition, error) {
 	return a.mmExchange.GetPositions()
+}
+
+// GetMargin returns the trading subaccount's margin, if the exchange reports it
+func (a *marketMakerExchangeAdapter) GetMargin() (*types.MarginState, error) {
+	if source, ok := a.mmExchange.(interface {
+		GetMargin() (*types.MarginState, error)
+	}); ok {
+		return source.GetMargin()
+	}
+	return nil, fmt.Errorf("%s does not report margin", a.name)
 }
\ No newline at end of file
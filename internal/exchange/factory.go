@@ -2,22 +2,89 @@ package exchange
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/wakamex/atomizer/internal/cache"
+	"github.com/wakamex/atomizer/internal/exchange/deribit"
 	"github.com/wakamex/atomizer/internal/exchange/derive"
+	"github.com/wakamex/atomizer/internal/exchange/paper"
+	"github.com/wakamex/atomizer/internal/exchange/replay"
+	"github.com/wakamex/atomizer/internal/instruments"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
-// NewExchange creates a new exchange instance based on the configuration
+// instrumentCacheTTL is how long instrument listings are cached on disk
+const instrumentCacheTTL = time.Hour
+
+var instrumentCacheOnce sync.Once
+
+// useInstrumentCache keeps the shared instrument registry in the user cache
+// directory, so restarts don't refetch every listing
+func useInstrumentCache() {
+	instrumentCacheOnce.Do(func() {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			log.Printf("No cache directory for instruments: %v", err)
+			return
+		}
+		fileCache, err := cache.NewFileMarketCache(filepath.Join(dir, "atomizer"))
+		if err != nil {
+			log.Printf("Failed to create instrument cache: %v", err)
+			return
+		}
+		instruments.Default.SetCache(fileCache, instrumentCacheTTL)
+	})
+}
+
+// NewExchange creates a new exchange instance based on the configuration. In
+// dry run mode the exchange only supplies market data to a paper exchange. A
+// replay path serves a recording in place of the exchange, and a record path
+// captures every call the strategy makes.
 func NewExchange(config *types.MarketMakerConfig) (types.MarketMakerExchange, error) {
-	switch config.Exchange {
-	case "derive":
-		return newDeriveExchange(config)
-	case "deribit":
-		return newDeribitExchange(config)
+	var ex types.MarketMakerExchange
+	var err error
+	switch {
+	case config.ReplayPath != "":
+		log.Printf("Replaying exchange calls from %s", config.ReplayPath)
+		ex, err = replay.LoadFile(config.ReplayPath)
+	case config.Exchange == "derive":
+		useInstrumentCache()
+		ex, err = newDeriveExchange(config)
+	case config.Exchange == "deribit":
+		useInstrumentCache()
+		ex, err = newDeribitExchange(config)
 	default:
 		return nil, fmt.Errorf("unsupported exchange: %s", config.Exchange)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DryRun {
+		ex = newPaperExchange(config, ex)
+	}
+
+	if config.RecordPath != "" {
+		log.Printf("Recording exchange calls to %s", config.RecordPath)
+		return replay.NewFileRecorder(ex, config.RecordPath)
+	}
+
+	return ex, nil
+}
+
+// newPaperExchange simulates trading against a live exchange's market data
+func newPaperExchange(config *types.MarketMakerConfig, feed types.MarketMakerExchange) *paper.PaperExchange {
+	paperConfig := paper.DefaultConfig(config.Exchange)
+	if config.PaperLatency > 0 {
+		paperConfig.Latency = config.PaperLatency
+	}
+
+	log.Printf("DRY RUN: paper trading against %s market data (latency %v)", config.Exchange, paperConfig.Latency)
+	return paper.NewPaperExchange(feed, paperConfig)
 }
 
 func newDeriveExchange(config *types.MarketMakerConfig) (types.MarketMakerExchange, error) {
@@ -41,30 +108,90 @@ func newDeriveExchange(config *types.MarketMakerConfig) (types.MarketMakerExchan
 		return nil, fmt.Errorf("failed to create Derive exchange: %w", err)
 	}
 
+	if config.Subaccount != 0 && config.Subaccount != deriveExchange.SubaccountID() {
+		deriveExchange, err = deriveExchange.ForSubaccount(config.Subaccount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use Derive subaccount: %w", err)
+		}
+		log.Printf("Trading from subaccount %d", config.Subaccount)
+	}
+
 	return deriveExchange, nil
 }
 
 func newDeribitExchange(config *types.MarketMakerConfig) (types.MarketMakerExchange, error) {
+	client, err := newDeribitClient(config.ExchangeTestMode)
+	if err != nil {
+		return nil, err
+	}
+
+	deribitExchange, err := deribit.NewDeribitMarketMakerExchange(client, config.ExchangeTestMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Deribit exchange: %w", err)
+	}
+
+	return deribitExchange, nil
+}
+
+// newDeribitClient authenticates with an Ed25519 key. An API key and shared
+// secret are only used when DERIBIT_AUTH=secret asks for them.
+func newDeribitClient(testnet bool) (*deribit.DeribitClient, error) {
 	// Get credentials from environment
 	apiKey := os.Getenv("DERIBIT_API_KEY")
 	apiSecret := os.Getenv("DERIBIT_API_SECRET")
 
+	switch auth := os.Getenv("DERIBIT_AUTH"); auth {
+	case "", "ed25519":
+	case "secret":
+		if apiKey == "" || apiSecret == "" {
+			return nil, fmt.Errorf("DERIBIT_AUTH=secret but DERIBIT_API_KEY and DERIBIT_API_SECRET environment variables not set")
+		}
+		log.Printf("WARNING: Authenticating with Deribit by API key and shared secret (DERIBIT_AUTH=secret). Ed25519 keys are preferred.")
+
+		client, err := deribit.NewDeribitClientWithSecret(apiKey, apiSecret, testnet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Deribit client: %w", err)
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown DERIBIT_AUTH %q, want ed25519 or secret", auth)
+	}
+
 	// Check for private key auth
 	privateKey := os.Getenv("DERIBIT_PRIVATE_KEY")
 	if privateKey == "" {
 		privateKey = os.Getenv("DERIBIT_ED25519_PRIVATE_KEY")
 	}
+	if privateKey == "" {
+		if keyFile := os.Getenv("DERIBIT_PRIVATE_KEY_FILE"); keyFile != "" {
+			keyBytes, err := os.ReadFile(keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read DERIBIT_PRIVATE_KEY_FILE: %w", err)
+			}
+			privateKey = string(keyBytes)
+		}
+	}
+	if privateKey == "" {
+		return nil, fmt.Errorf("no Deribit Ed25519 key: set DERIBIT_PRIVATE_KEY or DERIBIT_PRIVATE_KEY_FILE, or DERIBIT_AUTH=secret to use DERIBIT_API_KEY and DERIBIT_API_SECRET")
+	}
 
-	if privateKey != "" {
-		// Use private key authentication
-		// TODO: Create Deribit exchange with ED25519 auth
-		return nil, fmt.Errorf("deribit ED25519 auth not yet implemented")
+	// The client ID of an Ed25519 key is its API key
+	clientID := os.Getenv("DERIBIT_CLIENT_ID")
+	if clientID == "" {
+		clientID = apiKey
+	}
+	if clientID == "" {
+		return nil, fmt.Errorf("DERIBIT_CLIENT_ID environment variable not set")
 	}
 
-	if apiKey == "" || apiSecret == "" {
-		return nil, fmt.Errorf("DERIBIT_API_KEY and DERIBIT_API_SECRET environment variables not set")
+	ed25519Client, err := deribit.NewDeribitEd25519Client(clientID, privateKey, testnet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Deribit Ed25519 key: %w", err)
 	}
 
-	// TODO: Create DeribitExchange with API key/secret
-	return nil, fmt.Errorf("deribit exchange creation not yet implemented")
+	client, err := deribit.NewDeribitClientWithAuth(clientID, ed25519Client, testnet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Deribit client: %w", err)
+	}
+	return client, nil
 }
@@ -0,0 +1,31 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeribitSharedSecretMustBeAskedFor(t *testing.T) {
+	for _, name := range []string{"DERIBIT_PRIVATE_KEY", "DERIBIT_ED25519_PRIVATE_KEY", "DERIBIT_PRIVATE_KEY_FILE", "DERIBIT_CLIENT_ID"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("DERIBIT_API_KEY", "key")
+	t.Setenv("DERIBIT_API_SECRET", "secret")
+
+	tests := []struct {
+		auth string
+		want string
+	}{
+		{"", "DERIBIT_AUTH=secret"},
+		{"ed25519", "DERIBIT_AUTH=secret"},
+		{"password", "unknown DERIBIT_AUTH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.auth, func(t *testing.T) {
+			t.Setenv("DERIBIT_AUTH", tt.auth)
+			if _, err := newDeribitClient(true); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,697 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/fees"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Feed supplies the market data paper orders are matched against. Any
+// MarketMakerExchange, live or replayed, can be a feed.
+type Feed interface {
+	SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error)
+	GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error)
+}
+
+// Config controls the simulation
+type Config struct {
+	Exchange string        // Exchange the feed comes from, for instrument naming
+	Latency  time.Duration // Delay before orders and cancels reach the matching engine
+	Fees     fees.Schedule
+}
+
+// DefaultConfig returns the simulation settings for an exchange's feed
+func DefaultConfig(exchange string) Config {
+	schedule, err := fees.ForExchange(exchange)
+	if err != nil {
+		log.Printf("[Paper] %v, simulating without fees", err)
+	}
+	return Config{
+		Exchange: exchange,
+		Latency:  100 * time.Millisecond,
+		Fees:     schedule,
+	}
+}
+
+// PaperExchange simulates an exchange locally. Orders rest in a matching
+// engine and fill against the feed's tickers and books, with fees charged from
+// the configured schedule. Positions and P&L are tracked from the fills.
+type PaperExchange struct {
+	feed   Feed
+	config Config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	orders    map[string]*paperOrder // Open orders by ID
+	nextID    int64
+	market    map[string]types.TickerUpdate
+	watched   map[string]bool
+	positions map[string]*position
+
+	realizedPnL decimal.Decimal
+	feesPaid    decimal.Decimal
+
+	// Strategy subscriptions
+	tickerSubs map[int]*tickerSub
+	fillSubs   map[int]chan types.Fill
+	orderSubs  map[int]chan types.MarketMakerOrder
+	nextSubID  int
+}
+
+// paperOrder is an order in the matching engine
+type paperOrder struct {
+	types.MarketMakerOrder
+	opts types.OrderOptions
+	live bool // Reached the matching engine
+}
+
+// position is a signed position with its average entry price
+type position struct {
+	amount   decimal.Decimal
+	avgPrice decimal.Decimal
+}
+
+// tickerSub forwards tickers for a set of instruments to a strategy
+type tickerSub struct {
+	instruments map[string]bool
+	ch          chan types.TickerUpdate
+}
+
+// NewPaperExchange creates a paper exchange matching against feed
+func NewPaperExchange(feed Feed, config Config) *PaperExchange {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &PaperExchange{
+		feed:       feed,
+		config:     config,
+		ctx:        ctx,
+		cancel:     cancel,
+		orders:     make(map[string]*paperOrder),
+		market:     make(map[string]types.TickerUpdate),
+		watched:    make(map[string]bool),
+		positions:  make(map[string]*position),
+		tickerSubs: make(map[int]*tickerSub),
+		fillSubs:   make(map[int]chan types.Fill),
+		orderSubs:  make(map[int]chan types.MarketMakerOrder),
+	}
+}
+
+// watch subscribes to the feed for an instrument so its orders can match
+func (p *PaperExchange) watch(instrument string) error {
+	p.mu.Lock()
+	if p.watched[instrument] {
+		p.mu.Unlock()
+		return nil
+	}
+	p.watched[instrument] = true
+	p.mu.Unlock()
+
+	tickers, err := p.feed.SubscribeTickers(p.ctx, []string{instrument})
+	if err != nil {
+		p.mu.Lock()
+		delete(p.watched, instrument)
+		p.mu.Unlock()
+		return fmt.Errorf("failed to subscribe to %s market data: %w", instrument, err)
+	}
+
+	go func() {
+		for ticker := range tickers {
+			p.onTicker(ticker)
+		}
+	}()
+
+	return nil
+}
+
+// after runs fn once the configured latency has passed
+func (p *PaperExchange) after(fn func()) {
+	if p.config.Latency <= 0 {
+		fn()
+		return
+	}
+	time.AfterFunc(p.config.Latency, fn)
+}
+
+// onTicker matches resting orders against a market update and forwards it
+func (p *PaperExchange) onTicker(ticker types.TickerUpdate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.market[ticker.Instrument] = ticker
+
+	for _, order := range p.orders {
+		if !order.live || order.Instrument != ticker.Instrument {
+			continue
+		}
+
+		// The market trading through our price fills us at our price
+		if order.Side == "buy" && ticker.BestAsk.IsPositive() && ticker.BestAsk.LessThanOrEqual(order.Price) {
+			p.fill(order, order.Price, available(ticker.BestAskSize, order), false)
+		} else if order.Side == "sell" && ticker.BestBid.IsPositive() && ticker.BestBid.GreaterThanOrEqual(order.Price) {
+			p.fill(order, order.Price, available(ticker.BestBidSize, order), false)
+		}
+	}
+
+	for _, sub := range p.tickerSubs {
+		if !sub.instruments[ticker.Instrument] {
+			continue
+		}
+		select {
+		case sub.ch <- ticker:
+		default:
+			log.Printf("Ticker channel full, dropping update for %s", ticker.Instrument)
+		}
+	}
+}
+
+// available returns how much of order a level of size can fill. Feeds that
+// don't report sizes fill the whole order.
+func available(size decimal.Decimal, order *paperOrder) decimal.Decimal {
+	remaining := order.Amount.Sub(order.FilledAmount)
+	if size.IsPositive() && size.LessThan(remaining) {
+		return size
+	}
+	return remaining
+}
+
+// activate puts an order in the matching engine, taking any liquidity it
+// crosses before it rests. Post-only orders that would cross are rejected, and
+// IOC and FOK orders never rest.
+func (p *PaperExchange) activate(orderID, instrument string) {
+	book, bookErr := p.feed.GetOrderBook(instrument)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order, exists := p.orders[orderID]
+	if !exists {
+		return
+	}
+	order.live = true
+
+	var levels []types.OrderBookLevel
+	switch {
+	case bookErr == nil && book != nil:
+		levels = book.Asks
+		if order.Side == "sell" {
+			levels = book.Bids
+		}
+	default:
+		// Without a book, the top of the latest ticker is all we know
+		ticker := p.market[instrument]
+		level := types.OrderBookLevel{Price: ticker.BestAsk, Size: ticker.BestAskSize}
+		if order.Side == "sell" {
+			level = types.OrderBookLevel{Price: ticker.BestBid, Size: ticker.BestBidSize}
+		}
+		levels = []types.OrderBookLevel{level}
+	}
+
+	crossed := crossing(order, levels)
+	if order.opts.PostOnly && len(crossed) > 0 {
+		log.Printf("[Paper] Rejected post-only order %s: would cross at %s", orderID, crossed[0].Price)
+		p.finish(order, "rejected")
+		return
+	}
+	if order.opts.TIF() == types.TimeInForceFOK && !canFill(crossed, order.Amount) {
+		log.Printf("[Paper] Killed FOK order %s: not enough liquidity", orderID)
+		p.finish(order, "cancelled")
+		return
+	}
+
+	for _, level := range crossed {
+		if _, open := p.orders[orderID]; !open {
+			return
+		}
+		p.fill(order, level.Price, available(level.Size, order), true)
+	}
+
+	if _, open := p.orders[orderID]; open && order.opts.TIF() != types.TimeInForceGTC {
+		p.finish(order, "cancelled")
+	}
+}
+
+// crossing returns the leading levels an order's limit price crosses
+func crossing(order *paperOrder, levels []types.OrderBookLevel) []types.OrderBookLevel {
+	for i, level := range levels {
+		if !level.Price.IsPositive() ||
+			(order.Side == "buy" && level.Price.GreaterThan(order.Price)) ||
+			(order.Side == "sell" && level.Price.LessThan(order.Price)) {
+			return levels[:i]
+		}
+	}
+	return levels
+}
+
+// canFill reports whether levels hold amount. Levels without sizes are
+// assumed deep enough, as in available.
+func canFill(levels []types.OrderBookLevel, amount decimal.Decimal) bool {
+	depth := decimal.Zero
+	for _, level := range levels {
+		if !level.Size.IsPositive() {
+			return true
+		}
+		depth = depth.Add(level.Size)
+	}
+	return depth.GreaterThanOrEqual(amount)
+}
+
+// reducible returns how much of a position order could close
+// (must be called with lock held)
+func (p *PaperExchange) reducible(order *paperOrder) decimal.Decimal {
+	pos, exists := p.positions[order.Instrument]
+	if !exists {
+		return decimal.Zero
+	}
+	if (order.Side == "buy" && pos.amount.IsNegative()) || (order.Side == "sell" && pos.amount.IsPositive()) {
+		return pos.amount.Abs()
+	}
+	return decimal.Zero
+}
+
+// finish removes an open order with a final status and publishes it
+// (must be called with lock held)
+func (p *PaperExchange) finish(order *paperOrder, status string) {
+	order.Status = status
+	order.UpdatedAt = time.Now()
+	delete(p.orders, order.OrderID)
+	p.publishOrder(order.MarketMakerOrder)
+}
+
+// fill executes amount of order at price (must be called with lock held)
+func (p *PaperExchange) fill(order *paperOrder, price, amount decimal.Decimal, isTaker bool) {
+	if order.opts.ReduceOnly {
+		amount = decimal.Min(amount, p.reducible(order))
+	}
+	if !amount.IsPositive() {
+		if order.opts.ReduceOnly {
+			log.Printf("[Paper] Cancelled reduce-only order %s: no position to reduce", order.OrderID)
+			p.finish(order, "cancelled")
+		}
+		return
+	}
+
+	fee := decimal.NewFromFloat(p.fee(order.Instrument, price, amount, isTaker))
+	realized := p.updatePosition(order.Instrument, order.Side, price, amount)
+	p.realizedPnL = p.realizedPnL.Add(realized)
+	p.feesPaid = p.feesPaid.Add(fee)
+
+	now := time.Now()
+	order.FilledAmount = order.FilledAmount.Add(amount)
+	order.UpdatedAt = now
+	if !order.FilledAmount.LessThan(order.Amount) {
+		order.Status = "filled"
+		delete(p.orders, order.OrderID)
+	}
+
+	p.nextID++
+	p.publishFill(types.Fill{
+		TradeID:     fmt.Sprintf("paper-trade-%d", p.nextID),
+		OrderID:     order.OrderID,
+		Instrument:  order.Instrument,
+		Side:        order.Side,
+		Price:       price,
+		Amount:      amount,
+		Fee:         fee,
+		RealizedPnL: realized,
+		Timestamp:   now,
+	})
+	p.publishOrder(order.MarketMakerOrder)
+
+	log.Printf("[Paper] Filled %s %s %s @ %s (fee %s, %s)",
+		order.Side, amount, order.Instrument, price, fee.StringFixed(4), order.Status)
+
+	// A reduce-only order can't outlive the position it was reducing
+	if _, open := p.orders[order.OrderID]; open && order.opts.ReduceOnly && !p.reducible(order).IsPositive() {
+		p.finish(order, "cancelled")
+	}
+}
+
+// fee returns the simulated fee for a fill
+func (p *PaperExchange) fee(instrument string, price, amount decimal.Decimal, isTaker bool) float64 {
+	index := p.market[instrument].IndexPrice
+	if !instruments.IsOptionName(instrument) {
+		if index.IsZero() {
+			index = price
+		}
+		return p.config.Fees.PerpFee(isTaker, amount.InexactFloat64(), index.InexactFloat64())
+	}
+	return p.config.Fees.OptionFee(isTaker, amount.InexactFloat64(), index.InexactFloat64(), price.InexactFloat64())
+}
+
+// updatePosition applies a fill and returns the P&L it realized, excluding
+// fees (must be called with lock held)
+func (p *PaperExchange) updatePosition(instrument, side string, price, amount decimal.Decimal) decimal.Decimal {
+	pos, exists := p.positions[instrument]
+	if !exists {
+		pos = &position{}
+		p.positions[instrument] = pos
+	}
+
+	signed := amount
+	if side == "sell" {
+		signed = amount.Neg()
+	}
+
+	realized := decimal.Zero
+	if !pos.amount.IsZero() && pos.amount.Sign() != signed.Sign() {
+		// Reducing: realize P&L on the closed part
+		closed := decimal.Min(amount, pos.amount.Abs())
+		realized = price.Sub(pos.avgPrice).Mul(closed)
+		if pos.amount.IsNegative() {
+			realized = realized.Neg()
+		}
+	}
+
+	newAmount := pos.amount.Add(signed)
+	switch {
+	case newAmount.IsZero():
+		pos.avgPrice = decimal.Zero
+	case pos.amount.IsZero() || newAmount.Sign() != pos.amount.Sign():
+		// Opened or flipped: the remainder was entered at this price
+		pos.avgPrice = price
+	case newAmount.Abs().GreaterThan(pos.amount.Abs()):
+		// Increased: average in
+		pos.avgPrice = pos.avgPrice.Mul(pos.amount).Add(price.Mul(signed)).Div(newAmount)
+	}
+	pos.amount = newAmount
+
+	if pos.amount.IsZero() {
+		delete(p.positions, instrument)
+	}
+
+	return realized
+}
+
+// publishFill sends a fill to subscribers (must be called with lock held)
+func (p *PaperExchange) publishFill(fill types.Fill) {
+	for _, ch := range p.fillSubs {
+		select {
+		case ch <- fill:
+		default:
+			log.Printf("WARNING: Fill channel full, dropping fill %s on %s", fill.TradeID, fill.Instrument)
+		}
+	}
+}
+
+// publishOrder sends an order update to subscribers (must be called with lock held)
+func (p *PaperExchange) publishOrder(order types.MarketMakerOrder) {
+	for _, ch := range p.orderSubs {
+		select {
+		case ch <- order:
+		default:
+			log.Printf("WARNING: Order update channel full, dropping update for %s", order.OrderID)
+		}
+	}
+}
+
+// SubscribeTickers streams the feed's tickers for instruments until ctx is
+// cancelled, after they have been matched against resting orders
+func (p *PaperExchange) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	sub := &tickerSub{
+		instruments: make(map[string]bool),
+		ch:          make(chan types.TickerUpdate, 100),
+	}
+	for _, instrument := range instruments {
+		sub.instruments[instrument] = true
+	}
+
+	p.mu.Lock()
+	id := p.nextSubID
+	p.nextSubID++
+	p.tickerSubs[id] = sub
+	p.mu.Unlock()
+
+	for _, instrument := range instruments {
+		if err := p.watch(instrument); err != nil {
+			log.Printf("[Paper] %v", err)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		delete(p.tickerSubs, id)
+		close(sub.ch)
+		p.mu.Unlock()
+	}()
+
+	return sub.ch, nil
+}
+
+// SubscribeFills streams simulated executions until ctx is cancelled
+func (p *PaperExchange) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	ch := make(chan types.Fill, 1000)
+
+	p.mu.Lock()
+	id := p.nextSubID
+	p.nextSubID++
+	p.fillSubs[id] = ch
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		delete(p.fillSubs, id)
+		close(ch)
+		p.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// SubscribeOrderUpdates streams simulated order state changes until ctx is
+// cancelled
+func (p *PaperExchange) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	ch := make(chan types.MarketMakerOrder, 1000)
+
+	p.mu.Lock()
+	id := p.nextSubID
+	p.nextSubID++
+	p.orderSubs[id] = ch
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		delete(p.orderSubs, id)
+		close(ch)
+		p.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// PlaceLimitOrder accepts an order, which reaches the matching engine after
+// the configured latency
+func (p *PaperExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	if side != "buy" && side != "sell" {
+		return "", fmt.Errorf("invalid side: %s", side)
+	}
+	if !price.IsPositive() || !amount.IsPositive() {
+		return "", fmt.Errorf("invalid order: price %s, amount %s", price, amount)
+	}
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	// Round as the exchange would when the feed knows the instrument
+	if spec, err := p.GetInstrument(instrument); err == nil {
+		if price, amount, err = spec.Round(price, amount); err != nil {
+			return "", err
+		}
+	}
+
+	if err := p.watch(instrument); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.nextID++
+	orderID := fmt.Sprintf("paper-%d", p.nextID)
+	p.orders[orderID] = &paperOrder{MarketMakerOrder: types.MarketMakerOrder{
+		OrderID:    orderID,
+		Instrument: instrument,
+		Side:       side,
+		Price:      price,
+		Amount:     amount,
+		Status:     "open",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, opts: opts}
+	p.mu.Unlock()
+
+	log.Printf("[Paper] Placed %s order %s for %s %s @ %s", side, orderID, amount, instrument, price)
+
+	p.after(func() { p.activate(orderID, instrument) })
+	return orderID, nil
+}
+
+// ReplaceOrder cancels an order and places a new one
+func (p *PaperExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	if err := p.CancelOrder(orderID); err != nil {
+		return "", err
+	}
+	return p.PlaceLimitOrder(instrument, side, price, amount, opts)
+}
+
+// CancelOrder cancels an order once the configured latency has passed. The
+// order can still fill until then.
+func (p *PaperExchange) CancelOrder(orderID string) error {
+	p.mu.Lock()
+	_, exists := p.orders[orderID]
+	p.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("order %s not found", orderID)
+	}
+
+	p.after(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if order, exists := p.orders[orderID]; exists {
+			p.finish(order, "cancelled")
+		}
+	})
+
+	return nil
+}
+
+// GetOpenOrders returns the open paper orders
+func (p *PaperExchange) GetOpenOrders() ([]types.MarketMakerOrder, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	orders := make([]types.MarketMakerOrder, 0, len(p.orders))
+	for _, order := range p.orders {
+		orders = append(orders, order.MarketMakerOrder)
+	}
+	return orders, nil
+}
+
+// GetPositions returns the paper positions marked to the latest tickers
+func (p *PaperExchange) GetPositions() ([]types.ExchangePosition, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	positions := make([]types.ExchangePosition, 0, len(p.positions))
+	for instrument, pos := range p.positions {
+		direction := "buy"
+		if pos.amount.IsNegative() {
+			direction = "sell"
+		}
+
+		ticker := p.market[instrument]
+		mark := ticker.MarkPrice
+		if mark.IsZero() {
+			mark = pos.avgPrice
+		}
+
+		positions = append(positions, types.ExchangePosition{
+			InstrumentName: instrument,
+			Amount:         pos.amount.Abs().InexactFloat64(),
+			Direction:      direction,
+			AveragePrice:   pos.avgPrice.InexactFloat64(),
+			MarkPrice:      mark.InexactFloat64(),
+			IndexPrice:     ticker.IndexPrice.InexactFloat64(),
+			PnL:            mark.Sub(pos.avgPrice).Mul(pos.amount).InexactFloat64(),
+		})
+	}
+	return positions, nil
+}
+
+// PnL returns the realized P&L excluding fees and the fees paid so far
+func (p *PaperExchange) PnL() (realized, feesPaid decimal.Decimal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.realizedPnL, p.feesPaid
+}
+
+// GetInstrument returns the feed's instrument metadata when it has any
+func (p *PaperExchange) GetInstrument(instrument string) (*instruments.Instrument, error) {
+	if source, ok := p.feed.(interface {
+		GetInstrument(string) (*instruments.Instrument, error)
+	}); ok {
+		return source.GetInstrument(instrument)
+	}
+	return nil, fmt.Errorf("no instrument metadata for %s", instrument)
+}
+
+// GetOrderBook returns the feed's order book
+func (p *PaperExchange) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	return p.feed.GetOrderBook(instrument)
+}
+
+// SubscribeOrderBook subscribes the feed to an instrument's book when it
+// supports book subscriptions
+func (p *PaperExchange) SubscribeOrderBook(instrument string) error {
+	if subscriber, ok := p.feed.(interface{ SubscribeOrderBook(string) error }); ok {
+		return subscriber.SubscribeOrderBook(instrument)
+	}
+	return nil
+}
+
+// GetOrderBookForRFQ returns the book for an RFQ'd option in CCXT format
+func (p *PaperExchange) GetOrderBookForRFQ(req types.RFQResult, asset string) (types.CCXTOrderBook, error) {
+	instrument, err := p.ConvertToInstrument(asset, req.Strike, req.Expiry, req.IsPut)
+	if err != nil {
+		return types.CCXTOrderBook{}, err
+	}
+
+	book, err := p.GetOrderBook(instrument)
+	if err != nil {
+		return types.CCXTOrderBook{}, fmt.Errorf("failed to get order book: %w", err)
+	}
+	return book.ToCCXT(instrument), nil
+}
+
+// PlaceOrder places a limit order for an RFQ confirmation, on the opposite
+// side to the taker, returning its order ID
+func (p *PaperExchange) PlaceOrder(conf types.RFQConfirmation, instrument string, cfg interface{}) (string, error) {
+	side := "buy"
+	if conf.IsTakerBuy {
+		side = "sell"
+	}
+
+	price, err := decimal.NewFromString(conf.Price)
+	if err != nil {
+		return "", fmt.Errorf("invalid price: %w", err)
+	}
+	quantity, err := decimal.NewFromString(conf.Quantity)
+	if err != nil {
+		return "", fmt.Errorf("invalid quantity: %w", err)
+	}
+
+	return p.PlaceLimitOrder(instrument, side, price, quantity, types.OrderOptions{})
+}
+
+// ConvertToInstrument names an option in the feed exchange's format
+func (p *PaperExchange) ConvertToInstrument(asset string, strike string, expiry int64, isPut bool) (string, error) {
+	inst, err := instruments.FromRysk(asset, strike, expiry, isPut)
+	if err != nil {
+		return "", err
+	}
+	return inst.NameOn(p.config.Exchange), nil
+}
+
+// Close stops the feed subscriptions and closes the feed when it can be closed
+func (p *PaperExchange) Close() error {
+	p.cancel()
+	if closer, ok := p.feed.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+var _ types.UnifiedExchange = (*PaperExchange)(nil)
+var _ types.MarketMakerExchange = (*PaperExchange)(nil)
@@ -0,0 +1,169 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/fees"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// stubFeed never streams; tests drive onTicker directly
+type stubFeed struct {
+	book *types.MarketMakerOrderBook
+}
+
+func (f *stubFeed) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	return make(chan types.TickerUpdate), nil
+}
+
+func (f *stubFeed) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	if f.book == nil {
+		return nil, fmt.Errorf("no book")
+	}
+	return f.book, nil
+}
+
+func d(v float64) decimal.Decimal { return decimal.NewFromFloat(v) }
+
+func TestRestingOrderFills(t *testing.T) {
+	p := NewPaperExchange(&stubFeed{}, Config{Fees: fees.Derive})
+	fills, _ := p.SubscribeFills(context.Background())
+
+	p.onTicker(types.TickerUpdate{Instrument: "ETH-PERP", BestBid: d(2499), BestAsk: d(2501), BestAskSize: d(5)})
+
+	orderID, err := p.PlaceLimitOrder("ETH-PERP", "buy", d(2500), d(2), types.OrderOptions{})
+	if err != nil {
+		t.Fatalf("PlaceLimitOrder failed: %v", err)
+	}
+	if orders, _ := p.GetOpenOrders(); len(orders) != 1 {
+		t.Fatalf("expected order to rest, got %d open", len(orders))
+	}
+
+	// The ask drops through our bid with only 0.5 available
+	p.onTicker(types.TickerUpdate{Instrument: "ETH-PERP", BestBid: d(2498), BestAsk: d(2499.5), BestAskSize: d(0.5)})
+	fill := <-fills
+	if fill.OrderID != orderID || !fill.Amount.Equal(d(0.5)) || !fill.Price.Equal(d(2500)) {
+		t.Errorf("unexpected fill: %+v", fill)
+	}
+	// Maker perp fee: 0.0001 * 0.5 * 2500
+	if !fill.Fee.Equal(d(0.125)) {
+		t.Errorf("fee = %s, want 0.125", fill.Fee)
+	}
+
+	p.onTicker(types.TickerUpdate{Instrument: "ETH-PERP", BestBid: d(2498), BestAsk: d(2499.5), BestAskSize: d(10)})
+	<-fills
+	if orders, _ := p.GetOpenOrders(); len(orders) != 0 {
+		t.Errorf("expected order to be filled, got %d open", len(orders))
+	}
+
+	positions, _ := p.GetPositions()
+	if len(positions) != 1 || positions[0].Amount != 2 || positions[0].Direction != "buy" {
+		t.Errorf("unexpected positions: %+v", positions)
+	}
+}
+
+func TestMarketableOrderTakesBook(t *testing.T) {
+	feed := &stubFeed{book: &types.MarketMakerOrderBook{
+		Asks: []types.OrderBookLevel{{Price: d(10), Size: d(1)}, {Price: d(11), Size: d(1)}, {Price: d(13), Size: d(5)}},
+	}}
+	p := NewPaperExchange(feed, Config{})
+	fills, _ := p.SubscribeFills(context.Background())
+
+	if _, err := p.PlaceLimitOrder("ETH-20250530-3000-C", "buy", d(12), d(3), types.OrderOptions{}); err != nil {
+		t.Fatalf("PlaceLimitOrder failed: %v", err)
+	}
+
+	for _, want := range []float64{10, 11} {
+		if fill := <-fills; !fill.Price.Equal(d(want)) || !fill.Amount.Equal(d(1)) {
+			t.Errorf("fill = %s @ %s, want 1 @ %v", fill.Amount, fill.Price, want)
+		}
+	}
+
+	// The remainder rests at our limit
+	orders, _ := p.GetOpenOrders()
+	if len(orders) != 1 || !orders[0].FilledAmount.Equal(d(2)) {
+		t.Errorf("unexpected open orders: %+v", orders)
+	}
+}
+
+func TestCancelAndPnL(t *testing.T) {
+	p := NewPaperExchange(&stubFeed{}, Config{})
+
+	p.onTicker(types.TickerUpdate{Instrument: "ETH-PERP", BestBid: d(99), BestAsk: d(100)})
+	p.PlaceLimitOrder("ETH-PERP", "buy", d(100), d(2), types.OrderOptions{})
+
+	p.onTicker(types.TickerUpdate{Instrument: "ETH-PERP", BestBid: d(110), BestAsk: d(111)})
+	p.PlaceLimitOrder("ETH-PERP", "sell", d(110), d(3), types.OrderOptions{})
+
+	realized, _ := p.PnL()
+	if !realized.Equal(d(20)) {
+		t.Errorf("realized = %s, want 20", realized)
+	}
+
+	positions, _ := p.GetPositions()
+	if len(positions) != 1 || positions[0].Direction != "sell" || positions[0].AveragePrice != 110 {
+		t.Errorf("expected 1 short at 110 after flipping, got %+v", positions)
+	}
+
+	restingID, _ := p.PlaceLimitOrder("ETH-PERP", "buy", d(90), d(1), types.OrderOptions{})
+	if err := p.CancelOrder(restingID); err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if orders, _ := p.GetOpenOrders(); len(orders) != 0 {
+		t.Errorf("expected no open orders, got %d", len(orders))
+	}
+	if err := p.CancelOrder(restingID); err == nil {
+		t.Error("expected error cancelling a cancelled order")
+	}
+}
+
+func TestOrderOptions(t *testing.T) {
+	feed := &stubFeed{book: &types.MarketMakerOrderBook{
+		Bids: []types.OrderBookLevel{{Price: d(99), Size: d(1)}},
+		Asks: []types.OrderBookLevel{{Price: d(100), Size: d(1)}, {Price: d(101), Size: d(1)}},
+	}}
+	p := NewPaperExchange(feed, Config{})
+	updates, _ := p.SubscribeOrderUpdates(context.Background())
+	openOrders := func() int {
+		orders, _ := p.GetOpenOrders()
+		return len(orders)
+	}
+
+	// Post-only orders that would cross are rejected
+	if _, err := p.PlaceLimitOrder("ETH-PERP", "buy", d(100), d(1), types.OrderOptions{PostOnly: true}); err != nil {
+		t.Fatalf("PlaceLimitOrder failed: %v", err)
+	}
+	if update := <-updates; update.Status != "rejected" || openOrders() != 0 {
+		t.Errorf("post-only order status = %s with %d open, want rejected", update.Status, openOrders())
+	}
+
+	// FOK orders are killed when the book can't fill them in full
+	p.PlaceLimitOrder("ETH-PERP", "buy", d(100), d(2), types.OrderOptions{TimeInForce: types.TimeInForceFOK})
+	if update := <-updates; update.Status != "cancelled" || !update.FilledAmount.IsZero() {
+		t.Errorf("FOK order = %s filled %s, want cancelled unfilled", update.Status, update.FilledAmount)
+	}
+
+	// IOC orders take what crosses and cancel the rest
+	p.PlaceLimitOrder("ETH-PERP", "buy", d(100), d(2), types.OrderOptions{TimeInForce: types.TimeInForceIOC})
+	<-updates // Partial fill
+	if update := <-updates; update.Status != "cancelled" || !update.FilledAmount.Equal(d(1)) || openOrders() != 0 {
+		t.Errorf("IOC order = %s filled %s, want cancelled after filling 1", update.Status, update.FilledAmount)
+	}
+
+	// Reduce-only orders can't flip the long 1 we now hold
+	p.PlaceLimitOrder("ETH-PERP", "sell", d(99), d(3), types.OrderOptions{TimeInForce: types.TimeInForceIOC, ReduceOnly: true})
+	<-updates // Fill up to the position
+	if update := <-updates; update.Status != "cancelled" || !update.FilledAmount.Equal(d(1)) {
+		t.Errorf("reduce-only order = %s filled %s, want cancelled after filling 1", update.Status, update.FilledAmount)
+	}
+	if positions, _ := p.GetPositions(); len(positions) != 0 {
+		t.Errorf("expected flat position, got %+v", positions)
+	}
+
+	if _, err := p.PlaceLimitOrder("ETH-PERP", "buy", d(100), d(1), types.OrderOptions{PostOnly: true, TimeInForce: types.TimeInForceIOC}); err == nil {
+		t.Error("expected error for post-only IOC order")
+	}
+}
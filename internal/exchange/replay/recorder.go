@@ -0,0 +1,249 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Event is one line of a recording: a call with its arguments and result, or
+// an update on a subscription stream
+type Event struct {
+	Time   time.Time       `json:"time"`
+	Method string          `json:"method"`
+	Stream int             `json:"stream,omitempty"` // Subscription the update belongs to
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Stream update methods
+const (
+	methodTicker = "ticker"
+	methodFill   = "fill"
+	methodOrder  = "order"
+)
+
+// orderArgs are the arguments of order placement calls
+type orderArgs struct {
+	OrderID    string             `json:"order_id,omitempty"`
+	Instrument string             `json:"instrument,omitempty"`
+	Side       string             `json:"side,omitempty"`
+	Price      decimal.Decimal    `json:"price"`
+	Amount     decimal.Decimal    `json:"amount"`
+	Options    types.OrderOptions `json:"options"`
+}
+
+// Recorder wraps a MarketMakerExchange and writes every call, result and
+// stream update to a JSON lines recording that a Replayer can serve back
+type Recorder struct {
+	inner types.MarketMakerExchange
+
+	mu         sync.Mutex
+	enc        *json.Encoder
+	closer     io.Closer
+	nextStream int
+}
+
+// NewRecorder records calls on inner to w
+func NewRecorder(inner types.MarketMakerExchange, w io.Writer) *Recorder {
+	r := &Recorder{inner: inner, enc: json.NewEncoder(w)}
+	if closer, ok := w.(io.Closer); ok {
+		r.closer = closer
+	}
+	return r
+}
+
+// NewFileRecorder records calls on inner to a new file at path
+func NewFileRecorder(inner types.MarketMakerExchange, path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+	return NewRecorder(inner, f), nil
+}
+
+// record writes an event
+func (r *Recorder) record(method string, stream int, args, result interface{}, callErr error) {
+	event := Event{Time: time.Now(), Method: method, Stream: stream}
+	if args != nil {
+		event.Args, _ = json.Marshal(args)
+	}
+	if result != nil {
+		event.Result, _ = json.Marshal(result)
+	}
+	if callErr != nil {
+		event.Error = callErr.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(event); err != nil {
+		log.Printf("[Recorder] Failed to record %s: %v", method, err)
+	}
+}
+
+// newStream allocates a stream ID for a subscription
+func (r *Recorder) newStream() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextStream++
+	return r.nextStream
+}
+
+// SubscribeTickers records the subscription and every update on it
+func (r *Recorder) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	stream := r.newStream()
+	in, err := r.inner.SubscribeTickers(ctx, instruments)
+	r.record("SubscribeTickers", stream, instruments, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.TickerUpdate, 100)
+	go func() {
+		defer close(out)
+		for ticker := range in {
+			r.record(methodTicker, stream, nil, ticker, nil)
+			out <- ticker
+		}
+	}()
+	return out, nil
+}
+
+// SubscribeFills records the subscription and every fill on it
+func (r *Recorder) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	stream := r.newStream()
+	in, err := r.inner.SubscribeFills(ctx)
+	r.record("SubscribeFills", stream, nil, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.Fill, 1000)
+	go func() {
+		defer close(out)
+		for fill := range in {
+			r.record(methodFill, stream, nil, fill, nil)
+			out <- fill
+		}
+	}()
+	return out, nil
+}
+
+// SubscribeOrderUpdates records the subscription and every update on it
+func (r *Recorder) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	stream := r.newStream()
+	in, err := r.inner.SubscribeOrderUpdates(ctx)
+	r.record("SubscribeOrderUpdates", stream, nil, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.MarketMakerOrder, 1000)
+	go func() {
+		defer close(out)
+		for order := range in {
+			r.record(methodOrder, stream, nil, order, nil)
+			out <- order
+		}
+	}()
+	return out, nil
+}
+
+// PlaceLimitOrder records an order placement
+func (r *Recorder) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	orderID, err := r.inner.PlaceLimitOrder(instrument, side, price, amount, opts)
+	r.record("PlaceLimitOrder", 0, orderArgs{Instrument: instrument, Side: side, Price: price, Amount: amount, Options: opts}, orderID, err)
+	return orderID, err
+}
+
+// ReplaceOrder records an order replacement
+func (r *Recorder) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	newID, err := r.inner.ReplaceOrder(orderID, instrument, side, price, amount, opts)
+	r.record("ReplaceOrder", 0, orderArgs{OrderID: orderID, Instrument: instrument, Side: side, Price: price, Amount: amount, Options: opts}, newID, err)
+	return newID, err
+}
+
+// CancelOrder records a cancel
+func (r *Recorder) CancelOrder(orderID string) error {
+	err := r.inner.CancelOrder(orderID)
+	r.record("CancelOrder", 0, orderID, nil, err)
+	return err
+}
+
+// GetOpenOrders records the open orders returned
+func (r *Recorder) GetOpenOrders() ([]types.MarketMakerOrder, error) {
+	orders, err := r.inner.GetOpenOrders()
+	r.record("GetOpenOrders", 0, nil, orders, err)
+	return orders, err
+}
+
+// GetPositions records the positions returned
+func (r *Recorder) GetPositions() ([]types.ExchangePosition, error) {
+	positions, err := r.inner.GetPositions()
+	r.record("GetPositions", 0, nil, positions, err)
+	return positions, err
+}
+
+// GetOrderBook records the book returned
+func (r *Recorder) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	book, err := r.inner.GetOrderBook(instrument)
+	r.record("GetOrderBook", 0, instrument, book, err)
+	return book, err
+}
+
+// GetInstrument records instrument metadata when the wrapped exchange
+// provides it
+func (r *Recorder) GetInstrument(instrument string) (*instruments.Instrument, error) {
+	source, ok := r.inner.(interface {
+		GetInstrument(string) (*instruments.Instrument, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("no instrument metadata for %s", instrument)
+	}
+	spec, err := source.GetInstrument(instrument)
+	r.record("GetInstrument", 0, instrument, spec, err)
+	return spec, err
+}
+
+// SubscribeOrderBook records a book subscription when the wrapped exchange
+// supports them
+func (r *Recorder) SubscribeOrderBook(instrument string) error {
+	subscriber, ok := r.inner.(interface{ SubscribeOrderBook(string) error })
+	if !ok {
+		return nil
+	}
+	err := subscriber.SubscribeOrderBook(instrument)
+	r.record("SubscribeOrderBook", 0, instrument, nil, err)
+	return err
+}
+
+// Close closes the wrapped exchange if it can be closed, then the recording
+func (r *Recorder) Close() error {
+	var err error
+	if closer, ok := r.inner.(interface{ Close() error }); ok {
+		err = closer.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		if closeErr := r.closer.Close(); err == nil {
+			err = closeErr
+		}
+		r.closer = nil
+	}
+	return err
+}
+
+var _ types.MarketMakerExchange = (*Recorder)(nil)
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// stubExchange returns canned data
+type stubExchange struct {
+	nextID int
+}
+
+func (s *stubExchange) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	ch := make(chan types.TickerUpdate, len(instruments))
+	for i, instrument := range instruments {
+		ch <- types.TickerUpdate{Instrument: instrument, BestBid: decimal.NewFromInt(int64(100 + i))}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (s *stubExchange) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	ch := make(chan types.Fill)
+	close(ch)
+	return ch, nil
+}
+
+func (s *stubExchange) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	return nil, fmt.Errorf("not authenticated")
+}
+
+func (s *stubExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	s.nextID++
+	return fmt.Sprintf("order-%d", s.nextID), nil
+}
+
+func (s *stubExchange) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	return s.PlaceLimitOrder(instrument, side, price, amount, opts)
+}
+
+func (s *stubExchange) CancelOrder(orderID string) error {
+	return fmt.Errorf("order %s not found", orderID)
+}
+
+func (s *stubExchange) GetOpenOrders() ([]types.MarketMakerOrder, error) {
+	return nil, nil
+}
+
+func (s *stubExchange) GetPositions() ([]types.ExchangePosition, error) {
+	return []types.ExchangePosition{{InstrumentName: "ETH-PERP", Amount: 1.5, Direction: "sell"}}, nil
+}
+
+func (s *stubExchange) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	return &types.MarketMakerOrderBook{Bids: []types.OrderBookLevel{{Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(2)}}}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewRecorder(&stubExchange{}, &buf)
+	ctx := context.Background()
+
+	tickers, _ := recorder.SubscribeTickers(ctx, []string{"ETH-PERP", "BTC-PERP"})
+	for range tickers {
+	}
+	recorder.PlaceLimitOrder("ETH-PERP", "buy", decimal.NewFromInt(99), decimal.NewFromInt(1), types.OrderOptions{})
+	recorder.PlaceLimitOrder("ETH-PERP", "sell", decimal.NewFromInt(101), decimal.NewFromInt(1), types.OrderOptions{})
+	recorder.CancelOrder("order-1")
+	recorder.GetPositions()
+	recorder.GetOrderBook("ETH-PERP")
+	recorder.SubscribeOrderUpdates(ctx)
+
+	replayer, err := NewReplayer(&buf)
+	if err != nil {
+		t.Fatalf("NewReplayer failed: %v", err)
+	}
+
+	replayed, err := replayer.SubscribeTickers(ctx, []string{"ETH-PERP", "BTC-PERP"})
+	if err != nil {
+		t.Fatalf("SubscribeTickers failed: %v", err)
+	}
+	var got []string
+	for ticker := range replayed {
+		got = append(got, ticker.Instrument+"@"+ticker.BestBid.String())
+	}
+	if fmt.Sprint(got) != "[ETH-PERP@100 BTC-PERP@101]" {
+		t.Errorf("replayed tickers = %v", got)
+	}
+
+	// Calls are matched by instrument and side, so order across sides doesn't matter
+	if id, _ := replayer.PlaceLimitOrder("ETH-PERP", "sell", decimal.NewFromInt(101), decimal.NewFromInt(1), types.OrderOptions{}); id != "order-2" {
+		t.Errorf("sell order ID = %s, want order-2", id)
+	}
+	if id, _ := replayer.PlaceLimitOrder("ETH-PERP", "buy", decimal.NewFromInt(98), decimal.NewFromInt(1), types.OrderOptions{}); id != "order-1" {
+		t.Errorf("buy order ID = %s, want order-1", id)
+	}
+
+	if err := replayer.CancelOrder("order-1"); err == nil || err.Error() != "order order-1 not found" {
+		t.Errorf("cancel error = %v, want recorded error", err)
+	}
+
+	// Reads repeat their last result
+	for i := 0; i < 2; i++ {
+		positions, err := replayer.GetPositions()
+		if err != nil || len(positions) != 1 || positions[0].Amount != 1.5 {
+			t.Errorf("positions = %+v, %v", positions, err)
+		}
+	}
+	if book, err := replayer.GetOrderBook("ETH-PERP"); err != nil || !book.Bids[0].Price.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("book = %+v, %v", book, err)
+	}
+
+	if _, err := replayer.SubscribeOrderUpdates(ctx); err == nil {
+		t.Error("expected recorded subscription error")
+	}
+
+	// The buy was placed at a different price, and nothing was recorded for this
+	if _, err := replayer.GetOrderBook("BTC-PERP"); err == nil {
+		t.Error("expected error for unrecorded call")
+	}
+	if mismatches := replayer.Mismatches(); len(mismatches) != 2 {
+		t.Errorf("mismatches = %v, want 2", mismatches)
+	}
+}
@@ -0,0 +1,334 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Replayer serves a recording back as a MarketMakerExchange. Each call
+// returns the next recorded result for the same method and key arguments
+// (instrument and side, or order ID), so concurrent callers get consistent
+// answers. Subscriptions replay the stream recorded for the subscription made
+// in the same order, and close once it is exhausted.
+type Replayer struct {
+	mu         sync.Mutex
+	calls      map[string][]Event // by callKey
+	streams    map[int][]Event    // updates by stream
+	subs       map[string][]Event // subscribe calls by method, in order
+	mismatches []string
+	speed      float64
+}
+
+// NewReplayer reads a recording
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	p := &Replayer{
+		calls:   make(map[string][]Event),
+		streams: make(map[int][]Event),
+		subs:    make(map[string][]Event),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse recording line %d: %w", line, err)
+		}
+
+		switch event.Method {
+		case methodTicker, methodFill, methodOrder:
+			p.streams[event.Stream] = append(p.streams[event.Stream], event)
+		case "SubscribeTickers", "SubscribeFills", "SubscribeOrderUpdates":
+			p.subs[event.Method] = append(p.subs[event.Method], event)
+		default:
+			key, err := eventKey(event)
+			if err != nil {
+				return nil, fmt.Errorf("bad arguments on recording line %d: %w", line, err)
+			}
+			p.calls[key] = append(p.calls[key], event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	return p, nil
+}
+
+// LoadFile reads a recording from path
+func LoadFile(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+	return NewReplayer(f)
+}
+
+// SetSpeed paces stream updates at speed times their recorded rate. The
+// default, 0, replays them as fast as they are consumed.
+func (p *Replayer) SetSpeed(speed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.speed = speed
+}
+
+// Mismatches lists calls whose arguments differed from the recording, or that
+// had nothing recorded. An empty list means the run matched the recording.
+func (p *Replayer) Mismatches() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.mismatches...)
+}
+
+// eventKey returns the key a recorded call is served under
+func eventKey(event Event) (string, error) {
+	switch event.Method {
+	case "PlaceLimitOrder", "ReplaceOrder":
+		var args orderArgs
+		if err := json.Unmarshal(event.Args, &args); err != nil {
+			return "", err
+		}
+		return callKey(event.Method, args.OrderID, args.Instrument, args.Side), nil
+	case "CancelOrder", "GetOrderBook", "GetInstrument", "SubscribeOrderBook":
+		var arg string
+		if err := json.Unmarshal(event.Args, &arg); err != nil {
+			return "", err
+		}
+		return callKey(event.Method, arg), nil
+	default:
+		return callKey(event.Method), nil
+	}
+}
+
+// callKey joins a method and its key arguments
+func callKey(method string, args ...string) string {
+	key := method
+	for _, arg := range args {
+		key += "|" + arg
+	}
+	return key
+}
+
+// next pops the next recorded call for key, checking its full arguments.
+// Reads keep returning their last recorded result once exhausted, since
+// strategies poll them more or less often from run to run.
+func (p *Replayer) next(key string, args interface{}, result interface{}, isRead bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := p.calls[key]
+	if len(queue) == 0 {
+		p.mismatches = append(p.mismatches, fmt.Sprintf("%s: no recorded call", key))
+		return fmt.Errorf("replay: no recorded call for %s", key)
+	}
+	event := queue[0]
+	if !isRead || len(queue) > 1 {
+		p.calls[key] = queue[1:]
+	}
+
+	if args != nil {
+		if encoded, _ := json.Marshal(args); string(encoded) != string(event.Args) {
+			p.mismatches = append(p.mismatches, fmt.Sprintf("%s: called with %s, recorded %s", key, encoded, event.Args))
+		}
+	}
+
+	if result != nil && len(event.Result) > 0 {
+		if err := json.Unmarshal(event.Result, result); err != nil {
+			return fmt.Errorf("replay: bad recorded result for %s: %w", key, err)
+		}
+	}
+	if event.Error != "" {
+		return errors.New(event.Error)
+	}
+	return nil
+}
+
+// nextStream pops the next recorded subscription for method
+func (p *Replayer) nextStream(method string) ([]Event, float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subs := p.subs[method]
+	if len(subs) == 0 {
+		p.mismatches = append(p.mismatches, fmt.Sprintf("%s: no recorded subscription", method))
+		return nil, 0, fmt.Errorf("replay: no recorded subscription for %s", method)
+	}
+	p.subs[method] = subs[1:]
+	if subs[0].Error != "" {
+		return nil, 0, errors.New(subs[0].Error)
+	}
+	return p.streams[subs[0].Stream], p.speed, nil
+}
+
+// play decodes a stream's updates and hands them to send in recorded order
+func play(ctx context.Context, events []Event, speed float64, send func(json.RawMessage) bool) {
+	var last time.Time
+	for _, event := range events {
+		if speed > 0 && !last.IsZero() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(float64(event.Time.Sub(last)) / speed)):
+			}
+		}
+		last = event.Time
+
+		if !send(event.Result) {
+			return
+		}
+	}
+}
+
+// SubscribeTickers replays the next recorded ticker stream
+func (p *Replayer) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	events, speed, err := p.nextStream("SubscribeTickers")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.TickerUpdate)
+	go func() {
+		defer close(out)
+		play(ctx, events, speed, func(data json.RawMessage) bool {
+			var ticker types.TickerUpdate
+			if err := json.Unmarshal(data, &ticker); err != nil {
+				return true
+			}
+			select {
+			case out <- ticker:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return out, nil
+}
+
+// SubscribeFills replays the next recorded fill stream
+func (p *Replayer) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	events, speed, err := p.nextStream("SubscribeFills")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.Fill)
+	go func() {
+		defer close(out)
+		play(ctx, events, speed, func(data json.RawMessage) bool {
+			var fill types.Fill
+			if err := json.Unmarshal(data, &fill); err != nil {
+				return true
+			}
+			select {
+			case out <- fill:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return out, nil
+}
+
+// SubscribeOrderUpdates replays the next recorded order update stream
+func (p *Replayer) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	events, speed, err := p.nextStream("SubscribeOrderUpdates")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.MarketMakerOrder)
+	go func() {
+		defer close(out)
+		play(ctx, events, speed, func(data json.RawMessage) bool {
+			var order types.MarketMakerOrder
+			if err := json.Unmarshal(data, &order); err != nil {
+				return true
+			}
+			select {
+			case out <- order:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return out, nil
+}
+
+// PlaceLimitOrder returns the recorded order ID
+func (p *Replayer) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	var orderID string
+	err := p.next(callKey("PlaceLimitOrder", "", instrument, side),
+		orderArgs{Instrument: instrument, Side: side, Price: price, Amount: amount, Options: opts}, &orderID, false)
+	return orderID, err
+}
+
+// ReplaceOrder returns the recorded replacement order ID
+func (p *Replayer) ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	var newID string
+	err := p.next(callKey("ReplaceOrder", orderID, instrument, side),
+		orderArgs{OrderID: orderID, Instrument: instrument, Side: side, Price: price, Amount: amount, Options: opts}, &newID, false)
+	return newID, err
+}
+
+// CancelOrder returns the recorded cancel result
+func (p *Replayer) CancelOrder(orderID string) error {
+	return p.next(callKey("CancelOrder", orderID), nil, nil, false)
+}
+
+// GetOpenOrders returns the recorded open orders
+func (p *Replayer) GetOpenOrders() ([]types.MarketMakerOrder, error) {
+	var orders []types.MarketMakerOrder
+	err := p.next(callKey("GetOpenOrders"), nil, &orders, true)
+	return orders, err
+}
+
+// GetPositions returns the recorded positions
+func (p *Replayer) GetPositions() ([]types.ExchangePosition, error) {
+	var positions []types.ExchangePosition
+	err := p.next(callKey("GetPositions"), nil, &positions, true)
+	return positions, err
+}
+
+// GetOrderBook returns the recorded book
+func (p *Replayer) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	var book *types.MarketMakerOrderBook
+	if err := p.next(callKey("GetOrderBook", instrument), nil, &book, true); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// GetInstrument returns the recorded instrument metadata
+func (p *Replayer) GetInstrument(instrument string) (*instruments.Instrument, error) {
+	var spec *instruments.Instrument
+	if err := p.next(callKey("GetInstrument", instrument), nil, &spec, true); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// SubscribeOrderBook returns the recorded subscription result
+func (p *Replayer) SubscribeOrderBook(instrument string) error {
+	return p.next(callKey("SubscribeOrderBook", instrument), nil, nil, false)
+}
+
+var _ types.MarketMakerExchange = (*Replayer)(nil)
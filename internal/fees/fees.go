@@ -0,0 +1,88 @@
+package fees
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Rates describes the fees for one instrument class on an exchange. Rates are
+// fractions of index notional (0.0003 = 0.03%).
+type Rates struct {
+	Maker    float64
+	Taker    float64
+	BaseFee  float64 // Flat fee per taker trade in quote currency
+	PriceCap float64 // Cap as a fraction of the option price, 0 for no cap
+}
+
+// Schedule holds an exchange's option and perp fee rates
+type Schedule struct {
+	Exchange string
+	Option   Rates
+	Perp     Rates
+}
+
+// Derive fees: https://docs.derive.xyz/reference/fees-1
+var Derive = Schedule{
+	Exchange: "derive",
+	Option:   Rates{Maker: 0.0001, Taker: 0.0003, BaseFee: 0.5, PriceCap: 0.125},
+	Perp:     Rates{Maker: 0.0001, Taker: 0.0003, BaseFee: 0.1},
+}
+
+// Deribit fees for options and perpetuals
+var Deribit = Schedule{
+	Exchange: "deribit",
+	Option:   Rates{Maker: 0.0003, Taker: 0.0003, PriceCap: 0.125},
+	Perp:     Rates{Maker: 0, Taker: 0.0005},
+}
+
+// Conservative charges the highest of the known venues' rates, for pricing
+// fees on a venue without its own schedule
+var Conservative = Schedule{
+	Exchange: "conservative",
+	Option:   Rates{Maker: 0.0003, Taker: 0.0003, BaseFee: 0.5, PriceCap: 0.125},
+	Perp:     Rates{Maker: 0.0001, Taker: 0.0005, BaseFee: 0.1},
+}
+
+// ForExchange returns the fee schedule for an exchange
+func ForExchange(exchange string) (Schedule, error) {
+	switch strings.ToLower(exchange) {
+	case "derive":
+		return Derive, nil
+	case "deribit":
+		return Deribit, nil
+	default:
+		return Schedule{}, fmt.Errorf("no fee schedule for exchange %s", exchange)
+	}
+}
+
+// OptionFee returns the fee for trading amount option contracts at price
+// with the underlying index at index
+func (s Schedule) OptionFee(isTaker bool, amount, index, price float64) float64 {
+	return s.Option.fee(isTaker, amount, index, price)
+}
+
+// PerpFee returns the fee for trading amount perp contracts
+func (s Schedule) PerpFee(isTaker bool, amount, index float64) float64 {
+	return s.Perp.fee(isTaker, amount, index, 0)
+}
+
+func (r Rates) fee(isTaker bool, amount, index, price float64) float64 {
+	amount = math.Abs(amount)
+
+	rate := r.Maker
+	if isTaker {
+		rate = r.Taker
+	}
+
+	fee := rate * amount * index
+	if r.PriceCap > 0 && price > 0 {
+		fee = math.Min(fee, r.PriceCap*price*amount)
+	}
+
+	if isTaker && amount > 0 {
+		fee += r.BaseFee
+	}
+
+	return fee
+}
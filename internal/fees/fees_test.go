@@ -0,0 +1,54 @@
+package fees
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeriveFees(t *testing.T) {
+	tests := []struct {
+		name string
+		fee  float64
+		want float64
+	}{
+		// Examples from the Derive fee docs
+		{"Taker buys 2 ETH puts", Derive.OptionFee(true, 2, 2200, 100), 1.82},
+		{"Maker sells BTC perp", Derive.PerpFee(false, 0.1, 43000), 0.43},
+		{"Taker buys BTC perp", Derive.PerpFee(true, 0.1, 43000), 1.39},
+		// 0.03% of $3000 is $0.90 but 12.5% of a $2 option is $0.25
+		{"Cheap option capped", Derive.OptionFee(true, 1, 3000, 2), 0.75},
+		{"No trade no fee", Derive.OptionFee(true, 0, 3000, 100), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.fee-tt.want) > 1e-9 {
+				t.Errorf("fee = %.4f, want %.4f", tt.fee, tt.want)
+			}
+		})
+	}
+}
+
+func TestForExchange(t *testing.T) {
+	for _, name := range []string{"derive", "Deribit"} {
+		if _, err := ForExchange(name); err != nil {
+			t.Errorf("ForExchange(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := ForExchange("unknown"); err == nil {
+		t.Error("expected error for unknown exchange")
+	}
+}
+
+func TestConservativeCoversKnownVenues(t *testing.T) {
+	for _, s := range []Schedule{Derive, Deribit} {
+		for _, isTaker := range []bool{true, false} {
+			if got, want := Conservative.OptionFee(isTaker, 2, 3000, 100), s.OptionFee(isTaker, 2, 3000, 100); got < want {
+				t.Errorf("%s option fee (taker %v) = %.4f, conservative %.4f", s.Exchange, isTaker, want, got)
+			}
+			if got, want := Conservative.PerpFee(isTaker, 2, 3000), s.PerpFee(isTaker, 2, 3000); got < want {
+				t.Errorf("%s perp fee (taker %v) = %.4f, conservative %.4f", s.Exchange, isTaker, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package hedging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// DefaultFillTimeout is how long a hedge order rests before it is repriced
+const DefaultFillTimeout = 10 * time.Second
+
+// DefaultMaxReprices is how many times an unfilled hedge is repriced before
+// it is escalated
+const DefaultMaxReprices = 2
+
+// cancelGrace is how long to wait for a cancelled order's final state
+const cancelGrace = 2 * time.Second
+
+// orderStateTTL is how long reports on orders nobody is waiting for are kept,
+// for fills that arrive before PlaceOrder returns
+const orderStateTTL = time.Minute
+
+// orderVenue is a venue that streams the fills and state of our orders, such
+// as exchanges built on MarketMakerExchange
+type orderVenue interface {
+	SubscribeFills(ctx context.Context) (<-chan types.Fill, error)
+	SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error)
+	CancelOrder(orderID string) error
+}
+
+// orderHooks are called as a hedge order is placed and fills
+type orderHooks struct {
+	placed func(orderID string)
+	filled func(quantity, price decimal.Decimal) // The order's fill so far
+}
+
+// orderState is what a venue has reported about one of our orders
+type orderState struct {
+	filled   decimal.Decimal // Sum of fills
+	notional decimal.Decimal // Sum of fill prices times amounts
+	reported decimal.Decimal // Filled amount in the latest order update
+	status   string
+	updated  time.Time
+	watched  bool
+	changed  chan struct{}
+}
+
+// done reports whether the order will fill no further
+func (s *orderState) done(quantity decimal.Decimal) bool {
+	if s.filled.GreaterThanOrEqual(quantity) {
+		return true
+	}
+	// Wait for the fills an order update has reported
+	closed := s.status != "" && s.status != "open" && s.status != "untriggered"
+	return closed && s.filled.GreaterThanOrEqual(s.reported)
+}
+
+// fill returns the quantity filled and its average price. Quantity reported
+// filled without its fills is priced at the limit.
+func (s *orderState) fill(limit decimal.Decimal) (quantity, price decimal.Decimal) {
+	quantity, notional := s.filled, s.notional
+	if gap := s.reported.Sub(s.filled); gap.IsPositive() {
+		quantity = s.reported
+		notional = notional.Add(gap.Mul(limit))
+	}
+	if !quantity.IsPositive() {
+		return decimal.Zero, decimal.Zero
+	}
+	return quantity, notional.Div(quantity)
+}
+
+// orderStream follows our orders on one venue from its fill and order update
+// streams
+type orderStream struct {
+	venue venue
+	mu    sync.Mutex
+	state map[string]*orderState
+}
+
+// newOrderStream subscribes to a venue's fills and order updates until ctx
+// is cancelled
+func newOrderStream(ctx context.Context, v venue, source orderVenue) (*orderStream, error) {
+	fills, err := source.SubscribeFills(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := source.SubscribeOrderUpdates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &orderStream{venue: v, state: make(map[string]*orderState)}
+	go func() {
+		for fills != nil || updates != nil {
+			select {
+			case fill, ok := <-fills:
+				if !ok {
+					fills = nil
+					continue
+				}
+				s.apply(fill.OrderID, func(state *orderState) {
+					state.filled = state.filled.Add(fill.Amount)
+					state.notional = state.notional.Add(fill.Price.Mul(fill.Amount))
+				})
+			case update, ok := <-updates:
+				if !ok {
+					updates = nil
+					continue
+				}
+				s.apply(update.OrderID, func(state *orderState) {
+					state.status = update.Status
+					if update.FilledAmount.GreaterThan(state.reported) {
+						state.reported = update.FilledAmount
+					}
+				})
+			}
+		}
+	}()
+	return s, nil
+}
+
+// apply updates an order's state and wakes whoever waits on it
+func (s *orderStream) apply(orderID string, update func(*orderState)) {
+	if orderID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, state := range s.state {
+		if !state.watched && now.Sub(state.updated) > orderStateTTL {
+			delete(s.state, id)
+		}
+	}
+
+	state := s.get(orderID)
+	update(state)
+	state.updated = now
+	select {
+	case state.changed <- struct{}{}:
+	default:
+	}
+}
+
+// get returns an order's state, creating it if needed. Callers hold s.mu.
+func (s *orderStream) get(orderID string) *orderState {
+	state, ok := s.state[orderID]
+	if !ok {
+		state = &orderState{updated: time.Now(), changed: make(chan struct{}, 1)}
+		s.state[orderID] = state
+	}
+	return state
+}
+
+// watch keeps an order's state until forget, returning the channel signalled
+// on its changes
+func (s *orderStream) watch(orderID string) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.get(orderID)
+	state.watched = true
+	return state.changed
+}
+
+// forget stops keeping an order's state
+func (s *orderStream) forget(orderID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state, orderID)
+}
+
+// snapshot returns a copy of an order's state
+func (s *orderStream) snapshot(orderID string) *orderState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := *s.get(orderID)
+	return &state
+}
+
+// orderStream returns the stream following orders on a venue, subscribing on
+// first use. Venues without order streams return nil, and their orders are
+// taken as filled once placed.
+func (m *Manager) orderStream(v venue) *orderStream {
+	source, ok := v.exchange.(orderVenue)
+	if !ok {
+		return nil
+	}
+
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+
+	if stream, ok := m.streams[v.name]; ok {
+		return stream
+	}
+	stream, err := newOrderStream(m.ctx, v, source)
+	if err != nil {
+		log.Printf("[HedgeManager] Can't follow orders on %s, assuming hedges fill: %v", v.name, err)
+		return nil
+	}
+	m.streams[v.name] = stream
+	return stream
+}
+
+// awaitFill waits for a placed hedge order to fill. Orders still resting after
+// the fill timeout are cancelled, and whatever filled by then is returned once
+// the order is finished: the venue reported it filled or cancelled, or
+// accepted the cancel. An order that can't be shown finished may still fill,
+// so it is returned with an error rather than left to be repriced.
+func (m *Manager) awaitFill(ctx context.Context, stream *orderStream, orderID string, quantity, limit decimal.Decimal, progress func(filled, price decimal.Decimal)) (filled, price decimal.Decimal, err error) {
+	changed := stream.watch(orderID)
+	defer stream.forget(orderID)
+
+	timeout := time.NewTimer(m.fillTimeout)
+	defer timeout.Stop()
+
+	var (
+		cancelled bool
+		cancelErr error
+		reported  = decimal.Zero
+	)
+	for {
+		state := stream.snapshot(orderID)
+		filled, price = state.fill(limit)
+		if state.done(quantity) {
+			return filled, price, nil
+		}
+		if filled.GreaterThan(reported) {
+			progress(filled, price)
+			reported = filled
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if err := m.cancelHedge(stream, orderID); err != nil {
+				log.Printf("[HedgeManager] Order %s on %s may still be resting", orderID, stream.venue.name)
+			}
+			filled, price = stream.snapshot(orderID).fill(limit)
+			return filled, price, ctx.Err()
+		case <-timeout.C:
+			filled, price = stream.snapshot(orderID).fill(limit)
+			if cancelled {
+				if cancelErr != nil {
+					return filled, price, fmt.Errorf("order %s on %s neither cancelled nor finished: %w", orderID, stream.venue.name, cancelErr)
+				}
+				// The venue accepted the cancel, so nothing more can fill
+				log.Printf("[HedgeManager] No final state for cancelled order %s on %s, taking the fills seen", orderID, stream.venue.name)
+				return filled, price, nil
+			}
+			log.Printf("[HedgeManager] Order %s on %s unfilled after %v, cancelling", orderID, stream.venue.name, m.fillTimeout)
+			cancelErr = m.cancelHedge(stream, orderID)
+			cancelled = true
+			timeout.Reset(cancelGrace)
+		}
+	}
+}
+
+// cancelHedge cancels a resting hedge order. A failed cancel leaves the order
+// unresolved until the venue reports its final state.
+func (m *Manager) cancelHedge(stream *orderStream, orderID string) error {
+	source := stream.venue.exchange.(orderVenue)
+	if err := source.CancelOrder(orderID); err != nil {
+		log.Printf("[HedgeManager] Failed to cancel order %s on %s: %v", orderID, stream.venue.name, err)
+		return err
+	}
+	return nil
+}
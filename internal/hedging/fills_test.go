@@ -0,0 +1,176 @@
+package hedging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// streamVenue is a bookVenue that streams our orders. Each order fills the
+// amount scripted for it and rests until cancelled.
+type streamVenue struct {
+	*bookVenue
+	fillsAt   []decimal.Decimal // Fill price of each order
+	fillSizes []decimal.Decimal // Amount each order fills
+	nextBook  []types.CCXTOrderBook
+	fills     chan types.Fill
+	updates   chan types.MarketMakerOrder
+	filled    map[string]decimal.Decimal
+	cancelled []string
+	cancelErr error // Fails cancels, with no order update
+}
+
+func newStreamVenue(book types.CCXTOrderBook) *streamVenue {
+	return &streamVenue{
+		bookVenue: &bookVenue{book: book},
+		fills:     make(chan types.Fill, 10),
+		updates:   make(chan types.MarketMakerOrder, 10),
+		filled:    make(map[string]decimal.Decimal),
+	}
+}
+
+func (v *streamVenue) SubscribeFills(ctx context.Context) (<-chan types.Fill, error) {
+	return v.fills, nil
+}
+
+func (v *streamVenue) SubscribeOrderUpdates(ctx context.Context) (<-chan types.MarketMakerOrder, error) {
+	return v.updates, nil
+}
+
+func (v *streamVenue) PlaceOrder(conf types.RFQConfirmation, instrument string, cfg interface{}) (string, error) {
+	orderID, _ := v.bookVenue.PlaceOrder(conf, instrument, cfg)
+	n := len(v.placed) - 1
+	if len(v.nextBook) > 0 {
+		v.book, v.nextBook = v.nextBook[0], v.nextBook[1:]
+	}
+	if n < len(v.fillSizes) && v.fillSizes[n].IsPositive() {
+		v.filled[orderID] = v.fillSizes[n]
+		v.fills <- types.Fill{OrderID: orderID, Price: v.fillsAt[n], Amount: v.fillSizes[n]}
+		status := "open"
+		if v.fillSizes[n].Equal(decimal.RequireFromString(conf.Quantity)) {
+			status = "filled"
+		}
+		v.updates <- types.MarketMakerOrder{OrderID: orderID, FilledAmount: v.fillSizes[n], Status: status}
+	}
+	return orderID, nil
+}
+
+func (v *streamVenue) CancelOrder(orderID string) error {
+	if v.cancelErr != nil {
+		return v.cancelErr
+	}
+	v.cancelled = append(v.cancelled, orderID)
+	v.updates <- types.MarketMakerOrder{OrderID: orderID, FilledAmount: v.filled[orderID], Status: "cancelled"}
+	return nil
+}
+
+func TestRepricesPartlyFilledHedge(t *testing.T) {
+	// The first order fills 1 of 2 at 100 and is cancelled; the rest is
+	// repriced from the book, now 105, and fills at 104
+	derive := newStreamVenue(types.CCXTOrderBook{Asks: [][]float64{{100, 10}}})
+	derive.nextBook = []types.CCXTOrderBook{{Asks: [][]float64{{105, 10}}}}
+	derive.fillsAt = []decimal.Decimal{decimal.NewFromInt(100), decimal.NewFromInt(104)}
+	derive.fillSizes = []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(1)}
+
+	manager := NewManager(derive, &config.Config{ExchangeName: "derive"})
+	defer manager.Stop()
+	manager.SetFillPolicy(20*time.Millisecond, 1)
+	positions := positionLog{}
+	manager.SetPositionRecorder(positions)
+
+	trade := &types.TradeEvent{
+		ID:         "trade-1",
+		Instrument: "ETH-20250530-3000-C",
+		Quantity:   decimal.NewFromInt(2),
+		IsTakerBuy: true,
+		Timestamp:  time.Now(),
+	}
+	if err := manager.ExecuteHedge(context.Background(), trade); err != nil {
+		t.Fatalf("ExecuteHedge: %v", err)
+	}
+
+	want := []string{"buy 2 ETH-20250530-3000-C @ 100", "buy 1 ETH-20250530-3000-C @ 105"}
+	if len(derive.placed) != 2 || derive.placed[0] != want[0] || derive.placed[1] != want[1] {
+		t.Errorf("orders = %v, want %v", derive.placed, want)
+	}
+	if len(derive.cancelled) != 1 || derive.cancelled[0] != "order-1" {
+		t.Errorf("cancelled = %v, want order-1", derive.cancelled)
+	}
+	if trade.HedgeOrderID != "order-1,order-2" || trade.HedgeExchange != "derive" {
+		t.Errorf("hedge order = %s on %s", trade.HedgeOrderID, trade.HedgeExchange)
+	}
+	if !trade.HedgeQuantity.Equal(decimal.NewFromInt(2)) || !trade.HedgePrice.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("hedge fill = %s at %s, want 2 at 102", trade.HedgeQuantity, trade.HedgePrice)
+	}
+	if got := positions["derive ETH-20250530-3000-C"]; !got.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("derive position = %s, want 2", got)
+	}
+}
+
+func TestEscalatesUnfilledHedge(t *testing.T) {
+	derive := newStreamVenue(types.CCXTOrderBook{Bids: [][]float64{{100, 10}}})
+	manager := NewManager(derive, &config.Config{ExchangeName: "derive"})
+	defer manager.Stop()
+	manager.SetFillPolicy(20*time.Millisecond, 1)
+	positions := positionLog{}
+	manager.SetPositionRecorder(positions)
+
+	trade := &types.TradeEvent{
+		ID:         "trade-1",
+		Instrument: "ETH-20250530-3000-C",
+		Quantity:   decimal.NewFromInt(2),
+		Timestamp:  time.Now(),
+	}
+	if err := manager.ExecuteHedge(context.Background(), trade); err == nil {
+		t.Fatal("unfilled hedge succeeded")
+	}
+	if len(derive.placed) != 2 || len(derive.cancelled) != 2 {
+		t.Errorf("orders = %v, cancelled = %v, want two of each", derive.placed, derive.cancelled)
+	}
+	if trade.HedgeOrderID != "order-1,order-2" || !trade.HedgeQuantity.IsZero() {
+		t.Errorf("hedge = %s filled %s, want nothing filled", trade.HedgeOrderID, trade.HedgeQuantity)
+	}
+	if len(positions) != 0 {
+		t.Errorf("positions = %v, want none", positions)
+	}
+}
+
+func TestDoesNotRepriceUnresolvedHedge(t *testing.T) {
+	// The first order fills 1 of 2, then can't be cancelled and never
+	// reports a final state, so it may still fill the rest
+	derive := newStreamVenue(types.CCXTOrderBook{Asks: [][]float64{{100, 10}}})
+	derive.fillsAt = []decimal.Decimal{decimal.NewFromInt(100)}
+	derive.fillSizes = []decimal.Decimal{decimal.NewFromInt(1)}
+	derive.cancelErr = errors.New("timed out")
+
+	manager := NewManager(derive, &config.Config{ExchangeName: "derive"})
+	defer manager.Stop()
+	manager.SetFillPolicy(20*time.Millisecond, 2)
+	positions := positionLog{}
+	manager.SetPositionRecorder(positions)
+
+	trade := &types.TradeEvent{
+		ID:         "trade-1",
+		Instrument: "ETH-20250530-3000-C",
+		Quantity:   decimal.NewFromInt(2),
+		IsTakerBuy: true,
+		Timestamp:  time.Now(),
+	}
+	if err := manager.ExecuteHedge(context.Background(), trade); err == nil {
+		t.Fatal("hedge with an unresolved order succeeded")
+	}
+	if len(derive.placed) != 1 {
+		t.Errorf("orders = %v, want no reprice", derive.placed)
+	}
+	if trade.HedgeOrderID != "order-1" || !trade.HedgeQuantity.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("hedge = %s filled %s, want order-1 filled 1", trade.HedgeOrderID, trade.HedgeQuantity)
+	}
+	if got := positions["derive ETH-20250530-3000-C"]; !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("derive position = %s, want 1", got)
+	}
+}
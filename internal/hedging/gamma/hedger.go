@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/marketdata"
+	"github.com/wakamex/atomizer/internal/pricing"
 	"github.com/wakamex/atomizer/internal/types"
 	"github.com/shopspring/decimal"
 )
@@ -17,20 +21,20 @@ type Hedger struct {
 	exchange         types.Exchange
 	config           *config.Config
 	hedgeManager     types.HedgeManager
+	pricer           *pricing.Engine
+	marketData       *marketdata.Hub        // Live underlying prices, nil if not shared
+	watchlist        *marketdata.Watchlist  // Perps of the underlyings held
 	
 	// Greek tracking
 	positions        map[string]*OptionPosition  // instrument -> position with greeks
-	netDelta         decimal.Decimal
-	netGamma         decimal.Decimal
+	exposures        map[string]*Exposure        // underlying -> net Greeks and hedge
 	
 	// Hedging parameters
 	deltaThreshold   decimal.Decimal  // Max delta before hedging
 	gammaThreshold   decimal.Decimal  // Min gamma to trigger dynamic hedging
 	hedgeInterval    time.Duration    // How often to check/rehedge
 	minHedgeSize     decimal.Decimal  // Minimum hedge size
-	
-	// Hedge tracking
-	currentHedge     *HedgePosition   // Current perp/spot hedge position
+	deltaThresholds  map[string]decimal.Decimal // Per-underlying delta thresholds
 	
 	// Control
 	ctx              context.Context
@@ -42,6 +46,7 @@ type Hedger struct {
 // OptionPosition tracks an option position with its Greeks
 type OptionPosition struct {
 	Instrument       string
+	Underlying       string
 	Amount           decimal.Decimal  // Positive for long, negative for short
 	Strike           decimal.Decimal
 	Expiry           int64
@@ -63,7 +68,7 @@ type OptionPosition struct {
 
 // HedgePosition tracks the hedge position
 type HedgePosition struct {
-	Instrument       string           // ETH-PERP or spot ETH
+	Instrument       string           // Perp of the underlying, e.g. ETH-PERP
 	Amount           decimal.Decimal  // Current hedge amount
 	AvgPrice         decimal.Decimal
 	LastHedgeTime    time.Time
@@ -83,16 +88,46 @@ func NewHedger(exchange types.Exchange, cfg *config.Config, hedgeManager types.H
 		exchange:        exchange,
 		config:          cfg,
 		hedgeManager:    hedgeManager,
+		pricer:          pricing.NewEngine(pricing.NewTickerSource(cfg.ExchangeName, cfg.ExchangeTestMode)),
 		positions:       make(map[string]*OptionPosition),
-		deltaThreshold:  decimal.NewFromFloat(0.1),   // 0.1 ETH delta threshold
+		exposures:       make(map[string]*Exposure),
+		deltaThresholds: make(map[string]decimal.Decimal),
+		deltaThreshold:  decimal.NewFromFloat(0.1),   // 0.1 delta threshold
 		gammaThreshold:  gammaThreshold,
 		hedgeInterval:   30 * time.Second,
-		minHedgeSize:    decimal.NewFromFloat(0.01),  // Min 0.01 ETH hedge
+		minHedgeSize:    decimal.NewFromFloat(0.01),  // Min 0.01 hedge
 		ctx:             ctx,
 		cancel:          cancel,
 	}
 }
 
+// SetPricingEngine replaces the engine used to compute option Greeks
+func (h *Hedger) SetPricingEngine(engine *pricing.Engine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pricer = engine
+}
+
+// SetMarketData reads underlying prices from the perps streamed by a shared
+// market data hub
+func (h *Hedger) SetMarketData(hub *marketdata.Hub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.watchlist != nil {
+		h.watchlist.Close()
+	}
+	h.marketData = hub
+	h.watchlist = marketdata.NewWatchlist(hub)
+}
+
+// SetDeltaThreshold sets the delta threshold for one underlying, in its
+// units. Others use the default.
+func (h *Hedger) SetDeltaThreshold(underlying string, threshold decimal.Decimal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deltaThresholds[strings.ToUpper(underlying)] = threshold
+}
+
 // Start begins the gamma hedging loop
 func (h *Hedger) Start(ctx context.Context) {
 	h.mu.Lock()
@@ -131,23 +166,33 @@ func (h *Hedger) Stop() {
 	
 	log.Println("Stopping gamma hedger...")
 	h.cancel()
+	if h.watchlist != nil {
+		h.watchlist.Close()
+	}
 	h.running = false
 }
 
-// UpdatePosition updates an option position and its Greeks
+// UpdatePosition updates an option position and its Greeks. If greeks is
+// nil they are computed with the pricing engine.
 func (h *Hedger) UpdatePosition(trade *types.TradeEvent, greeks *OptionGreeks) {
+	inst := h.resolveInstrument(trade)
+	instrumentName := inst.Name
+	
+	if greeks == nil {
+		greeks = h.computeGreeks(instrumentName, inst.Strike, inst.Expiry.Unix(), inst.IsPut(), decimal.Zero)
+	}
+	
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	
-	instrumentName := h.constructInstrumentName(trade)
-	
 	position, exists := h.positions[instrumentName]
 	if !exists {
 		position = &OptionPosition{
 			Instrument: instrumentName,
-			Strike:     trade.Strike,
-			Expiry:     trade.Expiry,
-			IsPut:      trade.IsPut,
+			Underlying: inst.Base(),
+			Strike:     inst.Strike,
+			Expiry:     inst.Expiry.Unix(),
+			IsPut:      inst.IsPut(),
 			Amount:     decimal.Zero,
 		}
 		h.positions[instrumentName] = position
@@ -162,15 +207,9 @@ func (h *Hedger) UpdatePosition(trade *types.TradeEvent, greeks *OptionGreeks) {
 		position.Amount = position.Amount.Add(trade.Quantity)
 	}
 	
-	// Update Greeks if provided
+	// Update Greeks if we have them
 	if greeks != nil {
-		position.Delta = greeks.Delta
-		position.Gamma = greeks.Gamma
-		position.Vega = greeks.Vega
-		position.Theta = greeks.Theta
-		position.UnderlyingPrice = greeks.UnderlyingPrice
-		position.ImpliedVol = greeks.ImpliedVol
-		position.TimeToExpiry = greeks.TimeToExpiry
+		position.applyGreeks(greeks)
 	}
 	
 	position.LastUpdate = time.Now()
@@ -179,44 +218,63 @@ func (h *Hedger) UpdatePosition(trade *types.TradeEvent, greeks *OptionGreeks) {
 	h.recalculatePortfolioGreeks()
 }
 
-// performHedgeCheck checks if hedging is needed and executes if necessary
+// performHedgeCheck checks each underlying and hedges those that need it
 func (h *Hedger) performHedgeCheck() {
+	// Greeks move with spot and time, revalue before deciding
+	h.refreshGreeks()
+	
 	h.mu.RLock()
-	netDelta := h.netDelta
-	netGamma := h.netGamma
+	snapshot := make([]Exposure, 0, len(h.exposures))
+	for _, exposure := range h.exposures {
+		snapshot = append(snapshot, *exposure)
+	}
 	h.mu.RUnlock()
 	
-	// Check if we need to hedge based on delta
-	if netDelta.Abs().GreaterThan(h.deltaThreshold) {
-		log.Printf("Delta hedge triggered: Net Delta = %s", netDelta.StringFixed(4))
-		h.executeDeltaHedge(netDelta)
+	for _, exposure := range snapshot {
+		// Check if we need to hedge based on delta
+		if exposure.NetDelta.Abs().GreaterThan(h.deltaThresholdFor(exposure.Underlying)) {
+			log.Printf("Delta hedge triggered: %s Net Delta = %s", exposure.Underlying, exposure.NetDelta.StringFixed(4))
+			h.executeDeltaHedge(exposure.Underlying, exposure.NetDelta)
+		}
+		
+		// Check if we need dynamic gamma hedging
+		if exposure.NetGamma.Abs().GreaterThan(h.gammaThreshold) {
+			// For high gamma positions, we need more frequent rehedging
+			h.checkDynamicGammaHedge(exposure.Underlying, exposure.NetDelta, exposure.NetGamma)
+		}
 	}
-	
-	// Check if we need dynamic gamma hedging
-	if netGamma.Abs().GreaterThan(h.gammaThreshold) {
-		// For high gamma positions, we need more frequent rehedging
-		h.checkDynamicGammaHedge(netDelta, netGamma)
+}
+
+// deltaThresholdFor returns an underlying's delta threshold
+func (h *Hedger) deltaThresholdFor(underlying string) decimal.Decimal {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if threshold, ok := h.deltaThresholds[underlying]; ok {
+		return threshold
 	}
+	return h.deltaThreshold
 }
 
-// executeDeltaHedge executes a delta-neutral hedge
-func (h *Hedger) executeDeltaHedge(targetDelta decimal.Decimal) {
+// executeDeltaHedge executes a delta-neutral hedge in the underlying's perp
+func (h *Hedger) executeDeltaHedge(underlying string, targetDelta decimal.Decimal) {
 	// Calculate hedge size needed
 	hedgeSize := targetDelta.Neg() // Opposite of our delta exposure
 	
 	// Check minimum hedge size
 	if hedgeSize.Abs().LessThan(h.minHedgeSize) {
-		log.Printf("Hedge size %s below minimum %s, skipping", 
-			hedgeSize.StringFixed(4), h.minHedgeSize.StringFixed(4))
+		log.Printf("%s hedge size %s below minimum %s, skipping", 
+			underlying, hedgeSize.StringFixed(4), h.minHedgeSize.StringFixed(4))
 		return
 	}
 	
+	perp := instruments.PerpName(h.config.ExchangeName, underlying)
+	
 	// Create a synthetic trade event for the hedge
 	hedgeTrade := &types.TradeEvent{
 		ID:         fmt.Sprintf("gamma_hedge_%d", time.Now().UnixNano()),
 		Source:     types.TradeSourceHedge,
 		Status:     types.TradeStatusPending,
-		Instrument: "ETH-PERP", // Use perpetual for hedging
+		Instrument: perp, // Use perpetual for hedging
 		Quantity:   hedgeSize.Abs(),
 		IsTakerBuy: hedgeSize.GreaterThan(decimal.Zero), // Buy if we need positive delta
 		Timestamp:  time.Now(),
@@ -225,34 +283,37 @@ func (h *Hedger) executeDeltaHedge(targetDelta decimal.Decimal) {
 	// Execute hedge through hedge manager
 	err := h.hedgeManager.ExecuteHedge(h.ctx, hedgeTrade)
 	if err != nil {
-		log.Printf("Failed to execute delta hedge: %v", err)
+		log.Printf("Failed to execute %s delta hedge: %v", underlying, err)
 		return
 	}
 	
 	// Update hedge tracking
 	h.mu.Lock()
-	if h.currentHedge == nil {
-		h.currentHedge = &HedgePosition{
-			Instrument: "ETH-PERP",
+	exposure := h.exposure(underlying)
+	if exposure.Hedge == nil {
+		exposure.Hedge = &HedgePosition{
+			Instrument: perp,
 			Amount:     decimal.Zero,
 		}
 	}
-	h.currentHedge.Amount = h.currentHedge.Amount.Add(hedgeSize)
-	h.currentHedge.LastHedgeTime = time.Now()
-	h.currentHedge.LastHedgeDelta = targetDelta
+	exposure.Hedge.Amount = exposure.Hedge.Amount.Add(hedgeSize)
+	exposure.Hedge.LastHedgeTime = time.Now()
+	exposure.Hedge.LastHedgeDelta = targetDelta
+	hedgeAmount := exposure.Hedge.Amount
+	h.recalculatePortfolioGreeks()
 	h.mu.Unlock()
 	
-	log.Printf("Delta hedge executed: Size = %s, New hedge position = %s", 
-		hedgeSize.StringFixed(4), h.currentHedge.Amount.StringFixed(4))
+	log.Printf("Delta hedge executed: Size = %s %s, New hedge position = %s", 
+		hedgeSize.StringFixed(4), perp, hedgeAmount.StringFixed(4))
 }
 
 // checkDynamicGammaHedge implements dynamic hedging for high gamma positions
-func (h *Hedger) checkDynamicGammaHedge(netDelta, netGamma decimal.Decimal) {
+func (h *Hedger) checkDynamicGammaHedge(underlying string, netDelta, netGamma decimal.Decimal) {
 	// For high gamma, we rehedge more frequently based on time decay
 	h.mu.RLock()
 	lastHedgeTime := time.Time{}
-	if h.currentHedge != nil {
-		lastHedgeTime = h.currentHedge.LastHedgeTime
+	if exposure, ok := h.exposures[underlying]; ok && exposure.Hedge != nil {
+		lastHedgeTime = exposure.Hedge.LastHedgeTime
 	}
 	h.mu.RUnlock()
 	
@@ -261,28 +322,28 @@ func (h *Hedger) checkDynamicGammaHedge(netDelta, netGamma decimal.Decimal) {
 	
 	// For options near expiry with high gamma, hedge more frequently
 	var rehedgeThreshold time.Duration
-	if h.hasNearExpiryPositions() {
+	if h.hasNearExpiryPositions(underlying) {
 		rehedgeThreshold = 5 * time.Minute  // Hedge every 5 minutes for near expiry
 	} else {
 		rehedgeThreshold = 30 * time.Minute // Standard rehedge interval
 	}
 	
 	if timeSinceHedge > rehedgeThreshold {
-		log.Printf("Dynamic gamma hedge triggered: Gamma = %s, Time since last hedge = %v",
-			netGamma.StringFixed(4), timeSinceHedge)
-		h.executeDeltaHedge(netDelta)
+		log.Printf("Dynamic gamma hedge triggered: %s Gamma = %s, Time since last hedge = %v",
+			underlying, netGamma.StringFixed(4), timeSinceHedge)
+		h.executeDeltaHedge(underlying, netDelta)
 	}
 }
 
-// hasNearExpiryPositions checks if we have positions expiring soon
-func (h *Hedger) hasNearExpiryPositions() bool {
+// hasNearExpiryPositions checks if we have positions on an underlying expiring soon
+func (h *Hedger) hasNearExpiryPositions(underlying string) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	
 	nearExpiryThreshold := 7 * 24 * time.Hour // 7 days
 	
 	for _, pos := range h.positions {
-		if pos.Amount.IsZero() {
+		if pos.Amount.IsZero() || pos.Underlying != underlying {
 			continue
 		}
 		
@@ -295,36 +356,160 @@ func (h *Hedger) hasNearExpiryPositions() bool {
 	return false
 }
 
-// recalculatePortfolioGreeks recalculates net portfolio Greeks
-func (h *Hedger) recalculatePortfolioGreeks() {
-	h.netDelta = decimal.Zero
-	h.netGamma = decimal.Zero
-	
+// refreshGreeks revalues every open position with current market data
+func (h *Hedger) refreshGreeks() {
+	h.mu.RLock()
+	snapshot := make([]OptionPosition, 0, len(h.positions))
 	for _, pos := range h.positions {
 		if !pos.Amount.IsZero() {
-			h.netDelta = h.netDelta.Add(pos.Delta.Mul(pos.Amount))
-			h.netGamma = h.netGamma.Add(pos.Gamma.Mul(pos.Amount))
+			snapshot = append(snapshot, *pos)
 		}
 	}
+	h.mu.RUnlock()
 	
-	// Include hedge position in delta calculation
-	if h.currentHedge != nil && !h.currentHedge.Amount.IsZero() {
-		// Perp has delta of 1
-		h.netDelta = h.netDelta.Add(h.currentHedge.Amount)
+	h.watchUnderlyings(snapshot)
+	
+	updated := make(map[string]*OptionGreeks, len(snapshot))
+	for _, pos := range snapshot {
+		if greeks := h.computeGreeks(pos.Instrument, pos.Strike, pos.Expiry, pos.IsPut, h.underlyingPrice(pos.Underlying, pos.UnderlyingPrice)); greeks != nil {
+			updated[pos.Instrument] = greeks
+		}
 	}
+	
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	for instrument, greeks := range updated {
+		if pos, ok := h.positions[instrument]; ok {
+			pos.applyGreeks(greeks)
+			pos.LastUpdate = time.Now()
+		}
+	}
+	h.recalculatePortfolioGreeks()
 }
 
-// constructInstrumentName creates a standardized instrument name
-func (h *Hedger) constructInstrumentName(trade *types.TradeEvent) string {
-	optionType := "C"
-	if trade.IsPut {
-		optionType = "P"
+// watchUnderlyings keeps the perps of the underlyings held streaming through
+// the market data hub
+func (h *Hedger) watchUnderlyings(positions []OptionPosition) {
+	h.mu.RLock()
+	watchlist := h.watchlist
+	h.mu.RUnlock()
+	if watchlist == nil {
+		return
 	}
 	
-	expiryTime := time.Unix(trade.Expiry, 0)
-	expiryStr := expiryTime.Format("20060102")
+	perps := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		perp := instruments.PerpName(h.config.ExchangeName, pos.Underlying)
+		if err := watchlist.Watch(perp); err != nil {
+			log.Printf("Failed to stream %s: %v", perp, err)
+			continue
+		}
+		perps = append(perps, perp)
+	}
+	watchlist.Retain(perps)
+}
+
+// underlyingPrice returns the index price streamed with the underlying's perp,
+// or fallback when the hub has none
+func (h *Hedger) underlyingPrice(underlying string, fallback decimal.Decimal) decimal.Decimal {
+	h.mu.RLock()
+	hub := h.marketData
+	h.mu.RUnlock()
+	if hub == nil {
+		return fallback
+	}
+	if price, ok := hub.IndexPrice(instruments.PerpName(h.config.ExchangeName, underlying)); ok {
+		return price
+	}
+	return fallback
+}
+
+// computeGreeks values an option with the pricing engine, returning nil on failure
+func (h *Hedger) computeGreeks(instrument string, strike decimal.Decimal, expiry int64, isPut bool, spot decimal.Decimal) *OptionGreeks {
+	h.mu.RLock()
+	pricer := h.pricer
+	h.mu.RUnlock()
+	
+	valuation, err := pricer.Evaluate(pricing.Option{
+		Instrument: instrument,
+		Strike:     strike.InexactFloat64(),
+		Expiry:     expiry,
+		IsPut:      isPut,
+		Spot:       spot.InexactFloat64(),
+	})
+	if err != nil {
+		log.Printf("Failed to compute Greeks for %s: %v", instrument, err)
+		return nil
+	}
 	
-	return fmt.Sprintf("ETH-%s-%s-%s", expiryStr, trade.Strike.String(), optionType)
+	return &OptionGreeks{
+		Delta:           decimal.NewFromFloat(valuation.Delta),
+		Gamma:           decimal.NewFromFloat(valuation.Gamma),
+		Vega:            decimal.NewFromFloat(valuation.Vega),
+		Theta:           decimal.NewFromFloat(valuation.Theta),
+		UnderlyingPrice: decimal.NewFromFloat(valuation.Spot),
+		ImpliedVol:      decimal.NewFromFloat(valuation.IV),
+		TimeToExpiry:    decimal.NewFromFloat(valuation.TimeToExpiry),
+	}
+}
+
+// applyGreeks copies Greeks and market data onto the position
+func (p *OptionPosition) applyGreeks(greeks *OptionGreeks) {
+	p.Delta = greeks.Delta
+	p.Gamma = greeks.Gamma
+	p.Vega = greeks.Vega
+	p.Theta = greeks.Theta
+	p.UnderlyingPrice = greeks.UnderlyingPrice
+	p.ImpliedVol = greeks.ImpliedVol
+	p.TimeToExpiry = greeks.TimeToExpiry
+}
+
+// recalculatePortfolioGreeks recalculates each underlying's net Greeks
+// (must be called with lock held)
+func (h *Hedger) recalculatePortfolioGreeks() {
+	for _, exposure := range h.exposures {
+		exposure.NetDelta = decimal.Zero
+		exposure.NetGamma = decimal.Zero
+	}
+	
+	for _, pos := range h.positions {
+		if !pos.Amount.IsZero() {
+			exposure := h.exposure(pos.Underlying)
+			exposure.NetDelta = exposure.NetDelta.Add(pos.Delta.Mul(pos.Amount))
+			exposure.NetGamma = exposure.NetGamma.Add(pos.Gamma.Mul(pos.Amount))
+		}
+	}
+	
+	// Include hedge positions in delta calculation
+	for _, exposure := range h.exposures {
+		if exposure.Hedge != nil && !exposure.Hedge.Amount.IsZero() {
+			// Perp has delta of 1
+			exposure.NetDelta = exposure.NetDelta.Add(exposure.Hedge.Amount)
+		}
+	}
+}
+
+// exposure returns an underlying's exposure, creating it if needed (must be
+// called with lock held)
+func (h *Hedger) exposure(underlying string) *Exposure {
+	exposure, ok := h.exposures[underlying]
+	if !ok {
+		exposure = &Exposure{Underlying: underlying}
+		h.exposures[underlying] = exposure
+	}
+	return exposure
+}
+
+// resolveInstrument identifies the traded option from its name or Rysk
+// terms, falling back to the raw terms when its asset is unknown
+func (h *Hedger) resolveInstrument(trade *types.TradeEvent) *instruments.Instrument {
+	inst, err := instruments.Resolve(trade.Instrument, trade.Strike.String(), trade.Expiry, trade.IsPut)
+	if err != nil {
+		log.Printf("Failed to resolve instrument for trade %s: %v", trade.ID, err)
+		inst = instruments.NewOption(trade.Instrument, trade.Strike, time.Unix(trade.Expiry, 0), trade.IsPut)
+	}
+	return inst
 }
 
 // GetMetrics returns current gamma hedging metrics
@@ -332,18 +517,28 @@ func (h *Hedger) GetMetrics() GammaMetrics {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	
-	hedgeAmount := decimal.Zero
-	if h.currentHedge != nil {
-		hedgeAmount = h.currentHedge.Amount
-	}
-	
-	return GammaMetrics{
-		NetDelta:       h.netDelta,
-		NetGamma:       h.netGamma,
-		HedgeAmount:    hedgeAmount,
+	metrics := GammaMetrics{
 		PositionCount:  len(h.positions),
+		Underlyings:    make(map[string]Exposure, len(h.exposures)),
 		LastUpdateTime: time.Now(),
 	}
+	for underlying, exposure := range h.exposures {
+		metrics.NetDelta = metrics.NetDelta.Add(exposure.NetDelta)
+		metrics.NetGamma = metrics.NetGamma.Add(exposure.NetGamma)
+		if exposure.Hedge != nil {
+			metrics.HedgeAmount = metrics.HedgeAmount.Add(exposure.Hedge.Amount)
+		}
+		metrics.Underlyings[underlying] = *exposure
+	}
+	return metrics
+}
+
+// Exposure is the net exposure to one underlying and its hedge
+type Exposure struct {
+	Underlying string
+	NetDelta   decimal.Decimal
+	NetGamma   decimal.Decimal
+	Hedge      *HedgePosition
 }
 
 // OptionGreeks contains the Greeks for an option
@@ -357,11 +552,13 @@ type OptionGreeks struct {
 	TimeToExpiry    decimal.Decimal
 }
 
-// GammaMetrics contains gamma hedging metrics
+// GammaMetrics contains gamma hedging metrics. The totals sum across
+// underlyings, Underlyings has each one's own.
 type GammaMetrics struct {
 	NetDelta       decimal.Decimal
 	NetGamma       decimal.Decimal
 	HedgeAmount    decimal.Decimal
 	PositionCount  int
+	Underlyings    map[string]Exposure
 	LastUpdateTime time.Time
 }
\ No newline at end of file
@@ -5,34 +5,94 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/monitor"
 	"github.com/wakamex/atomizer/internal/types"
 	"github.com/shopspring/decimal"
 )
 
-// Manager handles hedge execution on exchanges
+// Manager handles hedge execution on exchanges. Option hedges are routed to
+// whichever of its venues is cheapest to fill on, and followed until filled.
 type Manager struct {
 	exchange      types.Exchange
 	config        *config.Config
+	venues        []venue
+	converter     *monitor.InstrumentConverter
+	positions     PositionRecorder
 	maxRetries    int
 	retryDelayMs  int
+	fillTimeout   time.Duration
+	maxReprices   int
+	streams       map[string]*orderStream // Order streams by venue name
+	streamsMu     sync.Mutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// PositionRecorder tracks hedge positions per venue
+type PositionRecorder interface {
+	RecordHedge(venue, instrument string, isBuy bool, quantity, price decimal.Decimal)
 }
 
 // NewManager creates a new hedge manager
 func NewManager(exchange types.Exchange, cfg *config.Config) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
 		exchange:     exchange,
 		config:       cfg,
+		venues:       []venue{{name: cfg.ExchangeName, exchange: exchange}},
+		converter:    monitor.NewInstrumentConverter(),
 		maxRetries:   3,
 		retryDelayMs: 1000,
+		fillTimeout:  DefaultFillTimeout,
+		maxReprices:  DefaultMaxReprices,
+		streams:      make(map[string]*orderStream),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// SetFillPolicy sets how long hedge orders rest before they are cancelled and
+// repriced, and how many reprices are made before a hedge is escalated
+func (m *Manager) SetFillPolicy(timeout time.Duration, maxReprices int) {
+	if timeout > 0 {
+		m.fillTimeout = timeout
+	}
+	if maxReprices >= 0 {
+		m.maxReprices = maxReprices
 	}
 }
 
-// ExecuteHedge places a hedge order for the given trade
+// Stop stops following hedge orders
+func (m *Manager) Stop() {
+	m.cancel()
+}
+
+// SetPositionRecorder records filled hedges, such as in the risk manager
+func (m *Manager) SetPositionRecorder(recorder PositionRecorder) {
+	m.positions = recorder
+}
+
+// ExecuteHedge places a hedge order for the given trade and follows it until
+// filled. Whatever is left unfilled is cancelled and repriced from a fresh
+// book on the same venue, and a hedge still unfilled after the reprices is
+// escalated as an error. Fills are recorded on the trade and with the
+// position recorder as they happen.
 func (m *Manager) ExecuteHedge(ctx context.Context, trade *types.TradeEvent) error {
-	log.Printf("Executing hedge for trade %s on %s", trade.ID, m.config.ExchangeName)
+	return m.ExecuteHedgeReporting(ctx, trade, nil)
+}
+
+// ExecuteHedgeReporting executes a hedge like ExecuteHedge, calling report
+// with the trade once its venue is picked, as each order is placed and as
+// orders fill, so that a hedge cut short can be picked up from its record
+func (m *Manager) ExecuteHedgeReporting(ctx context.Context, trade *types.TradeEvent, report func(*types.TradeEvent)) error {
+	if report == nil {
+		report = func(*types.TradeEvent) {}
+	}
 	
 	// Convert trade to hedge parameters
 	hedgeParams, err := m.buildHedgeParams(trade)
@@ -40,36 +100,101 @@ func (m *Manager) ExecuteHedge(ctx context.Context, trade *types.TradeEvent) err
 		return fmt.Errorf("failed to build hedge params: %w", err)
 	}
 	
-	// Get current order book
-	orderBook, err := m.getOrderBookWithRetry(ctx, trade)
+	// Pick the venue and price from the current order books
+	route, err := m.route(ctx, trade, hedgeParams)
 	if err != nil {
-		return fmt.Errorf("failed to get order book: %w", err)
+		return fmt.Errorf("failed to route hedge: %w", err)
 	}
+	hedgeParams.instrument = route.instrument
+	hedgePrice := route.price
+	trade.HedgeExchange = route.venue.name
+	report(trade)
 	
-	// Calculate hedge price
-	hedgePrice := m.calculateHedgePrice(orderBook, hedgeParams.isBuy)
-	
+	log.Printf("Executing hedge for trade %s on %s", trade.ID, route.venue.name)
 	log.Printf("[HedgeManager] Hedge params - isBuy: %v, calculated price: %s", 
 		hedgeParams.isBuy, hedgePrice.String())
 	
-	// Execute hedge with retries
+	total := hedgeParams.quantity
+	var (
+		orderIDs []string
+		filled   = decimal.Zero
+		notional = decimal.Zero
+	)
+	for reprice := 0; ; reprice++ {
+		if reprice > 0 {
+			hedgeParams.quantity = total.Sub(filled)
+			if hedgePrice, err = m.repriceHedge(ctx, trade, route.venue, hedgeParams); err != nil {
+				return fmt.Errorf("failed to reprice hedge: %w", err)
+			}
+			log.Printf("[HedgeManager] Repricing the remaining %s of trade %s's hedge at %s (%d of %d)",
+				hedgeParams.quantity, trade.ID, hedgePrice, reprice, m.maxReprices)
+		}
+		
+		hooks := orderHooks{
+			placed: func(orderID string) {
+				orderIDs = append(orderIDs, orderID)
+				trade.HedgeOrderID = strings.Join(orderIDs, ",")
+				report(trade)
+			},
+			filled: func(quantity, price decimal.Decimal) {
+				trade.HedgeQuantity = filled.Add(quantity)
+				trade.HedgePrice = notional.Add(quantity.Mul(price)).Div(trade.HedgeQuantity)
+				report(trade)
+			},
+		}
+		result, err := m.placeHedge(ctx, route.venue, hedgeParams, hedgePrice, hooks)
+		if result != nil && result.Quantity.IsPositive() {
+			filled = filled.Add(result.Quantity)
+			notional = notional.Add(result.Quantity.Mul(result.Price))
+			trade.HedgeQuantity = filled
+			trade.HedgePrice = notional.Div(filled)
+			report(trade)
+			
+			if m.positions != nil {
+				m.positions.RecordHedge(route.venue.name, result.Instrument, hedgeParams.isBuy, result.Quantity, result.Price)
+			}
+		}
+		if err != nil {
+			if result != nil {
+				// The order may still be working, so it is not repriced
+				log.Printf("[HedgeManager] ESCALATION: trade %s hedge filled %s of %s on %s; check order %s before hedging the rest manually: %v",
+					trade.ID, filled, total, route.venue.name, result.OrderID, err)
+			}
+			return err
+		}
+		
+		if filled.GreaterThanOrEqual(total) {
+			log.Printf("Hedge of trade %s filled: %s at %s, OrderID=%s",
+				trade.ID, filled, trade.HedgePrice, trade.HedgeOrderID)
+			return nil
+		}
+		if reprice >= m.maxReprices {
+			log.Printf("[HedgeManager] ESCALATION: trade %s hedge filled %s of %s on %s after %d reprices; hedge the rest manually",
+				trade.ID, filled, total, route.venue.name, reprice)
+			return fmt.Errorf("hedge unfilled after %d reprices: filled %s of %s", reprice, filled, total)
+		}
+	}
+}
+
+// placeHedge places a hedge order, retrying if placement fails, and waits
+// for it to fill. Once an order is placed its result is returned, with any
+// error from waiting on it.
+func (m *Manager) placeHedge(ctx context.Context, v venue, params *hedgeParams, price decimal.Decimal, hooks orderHooks) (*hedgeResult, error) {
 	var lastErr error
 	for attempt := 1; attempt <= m.maxRetries; attempt++ {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		default:
 		}
 		
-		result, err := m.executeSingleHedge(ctx, hedgeParams, hedgePrice)
-		if err == nil {
-			log.Printf("Hedge successful on attempt %d: OrderID=%s", attempt, result.OrderID)
-			
-			// Update trade with hedge information
-			trade.HedgeOrderID = result.OrderID
-			trade.HedgeExchange = m.config.ExchangeName
-			
-			return nil
+		result, err := m.executeSingleHedge(ctx, v, params, price, hooks)
+		if result != nil {
+			if err == nil {
+				log.Printf("Hedge order placed on attempt %d: OrderID=%s, filled %s at %s",
+					attempt, result.OrderID, result.Quantity, result.Price)
+			}
+			return result, err
 		}
 		
 		lastErr = err
@@ -78,21 +203,41 @@ func (m *Manager) ExecuteHedge(ctx context.Context, trade *types.TradeEvent) err
 		if attempt < m.maxRetries {
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return nil, ctx.Err()
 			case <-time.After(time.Duration(m.retryDelayMs) * time.Millisecond):
 				// Continue to next attempt
 			}
 		}
 	}
 	
-	return fmt.Errorf("hedge failed after %d attempts: %w", m.maxRetries, lastErr)
+	return nil, fmt.Errorf("hedge failed after %d attempts: %w", m.maxRetries, lastErr)
+}
+
+// repriceHedge prices the rest of a hedge from a fresh book on its venue, at
+// the level that would fill it
+func (m *Manager) repriceHedge(ctx context.Context, trade *types.TradeEvent, v venue, params *hedgeParams) (decimal.Decimal, error) {
+	book, err := m.getOrderBookWithRetry(ctx, v.exchange, trade, params, params.instrument)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	levels := book.Asks
+	if !params.isBuy {
+		levels = book.Bids
+	}
+	if _, worst, _ := sweep(levels, params.quantity); worst.IsPositive() {
+		return worst, nil
+	}
+	return m.calculateHedgePrice(book, params.isBuy), nil
 }
 
 // hedgeParams contains parameters for hedge execution
 type hedgeParams struct {
-	instrument string
+	instrument string // Name on the venue hedged on
+	inst       *instruments.Instrument
+	underlying string
 	quantity   decimal.Decimal
 	isBuy      bool
+	preview    bool // Routing for a quote, which can't wait on retries
 }
 
 // hedgeResult contains the result of a hedge execution
@@ -107,10 +252,9 @@ type hedgeResult struct {
 
 // buildHedgeParams converts trade to hedge parameters
 func (m *Manager) buildHedgeParams(trade *types.TradeEvent) (*hedgeParams, error) {
-	// Convert instrument name if needed
-	instrument, err := m.convertInstrumentName(trade)
+	inst, err := instruments.Resolve(trade.Instrument, trade.Strike.String(), trade.Expiry, trade.IsPut)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to resolve instrument: %w", err)
 	}
 	
 	// Hedge direction is opposite of trade
@@ -118,30 +262,27 @@ func (m *Manager) buildHedgeParams(trade *types.TradeEvent) (*hedgeParams, error
 	isBuy := trade.IsTakerBuy
 	
 	return &hedgeParams{
-		instrument: instrument,
+		instrument: trade.Instrument,
+		inst:       inst,
+		underlying: inst.Base(),
 		quantity:   trade.Quantity,
 		isBuy:      isBuy,
 	}, nil
 }
 
-// convertInstrumentName converts Rysk format to exchange format
-func (m *Manager) convertInstrumentName(trade *types.TradeEvent) (string, error) {
-	// If we already have the instrument name, use it
-	if trade.Instrument != "" && strings.Contains(trade.Instrument, "-") {
-		return trade.Instrument, nil
+// instrumentOn names the hedged instrument on a venue. Perps and futures are
+// hedged under the name they were given.
+func (m *Manager) instrumentOn(v venue, params *hedgeParams) string {
+	if !params.inst.IsOption() {
+		return params.instrument
 	}
-	
-	// Otherwise convert from trade parameters
-	return m.exchange.ConvertToInstrument(
-		"ETH", // Default asset, should be mapped from trade.Instrument
-		trade.Strike.String(),
-		trade.Expiry,
-		trade.IsPut,
-	)
+	return m.converter.ConvertForExchange(params.inst.Name, v.name)
 }
 
-// getOrderBookWithRetry gets order book with retry logic
-func (m *Manager) getOrderBookWithRetry(ctx context.Context, trade *types.TradeEvent) (*types.CCXTOrderBook, error) {
+// getOrderBookWithRetry gets an instrument's order book on a venue with retry
+// logic. The book is fetched by instrument name when the exchange supports
+// it, which perps need.
+func (m *Manager) getOrderBookWithRetry(ctx context.Context, exchange types.Exchange, trade *types.TradeEvent, params *hedgeParams, instrument string) (*types.CCXTOrderBook, error) {
 	rfq := types.RFQResult{
 		Asset:      trade.Instrument,
 		Strike:     trade.Strike.String(),
@@ -150,47 +291,47 @@ func (m *Manager) getOrderBookWithRetry(ctx context.Context, trade *types.TradeE
 		Quantity:   trade.Quantity.String(),
 		IsTakerBuy: trade.IsTakerBuy,
 	}
+	fetch := func() (types.CCXTOrderBook, error) {
+		return exchange.GetOrderBook(rfq, params.underlying)
+	}
+	if source, ok := exchange.(interface {
+		GetInstrumentOrderBook(string) (types.CCXTOrderBook, error)
+	}); ok {
+		fetch = func() (types.CCXTOrderBook, error) {
+			return source.GetInstrumentOrderBook(instrument)
+		}
+	}
+	
+	attempts := m.maxRetries
+	if params.preview {
+		attempts = 1
+	}
 	
 	var lastErr error
-	for i := 0; i < m.maxRetries; i++ {
+	for i := 0; i < attempts; i++ {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
 		
-		orderBook, err := m.exchange.GetOrderBook(rfq, "ETH") // Default asset
+		orderBook, err := fetch()
 		if err == nil {
 			return &orderBook, nil
 		}
 		
 		lastErr = err
-		if i < m.maxRetries-1 {
+		if i < attempts-1 {
 			time.Sleep(500 * time.Millisecond)
 		}
 	}
 	
-	return nil, fmt.Errorf("failed to get order book after %d attempts: %w", m.maxRetries, lastErr)
+	return nil, fmt.Errorf("failed to get order book after %d attempts: %w", attempts, lastErr)
 }
 
 // calculateHedgePrice determines optimal hedge price
 func (m *Manager) calculateHedgePrice(orderBook *types.CCXTOrderBook, isBuy bool) decimal.Decimal {
-	// During test mode, use defensive pricing
-	if m.config.ExchangeTestMode {
-		if isBuy && len(orderBook.Asks) > 0 {
-			// Buying - use a price above best ask
-			bestAsk := decimal.NewFromFloat(orderBook.Asks[0][0])
-			return bestAsk.Mul(decimal.NewFromFloat(1.01)) // 1% above ask
-		} else if !isBuy && len(orderBook.Bids) > 0 {
-			// Selling - use 2x ask price for defensive testing
-			if len(orderBook.Asks) > 0 {
-				bestAsk := decimal.NewFromFloat(orderBook.Asks[0][0])
-				return bestAsk.Mul(decimal.NewFromFloat(2.0))
-			}
-		}
-	}
-	
-	// Production pricing - use mid-market or slightly aggressive
+	// Take the best price on the other side
 	if isBuy && len(orderBook.Asks) > 0 {
 		return decimal.NewFromFloat(orderBook.Asks[0][0])
 	} else if !isBuy && len(orderBook.Bids) > 0 {
@@ -201,19 +342,28 @@ func (m *Manager) calculateHedgePrice(orderBook *types.CCXTOrderBook, isBuy bool
 	return decimal.NewFromFloat(0.05)
 }
 
-// executeSingleHedge executes a single hedge attempt
-func (m *Manager) executeSingleHedge(ctx context.Context, params *hedgeParams, price decimal.Decimal) (*hedgeResult, error) {
-	// Create RFQ confirmation for order placement
+// executeSingleHedge places a hedge order on a venue and, where the venue
+// streams our orders, waits for it to fill. The result holds the quantity
+// actually filled and its average price; orders on other venues are taken as
+// filled at the limit. A nil result means no order was placed.
+func (m *Manager) executeSingleHedge(ctx context.Context, v venue, params *hedgeParams, price decimal.Decimal, hooks orderHooks) (*hedgeResult, error) {
+	// Create RFQ confirmation for order placement. Exchanges take the side
+	// opposite the taker's, which is the hedge's.
 	conf := types.RFQConfirmation{
-		Price:    price.String(),
-		Quantity: params.quantity.String(),
+		Price:      price.String(),
+		Quantity:   params.quantity.String(),
+		IsTakerBuy: !params.isBuy,
 	}
 	
+	// Follow the venue's orders before placing, so no fill is missed
+	stream := m.orderStream(v)
+	
 	// Place order
-	err := m.exchange.PlaceOrder(conf, params.instrument, m.config)
+	orderID, err := v.exchange.PlaceOrder(conf, params.instrument, m.config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to place hedge order: %w", err)
 	}
+	hooks.placed(orderID)
 	
 	// Create result
 	direction := "buy"
@@ -222,7 +372,7 @@ func (m *Manager) executeSingleHedge(ctx context.Context, params *hedgeParams, p
 	}
 	
 	result := &hedgeResult{
-		OrderID:    fmt.Sprintf("hedge_%d", time.Now().UnixNano()),
+		OrderID:    orderID,
 		Instrument: params.instrument,
 		Direction:  direction,
 		Quantity:   params.quantity,
@@ -230,7 +380,12 @@ func (m *Manager) executeSingleHedge(ctx context.Context, params *hedgeParams, p
 		ExecutedAt: time.Now(),
 	}
 	
-	return result, nil
+	if stream == nil || orderID == "" {
+		return result, nil
+	}
+	result.Quantity, result.Price, err = m.awaitFill(ctx, stream, orderID, params.quantity, price, hooks.filled)
+	result.ExecutedAt = time.Now()
+	return result, err
 }
 
 // Ensure Manager implements the HedgeManager interface
@@ -4,36 +4,43 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
-	"github.com/wakamex/atomizer/internal/exchange/derive"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/marketdata"
+	"github.com/wakamex/atomizer/internal/pricing"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
 // PureGammaHedger implements aggressive gamma hedging for options positions
-// Unlike regular gamma hedging, this focuses on risk reduction, not market making
+// Unlike regular gamma hedging, this focuses on risk reduction, not market making.
+// Positions are aggregated by underlying and each underlying is hedged with
+// its own perp.
 type PureGammaHedger struct {
 	exchange         types.MarketMakerExchange
+	pricer           *pricing.Engine  // Model Greeks when the exchange ticker is unavailable
+	marketData       *marketdata.Hub  // Option tickers
+	watchlist        *marketdata.Watchlist // Options held, kept subscribed for their Greeks
 	debugMode        bool  // Enable debug logging
 	
 	// Greek tracking
 	positions        map[string]*OptionPosition  // instrument -> position with greeks
-	netDelta         decimal.Decimal
-	netGamma         decimal.Decimal
+	exposures        map[string]*UnderlyingExposure // underlying -> net Greeks and hedge
 	
-	// Hedging parameters
+	// Hedging parameters, used for underlyings without their own limits
 	deltaThreshold   decimal.Decimal  // Max delta before hedging
 	minHedgeSize     decimal.Decimal  // Minimum hedge size (exchange minimum)
 	hedgeInterval    time.Duration    // How often to check/rehedge
 	aggressiveness   decimal.Decimal  // How far through the spread to place orders (0-1)
+	limits           map[string]HedgeLimits // Per-underlying overrides
 	
 	// Hedge tracking
-	currentHedge     *HedgePosition   // Current perp/spot hedge position
-	lastHedgeTime    time.Time
-	consecutiveFails int              // Track consecutive hedge failures
+	hedgeInstruments map[string]string // underlying -> perp used for hedging, delta 1 per contract
+	subscribed       map[string]bool   // Hedge perps with an orderbook subscription
 	
 	// Control
 	ctx              context.Context
@@ -45,10 +52,13 @@ type PureGammaHedger struct {
 // OptionPosition represents an options position with Greeks
 type OptionPosition struct {
 	Instrument   string
+	Underlying   string
+	IsOption     bool
 	Quantity     decimal.Decimal
 	AvgPrice     decimal.Decimal
 	Delta        decimal.Decimal
 	Gamma        decimal.Decimal
+	IndexPrice   decimal.Decimal
 	LastUpdated  time.Time
 }
 
@@ -60,23 +70,51 @@ type HedgePosition struct {
 	UpdatedAt  time.Time
 }
 
+// HedgeLimits are the hedging thresholds for one underlying, in its units
+type HedgeLimits struct {
+	DeltaThreshold decimal.Decimal // Max delta before hedging
+	MinHedgeSize   decimal.Decimal // Smallest hedge worth placing
+}
+
+// UnderlyingExposure is the net exposure to one underlying and its hedge
+type UnderlyingExposure struct {
+	Underlying       string
+	HedgeInstrument  string
+	OptionsDelta     decimal.Decimal
+	PerpsDelta       decimal.Decimal
+	NetDelta         decimal.Decimal
+	NetGamma         decimal.Decimal
+	HasOptions       bool
+	CurrentHedge     *HedgePosition
+	LastHedgeTime    time.Time
+	ConsecutiveFails int // Track consecutive hedge failures
+}
+
 // NewPureGammaHedger creates a new pure gamma hedger
 func NewPureGammaHedger(exchange types.MarketMakerExchange) *PureGammaHedger {
 	ctx, cancel := context.WithCancel(context.Background())
+	hub := marketdata.NewHub(exchange)
 	
 	return &PureGammaHedger{
-		exchange:        exchange,
-		positions:       make(map[string]*OptionPosition),
-		deltaThreshold:  decimal.NewFromFloat(0.1),    // 0.1 ETH delta threshold
-		minHedgeSize:    decimal.NewFromFloat(0.1),    // Exchange minimum
-		hedgeInterval:   30 * time.Second,
-		aggressiveness:  decimal.NewFromFloat(0.5),    // Cross 50% of the spread
-		ctx:             ctx,
-		cancel:          cancel,
+		exchange:         exchange,
+		pricer:           pricing.NewEngine(nil),
+		marketData:       hub,
+		watchlist:        marketdata.NewWatchlist(hub),
+		positions:        make(map[string]*OptionPosition),
+		exposures:        make(map[string]*UnderlyingExposure),
+		limits:           make(map[string]HedgeLimits),
+		hedgeInstruments: make(map[string]string),
+		subscribed:       make(map[string]bool),
+		deltaThreshold:   decimal.NewFromFloat(0.1),    // 0.1 delta threshold
+		minHedgeSize:     decimal.NewFromFloat(0.1),    // Exchange minimum
+		hedgeInterval:    30 * time.Second,
+		aggressiveness:   decimal.NewFromFloat(0.5),    // Cross 50% of the spread
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 }
 
-// SetParameters updates hedger parameters
+// SetParameters updates the default hedger parameters
 func (gh *PureGammaHedger) SetParameters(deltaThreshold, minHedgeSize, aggressiveness decimal.Decimal, hedgeInterval time.Duration) {
 	gh.mu.Lock()
 	defer gh.mu.Unlock()
@@ -87,11 +125,64 @@ func (gh *PureGammaHedger) SetParameters(deltaThreshold, minHedgeSize, aggressiv
 	gh.hedgeInterval = hedgeInterval
 }
 
+// SetUnderlyingLimits sets the thresholds for one underlying, e.g. a smaller
+// delta threshold for BTC than for SOL
+func (gh *PureGammaHedger) SetUnderlyingLimits(underlying string, limits HedgeLimits) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	gh.limits[strings.ToUpper(underlying)] = limits
+}
+
+// SetHedgeInstrument sets the perp used to hedge its underlying, e.g.
+// ETH_USDC-PERPETUAL on Deribit. Its amounts must be in the underlying.
+// Underlyings without one are hedged with the listed perp found for them.
+func (gh *PureGammaHedger) SetHedgeInstrument(instrument string) error {
+	spec, err := gh.describe(instrument)
+	if err != nil {
+		return err
+	}
+	
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	gh.hedgeInstruments[spec.Base()] = instrument
+	return nil
+}
+
+// SetPricingEngine replaces the engine used for fallback Greeks. Without one
+// they use the position's index price and the default vol.
+func (gh *PureGammaHedger) SetPricingEngine(engine *pricing.Engine) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	gh.pricer = engine
+}
+
+// SetMarketData shares a market data hub with other consumers of the
+// exchange's tickers. Call before Start.
+func (gh *PureGammaHedger) SetMarketData(hub *marketdata.Hub) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	gh.watchlist.Close()
+	gh.marketData = hub
+	gh.watchlist = marketdata.NewWatchlist(hub)
+}
+
 // SetDebugMode enables/disables debug logging
 func (gh *PureGammaHedger) SetDebugMode(debug bool) {
 	gh.debugMode = debug
 }
 
+// Exposures returns the latest net exposure of every underlying
+func (gh *PureGammaHedger) Exposures() []UnderlyingExposure {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+	
+	result := make([]UnderlyingExposure, 0, len(gh.exposures))
+	for _, underlying := range gh.underlyings() {
+		result = append(result, *gh.exposures[underlying])
+	}
+	return result
+}
+
 // Start begins the pure gamma hedging loop
 func (gh *PureGammaHedger) Start() error {
 	gh.mu.Lock()
@@ -100,24 +191,26 @@ func (gh *PureGammaHedger) Start() error {
 		return fmt.Errorf("pure gamma hedger already running")
 	}
 	gh.running = true
+	configured := make([]string, 0, len(gh.hedgeInstruments))
+	for _, instrument := range gh.hedgeInstruments {
+		configured = append(configured, instrument)
+	}
 	gh.mu.Unlock()
 	
 	log.Printf("Starting Pure Gamma Hedger")
 	log.Printf("Configuration:")
-	log.Printf("  Delta Threshold: %s ETH", gh.deltaThreshold.String())
-	log.Printf("  Min Hedge Size: %s ETH", gh.minHedgeSize.String()) 
+	log.Printf("  Delta Threshold: %s", gh.deltaThreshold.String())
+	log.Printf("  Min Hedge Size: %s", gh.minHedgeSize.String()) 
+	for underlying, limits := range gh.limits {
+		log.Printf("  %s: Delta Threshold %s, Min Hedge Size %s", underlying, limits.DeltaThreshold, limits.MinHedgeSize)
+	}
 	log.Printf("  Hedge Interval: %v", gh.hedgeInterval)
 	log.Printf("  Aggressiveness: %s (0=passive, 1=aggressive)", gh.aggressiveness.String())
 	
-	// Subscribe to ETH-PERP orderbook
-	log.Printf("Subscribing to ETH-PERP orderbook...")
-	// Try to cast to derive exchange to access SubscribeOrderBook
-	if deriveExchange, ok := gh.exchange.(*derive.DeriveMarketMakerExchange); ok {
-		if err := deriveExchange.SubscribeOrderBook("ETH-PERP"); err != nil {
-			log.Printf("Warning: Failed to subscribe to ETH-PERP orderbook: %v", err)
-		}
-	} else {
-		log.Printf("Warning: Exchange does not support orderbook subscription")
+	// Subscribe to the configured hedge instruments' orderbooks, others are
+	// subscribed as their underlyings are found
+	for _, instrument := range configured {
+		gh.subscribe(instrument)
 	}
 	
 	// Start hedging loop
@@ -133,6 +226,7 @@ func (gh *PureGammaHedger) Stop() {
 	gh.mu.Unlock()
 	
 	gh.cancel()
+	gh.watchlist.Close()
 	log.Printf("Pure Gamma Hedger stopped")
 }
 
@@ -144,7 +238,6 @@ func (gh *PureGammaHedger) hedgingLoop() {
 	
 	// Initial position load and hedge
 	log.Printf("Starting initial hedge check...")
-	gh.loadPositions()
 	gh.checkAndHedge()
 	
 	ticker := time.NewTicker(gh.hedgeInterval)
@@ -158,70 +251,99 @@ func (gh *PureGammaHedger) hedgingLoop() {
 			return
 		case <-ticker.C:
 			log.Printf("Hedge interval tick - checking positions...")
-			gh.loadPositions()
 			gh.checkAndHedge()
 		}
 	}
 }
 
-// loadPositions loads current options positions from exchange
+// loadPositions loads current positions from exchange, attributing each to
+// its underlying. Exchanges managing several subaccounts report them all, and
+// an instrument held in more than one is hedged on its net quantity.
 func (gh *PureGammaHedger) loadPositions() {
-	positions, err := gh.exchange.GetPositions()
+	var positions []types.ExchangePosition
+	var err error
+	if multi, ok := gh.exchange.(interface {
+		GetAllPositions() ([]types.ExchangePosition, error)
+	}); ok {
+		positions, err = multi.GetAllPositions()
+	} else {
+		positions, err = gh.exchange.GetPositions()
+	}
 	if err != nil {
 		log.Printf("ERROR: Failed to load positions: %v", err)
 		return
 	}
 	
-	gh.mu.Lock()
-	defer gh.mu.Unlock()
-	
-	// Clear old positions
-	gh.positions = make(map[string]*OptionPosition)
-	gh.netDelta = decimal.Zero
-	gh.netGamma = decimal.Zero
-	
-	// Process each position
+	loaded := make(map[string]*OptionPosition, len(positions))
 	for _, pos := range positions {
-		// Create position
+		// Log every position we find
+		log.Printf("Found position: %s, amount=%.4f, avgPrice=%.2f, subaccount=%d", 
+			pos.InstrumentName, pos.Amount, pos.AveragePrice, pos.SubaccountID)
+		
+		if existing, ok := loaded[pos.InstrumentName]; ok {
+			existing.Quantity = existing.Quantity.Add(decimal.NewFromFloat(pos.Amount))
+			continue
+		}
+		
+		spec, err := gh.describe(pos.InstrumentName)
+		if err != nil {
+			log.Printf("Warning: Skipping %s, can't tell its underlying: %v", pos.InstrumentName, err)
+			continue
+		}
+		
 		optPos := &OptionPosition{
 			Instrument:  pos.InstrumentName,
+			Underlying:  spec.Base(),
+			IsOption:    spec.IsOption(),
 			Quantity:    decimal.NewFromFloat(pos.Amount),
 			AvgPrice:    decimal.NewFromFloat(pos.AveragePrice),
 			Delta:       decimal.Zero, // Will be updated from ticker
 			Gamma:       decimal.Zero, // Will be updated from ticker
+			IndexPrice:  decimal.NewFromFloat(pos.IndexPrice),
 			LastUpdated: time.Now(),
 		}
 		
-		// Log every position we find
-		log.Printf("Found position: %s, amount=%.4f, avgPrice=%.2f", 
-			pos.InstrumentName, pos.Amount, pos.AveragePrice)
-		
-		// Check if it's ETH-PERP (our hedge instrument)
-		if pos.InstrumentName == "ETH-PERP" {
-			// Track current hedge position
-			gh.currentHedge = &HedgePosition{
-				Instrument: pos.InstrumentName,
-				Quantity:   optPos.Quantity,
-				AvgPrice:   optPos.AvgPrice,
-				UpdatedAt:  time.Now(),
+		// Perps and futures are delta one per unit of the underlying
+		if !optPos.IsOption {
+			delta, err := deltaOne(spec, optPos.IndexPrice)
+			if err != nil {
+				log.Printf("Warning: Skipping %s: %v", pos.InstrumentName, err)
+				continue
 			}
-			// ETH-PERP has delta of 1
-			optPos.Delta = decimal.NewFromFloat(1.0)
-			optPos.Gamma = decimal.Zero
+			optPos.Delta = delta
 		}
 		
-		// Store position
-		gh.positions[pos.InstrumentName] = optPos
+		loaded[pos.InstrumentName] = optPos
 		
 		if gh.debugMode {
-			log.Printf("[DEBUG] Loaded position: %s, qty=%s", pos.InstrumentName, optPos.Quantity.String())
+			log.Printf("[DEBUG] Loaded position: %s (%s), qty=%s", pos.InstrumentName, optPos.Underlying, optPos.Quantity.String())
 		}
 	}
 	
-	log.Printf("Loaded %d option positions", len(gh.positions))
+	gh.mu.Lock()
+	gh.positions = loaded
+	gh.mu.Unlock()
+	
+	log.Printf("Loaded %d positions", len(loaded))
 }
 
-// checkAndHedge checks if hedging is needed and executes it
+// deltaOne returns a perp or future's delta per unit of amount. Inverse
+// contracts are sized in USD, so their delta depends on the index price.
+func deltaOne(spec *instruments.Instrument, indexPrice decimal.Decimal) (decimal.Decimal, error) {
+	if spec.ContractMultiplier.IsPositive() {
+		return spec.ContractMultiplier, nil
+	}
+	if spec.Exchange == "" {
+		// Parsed from the name alone, assume it is sized in the underlying
+		return decimal.NewFromInt(1), nil
+	}
+	if !indexPrice.IsPositive() {
+		return decimal.Zero, fmt.Errorf("inverse contract without an index price")
+	}
+	return decimal.NewFromInt(1).Div(indexPrice), nil
+}
+
+// checkAndHedge checks every underlying and hedges those that need it
 func (gh *PureGammaHedger) checkAndHedge() {
 	// Load latest positions
 	gh.loadPositions()
@@ -229,29 +351,38 @@ func (gh *PureGammaHedger) checkAndHedge() {
 	// Update Greeks from market data
 	gh.updateGreeks()
 	
-	// Calculate net exposure
 	gh.mu.RLock()
-	netDelta := gh.netDelta
+	underlyings := gh.underlyings()
 	gh.mu.RUnlock()
 	
-	// Check if we have any options positions
-	hasOptions := false
-	for _, pos := range gh.positions {
-		if isOptionInstrument(pos.Instrument) && !pos.Quantity.IsZero() {
-			hasOptions = true
-			break
-		}
+	for _, underlying := range underlyings {
+		gh.hedgeUnderlying(underlying)
 	}
+}
+
+// hedgeUnderlying checks if an underlying needs hedging and executes it
+func (gh *PureGammaHedger) hedgeUnderlying(underlying string) {
+	gh.mu.RLock()
+	exposure := gh.exposures[underlying]
+	netDelta := exposure.NetDelta
+	hasOptions := exposure.HasOptions
+	gh.mu.RUnlock()
+	
+	limits := gh.limitsFor(underlying)
+	instrument := gh.hedgeInstrumentFor(underlying)
+	gh.mu.Lock()
+	exposure.HedgeInstrument = instrument
+	gh.mu.Unlock()
 	
 	// Special case: no options but have perp position - always close it
 	if !hasOptions && !netDelta.IsZero() {
-		log.Printf("No options positions found, closing perp hedge of %s ETH", netDelta.Neg().StringFixed(4))
+		log.Printf("No %s options positions found, closing perp hedge of %s %s", underlying, netDelta.Neg().StringFixed(4), underlying)
 		// Force hedge regardless of threshold
-	} else if netDelta.Abs().LessThan(gh.deltaThreshold) {
+	} else if netDelta.Abs().LessThan(limits.DeltaThreshold) {
 		// Normal threshold check
 		if gh.debugMode {
-			log.Printf("[DEBUG] No hedge needed. Net delta: %s (threshold: %s)", 
-				netDelta.String(), gh.deltaThreshold.String())
+			log.Printf("[DEBUG] No %s hedge needed. Net delta: %s (threshold: %s)", 
+				underlying, netDelta.String(), limits.DeltaThreshold.String())
 		}
 		return
 	}
@@ -260,92 +391,120 @@ func (gh *PureGammaHedger) checkAndHedge() {
 	hedgeSize := netDelta.Neg() // Hedge in opposite direction
 	
 	// Check minimum size (skip for closing positions when no options)
-	if hedgeSize.Abs().LessThan(gh.minHedgeSize) {
+	if hedgeSize.Abs().LessThan(limits.MinHedgeSize) {
 		// Always allow closing positions when no options
 		if !hasOptions && !netDelta.IsZero() {
-			log.Printf("Closing position of %s ETH (below min size %s, but closing allowed)", 
-				hedgeSize.Abs().StringFixed(4), gh.minHedgeSize.StringFixed(4))
+			log.Printf("Closing position of %s %s (below min size %s, but closing allowed)", 
+				hedgeSize.Abs().StringFixed(4), underlying, limits.MinHedgeSize.StringFixed(4))
 		} else {
-			log.Printf("Hedge size %s below minimum %s, skipping", 
-				hedgeSize.Abs().StringFixed(4), gh.minHedgeSize.StringFixed(4))
+			log.Printf("%s hedge size %s below minimum %s, skipping", 
+				underlying, hedgeSize.Abs().StringFixed(4), limits.MinHedgeSize.StringFixed(4))
 			return
 		}
 	}
 	
 	// Execute hedge
-	log.Printf("Executing hedge: Net delta=%s, Hedge size=%s", 
-		netDelta.String(), hedgeSize.String())
+	log.Printf("Executing %s hedge: Net delta=%s, Hedge size=%s", 
+		underlying, netDelta.String(), hedgeSize.String())
 	
 	// Debug: show what we're about to do
 	action := "BUY"
 	if hedgeSize.IsNegative() {
 		action = "SELL"
 	}
-	log.Printf("Action: Will %s %s ETH to hedge", action, hedgeSize.Abs().StringFixed(4))
+	log.Printf("Action: Will %s %s %s of %s to hedge", action, hedgeSize.Abs().StringFixed(4), underlying, instrument)
 	
-	if err := gh.executeHedge(hedgeSize); err != nil {
+	if err := gh.executeHedge(instrument, hedgeSize); err != nil {
 		// Check if it's a minimum size error
 		if strings.Contains(err.Error(), "Order amount must be >") {
-			log.Printf("NOTICE: Cannot close position of %s ETH - exchange minimum is 0.1 ETH", hedgeSize.Abs().StringFixed(4))
+			minimum := gh.hedgeSpec(instrument).MinAmount
+			log.Printf("NOTICE: Cannot close position of %s %s - exchange minimum is %s", hedgeSize.Abs().StringFixed(4), underlying, minimum)
 			log.Printf("NOTICE: Options to handle this position:")
-			log.Printf("  1. Open a larger opposite position (e.g., buy 0.1 ETH), then close the net position")
+			log.Printf("  1. Open a larger opposite position (e.g., buy %s %s), then close the net position", minimum, underlying)
 			log.Printf("  2. Use the exchange web interface which may allow smaller closes")
-			log.Printf("  3. Wait for options positions that require hedging >= 0.1 ETH")
+			log.Printf("  3. Wait for options positions that require hedging >= %s %s", minimum, underlying)
 			// Don't increment failure count for minimum size errors
 			return
 		}
 		
-		gh.consecutiveFails++
-		log.Printf("ERROR: Hedge failed (attempt %d): %v", gh.consecutiveFails, err)
+		gh.mu.Lock()
+		exposure.ConsecutiveFails++
+		fails := exposure.ConsecutiveFails
+		gh.mu.Unlock()
+		log.Printf("ERROR: %s hedge failed (attempt %d): %v", underlying, fails, err)
 		
 		// After 3 failures, try market order
-		if gh.consecutiveFails >= 3 {
-			log.Printf("WARNING: 3 consecutive hedge failures. Attempting MARKET order...")
-			if err := gh.executeMarketHedge(hedgeSize); err != nil {
+		if fails >= 3 {
+			log.Printf("WARNING: 3 consecutive %s hedge failures. Attempting MARKET order...", underlying)
+			if err := gh.executeMarketHedge(instrument, hedgeSize); err != nil {
 				// Check again for minimum size error
 				if strings.Contains(err.Error(), "Order amount must be >") {
-					log.Printf("NOTICE: Market order also below minimum. Position size %s < 0.1 ETH minimum", hedgeSize.Abs().StringFixed(4))
+					log.Printf("NOTICE: Market order also below minimum. Position size %s %s < %s minimum", 
+						hedgeSize.Abs().StringFixed(4), underlying, gh.hedgeSpec(instrument).MinAmount)
 					log.Printf("NOTICE: Manual intervention required - see options above")
 				} else {
 					log.Printf("ERROR: Market hedge also failed: %v", err)
 					log.Printf("CRITICAL: Manual intervention required!")
 				}
 			} else {
-				gh.consecutiveFails = 0
-				gh.lastHedgeTime = time.Now()
+				gh.hedged(exposure)
 				log.Printf("Market hedge successful at %s", time.Now().Format("15:04:05"))
 			}
 		}
 	} else {
-		gh.consecutiveFails = 0
-		gh.lastHedgeTime = time.Now()
-		log.Printf("Hedge successful at %s", time.Now().Format("15:04:05"))
+		gh.hedged(exposure)
+		log.Printf("%s hedge successful at %s", underlying, time.Now().Format("15:04:05"))
 	}
 }
 
-// updateGreeks updates Greeks from market data (ticker updates)
-func (gh *PureGammaHedger) updateGreeks() {
+// hedged records a successful hedge
+func (gh *PureGammaHedger) hedged(exposure *UnderlyingExposure) {
 	gh.mu.Lock()
 	defer gh.mu.Unlock()
-	
-	// Reset totals
-	gh.netDelta = decimal.Zero
-	gh.netGamma = decimal.Zero
-	
-	// Track subtotals for reporting
-	optionsDelta := decimal.Zero
-	perpsDelta := decimal.Zero
-	
-	// Process all positions
+	exposure.ConsecutiveFails = 0
+	exposure.LastHedgeTime = time.Now()
+}
+
+// underlyings lists the underlyings with exposure in a stable order (must be
+// called with lock held)
+func (gh *PureGammaHedger) underlyings() []string {
+	underlyings := make([]string, 0, len(gh.exposures))
+	for underlying := range gh.exposures {
+		underlyings = append(underlyings, underlying)
+	}
+	sort.Strings(underlyings)
+	return underlyings
+}
+
+// limitsFor returns an underlying's thresholds, the defaults unless it has
+// its own
+func (gh *PureGammaHedger) limitsFor(underlying string) HedgeLimits {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+	if limits, ok := gh.limits[underlying]; ok {
+		return limits
+	}
+	return HedgeLimits{DeltaThreshold: gh.deltaThreshold, MinHedgeSize: gh.minHedgeSize}
+}
+
+// updateGreeks updates Greeks from market data (ticker updates) and
+// aggregates them by underlying
+func (gh *PureGammaHedger) updateGreeks() {
+	gh.mu.RLock()
+	positions := make([]*OptionPosition, 0, len(gh.positions))
 	for _, pos := range gh.positions {
-		instrument := pos.Instrument
-		
-		// Handle perps (delta = 1:1)
-		if instrument == "ETH-PERP" {
-			perpsDelta = perpsDelta.Add(pos.Quantity)
-			gh.netDelta = gh.netDelta.Add(pos.Quantity)
+		positions = append(positions, pos)
+	}
+	gh.mu.RUnlock()
+	
+	// Fetch Greeks before taking the lock, this hits the network
+	held := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		if !pos.IsOption {
 			continue
 		}
+		instrument := pos.Instrument
+		held = append(held, instrument)
 		
 		// For options, fetch real-time Greeks via ticker
 		if ticker, err := gh.fetchTicker(instrument); err == nil {
@@ -357,98 +516,236 @@ func (gh *PureGammaHedger) updateGreeks() {
 				pos.Gamma = *ticker.Gamma
 			}
 			pos.LastUpdated = time.Now()
-			
-			// Calculate position Greeks
-			posDelta := pos.Delta.Mul(pos.Quantity)
-			posGamma := pos.Gamma.Mul(pos.Quantity)
-			
-			optionsDelta = optionsDelta.Add(posDelta)
-			gh.netDelta = gh.netDelta.Add(posDelta)
-			gh.netGamma = gh.netGamma.Add(posGamma)
-			
-			if gh.debugMode {
-				log.Printf("[DEBUG] %s: qty=%s, delta=%s, gamma=%s, posDelta=%s", 
-					instrument, pos.Quantity.String(), pos.Delta.String(), 
-					pos.Gamma.String(), posDelta.String())
+		} else {
+			// Fall back to model Greeks if ticker fetch fails
+			log.Printf("Warning: Failed to fetch ticker for %s: %v (using model Greeks)", instrument, err)
+			if modelErr := gh.applyModelGreeks(pos); modelErr != nil {
+				log.Printf("Warning: Failed to price %s: %v (excluding from delta)", instrument, modelErr)
+				pos.Delta = decimal.Zero
+				pos.Gamma = decimal.Zero
+				continue
+			}
+		}
+		
+		if gh.debugMode {
+			log.Printf("[DEBUG] %s: qty=%s, delta=%s, gamma=%s, posDelta=%s", 
+				instrument, pos.Quantity.String(), pos.Delta.String(), 
+				pos.Gamma.String(), pos.Delta.Mul(pos.Quantity).String())
+		}
+	}
+	
+	// Stop streaming tickers for options no longer held
+	gh.watchlist.Retain(held)
+	
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	
+	// Rebuild the exposures, keeping each underlying's hedge history
+	previous := gh.exposures
+	gh.exposures = make(map[string]*UnderlyingExposure)
+	for _, pos := range positions {
+		exposure, ok := gh.exposures[pos.Underlying]
+		if !ok {
+			exposure = &UnderlyingExposure{Underlying: pos.Underlying}
+			if last, ok := previous[pos.Underlying]; ok {
+				exposure.LastHedgeTime = last.LastHedgeTime
+				exposure.ConsecutiveFails = last.ConsecutiveFails
+			}
+			gh.exposures[pos.Underlying] = exposure
+		}
+		
+		posDelta := pos.Delta.Mul(pos.Quantity)
+		exposure.NetDelta = exposure.NetDelta.Add(posDelta)
+		if pos.IsOption {
+			exposure.OptionsDelta = exposure.OptionsDelta.Add(posDelta)
+			exposure.NetGamma = exposure.NetGamma.Add(pos.Gamma.Mul(pos.Quantity))
+			if !pos.Quantity.IsZero() {
+				exposure.HasOptions = true
 			}
 		} else {
-			// Fallback to default Greeks if ticker fetch fails
-			log.Printf("Warning: Failed to fetch ticker for %s: %v (using defaults)", instrument, err)
-			pos.Delta = decimal.NewFromFloat(0.5)
-			pos.Gamma = decimal.NewFromFloat(0.01)
-			
-			posDelta := pos.Delta.Mul(pos.Quantity)
-			posGamma := pos.Gamma.Mul(pos.Quantity)
-			
-			optionsDelta = optionsDelta.Add(posDelta)
-			gh.netDelta = gh.netDelta.Add(posDelta)
-			gh.netGamma = gh.netGamma.Add(posGamma)
-		}
-	}
-	
-	// Check if we have any options positions
-	hasOptions := false
-	for _, pos := range gh.positions {
-		if isOptionInstrument(pos.Instrument) && !pos.Quantity.IsZero() {
-			hasOptions = true
-			break
+			exposure.PerpsDelta = exposure.PerpsDelta.Add(posDelta)
+		}
+		
+		// Track current hedge position
+		if hedge, ok := gh.hedgeInstruments[pos.Underlying]; ok && hedge == pos.Instrument {
+			exposure.HedgeInstrument = hedge
+			exposure.CurrentHedge = &HedgePosition{
+				Instrument: pos.Instrument,
+				Quantity:   pos.Quantity,
+				AvgPrice:   pos.AvgPrice,
+				UpdatedAt:  time.Now(),
+			}
 		}
 	}
 	
-	// Log portfolio Greeks with threshold status
-	if !optionsDelta.IsZero() || !perpsDelta.IsZero() {
+	// Log each underlying's Greeks with threshold status
+	for _, underlying := range gh.underlyings() {
+		exposure := gh.exposures[underlying]
+		if exposure.OptionsDelta.IsZero() && exposure.PerpsDelta.IsZero() {
+			continue
+		}
+		
+		threshold := gh.deltaThreshold
+		if limits, ok := gh.limits[underlying]; ok {
+			threshold = limits.DeltaThreshold
+		}
+		
 		status := "within threshold, no hedge needed"
-		if !hasOptions && !perpsDelta.IsZero() {
+		if !exposure.HasOptions && !exposure.PerpsDelta.IsZero() {
 			status = "no options positions, should close perp hedge"
-		} else if gh.netDelta.Abs().GreaterThanOrEqual(gh.deltaThreshold) {
+		} else if exposure.NetDelta.Abs().GreaterThanOrEqual(threshold) {
 			status = "exceeds threshold, hedging required"
 		}
-		log.Printf("Portfolio Delta: Options=%s, Perps=%s, Total=%s ETH (%s)", 
-			optionsDelta.StringFixed(4), perpsDelta.StringFixed(4), 
-			gh.netDelta.StringFixed(4), status)
+		log.Printf("%s Delta: Options=%s, Perps=%s, Total=%s %s (%s)", 
+			underlying, exposure.OptionsDelta.StringFixed(4), exposure.PerpsDelta.StringFixed(4), 
+			exposure.NetDelta.StringFixed(4), underlying, status)
+	}
+}
+
+// applyModelGreeks sets a position's Greeks from the pricing engine
+func (gh *PureGammaHedger) applyModelGreeks(pos *OptionPosition) error {
+	spec, err := gh.instrumentSpec(pos.Instrument)
+	if err != nil || !spec.IsOption() {
+		if spec, err = instruments.Parse(pos.Instrument); err != nil {
+			return err
+		}
+		if !spec.IsOption() {
+			return fmt.Errorf("not an option instrument: %s", pos.Instrument)
+		}
 	}
 	
-	// If no options positions but we have perp position, keep the actual delta
-	// The hedge calculation will invert it to close the position
-	if !hasOptions && !perpsDelta.IsZero() {
-		// gh.netDelta already contains perpsDelta from the loop above
-		// Don't modify it here - the hedge calculation will handle the inversion
+	valuation, err := gh.pricer.Evaluate(pricing.Option{
+		Instrument: pos.Instrument,
+		Strike:     spec.Strike.InexactFloat64(),
+		Expiry:     spec.Expiry.Unix(),
+		IsPut:      spec.IsPut(),
+		Spot:       pos.IndexPrice.InexactFloat64(),
+	})
+	if err != nil {
+		return err
 	}
+	
+	pos.Delta = decimal.NewFromFloat(valuation.Delta)
+	pos.Gamma = decimal.NewFromFloat(valuation.Gamma)
+	pos.LastUpdated = time.Now()
+	return nil
 }
 
-// fetchTicker fetches real-time ticker data for an instrument
+// fetchTicker returns an option's latest ticker from the market data hub,
+// subscribing to it the first time
 func (gh *PureGammaHedger) fetchTicker(instrument string) (*types.TickerUpdate, error) {
-	// Subscribe to ticker if needed
-	tickerCh, err := gh.exchange.SubscribeTickers(gh.ctx, []string{instrument})
-	if err != nil {
+	gh.mu.RLock()
+	hub, watchlist := gh.marketData, gh.watchlist
+	gh.mu.RUnlock()
+	
+	if err := watchlist.Watch(instrument); err != nil {
 		return nil, fmt.Errorf("failed to subscribe to ticker: %w", err)
 	}
 	
-	// Get the latest ticker update (with timeout)
-	select {
-	case ticker := <-tickerCh:
-		if ticker.Instrument == instrument {
-			return &ticker, nil
+	ctx, cancel := context.WithTimeout(gh.ctx, 2*time.Second)
+	defer cancel()
+	
+	ticker, err := hub.Ticker(ctx, instrument)
+	if err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
+
+// instrumentSpec returns an instrument's metadata when the exchange provides it
+func (gh *PureGammaHedger) instrumentSpec(instrument string) (*instruments.Instrument, error) {
+	source, ok := gh.exchange.(interface {
+		GetInstrument(string) (*instruments.Instrument, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("exchange has no instrument metadata")
+	}
+	return source.GetInstrument(instrument)
+}
+
+// describe returns an instrument's metadata, or its terms parsed from the
+// name when the exchange has no metadata for it
+func (gh *PureGammaHedger) describe(instrument string) (*instruments.Instrument, error) {
+	if spec, err := gh.instrumentSpec(instrument); err == nil {
+		return spec, nil
+	}
+	return instruments.Parse(instrument)
+}
+
+// hedgeInstrumentFor returns the perp an underlying is hedged with: the one
+// set for it, or else the first perp listed for it that is sized in the
+// underlying
+func (gh *PureGammaHedger) hedgeInstrumentFor(underlying string) string {
+	gh.mu.RLock()
+	instrument, ok := gh.hedgeInstruments[underlying]
+	gh.mu.RUnlock()
+	if ok {
+		return instrument
+	}
+	
+	instrument = instruments.PerpName("derive", underlying)
+	for _, candidate := range []string{instruments.PerpName("derive", underlying), instruments.PerpName("deribit", underlying)} {
+		if spec, err := gh.instrumentSpec(candidate); err == nil && spec.Kind == instruments.KindPerp && spec.ContractMultiplier.IsPositive() {
+			instrument = candidate
+			break
 		}
-	case <-time.After(2 * time.Second):
-		return nil, fmt.Errorf("ticker timeout")
 	}
+	log.Printf("Hedging %s with %s", underlying, instrument)
 	
-	return nil, fmt.Errorf("no ticker data received")
+	gh.mu.Lock()
+	gh.hedgeInstruments[underlying] = instrument
+	gh.mu.Unlock()
+	gh.subscribe(instrument)
+	return instrument
+}
+
+// subscribe subscribes to a hedge instrument's orderbook once
+func (gh *PureGammaHedger) subscribe(instrument string) {
+	gh.mu.Lock()
+	if gh.subscribed[instrument] {
+		gh.mu.Unlock()
+		return
+	}
+	gh.subscribed[instrument] = true
+	gh.mu.Unlock()
+	
+	log.Printf("Subscribing to %s orderbook...", instrument)
+	if subscriber, ok := gh.exchange.(interface{ SubscribeOrderBook(string) error }); ok {
+		if err := subscriber.SubscribeOrderBook(instrument); err != nil {
+			log.Printf("Warning: Failed to subscribe to %s orderbook: %v", instrument, err)
+		}
+	} else {
+		log.Printf("Warning: Exchange does not support orderbook subscription")
+	}
+}
+
+// hedgeSpec returns a hedge instrument's metadata, assuming a 0.1 tick and
+// minimum when the exchange can't provide it
+func (gh *PureGammaHedger) hedgeSpec(instrument string) *instruments.Instrument {
+	spec, err := gh.instrumentSpec(instrument)
+	if err != nil {
+		log.Printf("No metadata for %s, assuming 0.1 tick and minimum: %v", instrument, err)
+		return &instruments.Instrument{
+			Name:      instrument,
+			TickSize:  decimal.NewFromFloat(0.1),
+			MinAmount: decimal.NewFromFloat(0.1),
+		}
+	}
+	return spec
 }
 
 // executeHedge places the hedge order
-func (gh *PureGammaHedger) executeHedge(size decimal.Decimal) error {
-	instrument := "ETH-PERP" // Hedge instrument
-	minOrderSize := decimal.NewFromFloat(0.1) // Exchange minimum
+func (gh *PureGammaHedger) executeHedge(instrument string, size decimal.Decimal) error {
+	spec := gh.hedgeSpec(instrument)
+	minOrderSize := spec.MinAmount
 	
 	log.Printf("executeHedge called with size: %s", size.StringFixed(4))
 	
 	// Debug what we're going to do
 	if size.IsPositive() {
-		log.Printf("executeHedge: Need to BUY %s ETH", size.StringFixed(4))
+		log.Printf("executeHedge: Need to BUY %s %s", size.StringFixed(4), instrument)
 	} else {
-		log.Printf("executeHedge: Need to SELL %s ETH", size.Abs().StringFixed(4))
+		log.Printf("executeHedge: Need to SELL %s %s", size.Abs().StringFixed(4), instrument)
 	}
 	
 	// Check if we need to handle minimum size issue
@@ -469,8 +766,8 @@ func (gh *PureGammaHedger) executeHedge(size decimal.Decimal) error {
 		
 		log.Printf("Position size %s is below minimum %s, using increase-then-close strategy", 
 			size.Abs().StringFixed(4), minOrderSize.StringFixed(4))
-		log.Printf("Current actual position: %s ETH", currentPosition.StringFixed(4))
-		return gh.executeMinSizeClose(size, currentPosition, minOrderSize)
+		log.Printf("Current actual position: %s %s", currentPosition.StringFixed(4), instrument)
+		return gh.executeMinSizeClose(instrument, size, currentPosition, minOrderSize)
 	}
 	
 	// Get current orderbook
@@ -522,9 +819,7 @@ func (gh *PureGammaHedger) executeHedge(size decimal.Decimal) error {
 		hedgePrice = bestBid.Add(spread.Mul(gh.aggressiveness))
 	}
 	
-	// Round price to tick size (assume 0.1)
-	tickSize := decimal.NewFromFloat(0.1)
-	hedgePrice = hedgePrice.Div(tickSize).Round(0).Mul(tickSize)
+	hedgePrice = spec.RoundPrice(hedgePrice)
 	
 	log.Printf("Placing hedge order: %s %s %s @ %s", 
 		side, size.String(), instrument, hedgePrice.String())
@@ -533,30 +828,20 @@ func (gh *PureGammaHedger) executeHedge(size decimal.Decimal) error {
 	log.Printf("FINAL CHECK - About to place order: Side=%s, Size=%s", side, size.StringFixed(4))
 	
 	// Place the order
-	orderID, err := gh.exchange.PlaceLimitOrder(instrument, side, hedgePrice, size)
+	orderID, err := gh.exchange.PlaceLimitOrder(instrument, side, hedgePrice, size, types.OrderOptions{Urgent: true})
 	if err != nil {
 		return fmt.Errorf("failed to place order: %w", err)
 	}
 	
 	log.Printf("Hedge order placed: ID=%s", orderID)
 	
-	// Update current hedge position
-	gh.mu.Lock()
-	gh.currentHedge = &HedgePosition{
-		Instrument: instrument,
-		Quantity:   size,
-		AvgPrice:   hedgePrice,
-		UpdatedAt:  time.Now(),
-	}
-	gh.mu.Unlock()
+	gh.recordHedge(instrument, size, hedgePrice)
 	
 	return nil
 }
 
 // executeMarketHedge uses market orders as last resort
-func (gh *PureGammaHedger) executeMarketHedge(size decimal.Decimal) error {
-	instrument := "ETH-PERP"
-	
+func (gh *PureGammaHedger) executeMarketHedge(instrument string, size decimal.Decimal) error {
 	// Get current orderbook
 	orderBook, err := gh.exchange.GetOrderBook(instrument)
 	if err != nil {
@@ -582,48 +867,53 @@ func (gh *PureGammaHedger) executeMarketHedge(size decimal.Decimal) error {
 		return fmt.Errorf("no %s liquidity in orderbook", side)
 	}
 	
-	// Round to tick size
-	tickSize := decimal.NewFromFloat(0.1)
-	marketPrice = marketPrice.Div(tickSize).Round(0).Mul(tickSize)
+	marketPrice = gh.hedgeSpec(instrument).RoundPrice(marketPrice)
 	
 	log.Printf("Placing MARKET order (as aggressive limit): %s %s %s @ %s", 
 		side, size.String(), instrument, marketPrice.String())
 	
 	// Place the order
-	orderID, err := gh.exchange.PlaceLimitOrder(instrument, side, marketPrice, size)
+	orderID, err := gh.exchange.PlaceLimitOrder(instrument, side, marketPrice, size, types.OrderOptions{Urgent: true})
 	if err != nil {
 		return fmt.Errorf("failed to place market order: %w", err)
 	}
 	
 	log.Printf("Market hedge order placed: ID=%s", orderID)
 	
-	// Update hedge position
-	gh.mu.Lock()
-	gh.currentHedge = &HedgePosition{
-		Instrument: instrument,
-		Quantity:   size,
-		AvgPrice:   marketPrice,
-		UpdatedAt:  time.Now(),
-	}
-	gh.mu.Unlock()
+	gh.recordHedge(instrument, size, marketPrice)
 	
 	return nil
 }
 
+// recordHedge tracks the latest hedge order on its underlying's exposure
+func (gh *PureGammaHedger) recordHedge(instrument string, size, price decimal.Decimal) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	for _, exposure := range gh.exposures {
+		if gh.hedgeInstruments[exposure.Underlying] == instrument {
+			exposure.HedgeInstrument = instrument
+			exposure.CurrentHedge = &HedgePosition{
+				Instrument: instrument,
+				Quantity:   size,
+				AvgPrice:   price,
+				UpdatedAt:  time.Now(),
+			}
+		}
+	}
+}
+
 // executeMinSizeClose handles closing positions below minimum size
 // Strategy: First increase position by minimum order size, then close it entirely
 // Example: To close -0.08 ETH position (below 0.1 minimum):
 //   1. Sell 0.1 ETH more to reach -0.18 ETH
 //   2. Buy 0.18 ETH to close entire position
 // This incurs a small spread cost but allows closing positions below exchange minimums
-func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOrderSize decimal.Decimal) error {
-	instrument := "ETH-PERP"
-	
+func (gh *PureGammaHedger) executeMinSizeClose(instrument string, hedgeSize, currentPosition, minOrderSize decimal.Decimal) error {
 	log.Printf("Executing minimum size close strategy")
-	log.Printf("  Current position: %s ETH", currentPosition.StringFixed(4))
-	log.Printf("  Target hedge: %s ETH (to close position)", hedgeSize.StringFixed(4))
+	log.Printf("  Current position: %s %s", currentPosition.StringFixed(4), instrument)
+	log.Printf("  Target hedge: %s %s (to close position)", hedgeSize.StringFixed(4), instrument)
 	
-	// Step 1: Increase position by the minimum order size (0.1 ETH)
+	// Step 1: Increase position by the minimum order size
 	// This will make our position larger than minimum so we can close it
 	newPosition := currentPosition
 	
@@ -633,13 +923,13 @@ func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOr
 		// We're short, need to sell more to make it more negative
 		increaseSide = "sell"
 		newPosition = currentPosition.Sub(minOrderSize) // More negative
-		log.Printf("Step 1: Increasing short position by selling %s ETH (from %s to %s)", 
+		log.Printf("Step 1: Increasing short position by selling %s (from %s to %s)", 
 			minOrderSize.StringFixed(4), currentPosition.StringFixed(4), newPosition.StringFixed(4))
 	} else {
 		// We're long, need to buy more to make it more positive
 		increaseSide = "buy"
 		newPosition = currentPosition.Add(minOrderSize) // More positive
-		log.Printf("Step 1: Increasing long position by buying %s ETH (from %s to %s)", 
+		log.Printf("Step 1: Increasing long position by buying %s (from %s to %s)", 
 			minOrderSize.StringFixed(4), currentPosition.StringFixed(4), newPosition.StringFixed(4))
 	}
 	
@@ -665,13 +955,12 @@ func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOr
 		increasePrice = orderBook.Bids[0].Price.Mul(decimal.NewFromFloat(0.999))
 	}
 	
-	// Round to tick size
-	tickSize := decimal.NewFromFloat(0.1)
-	increasePrice = increasePrice.Div(tickSize).Round(0).Mul(tickSize)
+	spec := gh.hedgeSpec(instrument)
+	increasePrice = spec.RoundPrice(increasePrice)
 	
 	// Place the increase order (minimum size)
 	log.Printf("Placing increase order: %s %s @ %s", increaseSide, minOrderSize.StringFixed(4), increasePrice.StringFixed(2))
-	increaseOrderID, err := gh.exchange.PlaceLimitOrder(instrument, increaseSide, increasePrice, minOrderSize)
+	increaseOrderID, err := gh.exchange.PlaceLimitOrder(instrument, increaseSide, increasePrice, minOrderSize, types.OrderOptions{Urgent: true})
 	if err != nil {
 		return fmt.Errorf("failed to place increase order: %w", err)
 	}
@@ -724,7 +1013,7 @@ func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOr
 		closeSide = "sell" // Sell to close long
 	}
 	
-	log.Printf("Step 2: Closing full position of %s ETH with %s order", closeSize.StringFixed(4), closeSide)
+	log.Printf("Step 2: Closing full position of %s with %s order", closeSize.StringFixed(4), closeSide)
 	
 	// Refresh orderbook
 	orderBook, err = gh.exchange.GetOrderBook(instrument)
@@ -748,11 +1037,14 @@ func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOr
 		closePrice = orderBook.Bids[0].Price.Mul(decimal.NewFromFloat(0.999))
 	}
 	
-	closePrice = closePrice.Div(tickSize).Round(0).Mul(tickSize)
+	closePrice = spec.RoundPrice(closePrice)
 	
-	// Place the close order
+	// Place the close order. Reduce-only so that if the position has changed
+	// since we sized it, the excess is cancelled rather than opening a new
+	// position the other way.
 	log.Printf("Placing close order: %s %s @ %s", closeSide, closeSize.StringFixed(4), closePrice.StringFixed(2))
-	closeOrderID, err := gh.exchange.PlaceLimitOrder(instrument, closeSide, closePrice, closeSize)
+	closeOpts := types.OrderOptions{TimeInForce: types.TimeInForceIOC, ReduceOnly: true, Urgent: true}
+	closeOrderID, err := gh.exchange.PlaceLimitOrder(instrument, closeSide, closePrice, closeSize, closeOpts)
 	if err != nil {
 		return fmt.Errorf("failed to place close order: %w", err)
 	}
@@ -761,17 +1053,11 @@ func (gh *PureGammaHedger) executeMinSizeClose(hedgeSize, currentPosition, minOr
 	log.Printf("Successfully executed minimum size close strategy!")
 	
 	// Calculate the cost of this operation (spread loss on the extra size we had to trade)
-	extraSize := minOrderSize // We traded an extra 0.1 ETH (increased then closed)
+	extraSize := minOrderSize // We traded an extra minimum size (increased then closed)
 	estimatedCost := extraSize.Mul(closePrice.Sub(increasePrice).Abs())
-	log.Printf("Estimated cost of this operation: ~$%s (spread loss on %s ETH extra volume)", 
+	log.Printf("Estimated cost of this operation: ~$%s (spread loss on %s extra volume)", 
 		estimatedCost.StringFixed(2), extraSize.StringFixed(4))
 	
 	return nil
 }
 
-// isOptionInstrument checks if an instrument is an option
-func isOptionInstrument(instrument string) bool {
-	// Simple check - options usually end with -C or -P
-	return len(instrument) > 2 && 
-		(instrument[len(instrument)-2:] == "-C" || instrument[len(instrument)-2:] == "-P")
-}
\ No newline at end of file
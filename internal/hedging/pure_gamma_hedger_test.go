@@ -0,0 +1,127 @@
+package hedging
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// stubExchange serves fixed positions, option deltas and books, and records
+// orders
+type stubExchange struct {
+	types.MarketMakerExchange
+	positions []types.ExchangePosition
+	deltas    map[string]float64
+	orders    []string
+}
+
+func (s *stubExchange) GetPositions() ([]types.ExchangePosition, error) {
+	return s.positions, nil
+}
+
+func (s *stubExchange) SubscribeTickers(ctx context.Context, names []string) (<-chan types.TickerUpdate, error) {
+	ch := make(chan types.TickerUpdate, len(names))
+	for _, name := range names {
+		delta := decimal.NewFromFloat(s.deltas[name])
+		ch <- types.TickerUpdate{Instrument: name, Delta: &delta}
+	}
+	return ch, nil
+}
+
+func (s *stubExchange) GetOrderBook(instrument string) (*types.MarketMakerOrderBook, error) {
+	return &types.MarketMakerOrderBook{
+		Bids: []types.OrderBookLevel{{Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(10)}},
+		Asks: []types.OrderBookLevel{{Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(10)}},
+	}, nil
+}
+
+func (s *stubExchange) PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts types.OrderOptions) (string, error) {
+	s.orders = append(s.orders, fmt.Sprintf("%s %s %s", side, amount, instrument))
+	return fmt.Sprintf("order-%d", len(s.orders)), nil
+}
+
+func (s *stubExchange) GetInstrument(name string) (*instruments.Instrument, error) {
+	spec, err := instruments.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	spec.Exchange = "derive"
+	spec.TickSize = decimal.NewFromFloat(0.1)
+	spec.MinAmount = decimal.NewFromFloat(0.01)
+	spec.ContractMultiplier = decimal.NewFromInt(1)
+	return spec, nil
+}
+
+func TestHedgesEachUnderlyingWithItsPerp(t *testing.T) {
+	exchange := &stubExchange{
+		positions: []types.ExchangePosition{
+			{InstrumentName: "ETH-20250530-3000-C", Amount: 2},
+			{InstrumentName: "ETH-PERP", Amount: -0.2},
+			{InstrumentName: "BTC-20250530-100000-P", Amount: 1},
+			{InstrumentName: "SOL-20250530-150-C", Amount: 10},
+		},
+		deltas: map[string]float64{
+			"ETH-20250530-3000-C":   0.5,
+			"BTC-20250530-100000-P": -0.3,
+			"SOL-20250530-150-C":    0.4,
+		},
+	}
+
+	hedger := NewPureGammaHedger(exchange)
+	hedger.SetUnderlyingLimits("SOL", HedgeLimits{DeltaThreshold: decimal.NewFromInt(5), MinHedgeSize: decimal.NewFromInt(1)})
+	hedger.checkAndHedge()
+
+	exposures := hedger.Exposures()
+	if len(exposures) != 3 {
+		t.Fatalf("exposures = %+v, want BTC, ETH and SOL", exposures)
+	}
+	want := map[string]string{"BTC": "-0.3", "ETH": "0.8", "SOL": "4"}
+	for _, exposure := range exposures {
+		if !exposure.NetDelta.Equal(decimal.RequireFromString(want[exposure.Underlying])) {
+			t.Errorf("%s net delta = %s, want %s", exposure.Underlying, exposure.NetDelta, want[exposure.Underlying])
+		}
+	}
+
+	// SOL is within its own threshold
+	if fmt.Sprint(exchange.orders) != "[buy 0.3 BTC-PERP sell 0.8 ETH-PERP]" {
+		t.Errorf("orders = %v", exchange.orders)
+	}
+}
+
+// multiSubaccountExchange reports positions across subaccounts
+type multiSubaccountExchange struct {
+	*stubExchange
+	all []types.ExchangePosition
+}
+
+func (m *multiSubaccountExchange) GetAllPositions() ([]types.ExchangePosition, error) {
+	return m.all, nil
+}
+
+func TestNetsPositionsAcrossSubaccounts(t *testing.T) {
+	exchange := &multiSubaccountExchange{
+		stubExchange: &stubExchange{
+			deltas: map[string]float64{"ETH-20250530-3000-C": 0.5},
+		},
+		all: []types.ExchangePosition{
+			{InstrumentName: "ETH-20250530-3000-C", Amount: 2, SubaccountID: 1},
+			{InstrumentName: "ETH-20250530-3000-C", Amount: 1, SubaccountID: 2},
+			{InstrumentName: "ETH-PERP", Amount: -0.5, SubaccountID: 2},
+		},
+	}
+
+	hedger := NewPureGammaHedger(exchange)
+	hedger.checkAndHedge()
+
+	exposures := hedger.Exposures()
+	if len(exposures) != 1 || !exposures[0].NetDelta.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("exposures = %+v, want ETH net delta 1", exposures)
+	}
+	if fmt.Sprint(exchange.orders) != "[sell 1 ETH-PERP]" {
+		t.Errorf("orders = %v", exchange.orders)
+	}
+}
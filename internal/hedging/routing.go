@@ -0,0 +1,192 @@
+package hedging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/fees"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// venue is an exchange hedges can be placed on
+type venue struct {
+	name     string
+	exchange types.Exchange
+}
+
+// hedgeRoute is the venue, name and limit price chosen for a hedge
+type hedgeRoute struct {
+	venue      venue
+	instrument string
+	price      decimal.Decimal // Limit price in the venue's units
+	cost       decimal.Decimal // Expected cost per unit in USD, fees included
+	filled     bool            // Whether the book can fill the whole hedge
+	book       *types.CCXTOrderBook
+	usdPerUnit decimal.Decimal // USD value of one unit of the book's prices
+}
+
+// AddVenue adds an exchange option hedges may be routed to, such as Deribit
+// when quoting from Derive. Instrument names are converted to its format.
+func (m *Manager) AddVenue(name string, exchange types.Exchange) {
+	m.venues = append(m.venues, venue{name: name, exchange: exchange})
+}
+
+// RouteRFQ returns the venue a hedge of an RFQ would be routed to if it
+// traded now, without placing anything, so quotes can be priced for it. mid
+// is the mid of the venue's top of book in USD, zero if it is one sided.
+func (m *Manager) RouteRFQ(ctx context.Context, req types.RFQResult) (venue string, mid float64, err error) {
+	strike, _ := decimal.NewFromString(req.Strike)
+	quantity, _ := decimal.NewFromString(req.Quantity)
+	trade := &types.TradeEvent{
+		Instrument: req.Asset,
+		Strike:     strike,
+		Expiry:     req.Expiry,
+		IsPut:      req.IsPut,
+		Quantity:   quantity,
+		IsTakerBuy: req.IsTakerBuy,
+	}
+
+	params, err := m.buildHedgeParams(trade)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to build hedge params: %w", err)
+	}
+	params.preview = true
+
+	route, err := m.route(ctx, trade, params)
+	if err != nil {
+		return "", 0, err
+	}
+
+	book := route.book
+	if len(book.Bids) > 0 && len(book.Asks) > 0 && len(book.Bids[0]) > 0 && len(book.Asks[0]) > 0 {
+		usdPerUnit := route.usdPerUnit
+		if usdPerUnit.IsZero() {
+			// Routed without comparing venues, so in the venue's own units
+			usdPerUnit = decimal.NewFromInt(1)
+			if priceInUnderlying(route.venue.name, params) {
+				usdPerUnit = decimal.NewFromFloat(book.Index)
+			}
+		}
+		mid = (book.Bids[0][0] + book.Asks[0][0]) / 2 * usdPerUnit.InexactFloat64()
+	}
+	return route.venue.name, mid, nil
+}
+
+// route picks the venue expected to fill the hedge most cheaply. Venues whose
+// books can fill the whole hedge are preferred, and ties go to the venue
+// added first. Perps and futures are only hedged on the primary venue.
+func (m *Manager) route(ctx context.Context, trade *types.TradeEvent, params *hedgeParams) (*hedgeRoute, error) {
+	venues := m.venues
+	if !params.inst.IsOption() {
+		venues = venues[:1]
+	}
+
+	var (
+		quotes  []*hedgeRoute
+		index   decimal.Decimal
+		lastErr error
+	)
+	for _, v := range venues {
+		instrument := m.instrumentOn(v, params)
+		book, err := m.getOrderBookWithRetry(ctx, v.exchange, trade, params, instrument)
+		if err != nil {
+			log.Printf("[HedgeManager] No %s book on %s: %v", instrument, v.name, err)
+			lastErr = err
+			continue
+		}
+		if index.IsZero() && book.Index > 0 {
+			index = decimal.NewFromFloat(book.Index)
+		}
+		quotes = append(quotes, &hedgeRoute{venue: v, instrument: instrument, book: book})
+	}
+	if len(quotes) == 0 {
+		return nil, lastErr
+	}
+	if len(quotes) == 1 {
+		// Nothing to compare, so price as a single venue always has
+		only := quotes[0]
+		only.price = m.calculateHedgePrice(only.book, params.isBuy)
+		return only, nil
+	}
+
+	var best *hedgeRoute
+	for _, r := range quotes {
+		levels := r.book.Asks
+		if !params.isBuy {
+			levels = r.book.Bids
+		}
+		avg, worst, filled := sweep(levels, params.quantity)
+		if avg.IsZero() {
+			log.Printf("[HedgeManager] %s book on %s is empty", r.instrument, r.venue.name)
+			continue
+		}
+
+		// Deribit prices its coin-margined options in the underlying
+		usdPerUnit := decimal.NewFromInt(1)
+		if priceInUnderlying(r.venue.name, params) {
+			if !index.IsPositive() {
+				log.Printf("[HedgeManager] No index price to compare %s on %s", r.instrument, r.venue.name)
+				continue
+			}
+			usdPerUnit = index
+		}
+		fee := decimal.Zero
+		if schedule, err := fees.ForExchange(r.venue.name); err == nil {
+			amount := params.quantity.InexactFloat64()
+			total := schedule.OptionFee(true, amount, index.InexactFloat64(), avg.Mul(usdPerUnit).InexactFloat64())
+			fee = decimal.NewFromFloat(total / amount)
+		}
+
+		r.price = worst
+		r.filled = filled
+		r.usdPerUnit = usdPerUnit
+		r.cost = avg.Mul(usdPerUnit).Add(fee)
+		if !params.isBuy {
+			r.cost = avg.Mul(usdPerUnit).Neg().Add(fee)
+		}
+		log.Printf("[HedgeManager] %s on %s: expected cost %s per unit, fills whole hedge: %v",
+			r.instrument, r.venue.name, r.cost.StringFixed(4), r.filled)
+
+		if best == nil || (r.filled && !best.filled) || (r.filled == best.filled && r.cost.LessThan(best.cost)) {
+			best = r
+		}
+	}
+	if best == nil {
+		// No book to compare, so fall back to the primary venue
+		primary := quotes[0]
+		primary.price = m.calculateHedgePrice(primary.book, params.isBuy)
+		return primary, nil
+	}
+	return best, nil
+}
+
+// sweep walks book levels to fill quantity, returning the average and last
+// prices reached and whether the levels hold the whole quantity
+func sweep(levels [][]float64, quantity decimal.Decimal) (avg, worst decimal.Decimal, filled bool) {
+	remaining := quantity
+	notional := decimal.Zero
+	for _, level := range levels {
+		if len(level) < 2 || !remaining.IsPositive() {
+			break
+		}
+		price := decimal.NewFromFloat(level[0])
+		size := decimal.Min(decimal.NewFromFloat(level[1]), remaining)
+		notional = notional.Add(price.Mul(size))
+		remaining = remaining.Sub(size)
+		worst = price
+	}
+	done := quantity.Sub(remaining)
+	if !done.IsPositive() {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return notional.Div(done), worst, !remaining.IsPositive()
+}
+
+// priceInUnderlying reports whether a venue quotes the hedged option in its
+// underlying rather than in USD, as Deribit does for all but USDC options
+func priceInUnderlying(venueName string, params *hedgeParams) bool {
+	return strings.HasPrefix(venueName, "deribit") && params.inst.IsOption() && !strings.Contains(params.inst.Underlying, "_")
+}
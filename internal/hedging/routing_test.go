@@ -0,0 +1,152 @@
+package hedging
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// bookVenue serves one order book by instrument name and records orders
+type bookVenue struct {
+	types.Exchange
+	book   types.CCXTOrderBook
+	asked  []string
+	placed []string
+}
+
+func (v *bookVenue) GetInstrumentOrderBook(instrument string) (types.CCXTOrderBook, error) {
+	v.asked = append(v.asked, instrument)
+	return v.book, nil
+}
+
+func (v *bookVenue) PlaceOrder(conf types.RFQConfirmation, instrument string, cfg interface{}) (string, error) {
+	side := "buy"
+	if conf.IsTakerBuy {
+		side = "sell"
+	}
+	v.placed = append(v.placed, side+" "+conf.Quantity+" "+instrument+" @ "+conf.Price)
+	return fmt.Sprintf("order-%d", len(v.placed)), nil
+}
+
+// positionLog records hedges by venue
+type positionLog map[string]decimal.Decimal
+
+func (p positionLog) RecordHedge(venue, instrument string, isBuy bool, quantity, price decimal.Decimal) {
+	if !isBuy {
+		quantity = quantity.Neg()
+	}
+	p[venue+" "+instrument] = p[venue+" "+instrument].Add(quantity)
+}
+
+func TestRoutesOptionHedgeToCheapestVenue(t *testing.T) {
+	// Derive's ask is $100 with only 1 on offer. Deribit's 0.03 ETH at a
+	// $3000 index is $90 per contract and deep enough for the whole hedge.
+	derive := &bookVenue{book: types.CCXTOrderBook{
+		Asks: [][]float64{{100, 1}, {120, 10}},
+	}}
+	deribit := &bookVenue{book: types.CCXTOrderBook{
+		Asks:  [][]float64{{0.03, 5}},
+		Index: 3000,
+	}}
+
+	manager := NewManager(derive, &config.Config{ExchangeName: "derive"})
+	manager.AddVenue("deribit", deribit)
+	positions := positionLog{}
+	manager.SetPositionRecorder(positions)
+
+	trade := &types.TradeEvent{
+		ID:         "trade-1",
+		Instrument: "ETH-20250530-3000-C",
+		Quantity:   decimal.NewFromInt(2),
+		IsTakerBuy: true,
+		Timestamp:  time.Now(),
+	}
+	if err := manager.ExecuteHedge(context.Background(), trade); err != nil {
+		t.Fatalf("ExecuteHedge: %v", err)
+	}
+
+	if len(derive.asked) != 1 || derive.asked[0] != "ETH-20250530-3000-C" {
+		t.Errorf("Derive books asked = %v", derive.asked)
+	}
+	if len(deribit.asked) != 1 || deribit.asked[0] != "ETH-30MAY25-3000-C" {
+		t.Errorf("Deribit books asked = %v, want the Deribit name", deribit.asked)
+	}
+	if len(derive.placed) != 0 {
+		t.Errorf("Derive orders = %v, want none", derive.placed)
+	}
+	if len(deribit.placed) != 1 || deribit.placed[0] != "buy 2 ETH-30MAY25-3000-C @ 0.03" {
+		t.Errorf("Deribit orders = %v, want a buy of 2 at 0.03", deribit.placed)
+	}
+	if trade.HedgeExchange != "deribit" {
+		t.Errorf("HedgeExchange = %q, want deribit", trade.HedgeExchange)
+	}
+	if got := positions["deribit ETH-30MAY25-3000-C"]; !got.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("positions = %v, want 2 held on Deribit", positions)
+	}
+
+	// Selling into Derive's deeper bid beats Deribit's
+	derive.book = types.CCXTOrderBook{Bids: [][]float64{{95, 10}}}
+	deribit.book = types.CCXTOrderBook{Bids: [][]float64{{0.02, 10}}, Index: 3000}
+	trade = &types.TradeEvent{
+		ID:         "trade-2",
+		Instrument: "ETH-20250530-3000-C",
+		Quantity:   decimal.NewFromInt(1),
+		IsTakerBuy: false,
+		Timestamp:  time.Now(),
+	}
+	if err := manager.ExecuteHedge(context.Background(), trade); err != nil {
+		t.Fatalf("ExecuteHedge: %v", err)
+	}
+	if len(derive.placed) != 1 || derive.placed[0] != "sell 1 ETH-20250530-3000-C @ 95" {
+		t.Errorf("Derive orders = %v, want a sell of 1 at 95", derive.placed)
+	}
+	if trade.HedgeExchange != "derive" {
+		t.Errorf("HedgeExchange = %q, want derive", trade.HedgeExchange)
+	}
+}
+
+func TestRoutesRFQWithoutPlacing(t *testing.T) {
+	derive := &bookVenue{book: types.CCXTOrderBook{
+		Bids: [][]float64{{90, 10}},
+		Asks: [][]float64{{100, 10}},
+	}}
+	deribit := &bookVenue{book: types.CCXTOrderBook{
+		Bids:  [][]float64{{0.02, 10}},
+		Asks:  [][]float64{{0.03, 10}},
+		Index: 3000,
+	}}
+	req := types.RFQResult{
+		Asset:      "ETH-20250530-3000-C",
+		Quantity:   "2",
+		IsTakerBuy: true,
+	}
+
+	// Alone, Derive's mid is taken as it is
+	manager := NewManager(derive, &config.Config{ExchangeName: "derive"})
+	venue, mid, err := manager.RouteRFQ(context.Background(), req)
+	if err != nil {
+		t.Fatalf("RouteRFQ: %v", err)
+	}
+	if venue != "derive" || mid != 95 {
+		t.Errorf("routed to %s at mid %v, want derive at 95", venue, mid)
+	}
+
+	// Deribit's 0.025 ETH mid is $75 at a $3000 index
+	manager.AddVenue("deribit", deribit)
+	venue, mid, err = manager.RouteRFQ(context.Background(), req)
+	if err != nil {
+		t.Fatalf("RouteRFQ: %v", err)
+	}
+	if venue != "deribit" || math.Abs(mid-75) > 1e-9 {
+		t.Errorf("routed to %s at mid %v, want deribit at 75", venue, mid)
+	}
+	if len(derive.placed) != 0 || len(deribit.placed) != 0 {
+		t.Errorf("orders placed: derive %v, deribit %v", derive.placed, deribit.placed)
+	}
+}
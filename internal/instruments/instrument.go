@@ -0,0 +1,78 @@
+package instruments
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Instrument kinds
+const (
+	KindOption = "option"
+	KindPerp   = "perp"
+	KindFuture = "future"
+)
+
+// Instrument is the trading metadata of a listed instrument
+type Instrument struct {
+	Name       string          `json:"name"`
+	Exchange   string          `json:"exchange"`
+	Underlying string          `json:"underlying"` // e.g. ETH
+	Kind       string          `json:"kind"`
+	Strike     decimal.Decimal `json:"strike"`      // Options only
+	Expiry     time.Time       `json:"expiry"`      // Zero for perps
+	OptionType string          `json:"option_type"` // "C" or "P" for options
+	TickSize   decimal.Decimal `json:"tick_size"`
+	MinAmount  decimal.Decimal `json:"min_amount"`
+	AmountStep decimal.Decimal `json:"amount_step"`
+
+	// ContractMultiplier is the underlying per unit of amount. It is zero for
+	// inverse contracts, whose amounts are in USD.
+	ContractMultiplier decimal.Decimal `json:"contract_multiplier"`
+
+	// Extra holds venue specific fields, such as Derive's base asset address
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// IsOption reports whether the instrument is an option
+func (i *Instrument) IsOption() bool {
+	return i.Kind == KindOption
+}
+
+// IsPut reports whether the instrument is a put option
+func (i *Instrument) IsPut() bool {
+	return i.Kind == KindOption && i.OptionType == "P"
+}
+
+// RoundPrice rounds a price to the nearest tick
+func (i *Instrument) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	if !i.TickSize.IsPositive() {
+		return price
+	}
+	return price.Div(i.TickSize).Round(0).Mul(i.TickSize)
+}
+
+// RoundAmount rounds an amount down to a whole number of steps, so an order
+// is never larger than asked for
+func (i *Instrument) RoundAmount(amount decimal.Decimal) decimal.Decimal {
+	step := i.AmountStep
+	if !step.IsPositive() {
+		step = i.MinAmount
+	}
+	if !step.IsPositive() {
+		return amount
+	}
+	return amount.Div(step).Floor().Mul(step)
+}
+
+// Round rounds an order's price and amount, and checks the rounded amount
+// meets the minimum
+func (i *Instrument) Round(price, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	price = i.RoundPrice(price)
+	amount = i.RoundAmount(amount)
+	if i.MinAmount.IsPositive() && amount.LessThan(i.MinAmount) {
+		return price, amount, fmt.Errorf("amount %s below minimum %s for %s", amount, i.MinAmount, i.Name)
+	}
+	return price, amount, nil
+}
@@ -0,0 +1,306 @@
+package instruments
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/config"
+)
+
+// Instrument names on each venue:
+//
+//	Derive:  ETH-20250530-3000-C, ETH-PERP
+//	Deribit: ETH-30MAY25-3000-C, ETH-PERPETUAL, ETH-27JUN25
+//	Rysk:    asset address, strike with 8 decimals and unix expiry
+var (
+	deriveOptionPattern  = regexp.MustCompile(`^([A-Z0-9_]+)-(\d{8})-([0-9.D]+)-([CP])$`)
+	deribitOptionPattern = regexp.MustCompile(`^([A-Z0-9_]+)-(\d{1,2}[A-Z]{3}\d{2})-([0-9.D]+)-([CP])$`)
+	deribitFuturePattern = regexp.MustCompile(`^([A-Z0-9_]+)-(\d{1,2}[A-Z]{3}\d{2})$`)
+)
+
+// optionSettlementHour is the UTC hour options and futures expire on both
+// venues
+const optionSettlementHour = 8
+
+// ryskStrikeScale is the fixed point scale of Rysk strikes. Strikes at or
+// above it are taken to be fixed point, since no listed strike is that large.
+var ryskStrikeScale = decimal.New(1, 8)
+
+var (
+	ryskAssetsMu    sync.RWMutex
+	ryskAssets      = lowerKeys(config.DefaultAssetMapping) // Fallback for every chain
+	ryskChainAssets = map[int]map[string]string{}
+)
+
+// SetRyskAssets sets the asset address to underlying mapping used to resolve
+// Rysk assets on any chain
+func SetRyskAssets(mapping map[string]string) {
+	ryskAssetsMu.Lock()
+	defer ryskAssetsMu.Unlock()
+	ryskAssets = lowerKeys(mapping)
+}
+
+// SetRyskChainAssets sets the asset address to underlying mapping of one
+// chain, such as one loaded from Rysk's market list. It takes precedence over
+// the mapping set by SetRyskAssets.
+func SetRyskChainAssets(chainID int, mapping map[string]string) {
+	ryskAssetsMu.Lock()
+	defer ryskAssetsMu.Unlock()
+	ryskChainAssets[chainID] = lowerKeys(mapping)
+}
+
+// RyskUnderlyings returns every underlying a Rysk asset maps to, sorted
+func RyskUnderlyings() []string {
+	ryskAssetsMu.RLock()
+	defer ryskAssetsMu.RUnlock()
+
+	seen := make(map[string]bool)
+	var underlyings []string
+	add := func(mapping map[string]string) {
+		for _, underlying := range mapping {
+			if !seen[underlying] {
+				seen[underlying] = true
+				underlyings = append(underlyings, underlying)
+			}
+		}
+	}
+	add(ryskAssets)
+	for _, mapping := range ryskChainAssets {
+		add(mapping)
+	}
+	sort.Strings(underlyings)
+	return underlyings
+}
+
+// lowerKeys copies a mapping with its addresses lower cased
+func lowerKeys(mapping map[string]string) map[string]string {
+	lowered := make(map[string]string, len(mapping))
+	for address, underlying := range mapping {
+		lowered[strings.ToLower(address)] = underlying
+	}
+	return lowered
+}
+
+// ResolveUnderlying returns the underlying for a Rysk asset address on any
+// chain, or the asset itself when it is already a symbol such as ETH
+func ResolveUnderlying(asset string) (string, error) {
+	return ResolveRyskAsset(0, asset)
+}
+
+// ResolveRyskAsset returns the underlying for a Rysk asset address on a chain,
+// or the asset itself when it is already a symbol such as ETH. Chain 0
+// searches the mappings of every chain.
+func ResolveRyskAsset(chainID int, asset string) (string, error) {
+	if !strings.HasPrefix(asset, "0x") {
+		if asset == "" {
+			return "", fmt.Errorf("empty asset")
+		}
+		return strings.ToUpper(asset), nil
+	}
+	address := strings.ToLower(asset)
+
+	ryskAssetsMu.RLock()
+	defer ryskAssetsMu.RUnlock()
+	if chainID != 0 {
+		if underlying, ok := ryskChainAssets[chainID][address]; ok {
+			return underlying, nil
+		}
+	} else {
+		for _, mapping := range ryskChainAssets {
+			if underlying, ok := mapping[address]; ok {
+				return underlying, nil
+			}
+		}
+	}
+	if underlying, ok := ryskAssets[address]; ok {
+		return underlying, nil
+	}
+	if chainID != 0 {
+		return "", fmt.Errorf("unknown asset address %s on chain %d", asset, chainID)
+	}
+	return "", fmt.Errorf("unknown asset address: %s", asset)
+}
+
+// NewOption creates an option from its terms
+func NewOption(underlying string, strike decimal.Decimal, expiry time.Time, isPut bool) *Instrument {
+	optionType := "C"
+	if isPut {
+		optionType = "P"
+	}
+	inst := &Instrument{
+		Underlying: underlying,
+		Kind:       KindOption,
+		Strike:     strike,
+		Expiry:     expiry.UTC(),
+		OptionType: optionType,
+	}
+	inst.Name = inst.DeriveName()
+	return inst
+}
+
+// FromRysk creates an option from a Rysk asset address or symbol, strike and
+// unix expiry. The strike may be in Rysk's fixed point or plain.
+func FromRysk(asset, strike string, expiry int64, isPut bool) (*Instrument, error) {
+	underlying, err := ResolveUnderlying(asset)
+	if err != nil {
+		return nil, err
+	}
+	strikeDec, err := decimal.NewFromString(strike)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strike %q: %w", strike, err)
+	}
+	if !strikeDec.IsPositive() {
+		return nil, fmt.Errorf("invalid strike %q", strike)
+	}
+	if strikeDec.GreaterThanOrEqual(ryskStrikeScale) {
+		strikeDec = strikeDec.Div(ryskStrikeScale)
+	}
+	if expiry <= 0 {
+		return nil, fmt.Errorf("invalid expiry %d", expiry)
+	}
+	return NewOption(underlying, strikeDec, time.Unix(expiry, 0), isPut), nil
+}
+
+// Resolve identifies an option from a trade, which names it either by a
+// Derive or Deribit name or by a Rysk asset with separate terms
+func Resolve(instrument, strike string, expiry int64, isPut bool) (*Instrument, error) {
+	if inst, err := Parse(instrument); err == nil {
+		return inst, nil
+	}
+	return FromRysk(instrument, strike, expiry, isPut)
+}
+
+// Parse reads a Derive or Deribit instrument name
+func Parse(name string) (*Instrument, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	if m := deriveOptionPattern.FindStringSubmatch(name); m != nil {
+		date, err := time.Parse("20060102", m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry in %s: %w", name, err)
+		}
+		return parseOption(name, m[1], date, m[3], m[4])
+	}
+	if m := deribitOptionPattern.FindStringSubmatch(name); m != nil {
+		date, err := parseDeribitDate(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry in %s: %w", name, err)
+		}
+		return parseOption(name, m[1], date, m[3], m[4])
+	}
+	if m := deribitFuturePattern.FindStringSubmatch(name); m != nil {
+		date, err := parseDeribitDate(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry in %s: %w", name, err)
+		}
+		return &Instrument{
+			Name:       name,
+			Underlying: m[1],
+			Kind:       KindFuture,
+			Expiry:     date.Add(optionSettlementHour * time.Hour),
+		}, nil
+	}
+	if underlying, ok := strings.CutSuffix(name, "-PERPETUAL"); ok && underlying != "" {
+		return &Instrument{Name: name, Underlying: underlying, Kind: KindPerp}, nil
+	}
+	if underlying, ok := strings.CutSuffix(name, "-PERP"); ok && underlying != "" {
+		return &Instrument{Name: name, Underlying: underlying, Kind: KindPerp}, nil
+	}
+	return nil, fmt.Errorf("unrecognised instrument name: %s", name)
+}
+
+// parseOption builds a parsed option
+func parseOption(name, underlying string, date time.Time, strike, optionType string) (*Instrument, error) {
+	// Deribit writes decimal points in strikes as "d"
+	strikeDec, err := decimal.NewFromString(strings.ReplaceAll(strike, "D", "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid strike in %s: %w", name, err)
+	}
+	inst := NewOption(underlying, strikeDec, date.Add(optionSettlementHour*time.Hour), optionType == "P")
+	inst.Name = name
+	return inst, nil
+}
+
+// parseDeribitDate reads a Deribit date such as 30MAY25
+func parseDeribitDate(s string) (time.Time, error) {
+	// Month names parse case insensitively
+	return time.Parse("2Jan06", s)
+}
+
+// IsOptionName reports whether a Derive or Deribit name is an option
+func IsOptionName(name string) bool {
+	inst, err := Parse(name)
+	return err == nil && inst.IsOption()
+}
+
+// DeriveName formats the instrument's Derive name
+func (i *Instrument) DeriveName() string {
+	switch i.Kind {
+	case KindPerp:
+		return i.Underlying + "-PERP"
+	case KindFuture:
+		return fmt.Sprintf("%s-%s", i.Underlying, i.Expiry.UTC().Format("20060102"))
+	default:
+		return fmt.Sprintf("%s-%s-%s-%s", i.Underlying, i.Expiry.UTC().Format("20060102"), i.Strike.String(), i.OptionType)
+	}
+}
+
+// DeribitName formats the instrument's Deribit name
+func (i *Instrument) DeribitName() string {
+	date := strings.ToUpper(i.Expiry.UTC().Format("2Jan06"))
+	switch i.Kind {
+	case KindPerp:
+		return i.Underlying + "-PERPETUAL"
+	case KindFuture:
+		return fmt.Sprintf("%s-%s", i.Underlying, date)
+	default:
+		strike := strings.ReplaceAll(i.Strike.String(), ".", "d")
+		return fmt.Sprintf("%s-%s-%s-%s", i.Underlying, date, strike, i.OptionType)
+	}
+}
+
+// NameOn formats the instrument's name on an exchange, in Derive's format
+// for exchanges that use it or that aren't known
+func (i *Instrument) NameOn(exchange string) string {
+	if strings.HasPrefix(exchange, "deribit") {
+		return i.DeribitName()
+	}
+	return i.DeriveName()
+}
+
+// Base returns the underlying without any quote currency, e.g. SOL for
+// Deribit's SOL_USDC options
+func (i *Instrument) Base() string {
+	base, _, _ := strings.Cut(i.Underlying, "_")
+	return base
+}
+
+// PerpName returns the name of an underlying's perp on an exchange, sized in
+// the underlying. Deribit's inverse perps are sized in USD, so its linear USDC
+// perps are used there.
+func PerpName(exchange, underlying string) string {
+	if strings.HasPrefix(exchange, "deribit") {
+		return underlying + "_USDC-PERPETUAL"
+	}
+	return underlying + "-PERP"
+}
+
+// RyskStrike returns the option's strike in Rysk's fixed point
+func (i *Instrument) RyskStrike() string {
+	return i.Strike.Mul(ryskStrikeScale).Truncate(0).String()
+}
+
+// ConvertName converts a Derive or Deribit instrument name to an exchange's
+// format
+func ConvertName(name, exchange string) (string, error) {
+	inst, err := Parse(name)
+	if err != nil {
+		return "", err
+	}
+	return inst.NameOn(exchange), nil
+}
@@ -0,0 +1,113 @@
+package instruments
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		derive  string
+		deribit string
+		kind    string
+	}{
+		{"ETH-20250530-3000-C", "ETH-20250530-3000-C", "ETH-30MAY25-3000-C", KindOption},
+		{"BTC-5JUN25-100000-P", "BTC-20250605-100000-P", "BTC-5JUN25-100000-P", KindOption},
+		{"XRP-30MAY25-2d5-C", "XRP-20250530-2.5-C", "XRP-30MAY25-2d5-C", KindOption},
+		{"ETH-PERPETUAL", "ETH-PERP", "ETH-PERPETUAL", KindPerp},
+		{"BTC-PERP", "BTC-PERP", "BTC-PERPETUAL", KindPerp},
+		{"ETH-27JUN25", "ETH-20250627", "ETH-27JUN25", KindFuture},
+	}
+
+	for _, tt := range tests {
+		inst, err := Parse(tt.name)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.name, err)
+			continue
+		}
+		if inst.Kind != tt.kind || inst.DeriveName() != tt.derive || inst.DeribitName() != tt.deribit {
+			t.Errorf("Parse(%q) = %s %s / %s, want %s %s / %s",
+				tt.name, inst.Kind, inst.DeriveName(), inst.DeribitName(), tt.kind, tt.derive, tt.deribit)
+		}
+	}
+
+	inst, _ := Parse("ETH-30MAY25-3000-C")
+	if want := time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC); !inst.Expiry.Equal(want) || inst.Underlying != "ETH" || inst.IsPut() {
+		t.Errorf("parsed terms = %s %s put=%v", inst.Underlying, inst.Expiry, inst.IsPut())
+	}
+
+	for _, name := range []string{"ETH", "", "ETH-2025-3000-C", "ETH-30XXX25-3000-C"} {
+		if _, err := Parse(name); err == nil {
+			t.Errorf("Parse(%q) should fail", name)
+		}
+	}
+}
+
+func TestFromRysk(t *testing.T) {
+	expiry := time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC).Unix()
+
+	inst, err := FromRysk("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "300000000000", expiry, true)
+	if err != nil {
+		t.Fatalf("FromRysk failed: %v", err)
+	}
+	if inst.DeriveName() != "ETH-20250530-3000-P" || inst.DeribitName() != "ETH-30MAY25-3000-P" {
+		t.Errorf("names = %s / %s", inst.DeriveName(), inst.DeribitName())
+	}
+	if inst.RyskStrike() != "300000000000" {
+		t.Errorf("RyskStrike = %s", inst.RyskStrike())
+	}
+
+	// Lower case addresses, symbols and plain strikes resolve too
+	if inst, err := FromRysk("0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", "3000", expiry, false); err != nil || inst.NameOn("deribit") != "ETH-30MAY25-3000-C" {
+		t.Errorf("FromRysk lower case = %v, %v", inst, err)
+	}
+	if inst, err := FromRysk("btc", "100000", expiry, false); err != nil || inst.NameOn("derive") != "BTC-20250530-100000-C" {
+		t.Errorf("FromRysk symbol = %v, %v", inst, err)
+	}
+
+	if _, err := FromRysk("0x0000000000000000000000000000000000000001", "3000", expiry, false); err == nil {
+		t.Error("expected error for unknown asset address")
+	}
+}
+
+func TestPerpName(t *testing.T) {
+	if got := PerpName("derive", "SOL"); got != "SOL-PERP" {
+		t.Errorf("Derive perp = %s", got)
+	}
+	if got := PerpName("deribit_testnet", "BTC"); got != "BTC_USDC-PERPETUAL" {
+		t.Errorf("Deribit perp = %s", got)
+	}
+
+	inst, _ := Parse("SOL_USDC-30MAY25-150-C")
+	if inst.Base() != "SOL" {
+		t.Errorf("Base = %s, want SOL", inst.Base())
+	}
+}
+
+func TestResolveRyskAssetPerChain(t *testing.T) {
+	SetRyskChainAssets(84532, map[string]string{"0xB5A30B0FDC5EA94A52FDC42E3E9760CB8449FB37": "ETH"})
+	SetRyskChainAssets(998, map[string]string{"0x5555555555555555555555555555555555555555": "HYPE"})
+	defer SetRyskChainAssets(84532, nil)
+	defer SetRyskChainAssets(998, nil)
+
+	if got, err := ResolveRyskAsset(84532, "0xb5a30b0fdc5ea94a52fdc42e3e9760cb8449fb37"); err != nil || got != "ETH" {
+		t.Errorf("ResolveRyskAsset(84532) = %q, %v", got, err)
+	}
+	if _, err := ResolveRyskAsset(84532, "0x5555555555555555555555555555555555555555"); err == nil {
+		t.Error("expected another chain's asset not to resolve")
+	}
+	if got, err := ResolveUnderlying("0x5555555555555555555555555555555555555555"); err != nil || got != "HYPE" {
+		t.Errorf("ResolveUnderlying = %q, %v, want any chain's asset", got, err)
+	}
+
+	// The default mapping still applies on every chain
+	if got, err := ResolveRyskAsset(998, "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"); err != nil || got != "ETH" {
+		t.Errorf("ResolveRyskAsset default = %q, %v", got, err)
+	}
+
+	underlyings := RyskUnderlyings()
+	if len(underlyings) != 2 || underlyings[0] != "ETH" || underlyings[1] != "HYPE" {
+		t.Errorf("RyskUnderlyings = %v", underlyings)
+	}
+}
@@ -0,0 +1,250 @@
+package instruments
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Loader fetches every instrument listed on an exchange
+type Loader func() ([]Instrument, error)
+
+// Cache persists loaded instruments between runs. cache.MarketCache
+// implementations satisfy it.
+type Cache interface {
+	SetMarkets(exchange string, markets interface{}, ttl time.Duration) error
+	GetMarkets(exchange string, v interface{}) error
+}
+
+// reloadInterval limits how often a lookup miss reloads an exchange's
+// instruments, so unknown names don't hammer the API
+const reloadInterval = time.Minute
+
+// Registry serves instrument metadata for every registered exchange. Each
+// exchange's instruments are loaded on first use, from the cache when it holds
+// them, and reloaded when a lookup misses so new listings are picked up.
+type Registry struct {
+	mu          sync.Mutex
+	loading     map[string]*sync.Mutex // Serializes each exchange's loads
+	loaders     map[string]Loader
+	instruments map[string]map[string]*Instrument // By exchange, then name
+	loadedAt    map[string]time.Time
+	cache       Cache
+	ttl         time.Duration
+}
+
+// Default is the registry shared by the exchange adapters and strategies
+var Default = NewRegistry(nil, 0)
+
+// NewRegistry creates a registry caching instruments in cache for ttl. The
+// cache may be nil.
+func NewRegistry(cache Cache, ttl time.Duration) *Registry {
+	return &Registry{
+		loading:     make(map[string]*sync.Mutex),
+		loaders:     make(map[string]Loader),
+		instruments: make(map[string]map[string]*Instrument),
+		loadedAt:    make(map[string]time.Time),
+		cache:       cache,
+		ttl:         ttl,
+	}
+}
+
+// SetCache sets the cache loaded instruments are kept in
+func (r *Registry) SetCache(cache Cache, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = cache
+	r.ttl = ttl
+}
+
+// Register sets the loader for an exchange, replacing any earlier one
+func (r *Registry) Register(exchange string, loader Loader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaders[exchange] = loader
+}
+
+// Add adds instruments directly, for exchanges without a loader and tests
+func (r *Registry) Add(exchange string, list ...Instrument) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(exchange, list)
+}
+
+// Get returns an instrument's metadata
+func (r *Registry) Get(exchange, name string) (*Instrument, error) {
+	inst, reload, _ := r.lookup(exchange, name)
+	if inst != nil {
+		return inst, nil
+	}
+	if !reload {
+		return nil, fmt.Errorf("unknown %s instrument: %s", exchange, name)
+	}
+
+	guard := r.loadGuard(exchange)
+	guard.Lock()
+	defer guard.Unlock()
+
+	// Another lookup may have loaded the exchange while this one waited
+	inst, reload, firstUse := r.lookup(exchange, name)
+	if inst != nil {
+		return inst, nil
+	}
+	if !reload {
+		return nil, fmt.Errorf("unknown %s instrument: %s", exchange, name)
+	}
+
+	// Load on first use, or reload in case it was listed since
+	cached, err := r.load(exchange, firstUse)
+	if err != nil {
+		return nil, err
+	}
+	if inst := r.find(exchange, name); inst != nil {
+		return inst, nil
+	}
+	if cached {
+		// The cache may predate the listing
+		if _, err := r.load(exchange, false); err != nil {
+			return nil, err
+		}
+		if inst := r.find(exchange, name); inst != nil {
+			return inst, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown %s instrument: %s", exchange, name)
+}
+
+// List returns every instrument on an exchange
+func (r *Registry) List(exchange string) ([]*Instrument, error) {
+	if !r.loaded(exchange) {
+		guard := r.loadGuard(exchange)
+		guard.Lock()
+		if !r.loaded(exchange) {
+			if _, err := r.load(exchange, true); err != nil {
+				guard.Unlock()
+				return nil, err
+			}
+		}
+		guard.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]*Instrument, 0, len(r.instruments[exchange]))
+	for _, inst := range r.instruments[exchange] {
+		list = append(list, inst)
+	}
+	return list, nil
+}
+
+// lookup finds a loaded instrument or, when it isn't loaded, reports whether
+// the exchange may be (re)loaded to look for it and whether it ever was
+func (r *Registry) lookup(exchange, name string) (inst *Instrument, reload, firstUse bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if inst, ok := r.instruments[exchange][name]; ok {
+		return inst, false, false
+	}
+	loadedAt := r.loadedAt[exchange]
+	if loadedAt.IsZero() {
+		return nil, true, true
+	}
+	return nil, time.Since(loadedAt) >= reloadInterval, false
+}
+
+// find returns a loaded instrument, or nil
+func (r *Registry) find(exchange, name string) *Instrument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.instruments[exchange][name]
+}
+
+// loaded reports whether an exchange's instruments were ever loaded
+func (r *Registry) loaded(exchange string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.loadedAt[exchange].IsZero()
+}
+
+// loadGuard returns the mutex serializing an exchange's loads, so concurrent
+// misses wait for one load instead of each calling the API
+func (r *Registry) loadGuard(exchange string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	guard, ok := r.loading[exchange]
+	if !ok {
+		guard = &sync.Mutex{}
+		r.loading[exchange] = guard
+	}
+	return guard
+}
+
+// load fetches an exchange's instruments, trying the cache first if useCache
+// is set, and reports whether they came from the cache. The cache and loader
+// are called without the lock held, so a slow exchange doesn't block lookups
+// on the others (must be called with the exchange's load guard held).
+func (r *Registry) load(exchange string, useCache bool) (bool, error) {
+	r.mu.Lock()
+	loader, ok := r.loaders[exchange]
+	cache, ttl := r.cache, r.ttl
+	r.mu.Unlock()
+
+	key := exchange + "_instruments"
+	var list []Instrument
+
+	if useCache && cache != nil {
+		if err := cache.GetMarkets(key, &list); err == nil && len(list) > 0 {
+			r.mu.Lock()
+			r.store(exchange, list)
+			r.mu.Unlock()
+			return true, nil
+		}
+	}
+
+	if !ok {
+		r.mu.Lock()
+		r.loadedAt[exchange] = time.Now()
+		r.mu.Unlock()
+		return false, fmt.Errorf("no instrument loader for %s", exchange)
+	}
+
+	list, err := loader()
+	r.mu.Lock()
+	r.loadedAt[exchange] = time.Now()
+	if err == nil {
+		r.store(exchange, list)
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return false, fmt.Errorf("failed to load %s instruments: %w", exchange, err)
+	}
+	log.Printf("[Instruments] Loaded %d %s instruments", len(list), exchange)
+
+	if cache != nil {
+		if err := cache.SetMarkets(key, list, ttl); err != nil {
+			log.Printf("[Instruments] Failed to cache %s instruments: %v", exchange, err)
+		}
+	}
+	return false, nil
+}
+
+// store indexes instruments by name (must be called with lock held)
+func (r *Registry) store(exchange string, list []Instrument) {
+	byName := r.instruments[exchange]
+	if byName == nil {
+		byName = make(map[string]*Instrument, len(list))
+		r.instruments[exchange] = byName
+	}
+	for i := range list {
+		inst := list[i]
+		if inst.Exchange == "" {
+			inst.Exchange = exchange
+		}
+		byName[inst.Name] = &inst
+	}
+	if r.loadedAt[exchange].IsZero() {
+		r.loadedAt[exchange] = time.Now()
+	}
+}
@@ -0,0 +1,133 @@
+package instruments
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// memoryCache is a MarketCache kept in memory
+type memoryCache map[string][]byte
+
+func (c memoryCache) SetMarkets(exchange string, markets interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(markets)
+	c[exchange] = data
+	return err
+}
+
+func (c memoryCache) GetMarkets(exchange string, v interface{}) error {
+	data, ok := c[exchange]
+	if !ok {
+		return fmt.Errorf("cache miss")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func TestRound(t *testing.T) {
+	inst := &Instrument{Name: "ETH-PERP", TickSize: d("0.01"), MinAmount: d("0.1"), AmountStep: d("0.01")}
+
+	price, amount, err := inst.Round(d("2500.126"), d("0.1299"))
+	if err != nil || !price.Equal(d("2500.13")) || !amount.Equal(d("0.12")) {
+		t.Errorf("Round = %s, %s, %v; want 2500.13, 0.12", price, amount, err)
+	}
+
+	if _, _, err := inst.Round(d("2500"), d("0.0999")); err == nil {
+		t.Error("expected error below minimum amount")
+	}
+}
+
+func TestRegistryLoadsAndCaches(t *testing.T) {
+	loads := 0
+	listed := []Instrument{{Name: "ETH-PERP", Kind: KindPerp, TickSize: d("0.01")}}
+	loader := func() ([]Instrument, error) {
+		loads++
+		return listed, nil
+	}
+
+	store := memoryCache{}
+	r := NewRegistry(store, time.Hour)
+	r.Register("derive", loader)
+
+	inst, err := r.Get("derive", "ETH-PERP")
+	if err != nil || inst.Exchange != "derive" || !inst.TickSize.Equal(d("0.01")) {
+		t.Fatalf("Get = %+v, %v", inst, err)
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+
+	// Misses don't reload more than once per interval
+	if _, err := r.Get("derive", "BTC-PERP"); err == nil || loads != 1 {
+		t.Errorf("expected a miss without reloading, got %v after %d loads", err, loads)
+	}
+
+	// A new registry starts from the cache, and reloads when the cache is
+	// missing a new listing
+	listed = append(listed, Instrument{Name: "BTC-PERP", Kind: KindPerp})
+	r = NewRegistry(store, time.Hour)
+	r.Register("derive", loader)
+	if _, err := r.Get("derive", "ETH-PERP"); err != nil || loads != 1 {
+		t.Errorf("expected cached ETH-PERP, got %v after %d loads", err, loads)
+	}
+
+	r = NewRegistry(store, time.Hour)
+	r.Register("derive", loader)
+	if _, err := r.Get("derive", "BTC-PERP"); err != nil || loads != 2 {
+		t.Errorf("expected BTC-PERP after reloading, got %v after %d loads", err, loads)
+	}
+
+	if _, err := r.Get("deribit", "ETH-PERPETUAL"); err == nil {
+		t.Error("expected error for exchange without a loader")
+	}
+}
+
+func TestRegistryLoadsOutsideItsLock(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int32
+	r := NewRegistry(nil, 0)
+	r.Register("derive", func() ([]Instrument, error) {
+		loads.Add(1)
+		<-release
+		return []Instrument{{Name: "ETH-PERP", Kind: KindPerp}}, nil
+	})
+	r.Add("deribit", Instrument{Name: "ETH-PERPETUAL", Kind: KindPerp})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Get("derive", "ETH-PERP"); err != nil {
+				t.Errorf("Get: %v", err)
+			}
+		}()
+	}
+
+	// Other exchanges are served while Derive's load is blocked
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Get("deribit", "ETH-PERPETUAL")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Get deribit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get for another exchange blocked on a slow load")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1 for concurrent misses", n)
+	}
+}
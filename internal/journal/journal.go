@@ -0,0 +1,298 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Entry is one line of the journal: a trade as it stood after a state
+// transition
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Trade Trade     `json:"trade"`
+}
+
+// Trade is the journaled form of a types.TradeEvent
+type Trade struct {
+	ID            string                `json:"id"`
+	Source        types.TradeSourceType `json:"source"`
+	Status        types.TradeStatus     `json:"status"`
+	RFQId         string                `json:"rfq_id,omitempty"`
+	Instrument    string                `json:"instrument"`
+	Strike        decimal.Decimal       `json:"strike"`
+	Expiry        int64                 `json:"expiry"`
+	IsPut         bool                  `json:"is_put"`
+	Quantity      decimal.Decimal       `json:"quantity"`
+	Price         decimal.Decimal       `json:"price"`
+	IsTakerBuy    bool                  `json:"is_taker_buy"`
+	Timestamp     time.Time             `json:"timestamp"`
+	HedgeOrderID  string                `json:"hedge_order_id,omitempty"`
+	HedgeExchange string                `json:"hedge_exchange,omitempty"`
+	HedgeQuantity decimal.Decimal       `json:"hedge_quantity"`
+	HedgePrice    decimal.Decimal       `json:"hedge_price"`
+	ExpectedFee   decimal.Decimal       `json:"expected_fee"`
+	NetEdge       decimal.Decimal       `json:"net_edge"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// FromEvent converts a trade event for the journal
+func FromEvent(trade *types.TradeEvent) Trade {
+	t := Trade{
+		ID:            trade.ID,
+		Source:        trade.Source,
+		Status:        trade.Status,
+		RFQId:         trade.RFQId,
+		Instrument:    trade.Instrument,
+		Strike:        trade.Strike,
+		Expiry:        trade.Expiry,
+		IsPut:         trade.IsPut,
+		Quantity:      trade.Quantity,
+		Price:         trade.Price,
+		IsTakerBuy:    trade.IsTakerBuy,
+		Timestamp:     trade.Timestamp,
+		HedgeOrderID:  trade.HedgeOrderID,
+		HedgeExchange: trade.HedgeExchange,
+		HedgeQuantity: trade.HedgeQuantity,
+		HedgePrice:    trade.HedgePrice,
+		ExpectedFee:   trade.ExpectedFee,
+		NetEdge:       trade.NetEdge,
+	}
+	if trade.Error != nil {
+		t.Error = trade.Error.Error()
+	}
+	return t
+}
+
+// Event converts a journaled trade back to a trade event
+func (t Trade) Event() types.TradeEvent {
+	event := types.TradeEvent{
+		ID:            t.ID,
+		Source:        t.Source,
+		Status:        t.Status,
+		RFQId:         t.RFQId,
+		Instrument:    t.Instrument,
+		Strike:        t.Strike,
+		Expiry:        t.Expiry,
+		IsPut:         t.IsPut,
+		Quantity:      t.Quantity,
+		Price:         t.Price,
+		IsTakerBuy:    t.IsTakerBuy,
+		Timestamp:     t.Timestamp,
+		HedgeOrderID:  t.HedgeOrderID,
+		HedgeExchange: t.HedgeExchange,
+		HedgeQuantity: t.HedgeQuantity,
+		HedgePrice:    t.HedgePrice,
+		ExpectedFee:   t.ExpectedFee,
+		NetEdge:       t.NetEdge,
+	}
+	if t.Error != "" {
+		event.Error = errors.New(t.Error)
+	}
+	return event
+}
+
+// Unhedged reports whether a trade still needs processing or hedging. Failed
+// hedges are left for review rather than retried.
+func (t Trade) Unhedged() bool {
+	return t.Status == types.TradeStatusPending || t.Status == types.TradeStatusExecuted
+}
+
+// Query selects trades by when they happened and their latest status. Zero
+// fields match everything.
+type Query struct {
+	From   time.Time
+	To     time.Time
+	Status types.TradeStatus
+	Limit  int
+}
+
+// matches reports whether a trade's latest state matches the query
+func (q Query) matches(t Trade) bool {
+	if !q.From.IsZero() && t.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && t.Timestamp.After(q.To) {
+		return false
+	}
+	return q.Status == "" || t.Status == q.Status
+}
+
+// Journal is an append-only record of every trade state transition. A journal
+// backed by a file is replayed when opened, so trades survive restarts.
+type Journal struct {
+	mu      sync.RWMutex
+	file    *os.File
+	enc     *json.Encoder
+	latest  map[string]Trade   // Latest state of each trade
+	history map[string][]Entry // Transitions of each trade, oldest first
+	order   []string           // Trade IDs, in the order first journaled
+}
+
+// New creates a journal kept in memory only
+func New() *Journal {
+	return &Journal{
+		latest:  make(map[string]Trade),
+		history: make(map[string][]Entry),
+	}
+}
+
+// Open replays the journal at path, creating it if needed, and appends new
+// transitions to it. A partly written last line, as a crash can leave, is
+// skipped.
+func Open(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	j := New()
+	if err := j.replay(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Start appending on a fresh line after a partly written one
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to write journal: %w", err)
+			}
+		}
+	}
+	j.file = f
+	j.enc = json.NewEncoder(f)
+	return j, nil
+}
+
+// replay loads the entries in r
+func (j *Journal) replay(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("[Journal] Skipping unreadable line %d: %v", line, err)
+			continue
+		}
+		j.apply(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	if line > 0 {
+		log.Printf("[Journal] Replayed %d transitions of %d trades", line, len(j.latest))
+	}
+	return nil
+}
+
+// apply indexes an entry. Callers hold j.mu.
+func (j *Journal) apply(entry Entry) {
+	id := entry.Trade.ID
+	if _, ok := j.latest[id]; !ok {
+		j.order = append(j.order, id)
+	}
+	j.latest[id] = entry.Trade
+	j.history[id] = append(j.history[id], entry)
+}
+
+// Record appends a trade's current state, syncing it to disk before returning
+func (j *Journal) Record(trade *types.TradeEvent) error {
+	entry := Entry{Time: time.Now(), Trade: FromEvent(trade)}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.enc != nil {
+		if err := j.enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write journal: %w", err)
+		}
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync journal: %w", err)
+		}
+	}
+	j.apply(entry)
+	return nil
+}
+
+// Trade returns a trade's latest state
+func (j *Journal) Trade(id string) (types.TradeEvent, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	t, ok := j.latest[id]
+	if !ok {
+		return types.TradeEvent{}, false
+	}
+	return t.Event(), true
+}
+
+// History returns a trade's transitions, oldest first
+func (j *Journal) History(id string) []Entry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return append([]Entry(nil), j.history[id]...)
+}
+
+// Trades returns the latest state of the trades matching q, newest first
+func (j *Journal) Trades(q Query) []Trade {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var trades []Trade
+	for _, id := range j.order {
+		if t := j.latest[id]; q.matches(t) {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(a, b int) bool {
+		return trades[a].Timestamp.After(trades[b].Timestamp)
+	})
+	if q.Limit > 0 && len(trades) > q.Limit {
+		trades = trades[:q.Limit]
+	}
+	return trades
+}
+
+// Unhedged returns trades that were still being processed or hedged when
+// last journaled, oldest first
+func (j *Journal) Unhedged() []types.TradeEvent {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var trades []types.TradeEvent
+	for _, id := range j.order {
+		if t := j.latest[id]; t.Unhedged() {
+			trades = append(trades, t.Event())
+		}
+	}
+	return trades
+}
+
+// Close closes the journal's file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file, j.enc = nil, nil
+	return err
+}
@@ -0,0 +1,104 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestJournalReplaysTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.jsonl")
+	j, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	hedged := &types.TradeEvent{
+		ID:        "hedged",
+		Source:    types.TradeSourceRysk,
+		Status:    types.TradeStatusPending,
+		Quantity:  decimal.NewFromInt(2),
+		Timestamp: start,
+	}
+	failed := &types.TradeEvent{ID: "failed", Source: types.TradeSourceManual, Status: types.TradeStatusPending, Timestamp: start.Add(time.Minute)}
+	inFlight := &types.TradeEvent{ID: "in-flight", Source: types.TradeSourceRysk, Status: types.TradeStatusPending, Timestamp: start.Add(2 * time.Minute)}
+
+	for _, trade := range []*types.TradeEvent{hedged, failed, inFlight} {
+		if err := j.Record(trade); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	hedged.Status = types.TradeStatusExecuted
+	j.Record(hedged)
+	hedged.Status = types.TradeStatusHedged
+	hedged.HedgeOrderID = "order-1"
+	hedged.HedgeExchange = "derive"
+	j.Record(hedged)
+	failed.Status = types.TradeStatusFailed
+	failed.Error = errors.New("no liquidity")
+	j.Record(failed)
+	inFlight.Status = types.TradeStatusExecuted
+	j.Record(inFlight)
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A crash mid-write leaves a partial line, which is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2025-05-01T12:05:00Z","trade":{"id":"torn"`)
+	f.Close()
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer j.Close()
+
+	trade, ok := j.Trade("hedged")
+	if !ok || trade.Status != types.TradeStatusHedged || trade.HedgeOrderID != "order-1" || !trade.Quantity.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("replayed trade = %+v", trade)
+	}
+	if history := j.History("hedged"); len(history) != 3 {
+		t.Errorf("got %d transitions, want 3", len(history))
+	}
+	if trade, _ := j.Trade("failed"); trade.Error == nil || trade.Error.Error() != "no liquidity" {
+		t.Errorf("failed trade error = %v", trade.Error)
+	}
+	if _, ok := j.Trade("torn"); ok {
+		t.Error("partial line was replayed")
+	}
+
+	unhedged := j.Unhedged()
+	if len(unhedged) != 1 || unhedged[0].ID != "in-flight" {
+		t.Errorf("unhedged = %+v, want only in-flight", unhedged)
+	}
+
+	// Appends after the partial line still replay
+	j.Record(&types.TradeEvent{ID: "after", Status: types.TradeStatusPending, Timestamp: start.Add(10 * time.Minute)})
+	j.Close()
+	j, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, ok := j.Trade("after"); !ok {
+		t.Error("trade journaled after a partial line was lost")
+	}
+
+	trades := j.Trades(Query{From: start.Add(30 * time.Second), Status: types.TradeStatusFailed})
+	if len(trades) != 1 || trades[0].ID != "failed" {
+		t.Errorf("failed since 12:00:30 = %+v", trades)
+	}
+	trades = j.Trades(Query{Limit: 2})
+	if len(trades) != 2 || trades[0].ID != "after" || trades[1].ID != "in-flight" {
+		t.Errorf("latest two = %+v, want after and in-flight", trades)
+	}
+}
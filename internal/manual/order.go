@@ -18,6 +18,7 @@ type OrderConfig struct {
 	Side       string
 	Price      float64
 	Amount     float64
+	Options    types.OrderOptions
 }
 
 // OrderService handles manual order placement
@@ -60,7 +61,7 @@ func (s *OrderService) PlaceOrder(orderCfg OrderConfig) (string, error) {
 	log.Printf("Placing %s order: %s %s @ %s", orderCfg.Side, amount, orderCfg.Instrument, price)
 	
 	// Place the order
-	orderID, err := s.exchange.PlaceLimitOrder(orderCfg.Instrument, orderCfg.Side, price, amount)
+	orderID, err := s.exchange.PlaceLimitOrder(orderCfg.Instrument, orderCfg.Side, price, amount, orderCfg.Options)
 	if err != nil {
 		return "", fmt.Errorf("failed to place order: %w", err)
 	}
@@ -0,0 +1,232 @@
+package margin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// DefaultInterval is how often the monitor polls margin
+const DefaultInterval = 15 * time.Second
+
+// staleIntervals is how many poll intervals old a reading can be before
+// quoting is blocked on it
+const staleIntervals = 3
+
+// Source reports a subaccount's margin
+type Source interface {
+	GetMargin() (*types.MarginState, error)
+}
+
+// Monitor polls a subaccount's margin and holds back new quotes while margin
+// usage is at or above a limit
+type Monitor struct {
+	source   Source
+	maxUsage decimal.Decimal
+	interval time.Duration
+
+	mu        sync.RWMutex
+	state     *types.MarginState
+	polled    time.Time // When state was read
+	lastErr   error
+	blocked   int64
+	overLimit bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that blocks quoting at maxUsage utilization
+// (0 only reports margin)
+func NewMonitor(source Source, maxUsage decimal.Decimal, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Monitor{
+		source:   source,
+		maxUsage: maxUsage,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start polls margin once, failing if the source can't report it, then keeps
+// polling in the background
+func (m *Monitor) Start() error {
+	if err := m.poll(); err != nil {
+		return err
+	}
+
+	m.wg.Add(1)
+	go m.run()
+	return nil
+}
+
+// Stop stops polling
+func (m *Monitor) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// State returns the latest margin reading
+func (m *Monitor) State() (types.MarginState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.state == nil {
+		return types.MarginState{}, false
+	}
+	return *m.state, true
+}
+
+// LastError returns the error of the latest poll, if it failed
+func (m *Monitor) LastError() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastErr
+}
+
+// MaxUsage returns the utilization at which quoting is blocked
+func (m *Monitor) MaxUsage() decimal.Decimal {
+	return m.maxUsage
+}
+
+// Blocked returns how many quotes have been held back
+func (m *Monitor) Blocked() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.blocked
+}
+
+// Allow returns an error if the latest reading is at or above the usage
+// limit, counting the quote it holds back. Without a limit every quote is
+// allowed; with one, quotes are also held back until margin has been read
+// and while the latest reading is more than a few poll intervals old.
+func (m *Monitor) Allow() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.check()
+	if err != nil {
+		m.blocked++
+	}
+	return err
+}
+
+// Blocking reports whether new quotes are being held back
+func (m *Monitor) Blocking() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.check() != nil
+}
+
+// check returns why new quotes are held back, if they are. Callers hold m.mu.
+func (m *Monitor) check() error {
+	if !m.maxUsage.IsPositive() {
+		return nil
+	}
+	if m.state == nil {
+		return fmt.Errorf("no margin reading yet")
+	}
+	if age := time.Since(m.polled); age > staleIntervals*m.interval {
+		return fmt.Errorf("margin last read %s ago", age.Round(time.Second))
+	}
+	usage := m.state.Utilization()
+	if usage.LessThan(m.maxUsage) {
+		return nil
+	}
+	return fmt.Errorf("margin usage %s%% is at or above the %s%% limit",
+		usage.Mul(decimal.NewFromInt(100)).StringFixed(1), m.maxUsage.Mul(decimal.NewFromInt(100)).StringFixed(1))
+}
+
+// run polls margin every interval until stopped
+func (m *Monitor) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.poll(); err != nil {
+				log.Printf("[Margin] Failed to poll margin: %v", err)
+			}
+		}
+	}
+}
+
+// poll records a margin reading, logging when usage crosses the limit
+func (m *Monitor) poll() error {
+	state, err := m.source.GetMargin()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.lastErr = err
+		return fmt.Errorf("failed to get margin: %w", err)
+	}
+	m.state = state
+	m.polled = time.Now()
+	m.lastErr = nil
+
+	if !m.maxUsage.IsPositive() {
+		return nil
+	}
+	usage := state.Utilization()
+	overLimit := !usage.LessThan(m.maxUsage)
+	if overLimit != m.overLimit {
+		if overLimit {
+			log.Printf("[Margin] Subaccount %d margin usage %s%% reached the %s%% limit, pausing new quotes",
+				state.SubaccountID, usage.Mul(decimal.NewFromInt(100)).StringFixed(1), m.maxUsage.Mul(decimal.NewFromInt(100)).StringFixed(1))
+		} else {
+			log.Printf("[Margin] Subaccount %d margin usage back to %s%%, resuming quotes",
+				state.SubaccountID, usage.Mul(decimal.NewFromInt(100)).StringFixed(1))
+		}
+		m.overLimit = overLimit
+	}
+	return nil
+}
+
+// WriteMetrics writes the latest margin reading in Prometheus format
+func (m *Monitor) WriteMetrics(w io.Writer) {
+	state, ok := m.State()
+	if !ok {
+		return
+	}
+	subaccount := fmt.Sprintf("%d", state.SubaccountID)
+
+	gauges := []struct {
+		name  string
+		help  string
+		value decimal.Decimal
+	}{
+		{"margin_collateral", "Value of collateral held", state.Collateral},
+		{"margin_subaccount_value", "Collateral plus the mark-to-market of positions", state.SubaccountValue},
+		{"margin_initial", "Initial margin required by positions and open orders", state.InitialMargin},
+		{"margin_maintenance", "Margin required to avoid liquidation", state.MaintenanceMargin},
+		{"margin_available", "Margin left for new orders", state.AvailableMargin},
+		{"margin_utilization", "Share of the subaccount value used as initial margin", state.Utilization()},
+		{"margin_usage_limit", "Margin utilization at which new quotes are blocked", m.maxUsage},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s{subaccount=%q} %s\n", g.name, subaccount, g.value.StringFixed(4))
+	}
+
+	fmt.Fprintf(w, "# HELP margin_blocked_quotes_total Quotes held back by the margin limit\n")
+	fmt.Fprintf(w, "# TYPE margin_blocked_quotes_total counter\n")
+	fmt.Fprintf(w, "margin_blocked_quotes_total{subaccount=%q} %d\n", subaccount, m.Blocked())
+}
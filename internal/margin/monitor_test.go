@@ -0,0 +1,106 @@
+package margin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// stubSource reports fixed margin readings
+type stubSource struct {
+	state *types.MarginState
+	err   error
+}
+
+func (s *stubSource) GetMargin() (*types.MarginState, error) {
+	return s.state, s.err
+}
+
+func reading(value, initialMargin int64) *types.MarginState {
+	return &types.MarginState{
+		SubaccountID:    7,
+		SubaccountValue: decimal.NewFromInt(value),
+		InitialMargin:   decimal.NewFromInt(initialMargin),
+	}
+}
+
+func TestMonitorBlocksAboveLimit(t *testing.T) {
+	source := &stubSource{state: reading(1000, 500)}
+	monitor := NewMonitor(source, decimal.NewFromFloat(0.8), 0)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer monitor.Stop()
+
+	if err := monitor.Allow(); err != nil {
+		t.Fatalf("50%% usage blocked: %v", err)
+	}
+
+	source.state = reading(1000, 850)
+	monitor.poll()
+	if err := monitor.Allow(); err == nil {
+		t.Fatal("expected 85% usage to be blocked")
+	}
+
+	// A failed poll keeps blocking on the last reading
+	source.err = fmt.Errorf("timeout")
+	monitor.poll()
+	if err := monitor.Allow(); err == nil {
+		t.Fatal("expected the last reading to keep blocking")
+	}
+	if monitor.Blocked() != 2 {
+		t.Errorf("blocked = %d, want 2", monitor.Blocked())
+	}
+
+	var buf bytes.Buffer
+	monitor.WriteMetrics(&buf)
+	want := `margin_utilization{subaccount="7"} 0.8500`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestMonitorStartFailsWithoutMargin(t *testing.T) {
+	monitor := NewMonitor(&stubSource{err: fmt.Errorf("not supported")}, decimal.NewFromFloat(0.8), 0)
+	if err := monitor.Start(); err == nil {
+		monitor.Stop()
+		t.Fatal("expected Start to fail when margin is unavailable")
+	}
+}
+
+func TestMonitorBlocksWithoutFreshReading(t *testing.T) {
+	source := &stubSource{err: fmt.Errorf("timeout")}
+	monitor := NewMonitor(source, decimal.NewFromFloat(0.8), time.Minute)
+
+	// Nothing read yet
+	if err := monitor.Allow(); err == nil {
+		t.Fatal("expected quoting to be blocked before margin is read")
+	}
+
+	source.state, source.err = reading(1000, 500), nil
+	monitor.poll()
+	if err := monitor.Allow(); err != nil {
+		t.Fatalf("fresh 50%% reading blocked: %v", err)
+	}
+
+	// Polls keep failing until the reading is stale
+	source.err = fmt.Errorf("timeout")
+	monitor.poll()
+	monitor.polled = time.Now().Add(-staleIntervals*time.Minute - time.Second)
+	if err := monitor.Allow(); err == nil || !monitor.Blocking() {
+		t.Fatal("expected a stale reading to block quoting")
+	}
+	if monitor.Blocked() != 2 {
+		t.Errorf("blocked = %d, want 2", monitor.Blocked())
+	}
+
+	// Without a limit nothing is blocked
+	if err := NewMonitor(source, decimal.Zero, 0).Allow(); err != nil {
+		t.Errorf("monitor without a limit blocked: %v", err)
+	}
+}
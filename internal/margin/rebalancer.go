@@ -0,0 +1,179 @@
+package margin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Treasury reports the margin of several subaccounts and moves collateral
+// between them
+type Treasury interface {
+	GetAllMargins() ([]types.MarginState, error)
+	Transfer(fromSubaccount, toSubaccount uint64, amount decimal.Decimal) error
+}
+
+// Band is the margin usage a rebalancer keeps every subaccount within.
+// Subaccounts above High are topped up from those below Low, moving each
+// towards Target.
+type Band struct {
+	Low    decimal.Decimal
+	Target decimal.Decimal
+	High   decimal.Decimal
+}
+
+// Validate checks that Low <= Target <= High and Target is positive
+func (b Band) Validate() error {
+	if !b.Target.IsPositive() {
+		return fmt.Errorf("target margin usage must be positive")
+	}
+	if b.Low.GreaterThan(b.Target) || b.Target.GreaterThan(b.High) {
+		return fmt.Errorf("margin usage band must satisfy low <= target <= high, got %s/%s/%s", b.Low, b.Target, b.High)
+	}
+	return nil
+}
+
+// Transfer is a planned move of collateral between subaccounts
+type Transfer struct {
+	From   uint64
+	To     uint64
+	Amount decimal.Decimal
+}
+
+// Plan returns the transfers that bring subaccounts above the band back to
+// its target, funded by subaccounts below it without pushing them over the
+// target. Amounts are rounded down to whole cents and transfers under
+// minAmount are skipped.
+func Plan(states []types.MarginState, band Band, minAmount decimal.Decimal) []Transfer {
+	type account struct {
+		id    uint64
+		value decimal.Decimal
+		im    decimal.Decimal
+		usage decimal.Decimal
+	}
+	var needy, donors []*account
+	for _, state := range states {
+		a := &account{id: state.SubaccountID, value: state.SubaccountValue, im: state.InitialMargin, usage: state.Utilization()}
+		switch {
+		case a.usage.GreaterThan(band.High):
+			needy = append(needy, a)
+		case a.usage.LessThan(band.Low):
+			donors = append(donors, a)
+		}
+	}
+
+	// Worst off first, funded by the most spare margin first
+	sort.Slice(needy, func(i, j int) bool { return needy[i].usage.GreaterThan(needy[j].usage) })
+	spare := func(a *account) decimal.Decimal {
+		return a.value.Sub(a.im.Div(band.Target))
+	}
+	sort.Slice(donors, func(i, j int) bool { return spare(donors[i]).GreaterThan(spare(donors[j])) })
+
+	var transfers []Transfer
+	for _, to := range needy {
+		for _, from := range donors {
+			need := to.im.Div(band.Target).Sub(to.value).RoundDown(2)
+			if need.LessThan(minAmount) || !need.IsPositive() {
+				break
+			}
+			amount := decimal.Min(need, spare(from)).RoundDown(2)
+			if amount.LessThan(minAmount) || !amount.IsPositive() {
+				continue
+			}
+			transfers = append(transfers, Transfer{From: from.id, To: to.id, Amount: amount})
+			from.value = from.value.Sub(amount)
+			to.value = to.value.Add(amount)
+		}
+	}
+	return transfers
+}
+
+// Rebalancer periodically moves collateral between subaccounts to keep their
+// margin usage inside a band
+type Rebalancer struct {
+	treasury  Treasury
+	band      Band
+	minAmount decimal.Decimal
+	interval  time.Duration
+	dryRun    bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRebalancer creates a rebalancer. In dry run mode it only logs the
+// transfers it would make.
+func NewRebalancer(treasury Treasury, band Band, minAmount decimal.Decimal, interval time.Duration, dryRun bool) *Rebalancer {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Rebalancer{
+		treasury:  treasury,
+		band:      band,
+		minAmount: minAmount,
+		interval:  interval,
+		dryRun:    dryRun,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start rebalances every interval in the background
+func (r *Rebalancer) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := r.Rebalance(); err != nil {
+				log.Printf("[Rebalance] %v", err)
+			}
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops rebalancing
+func (r *Rebalancer) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Rebalance plans and makes the transfers the current margins call for,
+// returning those made
+func (r *Rebalancer) Rebalance() ([]Transfer, error) {
+	states, err := r.treasury.GetAllMargins()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get margins: %w", err)
+	}
+
+	var done []Transfer
+	for _, transfer := range Plan(states, r.band, r.minAmount) {
+		if r.dryRun {
+			log.Printf("[Rebalance] DRY RUN: would transfer %s from subaccount %d to %d", transfer.Amount, transfer.From, transfer.To)
+			done = append(done, transfer)
+			continue
+		}
+		if err := r.treasury.Transfer(transfer.From, transfer.To, transfer.Amount); err != nil {
+			return done, fmt.Errorf("failed to transfer %s from subaccount %d to %d: %w", transfer.Amount, transfer.From, transfer.To, err)
+		}
+		log.Printf("[Rebalance] Transferred %s from subaccount %d to %d", transfer.Amount, transfer.From, transfer.To)
+		done = append(done, transfer)
+	}
+	return done, nil
+}
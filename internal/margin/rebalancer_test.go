@@ -0,0 +1,35 @@
+package margin
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestPlanMovesSpareCollateralToTarget(t *testing.T) {
+	band := Band{Low: decimal.NewFromFloat(0.3), Target: decimal.NewFromFloat(0.5), High: decimal.NewFromFloat(0.7)}
+	states := []types.MarginState{
+		*reading(1000, 900), // 90% used, needs 800 to reach 50%
+		*reading(1000, 100), // 10% used, can spare 800
+		*reading(1000, 600), // Inside the band
+	}
+	states[1].SubaccountID = 8
+	states[2].SubaccountID = 9
+
+	transfers := Plan(states, band, decimal.NewFromInt(10))
+	if len(transfers) != 1 {
+		t.Fatalf("transfers = %+v, want one", transfers)
+	}
+	if transfers[0].From != 8 || transfers[0].To != 7 || !transfers[0].Amount.Equal(decimal.NewFromInt(800)) {
+		t.Errorf("transfer = %+v, want 800 from 8 to 7", transfers[0])
+	}
+
+	// A donor never gives more than keeps it at the target
+	states[1] = *reading(1000, 400)
+	states[1].SubaccountID = 8
+	transfers = Plan(states, band, decimal.NewFromInt(10))
+	if len(transfers) != 0 {
+		t.Errorf("transfers = %+v, want none from a donor inside the band", transfers)
+	}
+}
@@ -0,0 +1,362 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// subscriberBuffer is how many updates a subscriber can fall behind before
+// updates to it are dropped
+const subscriberBuffer = 100
+
+// resubscribeDelays are the waits before each attempt to replace an ended
+// upstream stream, counted since the feed's last update. Once they run out
+// the feed ends and its subscribers are closed.
+var resubscribeDelays = []time.Duration{0, time.Second, 5 * time.Second, 30 * time.Second}
+
+// staleAfter is how long a snapshot is served after the hub received it.
+// Receipt rather than the exchange's timestamp is used so that clock skew and
+// replayed recordings don't age tickers.
+var staleAfter = 30 * time.Second
+
+// TickerSource streams tickers from an exchange
+type TickerSource interface {
+	SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error)
+}
+
+// Hub shares ticker subscriptions between consumers. It holds one upstream
+// subscription per instrument for as long as any consumer is subscribed to
+// it, fans its updates out to every subscriber and keeps the latest snapshot
+// while the instrument is subscribed.
+type Hub struct {
+	source TickerSource
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	feeds  map[string]*feed
+	latest map[string]snapshot
+}
+
+// snapshot is an instrument's latest ticker and when it arrived
+type snapshot struct {
+	ticker   types.TickerUpdate
+	received time.Time
+}
+
+// feed is the upstream subscription of one instrument
+type feed struct {
+	instrument  string
+	refs        int
+	ctx         context.Context
+	cancel      context.CancelFunc
+	started     chan struct{} // Closed once the upstream subscribe returns
+	err         error         // Why the upstream subscribe failed
+	ready       chan struct{} // Closed on the first update
+	gotUpdate   bool
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription is one consumer's interest in a set of instruments
+type Subscription struct {
+	hub     *Hub
+	feeds   []*feed
+	updates chan types.TickerUpdate
+	closed  bool
+}
+
+// NewHub creates a hub over an exchange's ticker stream
+func NewHub(source TickerSource) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Hub{
+		source: source,
+		ctx:    ctx,
+		cancel: cancel,
+		feeds:  make(map[string]*feed),
+		latest: make(map[string]snapshot),
+	}
+}
+
+// Subscribe subscribes to instruments' tickers, subscribing upstream only to
+// instruments nobody else is subscribed to yet. Upstream subscribes happen
+// outside the hub's lock, so a slow one only holds up its own subscribers.
+func (h *Hub) Subscribe(instruments []string) (*Subscription, error) {
+	h.mu.Lock()
+	if h.ctx.Err() != nil {
+		h.mu.Unlock()
+		return nil, fmt.Errorf("market data hub is closed")
+	}
+
+	sub := &Subscription{
+		hub:     h,
+		updates: make(chan types.TickerUpdate, subscriberBuffer),
+	}
+
+	var pending []*feed
+	for _, instrument := range instruments {
+		f, ok := h.feeds[instrument]
+		if !ok {
+			f = h.newFeed(instrument)
+			pending = append(pending, f)
+		}
+		f.refs++
+		f.subscribers[sub] = struct{}{}
+		sub.feeds = append(sub.feeds, f)
+	}
+	h.mu.Unlock()
+
+	for _, f := range pending {
+		h.startFeed(f)
+	}
+
+	// Feeds other subscribers started may still be subscribing
+	for _, f := range sub.feeds {
+		<-f.started
+		if f.err != nil {
+			sub.Unsubscribe()
+			return nil, f.err
+		}
+	}
+
+	return sub, nil
+}
+
+// SubscribeTickers subscribes to instruments until ctx is done, closing the
+// returned channel then. It lets the hub stand in for an exchange's ticker
+// stream.
+func (h *Hub) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	sub, err := h.Subscribe(instruments)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-h.ctx.Done():
+		}
+		sub.Unsubscribe()
+	}()
+
+	return sub.Updates(), nil
+}
+
+// Latest returns the most recent ticker of a subscribed instrument, unless
+// none has arrived for longer than the staleness bound
+func (h *Hub) Latest(instrument string) (types.TickerUpdate, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	latest, ok := h.latest[instrument]
+	if !ok || time.Since(latest.received) > staleAfter {
+		return types.TickerUpdate{}, false
+	}
+	return latest.ticker, true
+}
+
+// IndexPrice returns the underlying index price from an instrument's latest
+// ticker
+func (h *Hub) IndexPrice(instrument string) (decimal.Decimal, bool) {
+	ticker, ok := h.Latest(instrument)
+	if !ok || !ticker.IndexPrice.IsPositive() {
+		return decimal.Zero, false
+	}
+	return ticker.IndexPrice, true
+}
+
+// Ticker returns an instrument's latest ticker, waiting for the first one
+// until ctx is done. The instrument must be subscribed.
+func (h *Hub) Ticker(ctx context.Context, instrument string) (types.TickerUpdate, error) {
+	h.mu.Lock()
+	f, ok := h.feeds[instrument]
+	h.mu.Unlock()
+	if !ok {
+		return types.TickerUpdate{}, fmt.Errorf("not subscribed to %s", instrument)
+	}
+
+	select {
+	case <-f.ready:
+	case <-f.ctx.Done():
+		return types.TickerUpdate{}, fmt.Errorf("%s ticker stream ended", instrument)
+	case <-ctx.Done():
+		return types.TickerUpdate{}, fmt.Errorf("no ticker for %s: %w", instrument, ctx.Err())
+	}
+
+	ticker, ok := h.Latest(instrument)
+	if !ok {
+		return types.TickerUpdate{}, fmt.Errorf("%s ticker is stale", instrument)
+	}
+	return ticker, nil
+}
+
+// Close ends every upstream subscription and closes all subscribers
+func (h *Hub) Close() {
+	h.cancel()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range h.feeds {
+		for sub := range f.subscribers {
+			h.release(sub)
+		}
+	}
+}
+
+// Updates returns the subscription's ticker updates. Updates are dropped
+// while the channel is full.
+func (s *Subscription) Updates() <-chan types.TickerUpdate {
+	return s.updates
+}
+
+// Closed reports whether the subscription has ended, by Unsubscribe or
+// because its upstream stream couldn't be resubscribed
+func (s *Subscription) Closed() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.closed
+}
+
+// Unsubscribe releases the subscription, ending upstream subscriptions that
+// have no subscribers left, and closes its channel
+func (s *Subscription) Unsubscribe() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.release(s)
+}
+
+// newFeed registers an instrument's feed before it is subscribed upstream
+// (must be called with lock held)
+func (h *Hub) newFeed(instrument string) *feed {
+	ctx, cancel := context.WithCancel(h.ctx)
+	f := &feed{
+		instrument:  instrument,
+		ctx:         ctx,
+		cancel:      cancel,
+		started:     make(chan struct{}),
+		ready:       make(chan struct{}),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+	h.feeds[instrument] = f
+	return f
+}
+
+// startFeed subscribes a new feed upstream and starts publishing it, or
+// drops it if the subscribe fails
+func (h *Hub) startFeed(f *feed) {
+	defer close(f.started)
+
+	updates, err := h.source.SubscribeTickers(f.ctx, []string{f.instrument})
+	if err != nil {
+		h.mu.Lock()
+		f.err = fmt.Errorf("failed to subscribe to %s ticker: %w", f.instrument, err)
+		if h.feeds[f.instrument] == f {
+			delete(h.feeds, f.instrument)
+		}
+		h.mu.Unlock()
+		f.cancel()
+		return
+	}
+
+	go h.run(f.ctx, f, updates)
+}
+
+// run publishes a feed's upstream updates until it is cancelled, subscribing
+// upstream again if the stream ends
+func (h *Hub) run(ctx context.Context, f *feed, updates <-chan types.TickerUpdate) {
+	attempts := 0 // Resubscribes since the last update
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ticker, ok := <-updates:
+			if ok {
+				attempts = 0
+				h.publish(f, ticker)
+				continue
+			}
+
+			log.Printf("[MarketData] %s ticker stream ended, resubscribing", f.instrument)
+			for updates = nil; updates == nil; attempts++ {
+				if attempts == len(resubscribeDelays) {
+					h.endFeed(f)
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(resubscribeDelays[attempts]):
+				}
+
+				stream, err := h.source.SubscribeTickers(ctx, []string{f.instrument})
+				if err != nil {
+					log.Printf("[MarketData] Failed to resubscribe to %s ticker: %v", f.instrument, err)
+					continue
+				}
+				updates = stream
+			}
+		}
+	}
+}
+
+// endFeed drops a feed that can't be resubscribed, closing its subscribers so
+// they don't carry on with stale tickers
+func (h *Hub) endFeed(f *feed) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	log.Printf("[MarketData] Giving up on %s ticker stream, closing its %d subscribers", f.instrument, len(f.subscribers))
+	for sub := range f.subscribers {
+		h.release(sub)
+	}
+	f.cancel()
+	if h.feeds[f.instrument] == f {
+		delete(h.feeds, f.instrument)
+		delete(h.latest, f.instrument)
+	}
+}
+
+// publish records a ticker and passes it to the feed's subscribers
+func (h *Hub) publish(f *feed, ticker types.TickerUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latest[f.instrument] = snapshot{ticker: ticker, received: time.Now()}
+	if !f.gotUpdate {
+		f.gotUpdate = true
+		close(f.ready)
+	}
+
+	for sub := range f.subscribers {
+		select {
+		case sub.updates <- ticker:
+		default:
+		}
+	}
+}
+
+// release drops a subscription's references (must be called with lock held)
+func (h *Hub) release(s *Subscription) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for _, f := range s.feeds {
+		delete(f.subscribers, s)
+		f.refs--
+		if f.refs > 0 {
+			continue
+		}
+		f.cancel()
+		if h.feeds[f.instrument] == f {
+			delete(h.feeds, f.instrument)
+			delete(h.latest, f.instrument)
+		}
+	}
+	close(s.updates)
+}
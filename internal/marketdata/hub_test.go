@@ -0,0 +1,335 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// countingSource hands out streams it can publish on and counts the upstream
+// subscriptions that are still open
+type countingSource struct {
+	mu      sync.Mutex
+	streams map[string]chan types.TickerUpdate
+	opened  int
+	active  int
+	err     error // Fails new subscriptions
+}
+
+func (s *countingSource) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	ch := make(chan types.TickerUpdate, 10)
+	s.streams[instruments[0]] = ch
+	s.opened++
+	s.active++
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+func (s *countingSource) send(instrument string, mark int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[instrument] <- types.TickerUpdate{Instrument: instrument, MarkPrice: decimal.NewFromInt(mark)}
+}
+
+// end closes an instrument's stream, as a dropped connection would
+func (s *countingSource) end(instrument string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.streams[instrument])
+}
+
+func (s *countingSource) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *countingSource) counts() (opened, active int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opened, s.active
+}
+
+func TestHubSharesUpstreamSubscriptions(t *testing.T) {
+	source := &countingSource{streams: make(map[string]chan types.TickerUpdate)}
+	hub := NewHub(source)
+	defer hub.Close()
+
+	first, err := hub.Subscribe([]string{"ETH-PERP", "BTC-PERP"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	second, err := hub.Subscribe([]string{"ETH-PERP"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if opened, _ := source.counts(); opened != 2 {
+		t.Fatalf("opened %d upstream subscriptions, want 2", opened)
+	}
+
+	// Both subscribers see the shared instrument's updates
+	source.send("ETH-PERP", 3000)
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case ticker := <-sub.Updates():
+			if ticker.Instrument != "ETH-PERP" {
+				t.Errorf("got %s, want ETH-PERP", ticker.Instrument)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if ticker, err := hub.Ticker(ctx, "ETH-PERP"); err != nil || !ticker.MarkPrice.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("Ticker = %v, %v", ticker.MarkPrice, err)
+	}
+
+	// ETH stays subscribed upstream while the second subscriber holds it
+	first.Unsubscribe()
+	waitForActive(t, source, 1)
+
+	second.Unsubscribe()
+	waitForActive(t, source, 0)
+	if _, ok := <-second.Updates(); ok {
+		t.Error("expected the unsubscribed channel to be closed")
+	}
+
+	// Nothing is served for an instrument nobody streams
+	if ticker, ok := hub.Latest("ETH-PERP"); ok {
+		t.Errorf("Latest = %v after unsubscribing, want none", ticker.MarkPrice)
+	}
+}
+
+func TestHubDropsStaleSnapshots(t *testing.T) {
+	defer func(delays []time.Duration, stale time.Duration) {
+		resubscribeDelays, staleAfter = delays, stale
+	}(resubscribeDelays, staleAfter)
+	resubscribeDelays = nil
+	staleAfter = 50 * time.Millisecond
+
+	source := &countingSource{streams: make(map[string]chan types.TickerUpdate)}
+	hub := NewHub(source)
+	defer hub.Close()
+
+	sub, err := hub.Subscribe([]string{"ETH-PERP"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	source.streams["ETH-PERP"] <- types.TickerUpdate{Instrument: "ETH-PERP", IndexPrice: decimal.NewFromInt(3000)}
+	<-sub.Updates()
+	if price, ok := hub.IndexPrice("ETH-PERP"); !ok || !price.Equal(decimal.NewFromInt(3000)) {
+		t.Fatalf("IndexPrice = %v, %v, want 3000", price, ok)
+	}
+
+	// A feed that goes quiet stops being served
+	time.Sleep(2 * staleAfter)
+	if price, ok := hub.IndexPrice("ETH-PERP"); ok {
+		t.Errorf("IndexPrice = %v from a quiet feed, want none", price)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := hub.Ticker(ctx, "ETH-PERP"); err == nil {
+		t.Error("expected Ticker to refuse a stale snapshot")
+	}
+
+	// As does one that ends for good, however fresh its last ticker
+	staleAfter = time.Minute
+	source.send("ETH-PERP", 3100)
+	<-sub.Updates()
+	source.end("ETH-PERP")
+	if _, ok := <-sub.Updates(); ok {
+		t.Fatal("expected the subscriber to be closed")
+	}
+	if ticker, ok := hub.Latest("ETH-PERP"); ok {
+		t.Errorf("Latest = %v from an ended feed, want none", ticker.MarkPrice)
+	}
+}
+
+func TestWatchlistRetain(t *testing.T) {
+	source := &countingSource{streams: make(map[string]chan types.TickerUpdate)}
+	hub := NewHub(source)
+	defer hub.Close()
+
+	watchlist := NewWatchlist(hub)
+	for i := 0; i < 3; i++ {
+		for _, instrument := range []string{"ETH-PERP", "BTC-PERP"} {
+			if err := watchlist.Watch(instrument); err != nil {
+				t.Fatalf("Watch failed: %v", err)
+			}
+		}
+	}
+	if opened, _ := source.counts(); opened != 2 {
+		t.Fatalf("opened %d upstream subscriptions, want 2", opened)
+	}
+
+	watchlist.Retain([]string{"BTC-PERP"})
+	waitForActive(t, source, 1)
+
+	watchlist.Close()
+	waitForActive(t, source, 0)
+}
+
+func TestHubResubscribesEndedStreams(t *testing.T) {
+	defer func(delays []time.Duration) { resubscribeDelays = delays }(resubscribeDelays)
+	resubscribeDelays = []time.Duration{0, time.Millisecond}
+
+	source := &countingSource{streams: make(map[string]chan types.TickerUpdate)}
+	hub := NewHub(source)
+	defer hub.Close()
+
+	sub, err := hub.Subscribe([]string{"ETH-PERP"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// The subscriber keeps getting updates from the new stream
+	source.end("ETH-PERP")
+	deadline := time.Now().Add(time.Second)
+	for opened, _ := source.counts(); opened != 2; opened, _ = source.counts() {
+		if time.Now().After(deadline) {
+			t.Fatalf("opened %d upstream subscriptions, want a resubscribe", opened)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	source.send("ETH-PERP", 3100)
+	select {
+	case ticker := <-sub.Updates():
+		if !ticker.MarkPrice.Equal(decimal.NewFromInt(3100)) {
+			t.Errorf("got mark %s, want 3100", ticker.MarkPrice)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an update after resubscribing")
+	}
+
+	// Once resubscribing fails, the subscriber is closed
+	source.fail(errors.New("connection refused"))
+	source.end("ETH-PERP")
+	select {
+	case _, ok := <-sub.Updates():
+		if ok {
+			t.Error("got an update, want the channel closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber was left open on a dead stream")
+	}
+
+	// A watchlist replaces the closed subscription once upstream is back
+	watchlist := NewWatchlist(hub)
+	defer watchlist.Close()
+	watchlist.subs["ETH-PERP"] = sub
+	source.fail(nil)
+	if err := watchlist.Watch("ETH-PERP"); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if watchlist.subs["ETH-PERP"] == sub {
+		t.Error("watchlist kept the closed subscription")
+	}
+}
+
+// slowSource holds up upstream subscribes to instruments until they are let
+// through
+type slowSource struct {
+	*countingSource
+	held map[string]chan struct{}
+}
+
+func (s *slowSource) SubscribeTickers(ctx context.Context, instruments []string) (<-chan types.TickerUpdate, error) {
+	if held, ok := s.held[instruments[0]]; ok {
+		<-held
+	}
+	return s.countingSource.SubscribeTickers(ctx, instruments)
+}
+
+func TestHubSubscribesUpstreamOutsideItsLock(t *testing.T) {
+	source := &slowSource{
+		countingSource: &countingSource{streams: make(map[string]chan types.TickerUpdate)},
+		held:           map[string]chan struct{}{"BTC-PERP": make(chan struct{})},
+	}
+	hub := NewHub(source)
+	defer hub.Close()
+
+	eth, err := hub.Subscribe([]string{"ETH-PERP"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	source.send("ETH-PERP", 3000)
+	<-eth.Updates()
+
+	// Two subscribers wait on the same slow BTC subscribe
+	subscribed := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := hub.Subscribe([]string{"BTC-PERP"})
+			subscribed <- err
+		}()
+	}
+
+	// Meanwhile readers and other instruments aren't held up
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.Latest("ETH-PERP")
+		if sub, err := hub.Subscribe([]string{"SOL-PERP"}); err == nil {
+			sub.Unsubscribe()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a slow upstream subscribe blocked the hub")
+	}
+
+	close(source.held["BTC-PERP"])
+	for i := 0; i < 2; i++ {
+		if err := <-subscribed; err != nil {
+			t.Errorf("Subscribe failed: %v", err)
+		}
+	}
+	if opened, _ := source.counts(); opened != 3 {
+		t.Errorf("opened %d upstream subscriptions, want 3", opened)
+	}
+
+	// A failed subscribe leaves nothing behind to retry against
+	source.fail(errors.New("connection refused"))
+	if _, err := hub.Subscribe([]string{"XRP-PERP"}); err == nil {
+		t.Fatal("expected Subscribe to fail")
+	}
+	source.fail(nil)
+	if _, err := hub.Subscribe([]string{"XRP-PERP"}); err != nil {
+		t.Errorf("Subscribe after recovery failed: %v", err)
+	}
+}
+
+func waitForActive(t *testing.T, source *countingSource, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, active := source.counts()
+		if active == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d upstream subscriptions active, want %d", active, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package marketdata
+
+import (
+	"sync"
+)
+
+// Watchlist keeps a consumer subscribed to a changing set of instruments so
+// their latest tickers stay in the hub
+type Watchlist struct {
+	hub  *Hub
+	mu   sync.Mutex
+	subs map[string]*Subscription
+}
+
+// NewWatchlist creates an empty watchlist on a hub
+func NewWatchlist(hub *Hub) *Watchlist {
+	return &Watchlist{
+		hub:  hub,
+		subs: make(map[string]*Subscription),
+	}
+}
+
+// Watch subscribes to an instrument unless it is already watched, replacing
+// a subscription the hub has closed
+func (w *Watchlist) Watch(instrument string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if sub, ok := w.subs[instrument]; ok && !sub.Closed() {
+		return nil
+	}
+	sub, err := w.hub.Subscribe([]string{instrument})
+	if err != nil {
+		return err
+	}
+	w.subs[instrument] = sub
+	return nil
+}
+
+// Retain unsubscribes from every watched instrument not in keep
+func (w *Watchlist) Retain(keep []string) {
+	kept := make(map[string]bool, len(keep))
+	for _, instrument := range keep {
+		kept[instrument] = true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for instrument, sub := range w.subs {
+		if !kept[instrument] {
+			sub.Unsubscribe()
+			delete(w.subs, instrument)
+		}
+	}
+}
+
+// Close unsubscribes from everything
+func (w *Watchlist) Close() {
+	w.Retain(nil)
+}
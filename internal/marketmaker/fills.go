@@ -0,0 +1,165 @@
+package marketmaker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// earlyUpdateTTL is how long an update for an order we haven't tracked yet is
+// kept, in case it raced ahead of PlaceLimitOrder returning
+const earlyUpdateTTL = time.Minute
+
+// earlyUpdate is a terminal order update waiting for its order to be tracked
+type earlyUpdate struct {
+	order    types.MarketMakerOrder
+	received time.Time
+}
+
+// subscribeToExecutions starts consuming the exchange's private fill and
+// order update streams
+func (mm *MarketMaker) subscribeToExecutions() error {
+	fillChan, err := mm.exchange.SubscribeFills(mm.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to fills: %w", err)
+	}
+
+	orderChan, err := mm.exchange.SubscribeOrderUpdates(mm.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to order updates: %w", err)
+	}
+
+	mm.wg.Add(1)
+	go mm.processFills(fillChan)
+
+	mm.wg.Add(1)
+	go mm.processOrderUpdates(orderChan)
+
+	return nil
+}
+
+// processFills applies executions to positions and stats
+func (mm *MarketMaker) processFills(fillChan <-chan types.Fill) {
+	defer mm.wg.Done()
+
+	for {
+		select {
+		case <-mm.ctx.Done():
+			return
+		case fill, ok := <-fillChan:
+			if !ok {
+				log.Println("Fill channel closed")
+				return
+			}
+			mm.handleFill(fill)
+		}
+	}
+}
+
+// processOrderUpdates keeps order tracking in line with the exchange
+func (mm *MarketMaker) processOrderUpdates(orderChan <-chan types.MarketMakerOrder) {
+	defer mm.wg.Done()
+
+	for {
+		select {
+		case <-mm.ctx.Done():
+			return
+		case order, ok := <-orderChan:
+			if !ok {
+				log.Println("Order update channel closed")
+				return
+			}
+			mm.handleOrderEvent(order)
+		}
+	}
+}
+
+// handleFill updates the position, volume and PnL for an execution
+func (mm *MarketMaker) handleFill(fill types.Fill) {
+	log.Printf("Fill: %s %s %s @ %s (order %s, fee %s)",
+		fill.Side, fill.Amount, fill.Instrument, fill.Price, fill.OrderID, fill.Fee)
+
+	mm.updatePosition(fill.Instrument, fill.Side, fill.Amount)
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.stats.TotalVolume = mm.stats.TotalVolume.Add(fill.Amount.Mul(fill.Price))
+	mm.stats.TotalPnL = mm.stats.TotalPnL.Add(fill.RealizedPnL).Sub(fill.Fee)
+
+	if order, exists := mm.activeOrders[fill.OrderID]; exists {
+		order.FilledAmount = order.FilledAmount.Add(fill.Amount)
+		order.UpdatedAt = fill.Timestamp
+	}
+}
+
+// handleOrderEvent applies an order state change, dropping filled and
+// cancelled orders from tracking so the quote updater replaces them
+func (mm *MarketMaker) handleOrderEvent(update types.MarketMakerOrder) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if _, exists := mm.activeOrders[update.OrderID]; !exists {
+		if !isOpenStatus(update.Status) {
+			mm.pruneEarlyUpdates()
+			mm.earlyUpdates[update.OrderID] = earlyUpdate{order: update, received: time.Now()}
+		}
+		return
+	}
+
+	mm.applyOrderUpdate(update)
+}
+
+// applyOrderUpdate updates a tracked order (must be called with lock held)
+func (mm *MarketMaker) applyOrderUpdate(update types.MarketMakerOrder) {
+	order, exists := mm.activeOrders[update.OrderID]
+	if !exists {
+		return
+	}
+
+	if isOpenStatus(update.Status) {
+		order.FilledAmount = update.FilledAmount
+		order.UpdatedAt = update.UpdatedAt
+		return
+	}
+
+	// Our own cancels are counted by cancelOrder
+	if update.Status == "filled" {
+		mm.stats.OrdersFilled++
+		if mm.stats.OrdersPlaced > 0 {
+			mm.stats.FillRate = decimal.NewFromInt(mm.stats.OrdersFilled).Div(decimal.NewFromInt(mm.stats.OrdersPlaced))
+		}
+	}
+
+	log.Printf("Order %s %s for %s %s (filled %s/%s)",
+		order.OrderID, update.Status, order.Side, order.Instrument, update.FilledAmount, order.Amount)
+	mm.removeOrderFromTracking(update.OrderID)
+	delete(mm.failedCancelAttempts, update.OrderID)
+}
+
+// applyEarlyUpdate applies an update that arrived before orderID was tracked
+// (must be called with lock held)
+func (mm *MarketMaker) applyEarlyUpdate(orderID string) {
+	if early, exists := mm.earlyUpdates[orderID]; exists {
+		delete(mm.earlyUpdates, orderID)
+		mm.applyOrderUpdate(early.order)
+	}
+}
+
+// pruneEarlyUpdates drops updates for orders that were never tracked (must be
+// called with lock held)
+func (mm *MarketMaker) pruneEarlyUpdates() {
+	for orderID, early := range mm.earlyUpdates {
+		if time.Since(early.received) > earlyUpdateTTL {
+			delete(mm.earlyUpdates, orderID)
+		}
+	}
+}
+
+// isOpenStatus reports whether an order can still trade
+func isOpenStatus(status string) bool {
+	return status == "open" || status == "untriggered"
+}
@@ -0,0 +1,63 @@
+package marketmaker
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestExecutionTracking(t *testing.T) {
+	config := types.DefaultMarketMakerConfig()
+	config.Instruments = []string{"ETH-PERP"}
+	mm := NewMarketMaker(config, nil)
+
+	mm.mu.Lock()
+	mm.trackOrder("bid-1", "ETH-PERP", "buy", decimal.NewFromInt(2500), decimal.NewFromInt(1))
+	mm.stats.OrdersPlaced++
+	mm.mu.Unlock()
+
+	mm.handleFill(types.Fill{
+		OrderID:     "bid-1",
+		Instrument:  "ETH-PERP",
+		Side:        "buy",
+		Price:       decimal.NewFromInt(2500),
+		Amount:      decimal.NewFromFloat(0.4),
+		Fee:         decimal.NewFromFloat(0.5),
+		RealizedPnL: decimal.NewFromInt(3),
+	})
+
+	assert.True(t, mm.getNetPosition("ETH-PERP").Equal(decimal.NewFromFloat(0.4)))
+	assert.True(t, mm.activeOrders["bid-1"].FilledAmount.Equal(decimal.NewFromFloat(0.4)))
+
+	mm.handleOrderEvent(types.MarketMakerOrder{OrderID: "bid-1", Status: "filled", FilledAmount: decimal.NewFromInt(1)})
+
+	stats := mm.GetStats()
+	assert.Equal(t, int64(1), stats.OrdersFilled)
+	assert.True(t, stats.TotalVolume.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, stats.TotalPnL.Equal(decimal.NewFromFloat(2.5)))
+	assert.True(t, stats.FillRate.Equal(decimal.NewFromInt(1)))
+	assert.NotContains(t, mm.activeOrders, "bid-1")
+	assert.Empty(t, mm.ordersByInstrument["ETH-PERP"])
+}
+
+func TestEarlyOrderUpdate(t *testing.T) {
+	config := types.DefaultMarketMakerConfig()
+	mm := NewMarketMaker(config, nil)
+
+	// The fill can be reported before PlaceLimitOrder returns the order ID
+	mm.handleOrderEvent(types.MarketMakerOrder{OrderID: "ask-1", Status: "filled"})
+
+	mm.mu.Lock()
+	mm.trackOrder("ask-1", "ETH-PERP", "sell", decimal.NewFromInt(2600), decimal.NewFromInt(1))
+	mm.mu.Unlock()
+
+	assert.NotContains(t, mm.activeOrders, "ask-1")
+	assert.Equal(t, int64(1), mm.GetStats().OrdersFilled)
+	assert.Empty(t, mm.earlyUpdates)
+
+	// Open updates for unknown orders are ignored
+	mm.handleOrderEvent(types.MarketMakerOrder{OrderID: "other", Status: "open"})
+	assert.Empty(t, mm.earlyUpdates)
+}
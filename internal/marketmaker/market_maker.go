@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/margin"
+	"github.com/wakamex/atomizer/internal/marketdata"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
@@ -21,10 +23,12 @@ type MarketMaker struct {
 	ordersByInstrument map[string]map[string]*types.MarketMakerOrder
 
 	// Market data
+	marketData    *marketdata.Hub
 	latestTickers map[string]*types.TickerUpdate
 
 	// Position tracking
 	positions map[string]decimal.Decimal
+	margin    *margin.Monitor
 
 	// Statistics
 	stats types.MarketMakerStats
@@ -46,6 +50,9 @@ type MarketMaker struct {
 
 	// Track last update time
 	lastUpdateTime map[string]time.Time
+
+	// Terminal order updates that arrived before their order was tracked
+	earlyUpdates map[string]earlyUpdate
 }
 
 // NewMarketMaker creates a new market maker instance
@@ -71,9 +78,22 @@ func NewMarketMaker(config *types.MarketMakerConfig, exchange types.MarketMakerE
 		updateLocks:          updateLocks,
 		failedCancelAttempts: make(map[string]int),
 		lastUpdateTime:       make(map[string]time.Time),
+		earlyUpdates:         make(map[string]earlyUpdate),
 	}
 }
 
+// SetMarketData subscribes to tickers through a shared hub instead of
+// directly on the exchange. Call before Start.
+func (mm *MarketMaker) SetMarketData(hub *marketdata.Hub) {
+	mm.marketData = hub
+}
+
+// SetMarginMonitor stops placing new quotes while margin usage is over the
+// monitor's limit. Call before Start.
+func (mm *MarketMaker) SetMarginMonitor(monitor *margin.Monitor) {
+	mm.margin = monitor
+}
+
 // Start begins market making
 func (mm *MarketMaker) Start() error {
 	mode := "two-sided (1 buy + 1 sell)"
@@ -115,11 +135,20 @@ func (mm *MarketMaker) Start() error {
 	mm.CancelAllOrdersOnStartup()
 
 	// Subscribe to ticker updates
-	tickerChan, err := mm.exchange.SubscribeTickers(mm.ctx, mm.config.Instruments)
+	var tickers marketdata.TickerSource = mm.exchange
+	if mm.marketData != nil {
+		tickers = mm.marketData
+	}
+	tickerChan, err := tickers.SubscribeTickers(mm.ctx, mm.config.Instruments)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to tickers: %w", err)
 	}
 
+	// Track positions and orders from real executions
+	if err := mm.subscribeToExecutions(); err != nil {
+		return err
+	}
+
 	// Subscribe to orderbook updates
 	mm.subscribeToOrderBooks()
 
@@ -133,7 +162,7 @@ func (mm *MarketMaker) Start() error {
 	mm.wg.Add(1)
 	go mm.statsReporter()
 
-	// Initial reconciliation
+	// Initial reconciliation; the order update stream keeps tracking current after this
 	mm.ReconcileOrders()
 
 	mm.stats.UptimeSeconds = 0
@@ -164,7 +193,10 @@ func (mm *MarketMaker) processTickers(tickerChan <-chan types.TickerUpdate) {
 			return
 		case ticker, ok := <-tickerChan:
 			if !ok {
-				log.Println("Ticker channel closed")
+				// Quoting on without tickers would quote stale prices
+				log.Println("Ticker channel closed, pulling quotes and stopping the market maker")
+				mm.cancel()
+				mm.CancelAllOrders()
 				return
 			}
 
@@ -148,6 +148,13 @@ func (mm *MarketMaker) handleOrderUpdate(order *types.MarketMakerOrder, instrume
 	return false
 }
 
+// orderOptions returns the options quotes are placed with. Below aggression 1
+// quotes are meant to rest, so they are post-only and never take liquidity
+// when the market moves through them before they land.
+func (mm *MarketMaker) orderOptions() types.OrderOptions {
+	return types.OrderOptions{PostOnly: mm.config.Aggression.LessThan(decimal.NewFromInt(1))}
+}
+
 // placeQuotes places bid and ask orders concurrently
 func (mm *MarketMaker) placeQuotes(instrument string, bidPrice, askPrice decimal.Decimal) error {
 	var bidOrderID, askOrderID string
@@ -161,7 +168,7 @@ func (mm *MarketMaker) placeQuotes(instrument string, bidPrice, askPrice decimal
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			bidOrderID, bidErr = mm.exchange.PlaceLimitOrder(instrument, "buy", bidPrice, mm.config.QuoteSize)
+			bidOrderID, bidErr = mm.exchange.PlaceLimitOrder(instrument, "buy", bidPrice, mm.config.QuoteSize, mm.orderOptions())
 		}()
 	}
 
@@ -170,7 +177,7 @@ func (mm *MarketMaker) placeQuotes(instrument string, bidPrice, askPrice decimal
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			askOrderID, askErr = mm.exchange.PlaceLimitOrder(instrument, "sell", askPrice, mm.config.QuoteSize)
+			askOrderID, askErr = mm.exchange.PlaceLimitOrder(instrument, "sell", askPrice, mm.config.QuoteSize, mm.orderOptions())
 		}()
 	}
 
@@ -218,7 +225,7 @@ func (mm *MarketMaker) placeQuotes(instrument string, bidPrice, askPrice decimal
 
 // placeSingleQuote places a single buy or sell order
 func (mm *MarketMaker) placeSingleQuote(instrument, side string, price decimal.Decimal) error {
-	orderID, err := mm.exchange.PlaceLimitOrder(instrument, side, price, mm.config.QuoteSize)
+	orderID, err := mm.exchange.PlaceLimitOrder(instrument, side, price, mm.config.QuoteSize, mm.orderOptions())
 	if err != nil {
 		return err
 	}
@@ -251,6 +258,9 @@ func (mm *MarketMaker) trackOrder(orderID, instrument, side string, price, amoun
 		mm.ordersByInstrument[instrument] = make(map[string]*types.MarketMakerOrder)
 	}
 	mm.ordersByInstrument[instrument][side] = order
+
+	// The order may have filled before we got its ID back
+	mm.applyEarlyUpdate(orderID)
 }
 
 // cancelOrder cancels a single order
@@ -325,6 +335,20 @@ func (mm *MarketMaker) cancelOrder(orderID string) bool {
 	return false
 }
 
+// cancelInstrumentOrders cancels an instrument's tracked orders
+func (mm *MarketMaker) cancelInstrumentOrders(instrument string) {
+	mm.mu.RLock()
+	orderIDs := make([]string, 0, len(mm.ordersByInstrument[instrument]))
+	for _, order := range mm.ordersByInstrument[instrument] {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+	mm.mu.RUnlock()
+
+	for _, orderID := range orderIDs {
+		mm.cancelOrder(orderID)
+	}
+}
+
 // removeOrderFromTracking removes an order from internal tracking (must be called with lock held)
 func (mm *MarketMaker) removeOrderFromTracking(orderID string) {
 	if order, exists := mm.activeOrders[orderID]; exists {
@@ -66,6 +66,16 @@ func (mm *MarketMaker) UpdateQuotesForInstrument(instrument string) error {
 		return nil
 	}
 
+	// Check margin usage. Quotes that can't be updated are pulled rather
+	// than left resting at stale prices.
+	if mm.margin != nil {
+		if err := mm.margin.Allow(); err != nil {
+			log.Printf("Pulling quotes for %s: %v", instrument, err)
+			mm.cancelInstrumentOrders(instrument)
+			return nil
+		}
+	}
+
 	// Update orders
 	return mm.updateOrCreateOrders(instrument, bidPrice, askPrice)
 }
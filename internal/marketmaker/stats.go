@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/ratelimit"
 	"github.com/wakamex/atomizer/internal/types"
 )
 
@@ -60,6 +61,23 @@ func (mm *MarketMaker) reportStats(startTime time.Time) {
 		len(mm.config.Instruments),
 		mm.stats.UptimeSeconds)
 
+	// Requests the client-side rate limiter held back since startup
+	for _, throttle := range ratelimit.AllStats() {
+		if throttle.Throttled > 0 {
+			log.Printf("Rate limit: %s %s %s priority throttled %d times, waited %v",
+				throttle.Exchange, throttle.Bucket, throttle.Priority, throttle.Throttled, throttle.Waited.Round(time.Millisecond))
+		}
+	}
+
+	// Margin usage of the trading subaccount
+	if mm.margin != nil {
+		if state, ok := mm.margin.State(); ok {
+			log.Printf("Margin: subaccount %d usage %s%%, available %s of %s",
+				state.SubaccountID, state.Utilization().Mul(decimal.NewFromInt(100)).StringFixed(1),
+				state.AvailableMargin.StringFixed(2), state.SubaccountValue.StringFixed(2))
+		}
+	}
+
 	// Detailed order state in debug mode
 	if debugMode {
 		mm.logDetailedOrderState()
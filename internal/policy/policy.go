@@ -0,0 +1,228 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// Action is what to do with an RFQ
+type Action string
+
+const (
+	ActionQuote   Action = "quote"   // Quote at the priced level
+	ActionDecline Action = "decline" // Send no quote
+	ActionAdjust  Action = "adjust"  // Quote at Decision.Price instead
+)
+
+// Decision is a rule's verdict on an RFQ
+type Decision struct {
+	Action Action
+	Rule   string
+	Reason string
+	Price  float64 // Price per contract to quote when adjusting
+}
+
+// Quote is the decision to quote as priced
+var Quote = Decision{Action: ActionQuote}
+
+// Decline returns a decision to send no quote
+func Decline(rule, format string, args ...interface{}) Decision {
+	return Decision{Action: ActionDecline, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Request is an RFQ with its terms resolved. Price, APR and Spot are set once
+// it has been priced.
+type Request struct {
+	ID         string
+	RFQ        types.RFQResult
+	Underlying string
+	Strike     float64
+	Expiry     time.Time
+	Quantity   float64 // Contracts
+
+	Price float64 // Quoted price per contract
+	APR   float64 // APR the taker earns at Price
+	Spot  float64 // Underlying price at pricing time
+}
+
+// NewRequest resolves an RFQ's underlying and terms from its Rysk fixed
+// point fields
+func NewRequest(rfqID string, rfq types.RFQResult) (*Request, error) {
+	underlying, err := instruments.ResolveRyskAsset(rfq.ChainID, rfq.Asset)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := instruments.FromRysk(underlying, rfq.Strike, rfq.Expiry, rfq.IsPut)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := parseQuantity(rfq.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		ID:         rfqID,
+		RFQ:        rfq,
+		Underlying: underlying,
+		Strike:     inst.Strike.InexactFloat64(),
+		Expiry:     inst.Expiry,
+		Quantity:   quantity,
+	}, nil
+}
+
+// Rule judges an RFQ before it is priced
+type Rule interface {
+	Name() string
+	Screen(req *Request) Decision
+}
+
+// PriceRule judges an RFQ's price before the quote is sent, and may adjust it
+type PriceRule interface {
+	Name() string
+	Review(req *Request) Decision
+}
+
+// Engine runs rules over each RFQ, logging and counting what they decide
+type Engine struct {
+	rules       []Rule
+	priceRules  []PriceRule
+	dummyQuotes bool
+
+	mu     sync.Mutex
+	counts map[decisionKey]int64
+}
+
+// decisionKey counts decisions by action and the rule that made them
+type decisionKey struct {
+	action Action
+	rule   string
+}
+
+// NewEngine creates an engine without rules, which quotes every RFQ it can
+// price
+func NewEngine() *Engine {
+	return &Engine{counts: make(map[decisionKey]int64)}
+}
+
+// AddRule adds a rule run before pricing. Rules run in the order added.
+func (e *Engine) AddRule(rule Rule) {
+	e.rules = append(e.rules, rule)
+}
+
+// AddPriceRule adds a rule run on the priced RFQ. Rules run in the order
+// added.
+func (e *Engine) AddPriceRule(rule PriceRule) {
+	e.priceRules = append(e.priceRules, rule)
+}
+
+// SetDummyQuotes answers RFQs that can't be priced with the dummy price
+// instead of declining them
+func (e *Engine) SetDummyQuotes(allow bool) {
+	e.dummyQuotes = allow
+}
+
+// DummyQuotes reports whether RFQs that can't be priced get the dummy price
+func (e *Engine) DummyQuotes() bool {
+	return e.dummyQuotes
+}
+
+// Screen runs the rules that need no price, returning the first decline
+func (e *Engine) Screen(req *Request) Decision {
+	for _, rule := range e.rules {
+		if d := rule.Screen(req); d.Action == ActionDecline {
+			return e.record(req.ID, d)
+		}
+	}
+	return Quote
+}
+
+// Review runs the price rules on a priced RFQ. A decline stops the review,
+// and adjustments carry into later rules through req.Price.
+func (e *Engine) Review(req *Request) Decision {
+	decision := Quote
+	for _, rule := range e.priceRules {
+		d := rule.Review(req)
+		switch d.Action {
+		case ActionDecline:
+			return e.record(req.ID, d)
+		case ActionAdjust:
+			log.Printf("[Policy %s] %s adjusted price %.4f -> %.4f: %s", req.ID, d.Rule, req.Price, d.Price, d.Reason)
+			req.Price = d.Price
+			decision = d
+		}
+	}
+	if decision.Action == ActionAdjust {
+		e.count(decision)
+		return decision
+	}
+	return e.record(req.ID, Quote)
+}
+
+// Fail declines an RFQ that couldn't be resolved or priced, unless dummy
+// quotes are allowed
+func (e *Engine) Fail(rfqID, rule string, err error) Decision {
+	if e.dummyQuotes {
+		return e.record(rfqID, Decision{Action: ActionQuote, Rule: rule, Reason: fmt.Sprintf("dummy price: %v", err)})
+	}
+	return e.record(rfqID, Decline(rule, "%v", err))
+}
+
+// record logs and counts a decision
+func (e *Engine) record(rfqID string, d Decision) Decision {
+	if d.Action == ActionDecline {
+		log.Printf("[Policy %s] Declined by %s: %s", rfqID, d.Rule, d.Reason)
+	} else if d.Reason != "" {
+		log.Printf("[Policy %s] Quoting despite %s: %s", rfqID, d.Rule, d.Reason)
+	}
+	e.count(d)
+	return d
+}
+
+// count counts a decision
+func (e *Engine) count(d Decision) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.counts[decisionKey{d.Action, d.Rule}]++
+}
+
+// Counts returns how many RFQs each rule has declined or adjusted and how many
+// were quoted, keyed by "action" or "action/rule"
+func (e *Engine) Counts() map[string]int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counts := make(map[string]int64, len(e.counts))
+	for key, n := range e.counts {
+		name := string(key.action)
+		if key.rule != "" {
+			name += "/" + key.rule
+		}
+		counts[name] += n
+	}
+	return counts
+}
+
+// WriteMetrics writes decision counts in Prometheus format
+func (e *Engine) WriteMetrics(w io.Writer) {
+	counts := e.Counts()
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "# HELP rfq_policy_decisions_total RFQs quoted, adjusted or declined by the quoting policy\n")
+	fmt.Fprintf(w, "# TYPE rfq_policy_decisions_total counter\n")
+	for _, name := range names {
+		action, rule, _ := strings.Cut(name, "/")
+		fmt.Fprintf(w, "rfq_policy_decisions_total{action=%q,rule=%q} %d\n", action, rule, counts[name])
+	}
+}
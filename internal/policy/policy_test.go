@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestScreenDeclinesOnFirstFailingRule(t *testing.T) {
+	engine := NewEngine()
+	engine.AddRule(MaxSize{"ETH": 50, "*": 10})
+	engine.AddRule(NewTakers(nil, []string{"0xBAD"}))
+
+	req := &Request{ID: "rfq-1", Underlying: "ETH", Quantity: 20, RFQ: types.RFQResult{Taker: "0xbad"}}
+	if d := engine.Screen(req); d.Action != ActionDecline || d.Rule != "taker" {
+		t.Errorf("denied taker: got %+v, want a taker decline", d)
+	}
+
+	req = &Request{ID: "rfq-2", Underlying: "BTC", Quantity: 20, RFQ: types.RFQResult{Taker: "0xgood"}}
+	if d := engine.Screen(req); d.Action != ActionDecline || d.Rule != "max-size" {
+		t.Errorf("oversized BTC: got %+v, want a max-size decline", d)
+	}
+
+	req = &Request{ID: "rfq-3", Underlying: "ETH", Quantity: 20, RFQ: types.RFQResult{Taker: "0xgood"}}
+	if d := engine.Screen(req); d.Action != ActionQuote {
+		t.Errorf("ETH within limit: got %+v, want a quote", d)
+	}
+
+	counts := engine.Counts()
+	if counts["decline/taker"] != 1 || counts["decline/max-size"] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+}
+
+func TestTakersAllowList(t *testing.T) {
+	takers := NewTakers([]string{"0xAbC", " "}, nil)
+	if d := takers.Screen(&Request{RFQ: types.RFQResult{Taker: "0xabc"}}); d.Action != ActionQuote {
+		t.Errorf("allowed taker: got %+v", d)
+	}
+	if d := takers.Screen(&Request{RFQ: types.RFQResult{Taker: "0xdef"}}); d.Action != ActionDecline {
+		t.Errorf("unlisted taker: got %+v, want a decline", d)
+	}
+}
+
+func TestExpiryWindow(t *testing.T) {
+	window := ExpiryWindow{Min: 24 * time.Hour, Max: 30 * 24 * time.Hour}
+	tests := []struct {
+		in   time.Duration
+		want Action
+	}{
+		{time.Hour, ActionDecline},
+		{7 * 24 * time.Hour, ActionQuote},
+		{60 * 24 * time.Hour, ActionDecline},
+	}
+	for _, tt := range tests {
+		req := &Request{Expiry: time.Now().Add(tt.in)}
+		if d := window.Screen(req); d.Action != tt.want {
+			t.Errorf("expiry in %v: got %+v, want %s", tt.in, d, tt.want)
+		}
+	}
+}
+
+func TestMinAPRRaisesSalesAndDeclinesBuys(t *testing.T) {
+	engine := NewEngine()
+	engine.AddPriceRule(MinAPR(20))
+
+	// An OTM call at 1 per contract is well under 20% APR on $3000 spot
+	expiry := time.Now().Add(365 * 24 * time.Hour)
+	sell := &Request{
+		ID:     "rfq-1",
+		RFQ:    types.RFQResult{IsTakerBuy: true},
+		Strike: 3500,
+		Expiry: expiry,
+		Price:  1,
+		Spot:   3000,
+	}
+	d := engine.Review(sell)
+	if d.Action != ActionAdjust {
+		t.Fatalf("selling to the taker: got %+v, want an adjustment", d)
+	}
+	if math.Abs(d.Price-600) > 1 || sell.Price != d.Price {
+		t.Errorf("adjusted price = %g (request %g), want about 600", d.Price, sell.Price)
+	}
+
+	buy := &Request{
+		ID:     "rfq-2",
+		RFQ:    types.RFQResult{IsTakerBuy: false},
+		Strike: 3500,
+		Expiry: expiry,
+		Price:  1,
+		Spot:   3000,
+	}
+	if d := engine.Review(buy); d.Action != ActionDecline || d.Rule != "min-apr" {
+		t.Errorf("buying from the taker: got %+v, want a min-apr decline", d)
+	}
+}
+
+func TestMoneyness(t *testing.T) {
+	band := Moneyness{Min: 0.8, Max: 1.2}
+	if d := band.Review(&Request{Strike: 3000, Spot: 3000}); d.Action != ActionQuote {
+		t.Errorf("at the money: got %+v", d)
+	}
+	if d := band.Review(&Request{Strike: 5000, Spot: 3000}); d.Action != ActionDecline {
+		t.Errorf("far out of the money: got %+v, want a decline", d)
+	}
+}
+
+func TestFailHonoursDummyQuotes(t *testing.T) {
+	engine := NewEngine()
+	if d := engine.Fail("rfq-1", "pricing", errors.New("no vol")); d.Action != ActionDecline {
+		t.Errorf("without dummy quotes: got %+v, want a decline", d)
+	}
+	engine.SetDummyQuotes(true)
+	if d := engine.Fail("rfq-2", "pricing", errors.New("no vol")); d.Action != ActionQuote {
+		t.Errorf("with dummy quotes: got %+v, want a quote", d)
+	}
+
+	var metrics strings.Builder
+	engine.WriteMetrics(&metrics)
+	for _, want := range []string{
+		`rfq_policy_decisions_total{action="decline",rule="pricing"} 1`,
+		`rfq_policy_decisions_total{action="quote",rule="pricing"} 1`,
+	} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("metrics missing %s:\n%s", want, metrics.String())
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/quoter"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// ryskQuantityDecimals is the fixed point scale of RFQ quantities
+const ryskQuantityDecimals = 1e18
+
+// parseQuantity converts an RFQ quantity from wei to contracts
+func parseQuantity(quantity string) (float64, error) {
+	wei, ok := new(big.Float).SetString(quantity)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse quantity: %s", quantity)
+	}
+	value, _ := wei.Quo(wei, big.NewFloat(ryskQuantityDecimals)).Float64()
+	return value, nil
+}
+
+// MaxSize declines RFQs larger than a limit per underlying. The "*" entry
+// applies to underlyings without their own.
+type MaxSize map[string]float64
+
+func (MaxSize) Name() string { return "max-size" }
+
+func (m MaxSize) Screen(req *Request) Decision {
+	limit, ok := m[req.Underlying]
+	if !ok {
+		limit, ok = m["*"]
+	}
+	if ok && req.Quantity > limit {
+		return Decline(m.Name(), "%g %s is over the %g limit", req.Quantity, req.Underlying, limit)
+	}
+	return Quote
+}
+
+// ExpiryWindow declines RFQs expiring sooner than Min or later than Max from
+// now. Zero bounds are not checked.
+type ExpiryWindow struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (ExpiryWindow) Name() string { return "expiry" }
+
+func (w ExpiryWindow) Screen(req *Request) Decision {
+	left := time.Until(req.Expiry)
+	if w.Min > 0 && left < w.Min {
+		return Decline(w.Name(), "expires in %v, under the %v minimum", left.Round(time.Minute), w.Min)
+	}
+	if w.Max > 0 && left > w.Max {
+		return Decline(w.Name(), "expires in %v, over the %v maximum", left.Round(time.Minute), w.Max)
+	}
+	return Quote
+}
+
+// Takers declines RFQs from denied takers, and from takers not allowed when
+// an allow list is set. Addresses are matched case insensitively.
+type Takers struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewTakers creates taker lists from addresses
+func NewTakers(allow, deny []string) Takers {
+	set := func(addresses []string) map[string]bool {
+		m := make(map[string]bool, len(addresses))
+		for _, address := range addresses {
+			if address = strings.TrimSpace(address); address != "" {
+				m[strings.ToLower(address)] = true
+			}
+		}
+		return m
+	}
+	return Takers{allow: set(allow), deny: set(deny)}
+}
+
+func (Takers) Name() string { return "taker" }
+
+func (t Takers) Screen(req *Request) Decision {
+	taker := strings.ToLower(req.RFQ.Taker)
+	if t.deny[taker] {
+		return Decline(t.Name(), "taker %s is denied", req.RFQ.Taker)
+	}
+	if len(t.allow) > 0 && !t.allow[taker] {
+		return Decline(t.Name(), "taker %q is not allowed", req.RFQ.Taker)
+	}
+	return Quote
+}
+
+// BookLiquidity declines RFQs the hedge venue's book can't absorb: the side a
+// hedge would take must hold MinCoverage times the RFQ quantity
+type BookLiquidity struct {
+	Exchange    types.Exchange
+	MinCoverage float64
+}
+
+func (BookLiquidity) Name() string { return "book-liquidity" }
+
+func (b BookLiquidity) Screen(req *Request) Decision {
+	book, err := b.Exchange.GetOrderBook(req.RFQ, req.Underlying)
+	if err != nil {
+		return Decline(b.Name(), "no book: %v", err)
+	}
+
+	// Selling to the taker is hedged by buying, which takes the asks
+	levels := book.Bids
+	if req.RFQ.IsTakerBuy {
+		levels = book.Asks
+	}
+	depth := 0.0
+	for _, level := range levels {
+		if len(level) >= 2 {
+			depth += level[1]
+		}
+	}
+	if need := req.Quantity * b.MinCoverage; depth < need {
+		return Decline(b.Name(), "book holds %g of the %g needed", depth, need)
+	}
+	return Quote
+}
+
+// TradeValidator checks a trade against risk limits, such as risk.Manager
+type TradeValidator interface {
+	ValidateTrade(trade *types.TradeEvent) error
+}
+
+// RiskHeadroom declines RFQs whose fill would breach risk limits
+type RiskHeadroom struct {
+	Risk TradeValidator
+}
+
+func (RiskHeadroom) Name() string { return "risk" }
+
+func (r RiskHeadroom) Screen(req *Request) Decision {
+	trade := &types.TradeEvent{
+		ID:         req.ID,
+		Source:     types.TradeSourceRysk,
+		RFQId:      req.ID,
+		Instrument: req.Underlying,
+		Strike:     decimal.NewFromFloat(req.Strike),
+		Expiry:     req.Expiry.Unix(),
+		IsPut:      req.RFQ.IsPut,
+		Quantity:   decimal.NewFromFloat(req.Quantity),
+		IsTakerBuy: req.RFQ.IsTakerBuy,
+		Timestamp:  time.Now(),
+	}
+	if err := r.Risk.ValidateTrade(trade); err != nil {
+		return Decline(r.Name(), "%v", err)
+	}
+	return Quote
+}
+
+// Moneyness declines RFQs whose strike is outside a band of spot, as a ratio
+// of strike to spot. Zero bounds are not checked.
+type Moneyness struct {
+	Min float64
+	Max float64
+}
+
+func (Moneyness) Name() string { return "moneyness" }
+
+func (m Moneyness) Review(req *Request) Decision {
+	if req.Spot <= 0 {
+		return Decline(m.Name(), "no spot price")
+	}
+	ratio := req.Strike / req.Spot
+	if (m.Min > 0 && ratio < m.Min) || (m.Max > 0 && ratio > m.Max) {
+		return Decline(m.Name(), "strike/spot %.3f is outside %g-%g", ratio, m.Min, m.Max)
+	}
+	return Quote
+}
+
+// MinAPR keeps quotes at or above an APR for the taker, in percent. Quotes
+// selling to the taker are raised to it, which only widens our edge; quotes
+// buying from the taker are declined, since raising them would eat into it.
+type MinAPR float64
+
+func (MinAPR) Name() string { return "min-apr" }
+
+func (m MinAPR) Review(req *Request) Decision {
+	days := time.Until(req.Expiry).Hours() / 24
+	apr := quoter.CalculateAPR(req.Price, req.Strike, req.Spot, days, req.RFQ.IsPut)
+	if apr >= float64(m) {
+		return Quote
+	}
+	if !req.RFQ.IsTakerBuy {
+		return Decline(m.Name(), "APR %.2f%% is under %.2f%%", apr, float64(m))
+	}
+
+	// Invert CalculateAPR: time value is a share of the strike for puts and
+	// of spot for calls
+	base, intrinsic := req.Spot, req.Spot-req.Strike
+	if req.RFQ.IsPut {
+		base, intrinsic = req.Strike, req.Strike-req.Spot
+	}
+	if base <= 0 || days <= 0 {
+		return Decline(m.Name(), "APR %.2f%% is under %.2f%%", apr, float64(m))
+	}
+	if intrinsic < 0 {
+		intrinsic = 0
+	}
+	return Decision{
+		Action: ActionAdjust,
+		Rule:   m.Name(),
+		Reason: fmt.Sprintf("APR %.2f%% raised to %.2f%%", apr, float64(m)),
+		Price:  intrinsic + float64(m)/100*base*days/365,
+	}
+}
@@ -0,0 +1,166 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	secondsPerYear = 365 * 24 * 60 * 60
+	daysPerYear    = 365.0
+)
+
+// Greeks contains an option's theoretical price and sensitivities.
+// Vega and Rho are per 1% move, Theta is per calendar day.
+type Greeks struct {
+	Price float64
+	Delta float64
+	Gamma float64
+	Vega  float64
+	Theta float64
+	Rho   float64
+}
+
+// BlackScholes prices a European option on a spot underlying with no carry
+func BlackScholes(spot, strike, timeToExpiry, rate, vol float64, isPut bool) Greeks {
+	if timeToExpiry <= 0 || vol <= 0 || spot <= 0 || strike <= 0 {
+		return intrinsic(spot, strike, isPut)
+	}
+
+	sqrtT := math.Sqrt(timeToExpiry)
+	d1 := (math.Log(spot/strike) + (rate+0.5*vol*vol)*timeToExpiry) / (vol * sqrtT)
+	d2 := d1 - vol*sqrtT
+	df := math.Exp(-rate * timeToExpiry)
+
+	g := Greeks{
+		Gamma: normPDF(d1) / (spot * vol * sqrtT),
+		Vega:  spot * normPDF(d1) * sqrtT / 100,
+	}
+
+	decay := -spot * normPDF(d1) * vol / (2 * sqrtT)
+	if isPut {
+		g.Price = strike*df*normCDF(-d2) - spot*normCDF(-d1)
+		g.Delta = normCDF(d1) - 1
+		g.Theta = (decay + rate*strike*df*normCDF(-d2)) / daysPerYear
+		g.Rho = -strike * timeToExpiry * df * normCDF(-d2) / 100
+	} else {
+		g.Price = spot*normCDF(d1) - strike*df*normCDF(d2)
+		g.Delta = normCDF(d1)
+		g.Theta = (decay - rate*strike*df*normCDF(d2)) / daysPerYear
+		g.Rho = strike * timeToExpiry * df * normCDF(d2) / 100
+	}
+
+	return g
+}
+
+// Black76 prices a European option on a forward. Delta and Gamma are with
+// respect to the forward price.
+func Black76(forward, strike, timeToExpiry, rate, vol float64, isPut bool) Greeks {
+	if timeToExpiry <= 0 || vol <= 0 || forward <= 0 || strike <= 0 {
+		return intrinsic(forward, strike, isPut)
+	}
+
+	sqrtT := math.Sqrt(timeToExpiry)
+	d1 := (math.Log(forward/strike) + 0.5*vol*vol*timeToExpiry) / (vol * sqrtT)
+	d2 := d1 - vol*sqrtT
+	df := math.Exp(-rate * timeToExpiry)
+
+	g := Greeks{
+		Gamma: df * normPDF(d1) / (forward * vol * sqrtT),
+		Vega:  df * forward * normPDF(d1) * sqrtT / 100,
+	}
+
+	decay := -df * forward * normPDF(d1) * vol / (2 * sqrtT)
+	if isPut {
+		g.Price = df * (strike*normCDF(-d2) - forward*normCDF(-d1))
+		g.Delta = -df * normCDF(-d1)
+	} else {
+		g.Price = df * (forward*normCDF(d1) - strike*normCDF(d2))
+		g.Delta = df * normCDF(d1)
+	}
+	g.Theta = (decay + rate*g.Price) / daysPerYear
+	g.Rho = -timeToExpiry * g.Price / 100
+
+	return g
+}
+
+// ImpliedVol solves for the Black-Scholes volatility matching price
+func ImpliedVol(price, spot, strike, timeToExpiry, rate float64, isPut bool) (float64, error) {
+	if timeToExpiry <= 0 {
+		return 0, fmt.Errorf("option has expired")
+	}
+
+	lower := intrinsic(spot, strike, isPut).Price
+	if price <= lower {
+		return 0, fmt.Errorf("price %.6f is not above intrinsic value %.6f", price, lower)
+	}
+
+	// Newton-Raphson from a reasonable starting point, falling back to bisection
+	vol := 0.5
+	for i := 0; i < 50; i++ {
+		g := BlackScholes(spot, strike, timeToExpiry, rate, vol, isPut)
+		diff := g.Price - price
+		if math.Abs(diff) < 1e-8 {
+			return vol, nil
+		}
+		vega := g.Vega * 100
+		if vega < 1e-10 {
+			break
+		}
+		next := vol - diff/vega
+		if next <= 0 || next > 10 || math.IsNaN(next) {
+			break
+		}
+		vol = next
+	}
+
+	low, high := 1e-4, 10.0
+	if BlackScholes(spot, strike, timeToExpiry, rate, high, isPut).Price < price {
+		return 0, fmt.Errorf("price %.6f is above the maximum model price", price)
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if BlackScholes(spot, strike, timeToExpiry, rate, mid, isPut).Price > price {
+			high = mid
+		} else {
+			low = mid
+		}
+		if high-low < 1e-8 {
+			break
+		}
+	}
+
+	return (low + high) / 2, nil
+}
+
+// YearsToExpiry returns the time from now until a unix expiry in years
+func YearsToExpiry(expiry int64, now time.Time) float64 {
+	seconds := float64(expiry - now.Unix())
+	if seconds <= 0 {
+		return 0
+	}
+	return seconds / secondsPerYear
+}
+
+// intrinsic returns the value of an option at expiry
+func intrinsic(underlying, strike float64, isPut bool) Greeks {
+	if isPut {
+		if underlying < strike {
+			return Greeks{Price: strike - underlying, Delta: -1}
+		}
+		return Greeks{}
+	}
+	if underlying > strike {
+		return Greeks{Price: underlying - strike, Delta: 1}
+	}
+	return Greeks{}
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+}
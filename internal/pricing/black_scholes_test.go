@@ -0,0 +1,168 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBlackScholes(t *testing.T) {
+	tests := []struct {
+		name      string
+		spot      float64
+		strike    float64
+		t         float64
+		rate      float64
+		vol       float64
+		isPut     bool
+		wantPrice float64
+		wantDelta float64
+	}{
+		// Reference values from Hull, Options Futures and Other Derivatives
+		{"Hull call", 42, 40, 0.5, 0.1, 0.2, false, 4.7594, 0.7791},
+		{"Hull put", 42, 40, 0.5, 0.1, 0.2, true, 0.8086, -0.2209},
+		{"ATM call no rate", 3000, 3000, 30.0 / 365, 0, 0.6, false, 205.62, 0.5343},
+		{"Expired ITM put", 2500, 3000, 0, 0, 0.6, true, 500, -1},
+		{"Expired OTM call", 2500, 3000, 0, 0, 0.6, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := BlackScholes(tt.spot, tt.strike, tt.t, tt.rate, tt.vol, tt.isPut)
+			if math.Abs(g.Price-tt.wantPrice) > 0.01 {
+				t.Errorf("price = %.4f, want %.4f", g.Price, tt.wantPrice)
+			}
+			if math.Abs(g.Delta-tt.wantDelta) > 0.001 {
+				t.Errorf("delta = %.4f, want %.4f", g.Delta, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestPutCallParity(t *testing.T) {
+	spot, strike, tte, rate, vol := 3100.0, 3000.0, 0.25, 0.05, 0.7
+
+	call := BlackScholes(spot, strike, tte, rate, vol, false)
+	put := BlackScholes(spot, strike, tte, rate, vol, true)
+
+	parity := spot - strike*math.Exp(-rate*tte)
+	if diff := call.Price - put.Price - parity; math.Abs(diff) > 1e-8 {
+		t.Errorf("put-call parity violated by %.10f", diff)
+	}
+	if math.Abs(call.Gamma-put.Gamma) > 1e-12 {
+		t.Errorf("call gamma %.8f != put gamma %.8f", call.Gamma, put.Gamma)
+	}
+	if math.Abs(call.Delta-put.Delta-1) > 1e-12 {
+		t.Errorf("call delta - put delta = %.8f, want 1", call.Delta-put.Delta)
+	}
+}
+
+func TestGreeksMatchFiniteDifferences(t *testing.T) {
+	spot, strike, tte, rate, vol := 3000.0, 3200.0, 0.1, 0.03, 0.65
+	g := BlackScholes(spot, strike, tte, rate, vol, false)
+
+	h := 0.01
+	up := BlackScholes(spot+h, strike, tte, rate, vol, false)
+	down := BlackScholes(spot-h, strike, tte, rate, vol, false)
+	if fd := (up.Price - down.Price) / (2 * h); math.Abs(fd-g.Delta) > 1e-6 {
+		t.Errorf("delta = %.8f, finite difference %.8f", g.Delta, fd)
+	}
+	if fd := (up.Price - 2*g.Price + down.Price) / (h * h); math.Abs(fd-g.Gamma) > 1e-5 {
+		t.Errorf("gamma = %.8f, finite difference %.8f", g.Gamma, fd)
+	}
+
+	volUp := BlackScholes(spot, strike, tte, rate, vol+0.0001, false)
+	if fd := (volUp.Price - g.Price) / 0.0001 / 100; math.Abs(fd-g.Vega) > 1e-3 {
+		t.Errorf("vega = %.6f, finite difference %.6f", g.Vega, fd)
+	}
+
+	day := 1.0 / 365
+	later := BlackScholes(spot, strike, tte-day, rate, vol, false)
+	if fd := later.Price - g.Price; math.Abs(fd-g.Theta) > 0.05 {
+		t.Errorf("theta = %.6f, finite difference %.6f", g.Theta, fd)
+	}
+}
+
+func TestBlack76MatchesBlackScholesOnForward(t *testing.T) {
+	spot, strike, tte, rate, vol := 3000.0, 2800.0, 0.5, 0.04, 0.55
+	forward := spot * math.Exp(rate*tte)
+
+	for _, isPut := range []bool{false, true} {
+		bs := BlackScholes(spot, strike, tte, rate, vol, isPut)
+		b76 := Black76(forward, strike, tte, rate, vol, isPut)
+		if math.Abs(bs.Price-b76.Price) > 1e-8 {
+			t.Errorf("isPut=%v: Black76 price %.8f != Black-Scholes %.8f", isPut, b76.Price, bs.Price)
+		}
+	}
+}
+
+func TestImpliedVol(t *testing.T) {
+	tests := []struct {
+		name   string
+		spot   float64
+		strike float64
+		t      float64
+		vol    float64
+		isPut  bool
+	}{
+		{"ATM call", 3000, 3000, 0.1, 0.6, false},
+		{"OTM put", 3000, 2500, 0.25, 0.8, true},
+		{"Deep ITM call", 3000, 2000, 0.05, 0.9, false},
+		{"Short dated OTM call", 3000, 3300, 2.0 / 365, 0.5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := BlackScholes(tt.spot, tt.strike, tt.t, 0, tt.vol, tt.isPut).Price
+			got, err := ImpliedVol(price, tt.spot, tt.strike, tt.t, 0, tt.isPut)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.vol) > 1e-4 {
+				t.Errorf("implied vol = %.6f, want %.6f", got, tt.vol)
+			}
+		})
+	}
+
+	if _, err := ImpliedVol(100, 3000, 2000, 0.1, 0, false); err == nil {
+		t.Error("expected error for price below intrinsic")
+	}
+}
+
+type staticSource struct {
+	data *MarketData
+}
+
+func (s staticSource) GetMarketData(instrument string) (*MarketData, error) {
+	return s.data, nil
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	expiry := time.Now().Add(30 * 24 * time.Hour).Unix()
+
+	engine := NewEngine(staticSource{&MarketData{Spot: 3000, IV: 0.8}})
+	v, err := engine.Evaluate(Option{Instrument: "ETH-TEST-3000-C", Strike: 3000, Expiry: expiry})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.IV != 0.8 || v.Spot != 3000 {
+		t.Errorf("expected market data to be used, got spot=%.2f iv=%.2f", v.Spot, v.IV)
+	}
+
+	// No source: falls back to the option's spot and the default vol
+	engine = NewEngine(nil)
+	v, err = engine.Evaluate(Option{Strike: 3000, Expiry: expiry, IsPut: true, Spot: 3100})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.IV != DefaultVol {
+		t.Errorf("iv = %.2f, want default %.2f", v.IV, DefaultVol)
+	}
+	if v.Delta >= 0 || v.Delta <= -0.5 {
+		t.Errorf("OTM put delta = %.4f, want in (-0.5, 0)", v.Delta)
+	}
+
+	if _, err := engine.Evaluate(Option{Strike: 3000, Expiry: expiry}); err == nil {
+		t.Error("expected error without an underlying price")
+	}
+}
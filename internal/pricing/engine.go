@@ -0,0 +1,160 @@
+package pricing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/exchange/derive"
+)
+
+// DefaultVol is used when no implied vol is available for an option
+const DefaultVol = 0.6
+
+// MarketData holds the inputs the engine needs from the market
+type MarketData struct {
+	Spot    float64 // Underlying index price
+	Forward float64 // Forward price for the option's expiry (0 if unknown)
+	IV      float64 // Implied vol as a decimal (0.65 = 65%)
+}
+
+// MarketDataSource provides market data for an option instrument
+type MarketDataSource interface {
+	GetMarketData(instrument string) (*MarketData, error)
+}
+
+// Option describes an option to value
+type Option struct {
+	Instrument string // Exchange instrument name used for market data lookups
+	Strike     float64
+	Expiry     int64 // Unix seconds
+	IsPut      bool
+	Spot       float64 // Fallback underlying price when the source has none
+}
+
+// Valuation is the result of valuing an option along with the inputs used
+type Valuation struct {
+	Greeks
+	Spot         float64
+	IV           float64
+	TimeToExpiry float64 // In years
+}
+
+// Engine values options from market data, falling back to defaults when
+// the market data source has nothing for an instrument
+type Engine struct {
+	source     MarketDataSource
+	rate       float64
+	defaultVol float64
+}
+
+// NewEngine creates a pricing engine. source may be nil.
+func NewEngine(source MarketDataSource) *Engine {
+	return &Engine{
+		source:     source,
+		defaultVol: DefaultVol,
+	}
+}
+
+// SetRate sets the risk-free rate used for discounting
+func (e *Engine) SetRate(rate float64) {
+	e.rate = rate
+}
+
+// SetDefaultVol sets the vol used when no implied vol is available
+func (e *Engine) SetDefaultVol(vol float64) {
+	e.defaultVol = vol
+}
+
+// Evaluate computes the price and Greeks of an option
+func (e *Engine) Evaluate(opt Option) (*Valuation, error) {
+	if opt.Strike <= 0 {
+		return nil, fmt.Errorf("invalid strike %.4f", opt.Strike)
+	}
+
+	var md MarketData
+	if e.source != nil && opt.Instrument != "" {
+		if data, err := e.source.GetMarketData(opt.Instrument); err == nil {
+			md = *data
+		}
+	}
+
+	spot := md.Spot
+	if spot <= 0 {
+		spot = opt.Spot
+	}
+	if spot <= 0 {
+		return nil, fmt.Errorf("no underlying price available for %s", opt.Instrument)
+	}
+
+	vol := md.IV
+	if vol <= 0 {
+		vol = e.defaultVol
+	}
+
+	t := YearsToExpiry(opt.Expiry, time.Now())
+
+	var greeks Greeks
+	if md.Forward > 0 {
+		greeks = Black76(md.Forward, opt.Strike, t, e.rate, vol, opt.IsPut)
+	} else {
+		greeks = BlackScholes(spot, opt.Strike, t, e.rate, vol, opt.IsPut)
+	}
+
+	return &Valuation{
+		Greeks:       greeks,
+		Spot:         spot,
+		IV:           vol,
+		TimeToExpiry: t,
+	}, nil
+}
+
+// DeriveTickerSource reads spot, forward and IV from Derive tickers
+type DeriveTickerSource struct {
+	ttl   time.Duration
+	cache map[string]cachedMarketData
+	mu    sync.Mutex
+}
+
+type cachedMarketData struct {
+	data      *MarketData
+	fetchedAt time.Time
+}
+
+// NewDeriveTickerSource creates a source backed by the Derive public ticker
+func NewDeriveTickerSource() *DeriveTickerSource {
+	return &DeriveTickerSource{
+		ttl:   30 * time.Second,
+		cache: make(map[string]cachedMarketData),
+	}
+}
+
+// GetMarketData returns market data for an option, named in Derive's or
+// Deribit's format
+func (s *DeriveTickerSource) GetMarketData(instrument string) (*MarketData, error) {
+	instrument = venueName(instrument, "derive")
+
+	s.mu.Lock()
+	if cached, ok := s.cache[instrument]; ok && time.Since(cached.fetchedAt) < s.ttl {
+		s.mu.Unlock()
+		return cached.data, nil
+	}
+	s.mu.Unlock()
+
+	ticker, err := derive.FetchDeriveTicker(instrument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ticker for %s: %w", instrument, err)
+	}
+
+	data := &MarketData{
+		Spot:    ticker.GetIndexPrice(),
+		Forward: ticker.GetForwardPrice(),
+		IV:      ticker.GetIV(),
+	}
+
+	s.mu.Lock()
+	s.cache[instrument] = cachedMarketData{data: data, fetchedAt: time.Now()}
+	s.mu.Unlock()
+
+	return data, nil
+}
@@ -0,0 +1,107 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/instruments"
+)
+
+// NewTickerSource returns the market data source for the venue options are
+// traded and hedged on
+func NewTickerSource(exchange string, testnet bool) MarketDataSource {
+	if strings.HasPrefix(exchange, "deribit") {
+		return NewDeribitTickerSource(testnet)
+	}
+	return NewDeriveTickerSource()
+}
+
+// venueName formats an instrument name for an exchange, leaving names that
+// can't be parsed as they are
+func venueName(instrument, exchange string) string {
+	name, err := instruments.ConvertName(instrument, exchange)
+	if err != nil {
+		return instrument
+	}
+	return name
+}
+
+// DeribitTickerSource reads spot, forward and IV from Deribit tickers
+type DeribitTickerSource struct {
+	client  *http.Client
+	baseURL string
+	ttl     time.Duration
+	cache   map[string]cachedMarketData
+	mu      sync.Mutex
+}
+
+// NewDeribitTickerSource creates a source backed by the Deribit public ticker
+func NewDeribitTickerSource(testnet bool) *DeribitTickerSource {
+	baseURL := "https://www.deribit.com/api/v2"
+	if testnet {
+		baseURL = "https://test.deribit.com/api/v2"
+	}
+	return &DeribitTickerSource{
+		client:  shared.NewHTTPClient(),
+		baseURL: baseURL,
+		ttl:     30 * time.Second,
+		cache:   make(map[string]cachedMarketData),
+	}
+}
+
+// GetMarketData returns market data for an option, named in Deribit's or
+// Derive's format
+func (s *DeribitTickerSource) GetMarketData(instrument string) (*MarketData, error) {
+	name := venueName(instrument, "deribit")
+
+	s.mu.Lock()
+	if cached, ok := s.cache[name]; ok && time.Since(cached.fetchedAt) < s.ttl {
+		s.mu.Unlock()
+		return cached.data, nil
+	}
+	s.mu.Unlock()
+
+	resp, err := s.client.Get(fmt.Sprintf("%s/public/ticker?instrument_name=%s", s.baseURL, url.QueryEscape(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ticker for %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result *struct {
+			IndexPrice      float64 `json:"index_price"`
+			UnderlyingPrice float64 `json:"underlying_price"`
+			MarkIV          float64 `json:"mark_iv"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode ticker for %s: %w", name, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("failed to fetch ticker for %s: %s", name, response.Error.Message)
+	}
+	if response.Result == nil {
+		return nil, fmt.Errorf("no ticker for %s", name)
+	}
+
+	data := &MarketData{
+		Spot:    response.Result.IndexPrice,
+		Forward: response.Result.UnderlyingPrice,
+		IV:      response.Result.MarkIV / 100, // Deribit reports vol in percent
+	}
+
+	s.mu.Lock()
+	s.cache[name] = cachedMarketData{data: data, fetchedAt: time.Now()}
+	s.mu.Unlock()
+
+	return data, nil
+}
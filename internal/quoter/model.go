@@ -0,0 +1,181 @@
+package quoter
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/fees"
+	"github.com/wakamex/atomizer/internal/pricing"
+	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/atomizer/internal/volsurface"
+)
+
+// Pricing modes for RFQ quotes
+const (
+	PricingModeBook  = "book"  // VWAP of the hedge venue book
+	PricingModeModel = "model" // Theoretical value from the vol surface
+	PricingModeBlend = "blend" // Weighted mix of model and book when both exist
+)
+
+// rysk strikes are fixed point with 8 decimals, quantities with 18 and
+// prices and fees with 6. Prices and fees are in USDC, whose 6 decimals they
+// share, so a MaxFee of 1000000 is $1.
+const (
+	ryskStrikeDecimals   = 1e8
+	ryskQuantityDecimals = 1e18
+	ryskPriceDecimals    = 1e6
+)
+
+// EdgeConfig controls the margin added to a quote's reference price
+type EdgeConfig struct {
+	Bps         float64 // bps of underlying notional per contract
+	SizeBps     float64 // additional bps per contract of size
+	MinAbsolute float64 // floor per contract in quote currency
+}
+
+// EdgeConfigFromConfig reads the edge settings from the application config
+func EdgeConfigFromConfig(cfg *config.Config) EdgeConfig {
+	return EdgeConfig{
+		Bps:         cfg.EdgeBps,
+		SizeBps:     cfg.EdgeSizeBps,
+		MinAbsolute: cfg.MinEdge,
+	}
+}
+
+// Edge returns the per-contract edge for a trade of quantity contracts
+func (e EdgeConfig) Edge(spot, quantity float64) float64 {
+	edge := spot * (e.Bps + e.SizeBps*quantity) / 10000
+	return math.Max(edge, e.MinAbsolute)
+}
+
+// modelPrice returns the theoretical value of the RFQ'd option from the surface
+func modelPrice(req types.RFQResult, underlying string, surfaces *volsurface.Service) (price, spot float64, err error) {
+	if surfaces == nil {
+		return 0, 0, fmt.Errorf("no vol surface configured")
+	}
+
+	surface, ok := surfaces.Surface(underlying)
+	if !ok {
+		return 0, 0, fmt.Errorf("no vol surface for %s", underlying)
+	}
+
+	strike, err := rfqStrike(req)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	iv, err := surface.IV(strike, req.Expiry)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get IV: %w", err)
+	}
+
+	t := pricing.YearsToExpiry(req.Expiry, time.Now())
+	forward := surface.Forward(req.Expiry)
+	greeks := pricing.Black76(forward, strike, t, 0, iv, req.IsPut)
+
+	return greeks.Price, surface.Spot, nil
+}
+
+// referencePrice combines the book and model prices according to mode
+func referencePrice(mode string, modelWeight float64, book, model float64, bookErr, modelErr error) (float64, error) {
+	switch mode {
+	case PricingModeModel:
+		if modelErr == nil {
+			return model, nil
+		}
+		if bookErr == nil {
+			return book, nil
+		}
+	case PricingModeBlend:
+		switch {
+		case bookErr == nil && modelErr == nil:
+			w := math.Min(math.Max(modelWeight, 0), 1)
+			return w*model + (1-w)*book, nil
+		case modelErr == nil:
+			return model, nil
+		case bookErr == nil:
+			return book, nil
+		}
+	default:
+		if bookErr == nil {
+			return book, nil
+		}
+		return 0, bookErr
+	}
+
+	return 0, fmt.Errorf("no price available: book: %v, model: %v", bookErr, modelErr)
+}
+
+// applyEdge moves price away from the taker by edge
+func applyEdge(price, edge float64, isTakerBuy bool) (float64, error) {
+	if isTakerBuy {
+		// We sell, charge more
+		return price + edge, nil
+	}
+
+	// We buy, pay less
+	if price-edge <= 0 {
+		return 0, fmt.Errorf("price %.4f does not cover edge %.4f", price, edge)
+	}
+	return price - edge, nil
+}
+
+// feeBound returns an upper bound on the per-contract fees of a trade: the
+// taker fee on the back-to-back hedge plus the RFQ's MaxFee. Rysk's actual fee
+// isn't known when quoting, so the most it may charge is passed through. Venues
+// without a fee schedule are priced at the highest known rates.
+func feeBound(req types.RFQResult, hedgeExchange string, spot, price, quantity float64) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+
+	schedule, err := fees.ForExchange(hedgeExchange)
+	if err != nil {
+		log.Printf("WARNING: %v, pricing hedge fees at the highest known rates", err)
+		schedule = fees.Conservative
+	}
+	fee := schedule.OptionFee(true, quantity, spot, price) / quantity
+
+	if maxFee, err := rfqMaxFee(req); err == nil {
+		fee += maxFee / quantity
+	}
+
+	return fee
+}
+
+// rfqMaxFee converts the fixed point RFQ max fee for the whole trade to a float
+func rfqMaxFee(req types.RFQResult) (float64, error) {
+	if req.MaxFee == "" {
+		return 0, nil
+	}
+	maxFee, ok := new(big.Float).SetString(req.MaxFee)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse max fee: %s", req.MaxFee)
+	}
+	value, _ := maxFee.Quo(maxFee, big.NewFloat(ryskPriceDecimals)).Float64()
+	return value, nil
+}
+
+// rfqStrike converts the fixed point RFQ strike to a float
+func rfqStrike(req types.RFQResult) (float64, error) {
+	strike, ok := new(big.Float).SetString(req.Strike)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse strike: %s", req.Strike)
+	}
+	value, _ := strike.Quo(strike, big.NewFloat(ryskStrikeDecimals)).Float64()
+	return value, nil
+}
+
+// rfqQuantity converts the RFQ quantity from wei to contracts
+func rfqQuantity(req types.RFQResult) (float64, error) {
+	quantity, ok := new(big.Float).SetString(req.Quantity)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse quantity: %s", req.Quantity)
+	}
+	value, _ := quantity.Quo(quantity, big.NewFloat(ryskQuantityDecimals)).Float64()
+	return value, nil
+}
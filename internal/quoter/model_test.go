@@ -0,0 +1,160 @@
+package quoter
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+func TestReferencePrice(t *testing.T) {
+	bookErr := errors.New("no book")
+	modelErr := errors.New("no surface")
+
+	tests := []struct {
+		name     string
+		mode     string
+		weight   float64
+		bookErr  error
+		modelErr error
+		want     float64
+		wantErr  error // Returned as is, or any error when errAny is set
+		errAny   bool
+	}{
+		{name: "book", mode: PricingModeBook, want: 100},
+		{name: "book ignores model", mode: PricingModeBook, modelErr: modelErr, want: 100},
+		{name: "book without book", mode: PricingModeBook, bookErr: bookErr, wantErr: bookErr},
+		{name: "unknown mode prices off book", mode: "vibes", want: 100},
+		{name: "unknown mode without book", mode: "vibes", bookErr: bookErr, wantErr: bookErr},
+
+		{name: "model", mode: PricingModeModel, want: 80},
+		{name: "model falls back to book", mode: PricingModeModel, modelErr: modelErr, want: 100},
+		{name: "model with neither", mode: PricingModeModel, bookErr: bookErr, modelErr: modelErr, errAny: true},
+
+		{name: "blend all book", mode: PricingModeBlend, weight: 0, want: 100},
+		{name: "blend all model", mode: PricingModeBlend, weight: 1, want: 80},
+		{name: "blend quarter model", mode: PricingModeBlend, weight: 0.25, want: 95},
+		{name: "blend weight clamped above", mode: PricingModeBlend, weight: 2, want: 80},
+		{name: "blend weight clamped below", mode: PricingModeBlend, weight: -1, want: 100},
+		{name: "blend without book", mode: PricingModeBlend, weight: 0, bookErr: bookErr, want: 80},
+		{name: "blend without model", mode: PricingModeBlend, weight: 1, modelErr: modelErr, want: 100},
+		{name: "blend with neither", mode: PricingModeBlend, weight: 0.5, bookErr: bookErr, modelErr: modelErr, errAny: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := referencePrice(tt.mode, tt.weight, 100, 80, tt.bookErr, tt.modelErr)
+			switch {
+			case tt.errAny:
+				if err == nil {
+					t.Fatalf("price = %v, want an error", got)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case math.Abs(got-tt.want) > 1e-9:
+				t.Errorf("price = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEdge(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64
+		edge       float64
+		isTakerBuy bool
+		want       float64
+		wantErr    bool
+	}{
+		{"taker buys, we charge more", 100, 5, true, 105, false},
+		{"taker sells, we pay less", 100, 5, false, 95, false},
+		{"no edge", 100, 0, false, 100, false},
+		{"taker buys past any price", 1, 5, true, 6, false},
+		{"edge eats the whole price", 5, 5, false, 0, true},
+		{"edge exceeds price", 4, 5, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEdge(tt.price, tt.edge, tt.isTakerBuy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("price = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("price = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeeBound(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		maxFee   string
+		price    float64
+		quantity float64
+		want     float64
+	}{
+		// 0.03% of 2 x $3000 is $1.80, plus Derive's $0.50 per trade
+		{"derive taker fee", "derive", "", 100, 2, 1.15},
+		// Rysk's $1 max fee is spread over the 2 contracts
+		{"derive with rysk fee", "derive", "1000000", 100, 2, 1.65},
+		{"deribit has no base fee", "deribit", "", 100, 2, 0.9},
+		// 12.5% of a $2 option is below 0.03% of $3000
+		{"fee capped at option price", "deribit", "", 2, 1, 0.25},
+		// 0.03% of 4 x $3000 plus $0.50 at the highest known rates, and
+		// Rysk's $2 max fee
+		{"unknown venue pays highest known rates", "unknown", "2000000", 100, 4, 1.525},
+		{"testnet venue pays highest known rates", "deribit-testnet", "", 100, 2, 1.15},
+		{"bad max fee is ignored", "deribit", "lots", 100, 2, 0.9},
+		{"nothing traded", "derive", "1000000", 100, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := types.RFQResult{MaxFee: tt.maxFee}
+			got := feeBound(req, tt.exchange, 3000, tt.price, tt.quantity)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("fee = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRFQFixedPoint(t *testing.T) {
+	req := types.RFQResult{
+		Strike:   "300000000000",
+		Quantity: "2500000000000000000",
+		MaxFee:   "1500000",
+	}
+	if strike, err := rfqStrike(req); err != nil || strike != 3000 {
+		t.Errorf("strike = %v (%v), want 3000", strike, err)
+	}
+	if quantity, err := rfqQuantity(req); err != nil || quantity != 2.5 {
+		t.Errorf("quantity = %v (%v), want 2.5", quantity, err)
+	}
+	if maxFee, err := rfqMaxFee(req); err != nil || maxFee != 1.5 {
+		t.Errorf("max fee = %v (%v), want 1.5", maxFee, err)
+	}
+
+	bad := types.RFQResult{Strike: "x", Quantity: "y", MaxFee: "z"}
+	if _, err := rfqStrike(bad); err == nil {
+		t.Error("expected error for bad strike")
+	}
+	if _, err := rfqQuantity(bad); err == nil {
+		t.Error("expected error for bad quantity")
+	}
+	if _, err := rfqMaxFee(bad); err == nil {
+		t.Error("expected error for bad max fee")
+	}
+}
@@ -1,6 +1,7 @@
 package quoter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -12,27 +13,46 @@ import (
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/marketdata"
 	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/atomizer/internal/volsurface"
 	"github.com/wakamex/rysk-v12-cli/ryskcore"
 )
 
 
+// routeTimeout bounds how long a quote waits to learn where its hedge would go
+const routeTimeout = 2 * time.Second
+
+// HedgeRouter picks the venue an RFQ's hedge would be placed on, returning
+// the mid of its book in USD
+type HedgeRouter interface {
+	RouteRFQ(ctx context.Context, req types.RFQResult) (venue string, mid float64, err error)
+}
+
 // MakeQuote generates a signed quote based on the given RFQ request
 func MakeQuote(req types.RFQResult, underlying string, originalRfqID string, cfg *config.Config, exchange types.Exchange) (ryskcore.Quote, error) {
-	quote, _, err := getExchangeQuote(req, underlying, exchange)
+	signed, _, err := MakeQuoteWithSurface(req, underlying, originalRfqID, cfg, exchange, nil, nil, nil)
+	return signed, err
+}
+
+// MakeQuoteWithSurface generates a signed quote, pricing off the vol surface
+// as well as the exchange book according to cfg.PricingMode. The unsigned
+// Quote carries the expected fee and edge behind the price. market, which
+// may be nil, supplies the live underlying price, and router, which may be
+// nil, the venue whose fees the hedge will pay and whose mid is recorded.
+func MakeQuoteWithSurface(req types.RFQResult, underlying string, originalRfqID string, cfg *config.Config, exchange types.Exchange, surfaces *volsurface.Service, market *marketdata.Hub, router HedgeRouter) (ryskcore.Quote, Quote, error) {
+	quote, err := getQuote(req, underlying, originalRfqID, cfg, exchange, surfaces, market, router)
 	if err != nil {
-		return ryskcore.Quote{}, fmt.Errorf("failed to get quote from exchange: %w", err)
+		return ryskcore.Quote{}, Quote{}, fmt.Errorf("failed to get quote: %w", err)
 	}
 
-	// Convert price from float to string
-	// Rysk uses 10^6 precision, so multiply by 10^6
-	priceInPrecision := quote.Price * 1e6
-	priceStr := strconv.FormatUint(uint64(priceInPrecision), 10)
+	priceStr := FormatPrice(quote.Price)
 
 	// Validate quantity format (should be in wei)
 	_, ok := new(big.Int).SetString(req.Quantity, 10)
 	if !ok {
-		return ryskcore.Quote{}, fmt.Errorf("failed to parse quantity: %s", req.Quantity)
+		return ryskcore.Quote{}, Quote{}, fmt.Errorf("failed to parse quantity: %s", req.Quantity)
 	}
 
 	// Get asset address
@@ -69,7 +89,7 @@ func MakeQuote(req types.RFQResult, underlying string, originalRfqID string, cfg
 	// Sign the quote using EIP-712
 	messageHash, _, err := ryskcore.CreateQuoteMessage(ryskQuote)
 	if err != nil {
-		return ryskcore.Quote{}, fmt.Errorf("failed to create quote message: %w", err)
+		return ryskcore.Quote{}, Quote{}, fmt.Errorf("failed to create quote message: %w", err)
 	}
 
 	// Convert private key to hex string for signing
@@ -78,44 +98,141 @@ func MakeQuote(req types.RFQResult, underlying string, originalRfqID string, cfg
 	
 	signature, err := ryskcore.Sign(messageHash, privateKeyHex)
 	if err != nil {
-		return ryskcore.Quote{}, fmt.Errorf("failed to sign quote: %w", err)
+		return ryskcore.Quote{}, Quote{}, fmt.Errorf("failed to sign quote: %w", err)
 	}
 
 	ryskQuote.Signature = signature
-	return ryskQuote, nil
+	return ryskQuote, quote, nil
 }
 
-// getExchangeQuote fetches a quote from the exchange based on the RFQ request
-func getExchangeQuote(req types.RFQResult, underlying string, exchange types.Exchange) (Quote, float64, error) {
-	// Get the order book
-	orderBook, err := exchange.GetOrderBook(req, underlying)
+// FormatPrice converts a price per contract to Rysk's 6 decimal fixed point
+func FormatPrice(price float64) string {
+	return strconv.FormatUint(uint64(price*ryskPriceDecimals), 10)
+}
+
+// getQuote prices the RFQ from the exchange book and/or the vol surface and
+// adds the configured edge
+func getQuote(req types.RFQResult, underlying string, rfqID string, cfg *config.Config, exchange types.Exchange, surfaces *volsurface.Service, market *marketdata.Hub, router HedgeRouter) (Quote, error) {
+	mode := cfg.PricingMode
+	if mode == "" {
+		mode = PricingModeBook
+	}
+
+	var (
+		model, spot float64
+		modelErr    = errors.New("model pricing disabled")
+	)
+	if mode != PricingModeBook {
+		model, spot, modelErr = modelPrice(req, underlying, surfaces)
+	}
+
+	// The book is fetched in every mode for its mid, even when the model
+	// prices alone
+	book, index, mid, bookErr := getExchangePrice(req, underlying, exchange)
+
+	price, err := referencePrice(mode, cfg.ModelWeight, book, model, bookErr, modelErr)
 	if err != nil {
-		return Quote{}, 0, fmt.Errorf("failed to get order book: %w", err)
+		return Quote{}, err
 	}
 
-	// Calculate the price including slippage
-	price, err := getPriceInclSlippage(orderBook, req)
+	if spot == 0 {
+		spot = index
+	}
+	// The streamed index is fresher than the surface's or the book's
+	if market != nil {
+		if live, ok := market.IndexPrice(instruments.PerpName(cfg.ExchangeName, underlying)); ok {
+			spot = live.InexactFloat64()
+		}
+	}
+
+	quantity, err := rfqQuantity(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	edge := EdgeConfigFromConfig(cfg).Edge(spot, quantity)
+	venue, venueMid := hedgeVenue(req, rfqID, cfg, router)
+	fee := feeBound(req, venue, spot, price, quantity)
+	if venueMid > 0 {
+		mid = venueMid
+	}
+
+	// Fees are passed through so they never eat into the edge
+	quoted, err := applyEdge(price, edge+fee, req.IsTakerBuy)
 	if err != nil {
-		return Quote{}, 0, fmt.Errorf("failed to calculate price with slippage: %w", err)
+		return Quote{}, err
 	}
 
+	log.Printf("[Quote %s] mode=%s book=%.4f (err=%v) model=%.4f (err=%v) ref=%.4f edge=%.4f feeBound=%.4f (%s) quoted=%.4f",
+		rfqID, mode, book, bookErr, model, modelErr, price, edge, fee, venue, quoted)
+
 	// Calculate APR
 	expiryTime := time.Unix(req.Expiry, 0)
 	daysToExpiry := expiryTime.Sub(time.Now()).Hours() / 24
-	strikeFloat, _ := strconv.ParseFloat(req.Strike, 64)
-	
-	apr := CalculateAPR(price, strikeFloat, orderBook.Index, daysToExpiry, req.IsPut)
+	strikeFloat, _ := rfqStrike(req)
 
 	return Quote{
-		Price: price,
-		APR:   apr,
-	}, apr, nil
+		Price:    quoted,
+		APR:      CalculateAPR(quoted, strikeFloat, spot, daysToExpiry, req.IsPut),
+		Spot:     spot,
+		Mid:      mid,
+		Quantity: quantity,
+		FeeBound: fee,
+		NetEdge:  edge,
+	}, nil
+}
+
+// hedgeVenue returns the venue the RFQ's hedge would be routed to and the mid
+// of its book, or the primary exchange and no mid when there is no router or
+// it can't pick one
+func hedgeVenue(req types.RFQResult, rfqID string, cfg *config.Config, router HedgeRouter) (string, float64) {
+	if router == nil {
+		return cfg.ExchangeName, 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), routeTimeout)
+	defer cancel()
+
+	venue, mid, err := router.RouteRFQ(ctx, req)
+	if err != nil {
+		log.Printf("[Quote %s] Failed to route hedge, pricing %s fees: %v", rfqID, cfg.ExchangeName, err)
+		return cfg.ExchangeName, 0
+	}
+	return venue, mid
+}
+
+// getExchangePrice fetches the book and returns the VWAP for the RFQ size,
+// along with the index and the mid of the top of the book. The index and mid
+// are returned even when the book is too thin for the VWAP.
+func getExchangePrice(req types.RFQResult, underlying string, exchange types.Exchange) (price, index, mid float64, err error) {
+	// Get the order book
+	orderBook, err := exchange.GetOrderBook(req, underlying)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get order book: %w", err)
+	}
+
+	if len(orderBook.Bids) > 0 && len(orderBook.Asks) > 0 && len(orderBook.Bids[0]) > 0 && len(orderBook.Asks[0]) > 0 {
+		mid = (orderBook.Bids[0][0] + orderBook.Asks[0][0]) / 2
+	}
+
+	// Calculate the price including slippage
+	price, err = getPriceInclSlippage(orderBook, req)
+	if err != nil {
+		return 0, orderBook.Index, mid, fmt.Errorf("failed to calculate price with slippage: %w", err)
+	}
+
+	return price, orderBook.Index, mid, nil
 }
 
-// Quote represents a price quote with APR
+// Quote represents a price quote with APR. FeeBound and NetEdge are per
+// contract in quote currency.
 type Quote struct {
-	Price float64
-	APR   float64
+	Price    float64
+	APR      float64
+	Spot     float64 // Underlying price the quote was made at
+	Mid      float64 // Mid of the hedge venue the router picks at quote time, zero without a book
+	Quantity float64
+	FeeBound float64 // Worst case: the RFQ's whole MaxFee plus the hedge's taker fee
+	NetEdge  float64
 }
 
 // getPriceInclSlippage calculates the execution price including slippage based on order book depth
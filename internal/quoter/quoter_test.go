@@ -0,0 +1,166 @@
+package quoter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/atomizer/internal/volsurface"
+)
+
+// bookExchange serves one order book for every RFQ
+type bookExchange struct {
+	types.Exchange
+	book types.CCXTOrderBook
+	err  error
+}
+
+func (e *bookExchange) GetOrderBook(req types.RFQResult, asset string) (types.CCXTOrderBook, error) {
+	return e.book, e.err
+}
+
+// fixedRouter routes every hedge to one venue
+type fixedRouter struct {
+	venue string
+	mid   float64
+	err   error
+}
+
+func (r fixedRouter) RouteRFQ(ctx context.Context, req types.RFQResult) (string, float64, error) {
+	return r.venue, r.mid, r.err
+}
+
+// flatSurfaces returns a surface service with ETH at 3000 and 60% vol
+// everywhere for one expiry
+func flatSurfaces(t *testing.T, expiry int64) *volsurface.Service {
+	t.Helper()
+	builder := volsurface.NewBuilder("ETH", 3000)
+	for _, strike := range []float64{2400, 2700, 3000, 3300, 3600} {
+		builder.Add(volsurface.Quote{Strike: strike, Expiry: expiry, IV: 0.6, Forward: 3000})
+	}
+	surface, err := builder.Build(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaces := volsurface.NewService(nil, nil, 0)
+	surfaces.SetSurface(surface)
+	return surfaces
+}
+
+func TestGetQuote(t *testing.T) {
+	expiry := time.Now().Add(30 * 24 * time.Hour).Unix()
+	surfaces := flatSurfaces(t, expiry)
+	book := types.CCXTOrderBook{
+		Bids:  [][]float64{{90, 10}},
+		Asks:  [][]float64{{100, 10}},
+		Index: 3000,
+	}
+
+	rfq := func(isTakerBuy bool, quantity string) types.RFQResult {
+		return types.RFQResult{
+			Strike:     "300000000000",
+			Expiry:     expiry,
+			Quantity:   quantity,
+			IsTakerBuy: isTakerBuy,
+		}
+	}
+	two := "2000000000000000000"
+	model, _, err := modelPrice(rfq(true, two), "ETH", surfaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// $2 of edge and, hedging 2 contracts on Derive, $1.15 of fees per
+	// contract: 0.03% of $3000 each plus $0.50 for the trade
+	const edge, fee = 2.0, 1.15
+
+	tests := []struct {
+		name       string
+		mode       string
+		weight     float64
+		surfaces   *volsurface.Service
+		bookErr    error
+		router     HedgeRouter
+		isTakerBuy bool
+		quantity   string
+		minEdge    float64
+		want       float64
+		wantFee    float64
+		wantMid    float64 // 95 from the primary book when zero
+		noMid      bool
+		wantErr    bool
+	}{
+		{name: "book, taker buys", mode: PricingModeBook, isTakerBuy: true, want: 100 + edge + fee, wantFee: fee},
+		{name: "book, taker sells", mode: PricingModeBook, want: 90 - edge - fee, wantFee: fee},
+		{name: "default mode is book", isTakerBuy: true, surfaces: surfaces, want: 100 + edge + fee, wantFee: fee},
+		{name: "hedge routed to deribit", mode: PricingModeBook, isTakerBuy: true, router: fixedRouter{venue: "deribit", mid: 75}, want: 100 + edge + 0.9, wantFee: 0.9, wantMid: 75},
+		{name: "routed venue without a mid", mode: PricingModeBook, isTakerBuy: true, router: fixedRouter{venue: "deribit"}, want: 100 + edge + 0.9, wantFee: 0.9},
+		{name: "hedge routed to a venue without fees", mode: PricingModeBook, isTakerBuy: true, router: fixedRouter{venue: "okx"}, want: 100 + edge + fee, wantFee: fee},
+		{name: "unroutable hedge pays primary fees", mode: PricingModeBook, isTakerBuy: true, router: fixedRouter{err: errors.New("no books")}, want: 100 + edge + fee, wantFee: fee},
+
+		{name: "model", mode: PricingModeModel, surfaces: surfaces, isTakerBuy: true, want: model + edge + fee, wantFee: fee},
+		{name: "model, taker sells", mode: PricingModeModel, surfaces: surfaces, want: model - edge - fee, wantFee: fee},
+		{name: "model takes mid from routed venue", mode: PricingModeModel, surfaces: surfaces, isTakerBuy: true, router: fixedRouter{venue: "derive", mid: 80}, want: model + edge + fee, wantFee: fee, wantMid: 80},
+		{name: "model with book too thin", mode: PricingModeModel, surfaces: surfaces, isTakerBuy: true, quantity: "20000000000000000000", want: model + edge + 0.925, wantFee: 0.925},
+		{name: "model without surface uses book", mode: PricingModeModel, isTakerBuy: true, want: 100 + edge + fee, wantFee: fee},
+
+		{name: "blend all book", mode: PricingModeBlend, weight: 0, surfaces: surfaces, isTakerBuy: true, want: 100 + edge + fee, wantFee: fee},
+		{name: "blend all model", mode: PricingModeBlend, weight: 1, surfaces: surfaces, isTakerBuy: true, want: model + edge + fee, wantFee: fee},
+		{name: "blend without book", mode: PricingModeBlend, weight: 0, surfaces: surfaces, bookErr: errors.New("down"), isTakerBuy: true, want: model + edge + fee, wantFee: fee, noMid: true},
+
+		{name: "book down", mode: PricingModeBook, surfaces: surfaces, bookErr: errors.New("down"), isTakerBuy: true, wantErr: true},
+		{name: "model and book down", mode: PricingModeModel, bookErr: errors.New("down"), isTakerBuy: true, wantErr: true},
+		{name: "book too thin", mode: PricingModeBook, isTakerBuy: true, quantity: "20000000000000000000", wantErr: true},
+		{name: "bad quantity", mode: PricingModeModel, surfaces: surfaces, isTakerBuy: true, quantity: "lots", wantErr: true},
+		{name: "edge exceeds bid", mode: PricingModeBook, minEdge: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ExchangeName: "derive",
+				PricingMode:  tt.mode,
+				ModelWeight:  tt.weight,
+				MinEdge:      edge,
+			}
+			if tt.minEdge > 0 {
+				cfg.MinEdge = tt.minEdge
+			}
+			quantity := two
+			if tt.quantity != "" {
+				quantity = tt.quantity
+			}
+			exchange := &bookExchange{book: book, err: tt.bookErr}
+
+			quote, err := getQuote(rfq(tt.isTakerBuy, quantity), "ETH", "rfq-1", cfg, exchange, tt.surfaces, nil, tt.router)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("quote = %+v, want an error", quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(quote.Price-tt.want) > 1e-3 {
+				t.Errorf("price = %.4f, want %.4f", quote.Price, tt.want)
+			}
+			if math.Abs(quote.FeeBound-tt.wantFee) > 1e-9 {
+				t.Errorf("fee = %.4f, want %.4f", quote.FeeBound, tt.wantFee)
+			}
+			wantMid := tt.wantMid
+			if wantMid == 0 && !tt.noMid {
+				wantMid = 95
+			}
+			if quote.Mid != wantMid {
+				t.Errorf("mid = %v, want %v", quote.Mid, wantMid)
+			}
+			if quote.NetEdge != edge || quote.Spot != 3000 {
+				t.Errorf("edge = %v, spot = %v; want 2, 3000", quote.NetEdge, quote.Spot)
+			}
+		})
+	}
+}
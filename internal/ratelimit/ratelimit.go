@@ -0,0 +1,257 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Priority orders requests competing for an exchange's rate limit
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Market data and metadata
+	PriorityNormal                 // Orders and account queries
+	PriorityHigh                   // Cancels and hedges
+)
+
+// String names the priority in metrics
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	default:
+		return "low"
+	}
+}
+
+// reserveShare is the share of a bucket's burst that requests of each
+// priority leave for higher priorities
+var reserveShare = map[Priority]float64{
+	PriorityLow:    0.5,
+	PriorityNormal: 0.2,
+	PriorityHigh:   0,
+}
+
+// Rate is a token bucket's sustained rate and burst
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits are an exchange's rate limits. Like Derive's, orders, replaces and
+// cancels count against the matching engine and everything else against a
+// separate non-matching budget.
+type Limits struct {
+	Matching    Rate
+	NonMatching Rate
+}
+
+// Bucket is a token bucket that serves higher priorities first by making
+// lower ones leave part of the burst unused
+type Bucket struct {
+	name  string
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	tokens    float64
+	updatedAt time.Time
+	throttled map[Priority]int64
+	waited    map[Priority]time.Duration
+}
+
+// NewBucket creates a full bucket
+func NewBucket(name string, rate Rate) *Bucket {
+	burst := float64(rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		name:      name,
+		rate:      rate.PerSecond,
+		burst:     burst,
+		tokens:    burst,
+		updatedAt: time.Now(),
+		throttled: make(map[Priority]int64),
+		waited:    make(map[Priority]time.Duration),
+	}
+}
+
+// Wait takes a token, blocking until one is available to the priority or
+// ctx is done
+func (b *Bucket) Wait(ctx context.Context, priority Priority) error {
+	if b.rate <= 0 {
+		return nil
+	}
+
+	start := time.Now()
+	throttled := false
+	for {
+		delay := b.take(priority)
+		if delay == 0 {
+			if throttled {
+				b.recordWait(priority, time.Since(start))
+			}
+			return nil
+		}
+		if !throttled {
+			throttled = true
+			b.recordThrottle(priority)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			b.recordWait(priority, time.Since(start))
+			return fmt.Errorf("rate limited on %s: %w", b.name, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// take takes a token if one is available to the priority, and otherwise
+// returns how long until one should be
+func (b *Bucket) take(priority Priority) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate)
+	b.updatedAt = now
+
+	// Leave at least one token usable, so a burst of 1 still serves everyone
+	reserve := math.Min(b.burst*reserveShare[priority], b.burst-1)
+	if b.tokens-1 >= reserve {
+		b.tokens--
+		return 0
+	}
+
+	missing := 1 + reserve - b.tokens
+	return time.Duration(missing / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) recordThrottle(priority Priority) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.throttled[priority]++
+}
+
+func (b *Bucket) recordWait(priority Priority, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.waited[priority] += d
+}
+
+// Stats are a bucket's throttle counts for one priority
+type Stats struct {
+	Exchange  string
+	Bucket    string
+	Priority  Priority
+	Throttled int64         // Requests that had to wait for a token
+	Waited    time.Duration // Total time spent waiting
+}
+
+// stats returns the bucket's throttle counts by priority
+func (b *Bucket) stats(exchange string) []Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]Stats, 0, len(reserveShare))
+	for _, priority := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		result = append(result, Stats{
+			Exchange:  exchange,
+			Bucket:    b.name,
+			Priority:  priority,
+			Throttled: b.throttled[priority],
+			Waited:    b.waited[priority],
+		})
+	}
+	return result
+}
+
+// Limiter holds an exchange's matching and non-matching buckets
+type Limiter struct {
+	Exchange    string
+	Matching    *Bucket
+	NonMatching *Bucket
+}
+
+// NewLimiter creates a limiter for an exchange's limits
+func NewLimiter(exchange string, limits Limits) *Limiter {
+	return &Limiter{
+		Exchange:    exchange,
+		Matching:    NewBucket("matching", limits.Matching),
+		NonMatching: NewBucket("non_matching", limits.NonMatching),
+	}
+}
+
+// Stats returns the limiter's throttle counts
+func (l *Limiter) Stats() []Stats {
+	return append(l.Matching.stats(l.Exchange), l.NonMatching.stats(l.Exchange)...)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Limiter)
+)
+
+// For returns the limiter shared by every call to an exchange, creating it
+// with limits the first time
+func For(exchange string, limits Limits) *Limiter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if limiter, ok := registry[exchange]; ok {
+		return limiter
+	}
+	limiter := NewLimiter(exchange, limits)
+	registry[exchange] = limiter
+	return limiter
+}
+
+// AllStats returns the throttle counts of every exchange's limiter
+func AllStats() []Stats {
+	registryMu.Lock()
+	exchanges := make([]string, 0, len(registry))
+	for exchange := range registry {
+		exchanges = append(exchanges, exchange)
+	}
+	registryMu.Unlock()
+	sort.Strings(exchanges)
+
+	var result []Stats
+	for _, exchange := range exchanges {
+		registryMu.Lock()
+		limiter := registry[exchange]
+		registryMu.Unlock()
+		result = append(result, limiter.Stats()...)
+	}
+	return result
+}
+
+// WriteMetrics writes the throttle counts in Prometheus format
+func WriteMetrics(w io.Writer) {
+	stats := AllStats()
+
+	fmt.Fprintf(w, "# HELP exchange_rate_limit_throttled_total Requests delayed by the client-side rate limiter\n")
+	fmt.Fprintf(w, "# TYPE exchange_rate_limit_throttled_total counter\n")
+	for _, s := range stats {
+		fmt.Fprintf(w, "exchange_rate_limit_throttled_total{exchange=%q,bucket=%q,priority=%q} %d\n",
+			s.Exchange, s.Bucket, s.Priority, s.Throttled)
+	}
+
+	fmt.Fprintf(w, "# HELP exchange_rate_limit_wait_seconds_total Time requests spent waiting for the rate limiter\n")
+	fmt.Fprintf(w, "# TYPE exchange_rate_limit_wait_seconds_total counter\n")
+	for _, s := range stats {
+		fmt.Fprintf(w, "exchange_rate_limit_wait_seconds_total{exchange=%q,bucket=%q,priority=%q} %.3f\n",
+			s.Exchange, s.Bucket, s.Priority, s.Waited.Seconds())
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketReservesBurstForHigherPriorities(t *testing.T) {
+	bucket := NewBucket("matching", Rate{PerSecond: 1, Burst: 10})
+
+	// Low priority requests stop at half the burst
+	taken := 0
+	for bucket.take(PriorityLow) == 0 {
+		taken++
+	}
+	if taken != 5 {
+		t.Fatalf("low priority took %d tokens, want 5", taken)
+	}
+
+	// Normal priority requests leave a fifth
+	taken = 0
+	for bucket.take(PriorityNormal) == 0 {
+		taken++
+	}
+	if taken != 3 {
+		t.Fatalf("normal priority took %d tokens, want 3", taken)
+	}
+
+	// Cancels and hedges get the rest
+	for i := 0; i < 2; i++ {
+		if delay := bucket.take(PriorityHigh); delay != 0 {
+			t.Fatalf("high priority request %d delayed %v", i, delay)
+		}
+	}
+	if delay := bucket.take(PriorityHigh); delay <= 0 {
+		t.Fatal("expected an empty bucket to delay high priority requests")
+	}
+}
+
+func TestBucketWaitRecordsThrottles(t *testing.T) {
+	limiter := NewLimiter("test", Limits{Matching: Rate{PerSecond: 100, Burst: 1}})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Matching.Wait(ctx, PriorityHigh); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+
+	var high Stats
+	for _, s := range limiter.Stats() {
+		if s.Bucket == "matching" && s.Priority == PriorityHigh {
+			high = s
+		}
+	}
+	if high.Throttled != 2 || high.Waited <= 0 {
+		t.Errorf("stats = %+v, want 2 throttled requests", high)
+	}
+
+	// A request that can't get a token before its deadline fails
+	slow := NewBucket("matching", Rate{PerSecond: 0.1, Burst: 1})
+	slow.take(PriorityHigh)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx, PriorityHigh); err == nil {
+		t.Error("expected Wait to fail when ctx ends")
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	// Limiters stay registered, so each run registers its own
+	exchange := fmt.Sprintf("metrics-test-%d", time.Now().UnixNano())
+	limiter := For(exchange, Limits{NonMatching: Rate{PerSecond: 1000, Burst: 1}})
+	limiter.NonMatching.Wait(context.Background(), PriorityLow)
+	limiter.NonMatching.Wait(context.Background(), PriorityLow)
+
+	if For(exchange, Limits{}) != limiter {
+		t.Fatal("For should return the registered limiter")
+	}
+
+	var buf bytes.Buffer
+	WriteMetrics(&buf)
+	want := fmt.Sprintf(`exchange_rate_limit_throttled_total{exchange=%q,bucket="non_matching",priority="low"} 1`, exchange)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,235 @@
+package rfq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/cache"
+	"github.com/wakamex/atomizer/internal/instruments"
+)
+
+// DefaultAssetsURL lists the assets Rysk's testnet trades on each chain
+const DefaultAssetsURL = "https://rip-testnet.rysk.finance/api/assets"
+
+// DefaultAssetRefresh is how often the asset list is reloaded
+const DefaultAssetRefresh = 10 * time.Minute
+
+// assetsCacheKey names the asset list in the market cache
+const assetsCacheKey = "rysk_assets"
+
+// Asset is one entry of Rysk's asset list
+type Asset struct {
+	Symbol                 string `json:"symbol"`
+	Address                string `json:"address"`
+	Decimals               int    `json:"decimals"`
+	ChainID                int    `json:"chainId"`
+	Active                 bool   `json:"active"`
+	Underlying             string `json:"underlying"`
+	UnderlyingAssetAddress string `json:"underlyingAssetAddress"`
+	MinTradeSize           string `json:"minTradeSize"`
+	MaxTradeSize           string `json:"maxTradeSize"`
+}
+
+// ChainAssets maps each chain ID to its active asset addresses' underlyings
+func ChainAssets(markets map[string][]Asset) (map[int]map[string]string, error) {
+	chains := make(map[int]map[string]string, len(markets))
+	for key, assets := range markets {
+		chainID, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain ID %q: %w", key, err)
+		}
+		mapping := make(map[string]string, len(assets))
+		for _, asset := range assets {
+			if !asset.Active || asset.Address == "" || asset.Underlying == "" {
+				continue
+			}
+			mapping[asset.Address] = asset.Underlying
+		}
+		chains[chainID] = mapping
+	}
+	return chains, nil
+}
+
+// AssetMapper keeps the Rysk asset to underlying mapping of every chain in
+// step with Rysk's asset list, which resolves assets for quoting, hedging
+// and risk
+type AssetMapper struct {
+	url    string
+	cache  cache.MarketCache
+	ttl    time.Duration
+	client *http.Client
+
+	mu     sync.RWMutex
+	chains map[int]map[string]string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAssetMapper creates a mapper for the asset list at url. A nil cache
+// always loads from the API.
+func NewAssetMapper(url string, marketCache cache.MarketCache, ttl time.Duration) *AssetMapper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AssetMapper{
+		url:    url,
+		cache:  marketCache,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+		chains: make(map[int]map[string]string),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Load applies the cached asset list, or the API's on a cache miss
+func (m *AssetMapper) Load() error {
+	if m.cache != nil {
+		var markets map[string][]Asset
+		if err := m.cache.GetMarkets(assetsCacheKey, &markets); err == nil {
+			return m.apply(markets)
+		}
+	}
+	return m.Refresh()
+}
+
+// Refresh reloads the asset list from the API, caching and applying it
+func (m *AssetMapper) Refresh() error {
+	markets, err := m.fetch()
+	if err != nil {
+		return err
+	}
+	if m.cache != nil {
+		if err := m.cache.SetMarkets(assetsCacheKey, markets, m.ttl); err != nil {
+			log.Printf("[Assets] Failed to cache asset list: %v", err)
+		}
+	}
+	return m.apply(markets)
+}
+
+// Start refreshes the asset list every interval in the background
+func (m *AssetMapper) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultAssetRefresh
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Refresh(); err != nil {
+					log.Printf("[Assets] Failed to refresh asset list: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing
+func (m *AssetMapper) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Mapping returns a chain's asset address to underlying mapping
+func (m *AssetMapper) Mapping(chainID int) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mapping := make(map[string]string, len(m.chains[chainID]))
+	for address, underlying := range m.chains[chainID] {
+		mapping[address] = underlying
+	}
+	return mapping
+}
+
+// Chains returns the IDs of the chains with assets
+func (m *AssetMapper) Chains() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chains := make([]int, 0, len(m.chains))
+	for chainID := range m.chains {
+		chains = append(chains, chainID)
+	}
+	return chains
+}
+
+// fetch downloads the asset list
+func (m *AssetMapper) fetch() (map[string][]Asset, error) {
+	resp, err := m.client.Get(m.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch asset list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("asset list returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset list: %w", err)
+	}
+
+	var markets map[string][]Asset
+	if err := json.Unmarshal(body, &markets); err != nil {
+		return nil, fmt.Errorf("failed to parse asset list: %w", err)
+	}
+	return markets, nil
+}
+
+// apply resolves assets with an asset list, logging chains whose mapping
+// changed
+func (m *AssetMapper) apply(markets map[string][]Asset) error {
+	chains, err := ChainAssets(markets)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for chainID, mapping := range chains {
+		if !sameMapping(m.chains[chainID], mapping) {
+			log.Printf("[Assets] Chain %d: %d assets", chainID, len(mapping))
+		}
+		instruments.SetRyskChainAssets(chainID, mapping)
+	}
+	for chainID := range m.chains {
+		if _, ok := chains[chainID]; !ok {
+			log.Printf("[Assets] Chain %d no longer listed", chainID)
+			instruments.SetRyskChainAssets(chainID, nil)
+		}
+	}
+	m.chains = chains
+	return nil
+}
+
+// sameMapping reports whether two mappings are equal
+func sameMapping(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for address, underlying := range a {
+		if b[address] != underlying {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package rfq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/instruments"
+)
+
+func TestAssetMapperLoadsEachChain(t *testing.T) {
+	list := `{
+		"84532": [
+			{"symbol": "WETH", "address": "0xb67bfa7b488df4f2efa874f4e59242e9130ae61f", "chainId": 84532, "active": true, "underlying": "ETH"},
+			{"symbol": "WBTC", "address": "0x0a3c4e9b6d1e2b8e8f1f2a3b4c5d6e7f80910111", "chainId": 84532, "active": false, "underlying": "BTC"}
+		],
+		"998": [
+			{"symbol": "WHYPE", "address": "0x5555555555555555555555555555555555555555", "chainId": 998, "active": true, "underlying": "HYPE"}
+		]
+	}`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(list))
+	}))
+	defer server.Close()
+
+	mapper := NewAssetMapper(server.URL, nil, time.Minute)
+	if err := mapper.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer instruments.SetRyskChainAssets(84532, nil)
+	defer instruments.SetRyskChainAssets(998, nil)
+
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+	if got, err := instruments.ResolveRyskAsset(998, "0x5555555555555555555555555555555555555555"); err != nil || got != "HYPE" {
+		t.Errorf("HYPE asset = %q, %v", got, err)
+	}
+	if _, err := instruments.ResolveRyskAsset(84532, "0x0a3c4e9b6d1e2b8e8f1f2a3b4c5d6e7f80910111"); err == nil {
+		t.Error("inactive asset should not resolve")
+	}
+	if mapping := mapper.Mapping(84532); len(mapping) != 1 || mapping["0xb67bfa7b488df4f2efa874f4e59242e9130ae61f"] != "ETH" {
+		t.Errorf("chain 84532 mapping = %v", mapping)
+	}
+
+	// Assets dropped from the list stop resolving on refresh
+	list = `{"84532": []}`
+	if err := mapper.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := instruments.ResolveRyskAsset(998, "0x5555555555555555555555555555555555555555"); err == nil {
+		t.Error("asset of an unlisted chain should not resolve")
+	}
+}
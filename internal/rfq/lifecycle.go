@@ -0,0 +1,532 @@
+package rfq
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/quoter"
+	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/rysk-v12-cli/ryskcore"
+)
+
+// DefaultLifecycleRecords is how many RFQs the lifecycle store keeps
+const DefaultLifecycleRecords = 10000
+
+// Status is where an RFQ is in its lifecycle
+type Status string
+
+const (
+	StatusReceived   Status = "received"   // Not yet quoted or declined
+	StatusDeclined   Status = "declined"   // We sent no quote
+	StatusQuoted     Status = "quoted"     // Our quote is live
+	StatusMissed     Status = "missed"     // Our quote expired without a trade
+	StatusFilled     Status = "filled"     // The taker traded on our quote
+	StatusLost       Status = "lost"       // The taker traded with another maker
+	StatusUnresolved Status = "unresolved" // The taker traded, but with no maker or nonce to say whose quote
+)
+
+// Record follows one RFQ from request to quote to confirmation. Prices are
+// per contract in quote currency.
+type Record struct {
+	RFQID        string
+	QuoteNonce   string
+	Underlying   string
+	AssetAddress string
+	ChainID      int
+	Strike       float64
+	Expiry       time.Time
+	IsPut        bool
+	IsTakerBuy   bool
+	Quantity     float64
+	Taker        string
+	Status       Status
+	Reason       string // Why no quote was sent
+
+	Price      float64 // Our quoted price
+	APR        float64 // APR the taker earns at Price
+	Mid        float64 // Hedge venue mid at quote time, zero if unknown
+	ValidUntil time.Time
+	TradePrice float64 // Price traded at, ours or the winning maker's
+
+	ReceivedAt time.Time
+	QuotedAt   time.Time
+	ResolvedAt time.Time // When we declined, or the trade was confirmed
+
+	terms terms
+}
+
+// status reports the record's status as of now, expiring quotes past their
+// validity
+func (r *Record) status(now time.Time) Status {
+	if r.Status == StatusQuoted && !r.ValidUntil.IsZero() && now.After(r.ValidUntil) {
+		return StatusMissed
+	}
+	return r.Status
+}
+
+// edgeBps is how far a price is from the mid in the maker's favour, in basis
+// points of the mid
+func (r *Record) edgeBps(price float64) (float64, bool) {
+	if r.Mid <= 0 || price <= 0 {
+		return 0, false
+	}
+	edge := (price - r.Mid) / r.Mid * 1e4
+	if !r.IsTakerBuy {
+		edge = -edge
+	}
+	return edge, true
+}
+
+// terms identifies an RFQ by what it trades, for confirmations that don't
+// carry our nonce
+type terms struct {
+	asset      string
+	strike     string
+	expiry     int64
+	isPut      bool
+	isTakerBuy bool
+}
+
+// Lifecycle tracks RFQs by ID and quote nonce, so fills can be matched back to
+// the quotes and market they were priced against
+type Lifecycle struct {
+	maker      string
+	maxRecords int
+
+	mu      sync.Mutex
+	records map[string]*Record
+	order   []string          // RFQ IDs, oldest first
+	nonces  map[string]string // Quote nonce to RFQ ID
+	terms   map[terms]string  // Latest RFQ ID with the terms
+}
+
+// NewLifecycle creates a store for the quotes of maker, keeping the latest
+// maxRecords RFQs
+func NewLifecycle(maker string, maxRecords int) *Lifecycle {
+	if maxRecords <= 0 {
+		maxRecords = DefaultLifecycleRecords
+	}
+	return &Lifecycle{
+		maker:      strings.ToLower(maker),
+		maxRecords: maxRecords,
+		records:    make(map[string]*Record),
+		nonces:     make(map[string]string),
+		terms:      make(map[terms]string),
+	}
+}
+
+// Received records an incoming RFQ. Repeats of an RFQ already seen are
+// ignored.
+func (l *Lifecycle) Received(rfqID string, rfq types.RFQResult) {
+	underlying, err := instruments.ResolveRyskAsset(rfq.ChainID, rfq.Asset)
+	if err != nil {
+		underlying = rfq.Asset
+	}
+	record := &Record{
+		RFQID:        rfqID,
+		Underlying:   underlying,
+		AssetAddress: rfq.Asset,
+		ChainID:      rfq.ChainID,
+		Expiry:       time.Unix(rfq.Expiry, 0),
+		IsPut:        rfq.IsPut,
+		IsTakerBuy:   rfq.IsTakerBuy,
+		Taker:        rfq.Taker,
+		Status:       StatusReceived,
+		ReceivedAt:   time.Now(),
+		terms:        termsOf(rfq.Asset, rfq.Strike, rfq.Expiry, rfq.IsPut, rfq.IsTakerBuy),
+	}
+	if strike, err := decimal.NewFromString(rfq.Strike); err == nil {
+		record.Strike = strike.Shift(-8).InexactFloat64()
+	}
+	if quantity, err := decimal.NewFromString(rfq.Quantity); err == nil {
+		record.Quantity = quantity.Shift(-18).InexactFloat64()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.records[rfqID]; ok {
+		return
+	}
+	l.records[rfqID] = record
+	l.order = append(l.order, rfqID)
+	l.terms[record.terms] = rfqID
+	l.trim()
+}
+
+// Priced records the APR and hedge venue mid behind an RFQ's quote
+func (l *Lifecycle) Priced(rfqID string, priced quoter.Quote) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[rfqID]; ok {
+		record.APR = priced.APR
+		record.Mid = priced.Mid
+	}
+}
+
+// Quoted records the quote sent for an RFQ
+func (l *Lifecycle) Quoted(rfqID string, quote *ryskcore.Quote) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.records[rfqID]
+	if !ok {
+		return
+	}
+	record.Status = StatusQuoted
+	record.QuoteNonce = quote.Nonce
+	record.Price = fromPriceUnits(quote.Price)
+	record.ValidUntil = time.Unix(quote.ValidUntil, 0)
+	record.QuotedAt = time.Now()
+	l.nonces[quote.Nonce] = rfqID
+}
+
+// Declined records that no quote was sent for an RFQ
+func (l *Lifecycle) Declined(rfqID, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[rfqID]; ok && record.Status == StatusReceived {
+		record.Status = StatusDeclined
+		record.Reason = reason
+		record.ResolvedAt = time.Now()
+	}
+}
+
+// Confirmed matches a trade confirmation to the RFQ it filled, by quote
+// nonce, RFQ ID or, failing both, the latest RFQ with the same terms. Our
+// quote is filled when the confirmation names us as maker or carries its
+// nonce, lost when it names another maker and unresolved otherwise. It
+// reports whether an RFQ matched.
+func (l *Lifecycle) Confirmed(rfqID string, conf *types.RFQConfirmation) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id, byNonce := "", false
+	if conf.QuoteNonce != "" {
+		id, byNonce = l.nonces[conf.QuoteNonce]
+	}
+	if !byNonce {
+		id = rfqID
+		if _, ok := l.records[id]; !ok {
+			id = l.terms[termsOf(conf.AssetAddress, conf.Strike, int64(conf.Expiry), conf.IsPut, conf.IsTakerBuy)]
+		}
+	}
+	record, ok := l.records[id]
+	if !ok {
+		return false
+	}
+
+	// The winning price is worth keeping even for RFQs we declined
+	record.TradePrice = fromPriceUnits(conf.Price)
+	if record.QuotedAt.IsZero() {
+		return true
+	}
+	switch {
+	case conf.Maker != "" && strings.EqualFold(conf.Maker, l.maker):
+		record.Status = StatusFilled
+	case conf.Maker != "":
+		record.Status = StatusLost
+	case byNonce:
+		record.Status = StatusFilled
+	default:
+		record.Status = StatusUnresolved
+	}
+	record.ResolvedAt = time.Now()
+	return true
+}
+
+// Records returns up to limit of the latest RFQs, newest first. A limit of
+// zero returns them all.
+func (l *Lifecycle) Records(limit int) []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit <= 0 || limit > len(l.order) {
+		limit = len(l.order)
+	}
+	now := time.Now()
+	records := make([]Record, 0, limit)
+	for i := len(l.order) - 1; i >= 0 && len(records) < limit; i-- {
+		record := *l.records[l.order[i]]
+		record.Status = record.status(now)
+		records = append(records, record)
+	}
+	return records
+}
+
+// trim drops the oldest RFQs over the limit. Callers hold l.mu.
+func (l *Lifecycle) trim() {
+	for len(l.order) > l.maxRecords {
+		id := l.order[0]
+		l.order = l.order[1:]
+		record := l.records[id]
+		delete(l.records, id)
+		if l.nonces[record.QuoteNonce] == id {
+			delete(l.nonces, record.QuoteNonce)
+		}
+		if l.terms[record.terms] == id {
+			delete(l.terms, record.terms)
+		}
+	}
+}
+
+// termsOf keys an RFQ or confirmation by its terms
+func termsOf(asset, strike string, expiry int64, isPut, isTakerBuy bool) terms {
+	return terms{
+		asset:      strings.ToLower(asset),
+		strike:     strike,
+		expiry:     expiry,
+		isPut:      isPut,
+		isTakerBuy: isTakerBuy,
+	}
+}
+
+// fromPriceUnits converts a Rysk fixed point price to quote currency
+func fromPriceUnits(price string) float64 {
+	value, err := decimal.NewFromString(price)
+	if err != nil {
+		return 0
+	}
+	return value.Shift(-6).InexactFloat64()
+}
+
+// Bucket groups RFQs for statistics. Fields not grouped by are empty.
+type Bucket struct {
+	Underlying string
+	Expiry     string // Time to expiry when received, such as "1-7d"
+	Size       string // Contracts, such as "1-10"
+}
+
+// BucketStats summarises the RFQs in a bucket. Edges are in basis points of
+// the hedge venue mid in our favour; latencies are in milliseconds.
+type BucketStats struct {
+	Bucket
+
+	RFQs     int
+	Quoted   int
+	Declined int
+	Filled   int
+	Lost     int
+	Missed   int
+	Live     int // Quotes not yet filled or expired
+
+	// Quotes traded on by a confirmation that named no maker, counted in
+	// neither the hit rate nor the edges
+	Unresolved int
+
+	QuoteRate float64 // Share of RFQs quoted
+	HitRate   float64 // Share of resolved quotes filled
+
+	AvgEdgeBps       float64 // Edge of every quote with a known mid
+	AvgFilledEdgeBps float64
+	AvgMissedEdgeBps float64 // Quotes missed or lost
+	AvgLossGapBps    float64 // How much worse our price was than the winner's
+
+	AvgQuoteLatencyMs float64 // From RFQ to quote
+	P95QuoteLatencyMs float64
+	AvgFillLatencyMs  float64 // From quote to confirmation
+}
+
+// Stats groups RFQs by any of "asset", "expiry" and "size", sorted by bucket
+func (l *Lifecycle) Stats(groupBy []string) []BucketStats {
+	var byAsset, byExpiry, bySize bool
+	for _, field := range groupBy {
+		switch strings.TrimSpace(field) {
+		case "asset":
+			byAsset = true
+		case "expiry":
+			byExpiry = true
+		case "size":
+			bySize = true
+		}
+	}
+
+	type accumulator struct {
+		stats                                 BucketStats
+		edges, filledEdges, missedEdges, gaps []float64
+		quoteLatencies, fillLatencies         []float64
+	}
+	buckets := make(map[Bucket]*accumulator)
+
+	now := time.Now()
+	for _, record := range l.Records(0) {
+		var bucket Bucket
+		if byAsset {
+			bucket.Underlying = record.Underlying
+		}
+		if byExpiry {
+			bucket.Expiry = expiryBucket(record.Expiry.Sub(record.ReceivedAt))
+		}
+		if bySize {
+			bucket.Size = sizeBucket(record.Quantity)
+		}
+		acc, ok := buckets[bucket]
+		if !ok {
+			acc = &accumulator{stats: BucketStats{Bucket: bucket}}
+			buckets[bucket] = acc
+		}
+
+		s := &acc.stats
+		s.RFQs++
+		if record.Status == StatusDeclined {
+			s.Declined++
+		}
+		if record.QuotedAt.IsZero() {
+			continue
+		}
+		s.Quoted++
+		acc.quoteLatencies = append(acc.quoteLatencies, milliseconds(record.QuotedAt.Sub(record.ReceivedAt)))
+
+		edge, hasEdge := record.edgeBps(record.Price)
+		if hasEdge {
+			acc.edges = append(acc.edges, edge)
+		}
+		switch record.status(now) {
+		case StatusFilled:
+			s.Filled++
+			acc.fillLatencies = append(acc.fillLatencies, milliseconds(record.ResolvedAt.Sub(record.QuotedAt)))
+			if hasEdge {
+				acc.filledEdges = append(acc.filledEdges, edge)
+			}
+		case StatusLost:
+			s.Lost++
+			if hasEdge {
+				acc.missedEdges = append(acc.missedEdges, edge)
+			}
+			if record.TradePrice > 0 {
+				gap := (record.Price - record.TradePrice) / record.TradePrice * 1e4
+				if !record.IsTakerBuy {
+					gap = -gap
+				}
+				acc.gaps = append(acc.gaps, gap)
+			}
+		case StatusMissed:
+			s.Missed++
+			if hasEdge {
+				acc.missedEdges = append(acc.missedEdges, edge)
+			}
+		case StatusUnresolved:
+			s.Unresolved++
+		default:
+			s.Live++
+		}
+	}
+
+	stats := make([]BucketStats, 0, len(buckets))
+	for _, acc := range buckets {
+		s := acc.stats
+		s.QuoteRate = ratio(s.Quoted, s.RFQs)
+		s.HitRate = ratio(s.Filled, s.Filled+s.Lost+s.Missed)
+		s.AvgEdgeBps = mean(acc.edges)
+		s.AvgFilledEdgeBps = mean(acc.filledEdges)
+		s.AvgMissedEdgeBps = mean(acc.missedEdges)
+		s.AvgLossGapBps = mean(acc.gaps)
+		s.AvgQuoteLatencyMs = mean(acc.quoteLatencies)
+		s.P95QuoteLatencyMs = percentile(acc.quoteLatencies, 0.95)
+		s.AvgFillLatencyMs = mean(acc.fillLatencies)
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i].Bucket, stats[j].Bucket
+		if a.Underlying != b.Underlying {
+			return a.Underlying < b.Underlying
+		}
+		if a.Expiry != b.Expiry {
+			return bucketOrder(expiryBuckets, a.Expiry) < bucketOrder(expiryBuckets, b.Expiry)
+		}
+		return bucketOrder(sizeBuckets, a.Size) < bucketOrder(sizeBuckets, b.Size)
+	})
+	return stats
+}
+
+// bucketBound names the values up to a bound
+type bucketBound struct {
+	name  string
+	upper float64
+}
+
+var expiryBuckets = []bucketBound{
+	{"0-1d", 1},
+	{"1-7d", 7},
+	{"7-30d", 30},
+	{"30-90d", 90},
+	{"90d+", math.Inf(1)},
+}
+
+var sizeBuckets = []bucketBound{
+	{"<1", 1},
+	{"1-10", 10},
+	{"10-100", 100},
+	{"100+", math.Inf(1)},
+}
+
+// expiryBucket names the bucket of a time to expiry
+func expiryBucket(left time.Duration) string {
+	return bucketName(expiryBuckets, left.Hours()/24)
+}
+
+// sizeBucket names the bucket of a quantity in contracts
+func sizeBucket(quantity float64) string {
+	return bucketName(sizeBuckets, quantity)
+}
+
+func bucketName(bounds []bucketBound, value float64) string {
+	for _, bound := range bounds {
+		if value < bound.upper {
+			return bound.name
+		}
+	}
+	return bounds[len(bounds)-1].name
+}
+
+func bucketOrder(bounds []bucketBound, name string) int {
+	for i, bound := range bounds {
+		if bound.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile returns the nearest-rank percentile of values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
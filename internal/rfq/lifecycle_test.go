@@ -0,0 +1,161 @@
+package rfq
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/quoter"
+	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/rysk-v12-cli/ryskcore"
+)
+
+func TestLifecycleMatchesConfirmationsAndComputesStats(t *testing.T) {
+	const (
+		maker = "0xMaker"
+		asset = "0x00000000000000000000000000000000000000aa"
+	)
+	lifecycle := NewLifecycle(maker, 10)
+	expiry := time.Now().Add(3 * 24 * time.Hour).Unix()
+
+	rfqFor := func(strike string) types.RFQResult {
+		return types.RFQResult{
+			Asset:      asset,
+			Strike:     strike,
+			Expiry:     expiry,
+			Quantity:   "2000000000000000000",
+			IsTakerBuy: true,
+		}
+	}
+	quote := func(id, nonce, price string) {
+		lifecycle.Quoted(id, &ryskcore.Quote{
+			Nonce:      nonce,
+			Price:      price,
+			ValidUntil: time.Now().Add(time.Minute).Unix(),
+		})
+	}
+
+	// Filled on our quote, matched by nonce: 110 against a 100 mid
+	lifecycle.Received("rfq-1", rfqFor("300000000000"))
+	lifecycle.Priced("rfq-1", quoter.Quote{Mid: 100, APR: 12})
+	quote("rfq-1", "nonce-1", "110000000")
+	if !lifecycle.Confirmed("trade", &types.RFQConfirmation{QuoteNonce: "nonce-1", Maker: "0xmaker", Price: "110000000"}) {
+		t.Fatal("confirmation with our nonce did not match")
+	}
+
+	// Lost to another maker at 104, matched by terms: 120 against a 100 mid
+	lifecycle.Received("rfq-2", rfqFor("310000000000"))
+	lifecycle.Priced("rfq-2", quoter.Quote{Mid: 100})
+	quote("rfq-2", "nonce-2", "120000000")
+	conf := &types.RFQConfirmation{
+		AssetAddress: asset,
+		Strike:       "310000000000",
+		Expiry:       int(expiry),
+		IsTakerBuy:   true,
+		Maker:        "0xother",
+		Price:        "104000000",
+	}
+	if !lifecycle.Confirmed("trade", conf) {
+		t.Fatal("confirmation with matching terms did not match")
+	}
+
+	// Declined, and a repeat of it ignored
+	lifecycle.Received("rfq-3", rfqFor("320000000000"))
+	lifecycle.Received("rfq-3", rfqFor("320000000000"))
+	lifecycle.Declined("rfq-3", "declined by policy")
+
+	records := lifecycle.Records(0)
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	want := map[string]Status{"rfq-1": StatusFilled, "rfq-2": StatusLost, "rfq-3": StatusDeclined}
+	for _, record := range records {
+		if record.Status != want[record.RFQID] {
+			t.Errorf("%s status = %s, want %s", record.RFQID, record.Status, want[record.RFQID])
+		}
+	}
+
+	stats := lifecycle.Stats([]string{"asset", "expiry", "size"})
+	if len(stats) != 1 {
+		t.Fatalf("got %d buckets, want 1: %+v", len(stats), stats)
+	}
+	s := stats[0]
+	if s.Underlying != asset || s.Expiry != "1-7d" || s.Size != "1-10" {
+		t.Errorf("bucket = %+v", s.Bucket)
+	}
+	if s.RFQs != 3 || s.Quoted != 2 || s.Declined != 1 || s.Filled != 1 || s.Lost != 1 {
+		t.Errorf("counts = %+v", s)
+	}
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"HitRate", s.HitRate, 0.5},
+		{"AvgEdgeBps", s.AvgEdgeBps, 1500},
+		{"AvgFilledEdgeBps", s.AvgFilledEdgeBps, 1000},
+		{"AvgMissedEdgeBps", s.AvgMissedEdgeBps, 2000},
+		{"AvgLossGapBps", s.AvgLossGapBps, (120.0 - 104) / 104 * 1e4},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-6 {
+			t.Errorf("%s = %g, want %g", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLifecycleExpiresUnfilledQuotes(t *testing.T) {
+	lifecycle := NewLifecycle("0xmaker", 1)
+	lifecycle.Received("rfq-1", types.RFQResult{Asset: "0xaa", Quantity: "1000000000000000000"})
+	lifecycle.Quoted("rfq-1", &ryskcore.Quote{Nonce: "n", Price: "1000000", ValidUntil: time.Now().Add(-time.Second).Unix()})
+	if got := lifecycle.Records(1)[0].Status; got != StatusMissed {
+		t.Errorf("expired quote status = %s, want missed", got)
+	}
+
+	// Only the newest RFQ is kept
+	lifecycle.Received("rfq-2", types.RFQResult{Asset: "0xaa"})
+	records := lifecycle.Records(0)
+	if len(records) != 1 || records[0].RFQID != "rfq-2" {
+		t.Errorf("records = %+v, want only rfq-2", records)
+	}
+	if lifecycle.Confirmed("", &types.RFQConfirmation{QuoteNonce: "n"}) {
+		t.Error("confirmation matched a dropped RFQ")
+	}
+}
+
+func TestLifecycleConfirmationsWithoutMaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  types.RFQConfirmation
+		rfqID string
+		want  Status
+	}{
+		{"our nonce", types.RFQConfirmation{QuoteNonce: "n"}, "", StatusFilled},
+		{"RFQ ID only", types.RFQConfirmation{}, "rfq-1", StatusUnresolved},
+		{"another maker's nonce", types.RFQConfirmation{QuoteNonce: "theirs"}, "rfq-1", StatusUnresolved},
+		{"our nonce, another maker", types.RFQConfirmation{QuoteNonce: "n", Maker: "0xother"}, "", StatusLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lifecycle := NewLifecycle("0xmaker", 10)
+			lifecycle.Received("rfq-1", types.RFQResult{Asset: "0xaa", Quantity: "1000000000000000000"})
+			lifecycle.Quoted("rfq-1", &ryskcore.Quote{Nonce: "n", Price: "1000000", ValidUntil: time.Now().Add(time.Minute).Unix()})
+
+			if !lifecycle.Confirmed(tt.rfqID, &tt.conf) {
+				t.Fatal("confirmation did not match")
+			}
+			if got := lifecycle.Records(1)[0].Status; got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+
+			// Unresolved quotes count towards neither hits nor misses
+			s := lifecycle.Stats(nil)[0]
+			wantUnresolved := 0
+			if tt.want == StatusUnresolved {
+				wantUnresolved = 1
+			}
+			if s.Unresolved != wantUnresolved || s.Live != 0 {
+				t.Errorf("unresolved = %d, live = %d; want %d, 0", s.Unresolved, s.Live, wantUnresolved)
+			}
+		})
+	}
+}
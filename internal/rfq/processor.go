@@ -8,9 +8,15 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/margin"
+	"github.com/wakamex/atomizer/internal/marketdata"
+	"github.com/wakamex/atomizer/internal/policy"
 	"github.com/wakamex/atomizer/internal/quoter"
 	"github.com/wakamex/atomizer/internal/types"
+	"github.com/wakamex/atomizer/internal/volsurface"
 	"github.com/wakamex/rysk-v12-cli/ryskcore"
 )
 
@@ -18,11 +24,31 @@ import (
 type Processor struct {
 	config               *config.Config
 	exchange             types.Exchange
+	surfaces             *volsurface.Service
+	marketData           *marketdata.Hub
+	watchlist            *marketdata.Watchlist // Perps of the underlyings quoted
+	margin               *margin.Monitor
+	policy               *policy.Engine
+	lifecycle            *Lifecycle
+	router               quoter.HedgeRouter // Venue hedges will be placed on, for their fees
 	lastQuoteTime        map[string]time.Time
 	lastQuoteTimeMutex   sync.Mutex
 	debounceDuration     time.Duration
+	economics            map[string]quoteEconomics // By signed quote nonce
+	economicsMutex       sync.Mutex
 }
 
+// quoteEconomics records the fee and edge priced into a sent quote
+type quoteEconomics struct {
+	rfqID       string
+	expectedFee decimal.Decimal
+	netEdge     decimal.Decimal
+	quotedAt    time.Time
+}
+
+// economicsTTL is how long quote economics are kept for trade confirmations
+const economicsTTL = time.Hour
+
 // NewProcessor creates a new RFQ processor
 func NewProcessor(cfg *config.Config, exchange types.Exchange) *Processor {
 	return &Processor{
@@ -30,11 +56,61 @@ func NewProcessor(cfg *config.Config, exchange types.Exchange) *Processor {
 		exchange:         exchange,
 		lastQuoteTime:    make(map[string]time.Time),
 		debounceDuration: 5 * time.Second,
+		economics:        make(map[string]quoteEconomics),
 	}
 }
 
+// SetVolSurface enables model pricing from the given surfaces
+func (p *Processor) SetVolSurface(surfaces *volsurface.Service) {
+	p.surfaces = surfaces
+}
+
+// SetMarketData prices quotes off the live underlying index streamed by a
+// shared market data hub
+func (p *Processor) SetMarketData(hub *marketdata.Hub) {
+	p.marketData = hub
+	p.watchlist = marketdata.NewWatchlist(hub)
+}
+
+// SetMarginMonitor stops responding to RFQs while margin usage is over the
+// monitor's limit
+func (p *Processor) SetMarginMonitor(monitor *margin.Monitor) {
+	p.margin = monitor
+}
+
+// SetPolicy screens RFQs and their prices with a quoting policy before
+// quoting. With a policy, RFQs that can't be priced are declined unless it
+// allows dummy quotes.
+func (p *Processor) SetPolicy(engine *policy.Engine) {
+	p.policy = engine
+}
+
+// SetLifecycle records each RFQ, the quote sent for it and its confirmation
+func (p *Processor) SetLifecycle(lifecycle *Lifecycle) {
+	p.lifecycle = lifecycle
+}
+
+// SetHedgeRouter prices the hedge fees of the venue each RFQ's hedge would
+// be routed to, rather than always the primary exchange's
+func (p *Processor) SetHedgeRouter(router quoter.HedgeRouter) {
+	p.router = router
+}
+
 // ProcessRFQ handles an incoming RFQ and generates a quote response
 func (p *Processor) ProcessRFQ(client RyskClient, rfq types.RFQResult, originalRfqID string) error {
+	if p.lifecycle != nil {
+		p.lifecycle.Received(originalRfqID, rfq)
+	}
+
+	// Taking on more positions could breach margin
+	if p.margin != nil {
+		if err := p.margin.Allow(); err != nil {
+			log.Printf("[Quote %s] Not quoting: %v", originalRfqID, err)
+			p.recordDecline(originalRfqID, err.Error())
+			return nil
+		}
+	}
+
 	// Check debounce
 	if p.isDebounced(originalRfqID) {
 		return nil
@@ -43,11 +119,42 @@ func (p *Processor) ProcessRFQ(client RyskClient, rfq types.RFQResult, originalR
 	// Generate quote
 	quote, err := p.generateQuote(rfq, originalRfqID)
 	if err != nil {
+		p.recordDecline(originalRfqID, err.Error())
 		return fmt.Errorf("failed to generate quote: %w", err)
 	}
 
+	if quote == nil {
+		p.recordDecline(originalRfqID, "declined by policy")
+		return nil
+	}
+
 	// Send quote response
-	return p.sendQuoteResponse(client, quote, originalRfqID)
+	if err := p.sendQuoteResponse(client, quote, originalRfqID); err != nil {
+		p.recordDecline(originalRfqID, err.Error())
+		return err
+	}
+	if p.lifecycle != nil {
+		p.lifecycle.Quoted(originalRfqID, quote)
+	}
+	return nil
+}
+
+// recordDecline records that no quote was sent for an RFQ
+func (p *Processor) recordDecline(rfqID, reason string) {
+	if p.lifecycle != nil {
+		p.lifecycle.Declined(rfqID, reason)
+	}
+}
+
+// RecordConfirmation matches a trade confirmation to the RFQ and quote it
+// filled
+func (p *Processor) RecordConfirmation(rfqID string, conf *types.RFQConfirmation) {
+	if p.lifecycle == nil {
+		return
+	}
+	if !p.lifecycle.Confirmed(rfqID, conf) {
+		log.Printf("[Quote %s] Confirmation for quote %s matches no tracked RFQ", rfqID, conf.QuoteNonce)
+	}
 }
 
 // isDebounced checks if we've recently quoted this RFQ
@@ -64,7 +171,8 @@ func (p *Processor) isDebounced(rfqID string) bool {
 	return false
 }
 
-// generateQuote creates a quote for the RFQ
+// generateQuote creates a quote for the RFQ, or returns nil if the quoting
+// policy declines it
 func (p *Processor) generateQuote(rfq types.RFQResult, originalRfqID string) (*ryskcore.Quote, error) {
 	// Create base quote params
 	quoteParams := &ryskcore.Quote{
@@ -81,10 +189,27 @@ func (p *Processor) generateQuote(rfq types.RFQResult, originalRfqID string) (*r
 		ValidUntil:   time.Now().Unix() + p.config.QuoteValidDurationSeconds,
 	}
 
+	// Screen the RFQ before pricing it
+	var req *policy.Request
+	if p.policy != nil {
+		var err error
+		if req, err = policy.NewRequest(originalRfqID, rfq); err != nil {
+			if p.policy.Fail(originalRfqID, "asset", err).Action == policy.ActionDecline {
+				return nil, nil
+			}
+		} else if p.policy.Screen(req).Action == policy.ActionDecline {
+			return nil, nil
+		}
+	}
+
 	// Try real-time pricing from exchange if asset mapping exists
-	if underlying, hasMapping := p.config.AssetMapping[rfq.Asset]; hasMapping {
-		exchangeQuote, err := p.makeExchangeQuote(rfq, underlying, originalRfqID)
+	if underlying, err := instruments.ResolveRyskAsset(rfq.ChainID, rfq.Asset); err == nil {
+		exchangeQuote, err := p.makeExchangeQuote(rfq, underlying, originalRfqID, req)
 		if err != nil {
+			// A dummy price is only sent if the policy allows it
+			if p.policy != nil && p.policy.Fail(originalRfqID, "pricing", err).Action == policy.ActionDecline {
+				return nil, nil
+			}
 			log.Printf("[Quote %s] Error getting %s quote: %v. Falling back to dummy price.", 
 				originalRfqID, p.config.ExchangeName, err)
 			// Sign the dummy quote since we're falling back
@@ -92,7 +217,8 @@ func (p *Processor) generateQuote(rfq types.RFQResult, originalRfqID string) (*r
 				return nil, fmt.Errorf("failed to sign quote: %w", err)
 			}
 		} else {
-			// Use exchange quote (already signed by quoter.MakeQuote)
+			// Use exchange quote (already signed by quoter.MakeQuote), nil
+			// if declined at its price
 			quoteParams = exchangeQuote
 		}
 	} else {
@@ -106,18 +232,96 @@ func (p *Processor) generateQuote(rfq types.RFQResult, originalRfqID string) (*r
 	return quoteParams, nil
 }
 
-// makeExchangeQuote generates a quote using exchange pricing
-func (p *Processor) makeExchangeQuote(rfq types.RFQResult, underlying string, rfqID string) (*ryskcore.Quote, error) {
+// makeExchangeQuote generates a quote using exchange pricing. With a policy
+// request the price is reviewed first, and nil is returned if it is declined.
+func (p *Processor) makeExchangeQuote(rfq types.RFQResult, underlying string, rfqID string, req *policy.Request) (*ryskcore.Quote, error) {
+	// Stream the underlying's perp so later quotes use its live index
+	if p.watchlist != nil {
+		if err := p.watchlist.Watch(instruments.PerpName(p.config.ExchangeName, underlying)); err != nil {
+			log.Printf("[Quote %s] Failed to stream %s index: %v", rfqID, underlying, err)
+		}
+	}
+	
 	// Use the quoter module to generate a properly signed quote
-	quote, err := quoter.MakeQuote(rfq, underlying, rfqID, p.config, p.exchange)
+	quote, priced, err := quoter.MakeQuoteWithSurface(rfq, underlying, rfqID, p.config, p.exchange, p.surfaces, p.marketData, p.router)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make quote: %w", err)
 	}
+
+	if req != nil {
+		req.Price, req.APR, req.Spot = priced.Price, priced.APR, priced.Spot
+		switch d := p.policy.Review(req); d.Action {
+		case policy.ActionDecline:
+			return nil, nil
+		case policy.ActionAdjust:
+			// Moving the price moves the edge with it
+			edge := d.Price - priced.Price
+			if !rfq.IsTakerBuy {
+				edge = -edge
+			}
+			priced.NetEdge += edge
+			priced.Price = d.Price
+			priced.APR = quoter.CalculateAPR(d.Price, req.Strike, req.Spot, time.Until(req.Expiry).Hours()/24, rfq.IsPut)
+			quote.Price = quoter.FormatPrice(d.Price)
+			if err := p.signQuote(&quote); err != nil {
+				return nil, fmt.Errorf("failed to sign quote: %w", err)
+			}
+		}
+	}
+
+	p.recordEconomics(rfqID, quote.Nonce, priced)
+	if p.lifecycle != nil {
+		p.lifecycle.Priced(rfqID, priced)
+	}
 	
 	// Convert to pointer for compatibility
 	return &quote, nil
 }
 
+// recordEconomics stores the trade-level fee and edge of a quote by the nonce
+// it was signed with, which trade confirmations carry
+func (p *Processor) recordEconomics(rfqID, nonce string, priced quoter.Quote) {
+	quantity := decimal.NewFromFloat(priced.Quantity)
+
+	p.economicsMutex.Lock()
+	defer p.economicsMutex.Unlock()
+
+	for id, e := range p.economics {
+		if time.Since(e.quotedAt) > economicsTTL {
+			delete(p.economics, id)
+		}
+	}
+
+	p.economics[nonce] = quoteEconomics{
+		rfqID:       rfqID,
+		expectedFee: decimal.NewFromFloat(priced.FeeBound).Mul(quantity),
+		netEdge:     decimal.NewFromFloat(priced.NetEdge).Mul(quantity),
+		quotedAt:    time.Now(),
+	}
+}
+
+// QuoteEconomics returns the expected fee and net edge of a sent quote, for
+// the whole trade in quote currency. The quote is found by its nonce, or by
+// its RFQ ID for confirmations without one.
+func (p *Processor) QuoteEconomics(rfqID, nonce string) (expectedFee, netEdge decimal.Decimal, ok bool) {
+	p.economicsMutex.Lock()
+	defer p.economicsMutex.Unlock()
+
+	e, ok := p.economics[nonce]
+	if !ok && rfqID != "" {
+		var latest time.Time
+		for _, candidate := range p.economics {
+			if candidate.rfqID == rfqID && candidate.quotedAt.After(latest) {
+				e, ok, latest = candidate, true, candidate.quotedAt
+			}
+		}
+	}
+	if !ok {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return e.expectedFee, e.netEdge, true
+}
+
 // signQuote signs the quote using EIP-712
 func (p *Processor) signQuote(quote *ryskcore.Quote) error {
 	// Create the message hash
@@ -162,6 +366,12 @@ func (p *Processor) sendQuoteResponse(client RyskClient, quote *ryskcore.Quote,
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// A dry run never sends quotes, so it can't be filled for real
+	if p.config.DryRun {
+		log.Printf("[Quote %s] Dry run, not sending signed quote: %s", rfqID, requestBytes)
+		return nil
+	}
+	
 	// Send response
 	client.Send(requestBytes)
 	
@@ -0,0 +1,49 @@
+package rfq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// countingClient counts the messages sent to Rysk
+type countingClient int
+
+func (c *countingClient) Send(data []byte) { *c++ }
+
+func TestDryRunDoesNotSendQuotes(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfq := types.RFQResult{
+		Asset:      "0x0000000000000000000000000000000000000001",
+		Strike:     "300000000000",
+		Expiry:     time.Now().Add(24 * time.Hour).Unix(),
+		Quantity:   "1000000000000000000",
+		IsTakerBuy: true,
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		cfg := &config.Config{
+			MakerAddress:     crypto.PubkeyToAddress(key.PublicKey).Hex(),
+			ParsedPrivateKey: key,
+			DummyPrice:       "1000000",
+			DryRun:           dryRun,
+		}
+		var sent countingClient
+		if err := NewProcessor(cfg, nil).ProcessRFQ(&sent, rfq, "rfq-1"); err != nil {
+			t.Fatalf("dry run %v: ProcessRFQ: %v", dryRun, err)
+		}
+		want := countingClient(1)
+		if dryRun {
+			want = 0
+		}
+		if sent != want {
+			t.Errorf("dry run %v: sent %d quotes, want %d", dryRun, sent, want)
+		}
+	}
+}
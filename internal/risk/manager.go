@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
+	"github.com/wakamex/atomizer/internal/pricing"
 	"github.com/wakamex/atomizer/internal/types"
 	"github.com/shopspring/decimal"
 )
@@ -19,6 +21,7 @@ type Manager struct {
 	maxDeltaExposure decimal.Decimal
 	maxGammaExposure decimal.Decimal
 	stopLossThreshold decimal.Decimal
+	pricer           *pricing.Engine
 	mu               sync.RWMutex
 }
 
@@ -42,17 +45,32 @@ func NewManager(cfg *config.Config) *Manager {
 		maxDeltaExposure:  maxDelta,
 		maxGammaExposure:  maxGamma,
 		stopLossThreshold: stopLoss,
+		pricer:            pricing.NewEngine(pricing.NewTickerSource(cfg.ExchangeName, cfg.ExchangeTestMode)),
 	}
 }
 
+// SetPricingEngine replaces the engine used to compute option Greeks
+func (m *Manager) SetPricingEngine(engine *pricing.Engine) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pricer = engine
+}
+
 // ValidateTrade checks if a trade is within risk limits
 func (m *Manager) ValidateTrade(trade *types.TradeEvent) error {
+	// Resolve the instrument, without it the trade's Greeks are unknown
+	inst, err := m.resolveInstrument(trade)
+	if err != nil {
+		return err
+	}
+	instrumentName := inst.Name
+	
+	// Price the trade before taking the lock, this may hit the network
+	tradeDelta, tradeGamma, _ := m.estimateGreeks(inst)
+	
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	
-	// Construct instrument name
-	instrumentName := m.constructInstrumentName(trade)
-	
 	// Check position size limit
 	currentPosition, exists := m.positions[instrumentName]
 	newPositionSize := trade.Quantity
@@ -73,10 +91,6 @@ func (m *Manager) ValidateTrade(trade *types.TradeEvent) error {
 		return fmt.Errorf("trade would exceed maximum position size of %s", m.maxPositionSize.String())
 	}
 	
-	// Estimate Greeks for the trade
-	tradeDelta := m.estimateDelta(trade)
-	tradeGamma := m.estimateGamma(trade)
-	
 	// Calculate portfolio Greeks after trade
 	totalDelta, totalGamma := m.calculatePortfolioGreeks()
 	
@@ -105,11 +119,20 @@ func (m *Manager) ValidateTrade(trade *types.TradeEvent) error {
 
 // UpdatePosition updates position tracking after a trade
 func (m *Manager) UpdatePosition(trade *types.TradeEvent) {
+	// A trade that can't be resolved is still tracked, under its raw
+	// instrument and flagged as estimated since its Greeks are unknown
+	instrumentName := trade.Instrument
+	delta, gamma, estimated := decimal.Zero, decimal.Zero, true
+	if inst, err := m.resolveInstrument(trade); err != nil {
+		log.Printf("WARNING: %v, tracking %s without Greeks", err, instrumentName)
+	} else {
+		instrumentName = inst.Name
+		delta, gamma, estimated = m.estimateGreeks(inst)
+	}
+	
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
-	instrumentName := m.constructInstrumentName(trade)
-	
 	position, exists := m.positions[instrumentName]
 	if !exists {
 		position = &types.Position{
@@ -143,8 +166,9 @@ func (m *Manager) UpdatePosition(trade *types.TradeEvent) {
 	}
 	
 	// Update Greeks
-	position.Delta = m.estimateDelta(trade)
-	position.Gamma = m.estimateGamma(trade)
+	position.Delta = delta
+	position.Gamma = gamma
+	position.Estimated = estimated
 	position.LastUpdated = time.Now()
 	
 	// Log position update
@@ -159,6 +183,66 @@ func (m *Manager) UpdatePosition(trade *types.TradeEvent) {
 	}
 }
 
+// RecordHedge tracks a hedge filled on a venue. Hedge positions are kept
+// apart from RFQ positions and from each other, keyed by venue and
+// instrument, and count towards the portfolio Greeks. Perps and futures
+// count as one delta per unit.
+func (m *Manager) RecordHedge(venue, instrument string, isBuy bool, quantity, price decimal.Decimal) {
+	inst, err := instruments.Parse(instrument)
+	if err != nil {
+		log.Printf("Failed to resolve hedge instrument %s on %s: %v", instrument, venue, err)
+		return
+	}
+	delta, gamma, estimated := decimal.NewFromInt(1), decimal.Zero, false
+	if inst.IsOption() {
+		delta, gamma, estimated = m.estimateGreeks(inst)
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	key := venuePositionKey(venue, instrument)
+	position, exists := m.positions[key]
+	if !exists {
+		position = &types.Position{
+			Instrument: instrument,
+			Venue:      venue,
+		}
+		m.positions[key] = position
+	}
+	
+	signed := quantity
+	if !isBuy {
+		signed = quantity.Neg()
+	}
+	newQuantity := position.Quantity.Add(signed)
+	switch {
+	case newQuantity.IsZero():
+		position.AvgPrice = decimal.Zero
+	case position.Quantity.IsZero() || position.Quantity.Sign() != newQuantity.Sign():
+		// Opened, or flipped through zero at the hedge price
+		position.AvgPrice = price
+	case position.Quantity.Sign() == signed.Sign():
+		// Added to, so average in the hedge price
+		totalValue := position.Quantity.Abs().Mul(position.AvgPrice).Add(quantity.Mul(price))
+		position.AvgPrice = totalValue.Div(newQuantity.Abs())
+	}
+	position.Quantity = newQuantity
+	position.Delta = delta
+	position.Gamma = gamma
+	position.Estimated = estimated
+	position.LastUpdated = time.Now()
+	
+	log.Printf("Updated %s hedge position %s: Qty=%s, AvgPrice=%s, Delta=%s, Gamma=%s",
+		venue, instrument, position.Quantity.String(), position.AvgPrice.String(),
+		position.Delta.String(), position.Gamma.String())
+}
+
+// venuePositionKey keys a hedge position by its venue and instrument
+func venuePositionKey(venue, instrument string) string {
+	return venue + ":" + instrument
+}
+
 // GetGreeks returns current portfolio Greeks
 func (m *Manager) GetGreeks() (delta, gamma decimal.Decimal) {
 	m.mu.RLock()
@@ -206,62 +290,45 @@ func (m *Manager) GetMetrics() types.RiskMetrics {
 	}
 }
 
-// constructInstrumentName creates instrument name from trade details
-func (m *Manager) constructInstrumentName(trade *types.TradeEvent) string {
-	// If we already have an instrument name, use it
-	if trade.Instrument != "" && trade.Instrument != trade.Instrument {
-		return trade.Instrument
-	}
-	
-	// Otherwise construct from components
-	optionType := "C"
-	if trade.IsPut {
-		optionType = "P"
+// resolveInstrument identifies the traded option from its name or Rysk terms
+func (m *Manager) resolveInstrument(trade *types.TradeEvent) (*instruments.Instrument, error) {
+	inst, err := instruments.Resolve(trade.Instrument, trade.Strike.String(), trade.Expiry, trade.IsPut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve instrument for trade %s: %w", trade.ID, err)
 	}
-	
-	// Format: ASSET-EXPIRY-STRIKE-TYPE
-	expiryTime := time.Unix(trade.Expiry, 0)
-	expiryStr := expiryTime.Format("20060102")
-	
-	return fmt.Sprintf("%s-%s-%s-%s", 
-		"ETH", // Default to ETH, should map from asset address
-		expiryStr,
-		trade.Strike.String(),
-		optionType)
+	return inst, nil
 }
 
-// estimateDelta estimates option delta based on simple heuristics
-func (m *Manager) estimateDelta(trade *types.TradeEvent) decimal.Decimal {
-	// Simple estimation based on moneyness and time to expiry
-	// In production, use proper Black-Scholes or get from exchange
-	
-	timeToExpiry := time.Until(time.Unix(trade.Expiry, 0))
-	daysToExpiry := timeToExpiry.Hours() / 24
-	
-	// Very simple approximation
-	if trade.IsPut {
-		if daysToExpiry < 7 {
-			return decimal.NewFromFloat(-0.3)
-		}
-		return decimal.NewFromFloat(-0.5)
-	} else {
-		if daysToExpiry < 7 {
-			return decimal.NewFromFloat(0.7)
-		}
-		return decimal.NewFromFloat(0.5)
+// estimateGreeks computes per-unit option delta and gamma with the pricing
+// engine. estimated is set when they assume the option is at the money.
+func (m *Manager) estimateGreeks(inst *instruments.Instrument) (delta, gamma decimal.Decimal, estimated bool) {
+	m.mu.RLock()
+	pricer := m.pricer
+	m.mu.RUnlock()
+	
+	instrumentName := inst.Name
+	opt := pricing.Option{
+		Instrument: instrumentName,
+		Strike:     inst.Strike.InexactFloat64(),
+		Expiry:     inst.Expiry.Unix(),
+		IsPut:      inst.IsPut(),
 	}
-}
-
-// estimateGamma estimates option gamma
-func (m *Manager) estimateGamma(trade *types.TradeEvent) decimal.Decimal {
-	// Simple estimation - gamma is highest ATM and near expiry
-	timeToExpiry := time.Until(time.Unix(trade.Expiry, 0))
-	daysToExpiry := timeToExpiry.Hours() / 24
-	
-	if daysToExpiry < 7 {
-		return decimal.NewFromFloat(0.1)
+	
+	valuation, err := pricer.Evaluate(opt)
+	if err != nil {
+		// Without an underlying price assume the option is at the money,
+		// which is where gamma is largest
+		log.Printf("WARNING: No underlying price for %s (%v), estimating Greeks at the money", instrumentName, err)
+		estimated = true
+		opt.Spot = opt.Strike
+		valuation, err = pricer.Evaluate(opt)
+		if err != nil {
+			log.Printf("Failed to price %s: %v", instrumentName, err)
+			return decimal.Zero, decimal.Zero, true
+		}
 	}
-	return decimal.NewFromFloat(0.05)
+	
+	return decimal.NewFromFloat(valuation.Delta), decimal.NewFromFloat(valuation.Gamma), estimated
 }
 
 // calculatePortfolioGreeks calculates total portfolio Greeks
@@ -0,0 +1,73 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/pricing"
+	"github.com/wakamex/atomizer/internal/types"
+)
+
+// fixedSource serves the same market data for every instrument
+type fixedSource struct {
+	data pricing.MarketData
+}
+
+func (s fixedSource) GetMarketData(instrument string) (*pricing.MarketData, error) {
+	return &s.data, nil
+}
+
+func TestRecordHedgeFlagsEstimatedGreeks(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     pricing.MarketDataSource
+		instrument string
+		estimated  bool
+	}{
+		{"no underlying price", nil, "ETH-20261225-3000-C", true},
+		{"priced from the venue", fixedSource{pricing.MarketData{Spot: 2500, IV: 0.6}}, "ETH-20261225-3000-C", false},
+		{"perp", nil, "ETH-PERP", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&config.Config{})
+			m.SetPricingEngine(pricing.NewEngine(tt.source))
+			m.RecordHedge("derive", tt.instrument, true, decimal.NewFromInt(1), decimal.NewFromInt(100))
+
+			position := m.GetPositions()[venuePositionKey("derive", tt.instrument)]
+			if position.Estimated != tt.estimated {
+				t.Errorf("estimated = %v, want %v", position.Estimated, tt.estimated)
+			}
+			if position.Delta.IsZero() {
+				t.Errorf("delta = 0, want it priced")
+			}
+		})
+	}
+}
+
+func TestUnresolvableTradeHasNoGreeks(t *testing.T) {
+	trade := &types.TradeEvent{
+		ID:         "trade-1",
+		Instrument: "0x00000000000000000000000000000000deadbeef",
+		Strike:     decimal.RequireFromString("300000000000"),
+		Expiry:     time.Date(2026, 12, 25, 8, 0, 0, 0, time.UTC).Unix(),
+		Quantity:   decimal.NewFromInt(1),
+		Price:      decimal.NewFromInt(100),
+	}
+
+	m := NewManager(&config.Config{})
+	if err := m.ValidateTrade(trade); err == nil {
+		t.Error("expected an unresolvable trade to fail validation")
+	}
+
+	m.UpdatePosition(trade)
+	position, ok := m.GetPositions()[trade.Instrument]
+	if !ok {
+		t.Fatalf("positions = %v, want the trade tracked under its asset", m.GetPositions())
+	}
+	if !position.Estimated || !position.Delta.IsZero() || !position.Quantity.Equal(trade.Quantity) {
+		t.Errorf("position = %+v, want quantity 1, estimated, without Greeks", position)
+	}
+}
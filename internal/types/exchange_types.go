@@ -15,16 +15,39 @@ type ExchangePosition struct {
 	MarkPrice      float64
 	IndexPrice     float64
 	PnL            float64
+	SubaccountID   uint64 // Derive subaccount holding the position (0 elsewhere)
+}
+
+// MarginState is a subaccount's collateral and margin usage
+type MarginState struct {
+	SubaccountID      uint64
+	Collateral        decimal.Decimal // Value of collateral held
+	SubaccountValue   decimal.Decimal // Collateral plus the mark-to-market of positions
+	InitialMargin     decimal.Decimal // Initial margin required by positions and open orders
+	MaintenanceMargin decimal.Decimal // Margin required to avoid liquidation
+	AvailableMargin   decimal.Decimal // Margin left for new orders
+	UpdatedAt         time.Time
+}
+
+// Utilization is the share of the subaccount value used as initial margin.
+// A subaccount with no value is fully utilized.
+func (m MarginState) Utilization() decimal.Decimal {
+	if !m.SubaccountValue.IsPositive() {
+		return decimal.NewFromInt(1)
+	}
+	return m.InitialMargin.Div(m.SubaccountValue)
 }
 
 // Position represents a current position with Greeks
 // This is used internally by the market maker for risk management
 type Position struct {
 	Instrument  string
+	Venue       string // Exchange a hedge position is held on, empty for RFQ trades
 	Quantity    decimal.Decimal
 	AvgPrice    decimal.Decimal
 	Delta       decimal.Decimal
 	Gamma       decimal.Decimal
+	Estimated   bool // Greeks assume the option is at the money, for want of an underlying price
 	LastUpdated time.Time
 }
 
@@ -42,8 +65,8 @@ type Exchange interface {
 	// Get order book for RFQ pricing
 	GetOrderBook(req RFQResult, asset string) (CCXTOrderBook, error)
 	
-	// Place an order based on RFQ confirmation
-	PlaceOrder(conf RFQConfirmation, instrument string, cfg interface{}) error
+	// Place an order based on RFQ confirmation, returning the exchange's order ID
+	PlaceOrder(conf RFQConfirmation, instrument string, cfg interface{}) (string, error)
 	
 	// Convert option parameters to exchange-specific instrument name
 	ConvertToInstrument(asset string, strike string, expiry int64, isPut bool) (string, error)
@@ -2,6 +2,8 @@ package types
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -13,10 +15,10 @@ type MarketMakerExchange interface {
 	SubscribeTickers(ctx context.Context, instruments []string) (<-chan TickerUpdate, error)
 
 	// Place a limit order
-	PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal) (string, error)
+	PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts OrderOptions) (string, error)
 
 	// Replace an existing order with new parameters (atomic cancel + create)
-	ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal) (string, error)
+	ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts OrderOptions) (string, error)
 
 	// Cancel an order
 	CancelOrder(orderID string) error
@@ -29,6 +31,12 @@ type MarketMakerExchange interface {
 
 	// Get order book for an instrument
 	GetOrderBook(instrument string) (*MarketMakerOrderBook, error)
+
+	// Subscribe to executions of our orders
+	SubscribeFills(ctx context.Context) (<-chan Fill, error)
+
+	// Subscribe to state changes of our orders (fills, cancels, expiries)
+	SubscribeOrderUpdates(ctx context.Context) (<-chan MarketMakerOrder, error)
 }
 
 // TickerUpdate represents a real-time ticker update
@@ -40,6 +48,7 @@ type TickerUpdate struct {
 	BestAskSize decimal.Decimal
 	LastPrice   decimal.Decimal
 	MarkPrice   decimal.Decimal
+	IndexPrice  decimal.Decimal // Underlying index, zero if not reported
 	Timestamp   time.Time
 	// Greeks for options
 	Delta *decimal.Decimal
@@ -62,9 +71,27 @@ type OrderBookLevel struct {
 type MarketMakerOrderBook struct {
 	Bids      []OrderBookLevel // Sorted by price descending (best bid first)
 	Asks      []OrderBookLevel // Sorted by price ascending (best ask first)
+	Index     decimal.Decimal  // Underlying index, zero if not reported
 	Timestamp time.Time
 }
 
+// ToCCXT converts the book to the CCXT format used for RFQ pricing
+func (b *MarketMakerOrderBook) ToCCXT(symbol string) CCXTOrderBook {
+	book := CCXTOrderBook{
+		Symbol: symbol,
+		Bids:   make([][]float64, len(b.Bids)),
+		Asks:   make([][]float64, len(b.Asks)),
+		Index:  b.Index.InexactFloat64(),
+	}
+	for i, bid := range b.Bids {
+		book.Bids[i] = []float64{bid.Price.InexactFloat64(), bid.Size.InexactFloat64()}
+	}
+	for i, ask := range b.Asks {
+		book.Asks[i] = []float64{ask.Price.InexactFloat64(), ask.Size.InexactFloat64()}
+	}
+	return book
+}
+
 // MarketMakerOrder represents an active order for market making
 type MarketMakerOrder struct {
 	OrderID      string
@@ -78,11 +105,77 @@ type MarketMakerOrder struct {
 	UpdatedAt    time.Time
 }
 
+// TimeInForce controls how long an order can rest on the book
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "gtc" // Good til cancelled
+	TimeInForceIOC TimeInForce = "ioc" // Immediate or cancel: fill what crosses, cancel the rest
+	TimeInForceFOK TimeInForce = "fok" // Fill or kill: fill in full immediately or not at all
+)
+
+// OrderOptions are execution instructions for a limit order. The zero value
+// is a plain good-til-cancelled limit order.
+type OrderOptions struct {
+	TimeInForce TimeInForce // Defaults to GTC
+	PostOnly    bool        // Reject the order rather than take liquidity
+	ReduceOnly  bool        // Only reduce an existing position, never open or flip one
+	Urgent      bool        // Goes ahead of routine requests under the exchange's rate limit, e.g. hedges
+}
+
+// TIF returns the time in force, defaulting to GTC
+func (o OrderOptions) TIF() TimeInForce {
+	if o.TimeInForce == "" {
+		return TimeInForceGTC
+	}
+	return o.TimeInForce
+}
+
+// Validate checks the options are supported and consistent
+func (o OrderOptions) Validate() error {
+	switch o.TIF() {
+	case TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+	default:
+		return fmt.Errorf("unsupported time in force: %s", o.TimeInForce)
+	}
+	if o.PostOnly && o.TIF() != TimeInForceGTC {
+		return fmt.Errorf("post-only orders must be good til cancelled, not %s", o.TimeInForce)
+	}
+	return nil
+}
+
+// ParseTimeInForce parses a time in force name such as "ioc"
+func ParseTimeInForce(s string) (TimeInForce, error) {
+	tif := TimeInForce(strings.ToLower(s))
+	if err := (OrderOptions{TimeInForce: tif}).Validate(); err != nil {
+		return "", err
+	}
+	return tif, nil
+}
+
+// Fill represents an execution of one of our orders
+type Fill struct {
+	TradeID     string
+	OrderID     string
+	Instrument  string
+	Side        string // "buy" or "sell"
+	Price       decimal.Decimal
+	Amount      decimal.Decimal
+	Fee         decimal.Decimal
+	RealizedPnL decimal.Decimal // Excluding fees
+	Timestamp   time.Time
+}
+
 // MarketMakerConfig holds configuration for the market maker
 type MarketMakerConfig struct {
 	// Exchange configuration
 	Exchange         string // "derive" or "deribit"
 	ExchangeTestMode bool
+	DryRun           bool          // Simulate orders on a paper exchange fed by live market data
+	PaperLatency     time.Duration // Order and cancel latency in dry run mode
+	RecordPath       string        // Record all exchange calls to this file
+	ReplayPath       string        // Replay a recording instead of connecting to the exchange
+	Subaccount       uint64        // Derive subaccount to trade from (0 = DERIVE_SUBACCOUNT_ID or the default)
 
 	// Market making parameters
 	Instruments     []string        // List of instruments to make markets on
@@ -93,6 +186,7 @@ type MarketMakerConfig struct {
 	// Risk parameters
 	MaxPositionSize  decimal.Decimal // Maximum position per instrument
 	MaxTotalExposure decimal.Decimal // Maximum total exposure across all instruments
+	MaxMarginUsage   decimal.Decimal // Stop quoting at this share of the subaccount value used as margin (0 = no limit)
 
 	// Order management
 	CancelThreshold  decimal.Decimal // Price movement threshold to trigger order updates
@@ -124,6 +218,7 @@ func DefaultMarketMakerConfig() *MarketMakerConfig {
 		RefreshInterval:          3 * time.Second,
 		MaxPositionSize:          decimal.NewFromFloat(10),
 		MaxTotalExposure:         decimal.NewFromFloat(100),
+		MaxMarginUsage:           decimal.NewFromFloat(0.8), // Stop quoting at 80% margin usage
 		CancelThreshold:          decimal.NewFromFloat(0.005), // 0.5% price movement
 		MaxOrdersPerSide:         1,
 		MinSpreadBps:             5,                         // 0.05%
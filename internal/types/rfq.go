@@ -29,6 +29,7 @@ type RFQResult struct {
 	IsPut      bool   `json:"isPut,omitempty"`
 	Strike     string `json:"strike,omitempty"`
 	IsTakerBuy bool   `json:"isTakerBuy,omitempty"`
+	Taker      string `json:"taker,omitempty"`
 }
 
 // RFQConfirmation represents the structure of an incoming RFQ confirmation message
@@ -42,8 +42,12 @@ type TradeEvent struct {
 	Price           decimal.Decimal
 	IsTakerBuy      bool
 	Timestamp       time.Time
-	HedgeOrderID    string
+	HedgeOrderID    string          // Exchange order IDs, comma-separated when repriced
 	HedgeExchange   string
+	HedgeQuantity   decimal.Decimal // Hedge quantity filled
+	HedgePrice      decimal.Decimal // Average hedge fill price, in the venue's units
+	ExpectedFee     decimal.Decimal // Worst-case Rysk and hedge fees priced into the quote
+	NetEdge         decimal.Decimal // Edge left after fees
 	Error           error
 }
 
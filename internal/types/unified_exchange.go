@@ -10,15 +10,17 @@ import (
 type UnifiedExchange interface {
 	// RFQ/Arbitrage methods
 	GetOrderBookForRFQ(req RFQResult, asset string) (CCXTOrderBook, error)
-	PlaceOrder(conf RFQConfirmation, instrument string, cfg interface{}) error
+	PlaceOrder(conf RFQConfirmation, instrument string, cfg interface{}) (string, error)
 	ConvertToInstrument(asset string, strike string, expiry int64, isPut bool) (string, error)
 	
 	// Market Maker methods
 	GetOrderBook(instrument string) (*MarketMakerOrderBook, error)
-	PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal) (string, error)
-	ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal) (string, error)
+	PlaceLimitOrder(instrument string, side string, price, amount decimal.Decimal, opts OrderOptions) (string, error)
+	ReplaceOrder(orderID string, instrument string, side string, price, amount decimal.Decimal, opts OrderOptions) (string, error)
 	CancelOrder(orderID string) error
 	GetOpenOrders() ([]MarketMakerOrder, error)
+	SubscribeFills(ctx context.Context) (<-chan Fill, error)
+	SubscribeOrderUpdates(ctx context.Context) (<-chan MarketMakerOrder, error)
 	
 	// Common methods
 	GetPositions() ([]ExchangePosition, error)
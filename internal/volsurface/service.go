@@ -0,0 +1,115 @@
+package volsurface
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service keeps surfaces for a set of underlyings fresh and answers IV queries
+type Service struct {
+	source          QuoteSource
+	underlyings     []string
+	refreshInterval time.Duration
+	surfaces        map[string]*Surface
+	mu              sync.RWMutex
+}
+
+// NewService creates a surface service for the given underlyings
+func NewService(source QuoteSource, underlyings []string, refreshInterval time.Duration) *Service {
+	if refreshInterval <= 0 {
+		refreshInterval = time.Minute
+	}
+	return &Service{
+		source:          source,
+		underlyings:     underlyings,
+		refreshInterval: refreshInterval,
+		surfaces:        make(map[string]*Surface),
+	}
+}
+
+// Start builds every surface and keeps refreshing them until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	s.refreshAll(ctx)
+
+	go func() {
+		ticker := time.NewTicker(s.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshAll(ctx)
+			}
+		}
+	}()
+}
+
+// refreshAll rebuilds each underlying's surface, keeping the old one on failure
+func (s *Service) refreshAll(ctx context.Context) {
+	for _, underlying := range s.underlyings {
+		if err := s.Refresh(ctx, underlying); err != nil {
+			log.Printf("[VolSurface] Failed to refresh %s surface from %s: %v", underlying, s.source.Name(), err)
+		}
+	}
+}
+
+// Refresh fetches quotes and rebuilds the surface for one underlying
+func (s *Service) Refresh(ctx context.Context, underlying string) error {
+	spot, quotes, err := s.source.FetchQuotes(ctx, underlying)
+	if err != nil {
+		return err
+	}
+
+	builder := NewBuilder(underlying, spot)
+	for _, q := range quotes {
+		builder.Add(q)
+	}
+
+	surface, err := builder.Build(time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.SetSurface(surface)
+
+	fitted := 0
+	for _, slice := range surface.Slices {
+		if slice.SVI != nil {
+			fitted++
+		}
+	}
+	log.Printf("[VolSurface] Built %s surface: spot=%.2f, %d expiries (%d SVI fits) from %d quotes",
+		underlying, spot, len(surface.Slices), fitted, len(quotes))
+
+	return nil
+}
+
+// SetSurface stores a surface, replacing any existing one for its underlying
+func (s *Service) SetSurface(surface *Surface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.surfaces[strings.ToUpper(surface.Underlying)] = surface
+}
+
+// Surface returns the latest surface for an underlying
+func (s *Service) Surface(underlying string) (*Surface, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	surface, ok := s.surfaces[strings.ToUpper(underlying)]
+	return surface, ok
+}
+
+// IV returns the implied vol for an underlying at a strike and unix expiry
+func (s *Service) IV(underlying string, strike float64, expiry int64) (float64, error) {
+	surface, ok := s.Surface(underlying)
+	if !ok {
+		return 0, fmt.Errorf("no vol surface for %s", underlying)
+	}
+	return surface.IV(strike, expiry)
+}
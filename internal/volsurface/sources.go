@@ -0,0 +1,205 @@
+package volsurface
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wakamex/atomizer/internal/exchange/derive"
+	"github.com/wakamex/atomizer/internal/exchange/shared"
+	"github.com/wakamex/atomizer/internal/instruments"
+)
+
+// QuoteSource supplies option quotes for an underlying
+type QuoteSource interface {
+	Name() string
+	FetchQuotes(ctx context.Context, underlying string) (spot float64, quotes []Quote, err error)
+}
+
+// DeriveSource builds quotes from Derive option tickers
+type DeriveSource struct {
+	concurrency int
+}
+
+// NewDeriveSource creates a quote source backed by the Derive public API
+func NewDeriveSource() *DeriveSource {
+	return &DeriveSource{concurrency: 8}
+}
+
+// Name returns the source name
+func (d *DeriveSource) Name() string {
+	return "derive"
+}
+
+// FetchQuotes loads all active options for an underlying and their tickers
+func (d *DeriveSource) FetchQuotes(ctx context.Context, underlying string) (float64, []Quote, error) {
+	markets, err := derive.LoadAllDeriveMarkets()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to load derive markets: %w", err)
+	}
+
+	var names []string
+	for name, inst := range markets {
+		if inst.IsActive && strings.EqualFold(inst.BaseCurrency, underlying) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, nil, fmt.Errorf("no active derive options for %s", underlying)
+	}
+
+	var (
+		quotes []Quote
+		spot   float64
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+	sem := make(chan struct{}, d.concurrency)
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		inst := markets[name]
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ticker, err := derive.FetchDeriveTicker(inst.InstrumentName)
+			if err != nil || ticker.OptionPricing == nil {
+				return
+			}
+
+			strike, err := strconv.ParseFloat(inst.OptionDetails.Strike, 64)
+			if err != nil {
+				return
+			}
+
+			// Prefer the mid of bid/ask IVs over the mark IV
+			iv := ticker.GetIV()
+			bidIV, _ := strconv.ParseFloat(ticker.OptionPricing.BidIV, 64)
+			askIV, _ := strconv.ParseFloat(ticker.OptionPricing.AskIV, 64)
+			if bidIV > 0 && askIV > 0 {
+				iv = (bidIV + askIV) / 2
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if index := ticker.GetIndexPrice(); index > 0 {
+				spot = index
+			}
+			quotes = append(quotes, Quote{
+				Strike:  strike,
+				Expiry:  inst.OptionDetails.Expiry,
+				IsPut:   inst.OptionDetails.OptionType == "P",
+				Forward: ticker.GetForwardPrice(),
+				IV:      iv,
+			})
+		}()
+	}
+	wg.Wait()
+
+	if len(quotes) == 0 {
+		return 0, nil, fmt.Errorf("no derive tickers with option pricing for %s", underlying)
+	}
+
+	return spot, quotes, nil
+}
+
+// DeribitSource builds quotes from Deribit's public book summaries
+type DeribitSource struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewDeribitSource creates a quote source backed by the Deribit public API
+func NewDeribitSource(testnet bool) *DeribitSource {
+	baseURL := "https://www.deribit.com/api/v2"
+	if testnet {
+		baseURL = "https://test.deribit.com/api/v2"
+	}
+	return &DeribitSource{
+		client:  shared.NewHTTPClient(),
+		baseURL: baseURL,
+	}
+}
+
+// Name returns the source name
+func (d *DeribitSource) Name() string {
+	return "deribit"
+}
+
+// FetchQuotes loads mark IVs for every option on an underlying in one call
+func (d *DeribitSource) FetchQuotes(ctx context.Context, underlying string) (float64, []Quote, error) {
+	url := fmt.Sprintf("%s/public/get_book_summary_by_currency?currency=%s&kind=option",
+		d.baseURL, strings.ToUpper(underlying))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch book summaries: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result []struct {
+			InstrumentName    string  `json:"instrument_name"`
+			MarkIV            float64 `json:"mark_iv"`
+			UnderlyingPrice   float64 `json:"underlying_price"`
+			EstimatedDelivery float64 `json:"estimated_delivery_price"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var (
+		spot   float64
+		quotes []Quote
+	)
+	for _, summary := range response.Result {
+		strike, expiry, isPut, err := parseDeribitOption(summary.InstrumentName)
+		if err != nil || summary.MarkIV <= 0 {
+			continue
+		}
+		if summary.EstimatedDelivery > 0 {
+			spot = summary.EstimatedDelivery
+		}
+		quotes = append(quotes, Quote{
+			Strike:  strike,
+			Expiry:  expiry,
+			IsPut:   isPut,
+			Forward: summary.UnderlyingPrice,
+			IV:      summary.MarkIV / 100, // Deribit reports vol in percent
+		})
+	}
+
+	if len(quotes) == 0 {
+		return 0, nil, fmt.Errorf("no deribit options for %s", underlying)
+	}
+
+	return spot, quotes, nil
+}
+
+// parseDeribitOption parses names like ETH-30MAY25-3000-C
+func parseDeribitOption(name string) (strike float64, expiry int64, isPut bool, err error) {
+	inst, err := instruments.Parse(name)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !inst.IsOption() {
+		return 0, 0, false, fmt.Errorf("not an option: %s", name)
+	}
+	return inst.Strike.InexactFloat64(), inst.Expiry.Unix(), inst.IsPut(), nil
+}
@@ -0,0 +1,271 @@
+package volsurface
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/pricing"
+)
+
+// Quote is a single option observation used to build a surface. Either IV
+// or Price must be set; if IV is zero it is solved from Price.
+type Quote struct {
+	Strike  float64
+	Expiry  int64 // Unix seconds
+	IsPut   bool
+	Forward float64 // Forward for the expiry, spot is used if zero
+	IV      float64 // Implied vol as a decimal
+	Price   float64 // Option price in quote currency
+}
+
+// Slice is the fitted smile for a single expiry
+type Slice struct {
+	Expiry       int64
+	Forward      float64
+	TimeToExpiry float64
+	SVI          *SVIParams // nil if there were too few strikes to fit
+
+	// Observed points sorted by log-moneyness, used when SVI is unavailable
+	ks []float64
+	ws []float64
+}
+
+// TotalVariance returns the slice's total variance at log-moneyness k
+func (s *Slice) TotalVariance(k float64) float64 {
+	if s.SVI != nil {
+		return s.SVI.TotalVariance(k)
+	}
+
+	// Linear interpolation in total variance, flat beyond the wings
+	if k <= s.ks[0] {
+		return s.ws[0]
+	}
+	last := len(s.ks) - 1
+	if k >= s.ks[last] {
+		return s.ws[last]
+	}
+	i := sort.SearchFloat64s(s.ks, k)
+	frac := (k - s.ks[i-1]) / (s.ks[i] - s.ks[i-1])
+	return s.ws[i-1] + frac*(s.ws[i]-s.ws[i-1])
+}
+
+// Surface is an implied volatility surface for a single underlying
+type Surface struct {
+	Underlying string
+	Spot       float64
+	AsOf       time.Time
+	Slices     []*Slice // Sorted by expiry
+}
+
+// IV returns the implied vol for a strike and unix expiry. Between listed
+// expiries total variance is interpolated linearly in time at constant
+// log-moneyness; outside them vol is held flat.
+func (s *Surface) IV(strike float64, expiry int64) (float64, error) {
+	if len(s.Slices) == 0 {
+		return 0, fmt.Errorf("surface for %s has no expiries", s.Underlying)
+	}
+	if strike <= 0 {
+		return 0, fmt.Errorf("invalid strike %.4f", strike)
+	}
+
+	t := pricing.YearsToExpiry(expiry, s.AsOf)
+	if t <= 0 {
+		return 0, fmt.Errorf("expiry %d is not after surface time %s", expiry, s.AsOf.Format(time.RFC3339))
+	}
+
+	idx := sort.Search(len(s.Slices), func(i int) bool { return s.Slices[i].Expiry >= expiry })
+
+	var w float64
+	switch {
+	case idx < len(s.Slices) && s.Slices[idx].Expiry == expiry:
+		slice := s.Slices[idx]
+		w = slice.TotalVariance(math.Log(strike / slice.Forward))
+	case idx == 0:
+		first := s.Slices[0]
+		w = first.TotalVariance(math.Log(strike/first.Forward)) * t / first.TimeToExpiry
+	case idx == len(s.Slices):
+		last := s.Slices[len(s.Slices)-1]
+		w = last.TotalVariance(math.Log(strike/last.Forward)) * t / last.TimeToExpiry
+	default:
+		before, after := s.Slices[idx-1], s.Slices[idx]
+		frac := (t - before.TimeToExpiry) / (after.TimeToExpiry - before.TimeToExpiry)
+		forward := before.Forward + frac*(after.Forward-before.Forward)
+		k := math.Log(strike / forward)
+		w1 := before.TotalVariance(k)
+		w2 := after.TotalVariance(k)
+		w = w1 + frac*(w2-w1)
+	}
+
+	if w <= 0 {
+		return 0, fmt.Errorf("non-positive variance at strike %.2f", strike)
+	}
+	return math.Sqrt(w / t), nil
+}
+
+// Builder accumulates quotes and fits a Surface from them
+type Builder struct {
+	underlying string
+	spot       float64
+	quotes     []Quote
+}
+
+// NewBuilder creates a surface builder for an underlying at the given spot
+func NewBuilder(underlying string, spot float64) *Builder {
+	return &Builder{
+		underlying: underlying,
+		spot:       spot,
+	}
+}
+
+// Add adds a quote to the builder
+func (b *Builder) Add(q Quote) {
+	b.quotes = append(b.quotes, q)
+}
+
+// Build solves implied vols and fits a smile for each expiry
+func (b *Builder) Build(now time.Time) (*Surface, error) {
+	if b.spot <= 0 {
+		return nil, fmt.Errorf("invalid spot %.4f for %s", b.spot, b.underlying)
+	}
+
+	byExpiry := make(map[int64][]Quote)
+	for _, q := range b.quotes {
+		if q.Strike > 0 && q.Expiry > now.Unix() {
+			byExpiry[q.Expiry] = append(byExpiry[q.Expiry], q)
+		}
+	}
+
+	surface := &Surface{
+		Underlying: b.underlying,
+		Spot:       b.spot,
+		AsOf:       now,
+	}
+
+	for expiry, quotes := range byExpiry {
+		slice, err := b.buildSlice(expiry, quotes, now)
+		if err != nil {
+			continue
+		}
+		surface.Slices = append(surface.Slices, slice)
+	}
+
+	if len(surface.Slices) == 0 {
+		return nil, fmt.Errorf("no usable quotes for %s", b.underlying)
+	}
+
+	sort.Slice(surface.Slices, func(i, j int) bool {
+		return surface.Slices[i].Expiry < surface.Slices[j].Expiry
+	})
+
+	return surface, nil
+}
+
+// buildSlice fits the smile for one expiry
+func (b *Builder) buildSlice(expiry int64, quotes []Quote, now time.Time) (*Slice, error) {
+	t := pricing.YearsToExpiry(expiry, now)
+
+	forward, n := 0.0, 0
+	for _, q := range quotes {
+		if q.Forward > 0 {
+			forward += q.Forward
+			n++
+		}
+	}
+	if n > 0 {
+		forward /= float64(n)
+	} else {
+		forward = b.spot
+	}
+
+	// Average calls and puts at the same strike
+	sums := make(map[float64]float64)
+	counts := make(map[float64]int)
+	for _, q := range quotes {
+		iv := q.IV
+		if iv <= 0 && q.Price > 0 {
+			solved, err := pricing.ImpliedVol(q.Price, forward, q.Strike, t, 0, q.IsPut)
+			if err != nil {
+				continue
+			}
+			iv = solved
+		}
+		if iv <= 0 || iv > 5 {
+			continue
+		}
+		sums[q.Strike] += iv
+		counts[q.Strike]++
+	}
+
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("no implied vols for expiry %d", expiry)
+	}
+
+	strikes := make([]float64, 0, len(sums))
+	for strike := range sums {
+		strikes = append(strikes, strike)
+	}
+	sort.Float64s(strikes)
+
+	slice := &Slice{
+		Expiry:       expiry,
+		Forward:      forward,
+		TimeToExpiry: t,
+		ks:           make([]float64, len(strikes)),
+		ws:           make([]float64, len(strikes)),
+	}
+	for i, strike := range strikes {
+		iv := sums[strike] / float64(counts[strike])
+		slice.ks[i] = math.Log(strike / forward)
+		slice.ws[i] = iv * iv * t
+	}
+
+	if len(strikes) >= minSVIPoints {
+		if params, err := FitSVI(slice.ks, slice.ws); err == nil && fitsWithin(params, slice, maxSVIError) {
+			slice.SVI = &params
+		}
+	}
+
+	return slice, nil
+}
+
+// maxSVIError is the largest vol error at an observed strike before a fit is
+// rejected in favour of interpolating the observed points
+const maxSVIError = 0.05
+
+// fitsWithin reports whether params reproduce the slice's observed vols
+func fitsWithin(params SVIParams, slice *Slice, tolerance float64) bool {
+	for i, k := range slice.ks {
+		w := params.TotalVariance(k)
+		if w <= 0 {
+			return false
+		}
+		observed := math.Sqrt(slice.ws[i] / slice.TimeToExpiry)
+		fitted := math.Sqrt(w / slice.TimeToExpiry)
+		if math.Abs(fitted-observed) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// Forward returns the forward price for a unix expiry, interpolated linearly
+// in time between listed expiries and held flat outside them
+func (s *Surface) Forward(expiry int64) float64 {
+	if len(s.Slices) == 0 {
+		return s.Spot
+	}
+
+	idx := sort.Search(len(s.Slices), func(i int) bool { return s.Slices[i].Expiry >= expiry })
+	switch {
+	case idx == 0:
+		return s.Slices[0].Forward
+	case idx == len(s.Slices):
+		return s.Slices[len(s.Slices)-1].Forward
+	}
+
+	before, after := s.Slices[idx-1], s.Slices[idx]
+	frac := float64(expiry-before.Expiry) / float64(after.Expiry-before.Expiry)
+	return before.Forward + frac*(after.Forward-before.Forward)
+}
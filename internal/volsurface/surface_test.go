@@ -0,0 +1,135 @@
+package volsurface
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wakamex/atomizer/internal/pricing"
+)
+
+func TestFitSVIRecoversSmile(t *testing.T) {
+	want := SVIParams{A: 0.01, B: 0.08, Rho: -0.4, M: 0.02, Sigma: 0.15}
+
+	var ks, ws []float64
+	for k := -0.5; k <= 0.5; k += 0.1 {
+		ks = append(ks, k)
+		ws = append(ws, want.TotalVariance(k))
+	}
+
+	got, err := FitSVI(ks, ws)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, k := range ks {
+		if diff := math.Abs(got.TotalVariance(k) - want.TotalVariance(k)); diff > 1e-4 {
+			t.Errorf("w(%.2f) off by %.6f", k, diff)
+		}
+	}
+
+	if _, err := FitSVI(ks[:3], ws[:3]); err == nil {
+		t.Error("expected error with too few points")
+	}
+}
+
+func TestSurfaceIV(t *testing.T) {
+	now := time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)
+	near := now.Add(30 * 24 * time.Hour).Unix()
+	far := now.Add(90 * 24 * time.Hour).Unix()
+
+	builder := NewBuilder("ETH", 3000)
+	for _, strike := range []float64{2400, 2700, 3000, 3300, 3600} {
+		// Skewed smile, higher vol further out of the money
+		iv := 0.6 + 0.3*math.Pow(math.Log(strike/3000), 2)
+		builder.Add(Quote{Strike: strike, Expiry: near, IV: iv, Forward: 3000})
+		builder.Add(Quote{Strike: strike, Expiry: far, IV: iv + 0.1, Forward: 3000})
+	}
+
+	surface, err := builder.Build(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(surface.Slices) != 2 {
+		t.Fatalf("expected 2 slices, got %d", len(surface.Slices))
+	}
+
+	tests := []struct {
+		name   string
+		strike float64
+		expiry int64
+		want   float64
+		tol    float64
+	}{
+		{"listed ATM", 3000, near, 0.6, 0.01},
+		{"listed wing", 3600, near, 0.6 + 0.3*math.Pow(math.Log(1.2), 2), 0.01},
+		{"unlisted strike", 3150, near, 0.6 + 0.3*math.Pow(math.Log(1.05), 2), 0.01},
+		{"before first expiry", 3000, now.Add(7 * 24 * time.Hour).Unix(), 0.6, 0.01},
+		{"after last expiry", 3000, now.Add(180 * 24 * time.Hour).Unix(), 0.7, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := surface.IV(tt.strike, tt.expiry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("IV = %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+
+	// Between expiries total variance is interpolated, so vol lies between
+	mid, err := surface.IV(3000, now.Add(60*24*time.Hour).Unix())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mid <= 0.6 || mid >= 0.7 {
+		t.Errorf("interpolated IV = %.4f, want between 0.6 and 0.7", mid)
+	}
+
+	if _, err := surface.IV(3000, now.Add(-time.Hour).Unix()); err == nil {
+		t.Error("expected error for expiry in the past")
+	}
+}
+
+func TestBuilderSolvesIVFromPrice(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(30 * 24 * time.Hour).Unix()
+	tte := pricing.YearsToExpiry(expiry, now)
+
+	builder := NewBuilder("BTC", 60000)
+	price := pricing.BlackScholes(60000, 65000, tte, 0, 0.55, false).Price
+	builder.Add(Quote{Strike: 65000, Expiry: expiry, Price: price})
+
+	surface, err := builder.Build(now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := surface.IV(65000, expiry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(got-0.55) > 1e-3 {
+		t.Errorf("IV = %.4f, want 0.55", got)
+	}
+}
+
+func TestParseDeribitOption(t *testing.T) {
+	strike, expiry, isPut, err := parseDeribitOption("ETH-30MAY25-3000-P")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strike != 3000 || !isPut {
+		t.Errorf("got strike=%.0f isPut=%v", strike, isPut)
+	}
+	if want := time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC).Unix(); expiry != want {
+		t.Errorf("expiry = %d, want %d", expiry, want)
+	}
+
+	if _, _, _, err := parseDeribitOption("ETH-PERPETUAL"); err == nil {
+		t.Error("expected error for non-option")
+	}
+}
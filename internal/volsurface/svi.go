@@ -0,0 +1,155 @@
+package volsurface
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// minSVIPoints is the number of strikes needed to fit the five SVI parameters
+const minSVIPoints = 5
+
+// SVIParams are raw SVI parameters for one expiry. Total implied variance at
+// log-moneyness k is w(k) = A + B*(Rho*(k-M) + sqrt((k-M)^2 + Sigma^2)).
+type SVIParams struct {
+	A     float64
+	B     float64
+	Rho   float64
+	M     float64
+	Sigma float64
+}
+
+// TotalVariance returns the total implied variance at log-moneyness k
+func (p SVIParams) TotalVariance(k float64) float64 {
+	x := k - p.M
+	return p.A + p.B*(p.Rho*x+math.Sqrt(x*x+p.Sigma*p.Sigma))
+}
+
+// valid reports whether the parameters give a non-negative smile
+func (p SVIParams) valid() bool {
+	return p.B >= 0 && math.Abs(p.Rho) < 1 && p.Sigma > 0 &&
+		p.A+p.B*p.Sigma*math.Sqrt(1-p.Rho*p.Rho) >= 0
+}
+
+// FitSVI fits raw SVI parameters to total variances ws at log-moneyness ks
+func FitSVI(ks, ws []float64) (SVIParams, error) {
+	if len(ks) != len(ws) {
+		return SVIParams{}, fmt.Errorf("mismatched inputs: %d strikes, %d variances", len(ks), len(ws))
+	}
+	if len(ks) < minSVIPoints {
+		return SVIParams{}, fmt.Errorf("need at least %d points to fit SVI, got %d", minSVIPoints, len(ks))
+	}
+
+	minW, meanW := math.Inf(1), 0.0
+	for _, w := range ws {
+		minW = math.Min(minW, w)
+		meanW += w
+	}
+	meanW /= float64(len(ws))
+	if meanW <= 0 {
+		return SVIParams{}, fmt.Errorf("total variances must be positive")
+	}
+
+	objective := func(x []float64) float64 {
+		p := SVIParams{A: x[0], B: x[1], Rho: x[2], M: x[3], Sigma: x[4]}
+		if !p.valid() {
+			return math.Inf(1)
+		}
+		sum := 0.0
+		for i, k := range ks {
+			diff := (p.TotalVariance(k) - ws[i]) / meanW
+			sum += diff * diff
+		}
+		return sum
+	}
+
+	start := []float64{minW * 0.9, meanW, -0.3, 0, 0.1}
+	step := []float64{meanW * 0.5, meanW, 0.3, 0.1, 0.1}
+	best := nelderMead(objective, start, step, 4000)
+
+	params := SVIParams{A: best[0], B: best[1], Rho: best[2], M: best[3], Sigma: best[4]}
+	if !params.valid() {
+		return SVIParams{}, fmt.Errorf("SVI fit did not converge to valid parameters")
+	}
+	return params, nil
+}
+
+// nelderMead minimises f starting from x0 using the downhill simplex method
+func nelderMead(f func([]float64) float64, x0, step []float64, maxIter int) []float64 {
+	n := len(x0)
+	simplex := make([][]float64, n+1)
+	values := make([]float64, n+1)
+	for i := range simplex {
+		simplex[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			simplex[i][i-1] += step[i-1]
+		}
+		values[i] = f(simplex[i])
+	}
+
+	point := func(base, dir []float64, scale float64) []float64 {
+		out := make([]float64, n)
+		for j := range out {
+			out[j] = base[j] + scale*(dir[j]-base[j])
+		}
+		return out
+	}
+
+	for iter := 0; iter < maxIter; iter++ {
+		order := make([]int, n+1)
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+		sorted := make([][]float64, n+1)
+		sortedValues := make([]float64, n+1)
+		for i, idx := range order {
+			sorted[i], sortedValues[i] = simplex[idx], values[idx]
+		}
+		simplex, values = sorted, sortedValues
+
+		if math.Abs(values[n]-values[0]) < 1e-14 {
+			break
+		}
+
+		centroid := make([]float64, n)
+		for i := 0; i < n; i++ {
+			for j := range centroid {
+				centroid[j] += simplex[i][j] / float64(n)
+			}
+		}
+
+		reflected := point(centroid, simplex[n], -1)
+		fr := f(reflected)
+		switch {
+		case fr < values[0]:
+			expanded := point(centroid, simplex[n], -2)
+			if fe := f(expanded); fe < fr {
+				simplex[n], values[n] = expanded, fe
+			} else {
+				simplex[n], values[n] = reflected, fr
+			}
+		case fr < values[n-1]:
+			simplex[n], values[n] = reflected, fr
+		default:
+			contracted := point(centroid, simplex[n], 0.5)
+			if fc := f(contracted); fc < values[n] {
+				simplex[n], values[n] = contracted, fc
+				continue
+			}
+			// Shrink towards the best point
+			for i := 1; i <= n; i++ {
+				simplex[i] = point(simplex[0], simplex[i], 0.5)
+				values[i] = f(simplex[i])
+			}
+		}
+	}
+
+	bestIdx := 0
+	for i := range values {
+		if values[i] < values[bestIdx] {
+			bestIdx = i
+		}
+	}
+	return simplex[bestIdx]
+}
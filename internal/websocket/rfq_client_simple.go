@@ -8,6 +8,7 @@ import (
 
 	"github.com/wakamex/atomizer/internal/arbitrage"
 	"github.com/wakamex/atomizer/internal/config"
+	"github.com/wakamex/atomizer/internal/instruments"
 	"github.com/wakamex/atomizer/internal/rfq"
 	"github.com/wakamex/atomizer/internal/types"
 )
@@ -115,12 +116,10 @@ func (c *SimpleRFQClient) handleTradeConfirmation(notification types.RFQNotifica
 	}
 	
 	// Check asset mapping
-	if c.config.AssetMapping != nil {
-		if underlying, hasMapping := c.config.AssetMapping[conf.AssetAddress]; hasMapping {
-			log.Printf("Trade confirmation for %s (%s), Quote ID: %s", conf.AssetAddress, underlying, conf.QuoteNonce)
-		} else {
-			log.Printf("WARNING: No asset mapping for %s. Cannot hedge.", conf.AssetAddress)
-		}
+	if underlying, err := instruments.ResolveRyskAsset(conf.ChainID, conf.AssetAddress); err == nil {
+		log.Printf("Trade confirmation for %s (%s), Quote ID: %s", conf.AssetAddress, underlying, conf.QuoteNonce)
+	} else {
+		log.Printf("WARNING: No asset mapping for %s on chain %d. Cannot hedge.", conf.AssetAddress, conf.ChainID)
 	}
 	
 	// Get RFQ result for additional context
@@ -129,6 +128,9 @@ func (c *SimpleRFQClient) handleTradeConfirmation(notification types.RFQNotifica
 		rfqResult = notification.Result
 	}
 	
+	// Match the trade to the quote it filled
+	c.processor.RecordConfirmation(rfqResult.RFQId, conf)
+	
 	// Submit to orchestrator
 	if err := c.orchestrator.SubmitRFQTrade(rfqResult, conf); err != nil {
 		log.Printf("Failed to submit trade to orchestrator: %v", err)